@@ -0,0 +1,78 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+//==============================================================================================================================
+//	 BondHash - Returns the hash of the bond as the chaincode computes it, the sha256 of its JSON. Bond mirrors the
+//				fields of the chaincode in order so it marshals to the same JSON.
+//==============================================================================================================================
+func BondHash(b Bond) (string, error) {
+
+	bytes, err := json.Marshal(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bytes)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//==============================================================================================================================
+//	 MerkleRoot - Returns the Merkle root over the hex hashes by realEstateID, taken in realEstateID order as
+//				  get_registry_checksum does.
+//==============================================================================================================================
+func MerkleRoot(hashes map[string]string) (string, error) {
+
+	ids := make([]string, 0, len(hashes))
+
+	for id := range hashes {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	level := make([][]byte, 0, len(ids))
+
+	for _, id := range ids {
+
+		leaf, err := hex.DecodeString(hashes[id])
+
+		if err != nil {
+			return "", err
+		}
+
+		level = append(level, leaf)
+	}
+
+	if len(level) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	for len(level) > 1 {
+
+		var next [][]byte
+
+		for i := 0; i < len(level); i += 2 {
+
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+
+			sum := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+
+		level = next
+	}
+
+	return hex.EncodeToString(level[0]), nil
+}
@@ -0,0 +1,383 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+//==============================================================================================================================
+//	 Bond Registry Client - Typed access to the chaincode for Go applications. Methods take and return the records of
+//							the chaincode rather than argument lists and payloads, carry sensitive values in the
+//							transient map, and turn rejected transactions into an *Error, see errors.go.
+//
+//		registry := client.New(client.FromGateway(network.GetContract("learn-chaincode")))
+//		id, err := registry.CreateBond(ctx, client.Bond{RealEstateID: "1232.21", ...})
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Contract - The chaincode the client calls. Evaluate queries a peer, Submit orders a transaction and waits for it
+//				to commit. Both return the payload of the response.
+//==============================================================================================================================
+type Contract interface {
+	Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error)
+	Submit(function string, transient map[string][]byte, args ...string) ([]byte, error)
+}
+
+// Types of owner identifier, see the owner identifiers of the chaincode
+const OWNER_NATIONAL_ID = "national_id"
+const OWNER_CR = "cr"
+
+//==============================================================================================================================
+//	 Bond and the records it is made of, as the chaincode stores them.
+//==============================================================================================================================
+type Bond struct {
+	ID              string      `json:"id"`
+	RealEstateID    string      `json:"real_estate_id"`
+	OwnerNationalID string      `json:"owner_national_id"`
+	OwnerIDType     string      `json:"owner_id_type,omitempty"` // OWNER_CR for a company, a national ID when empty
+	OwnerMSP        string      `json:"owner_msp,omitempty"`
+	Status          string      `json:"status"`
+	Area            Land_Area   `json:"area"`
+	Coordinates     Coordinates `json:"coordinates"`
+	Boundary        *Polygon    `json:"boundary,omitempty"`
+	Geohash         string      `json:"geohash"`
+	CreatedAt       string      `json:"created_at,omitempty"`
+	UpdatedAt       string      `json:"updated_at"`
+	CreatedTx       string      `json:"created_tx,omitempty"`
+	LastModifiedTx  string      `json:"last_modified_tx,omitempty"`
+	DistrictCode    string      `json:"district_code,omitempty"`
+	CityCode        string      `json:"city_code,omitempty"`
+	Street          string      `json:"street,omitempty"`
+	ExportedTo      string      `json:"exported_to,omitempty"`
+	TokenID         string      `json:"token_id,omitempty"`
+	Provenance      *Provenance `json:"provenance,omitempty"`
+	SchemaVersion   int         `json:"schema_version"`
+	Version         int         `json:"version"`
+}
+
+type Provenance struct {
+	Origin           string `json:"origin"`
+	LegacyDeedNumber string `json:"legacy_deed_number"`
+	MigrationBatch   string `json:"migration_batch"`
+	MigratedAt       string `json:"migrated_at"`
+	MigratedBy       string `json:"migrated_by"`
+}
+
+type Land_Area struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type Coordinates struct {
+	Long    float64 `json:"long"`
+	Lat     float64 `json:"lat"`
+	Unknown bool    `json:"unknown,omitempty"` // legacy coordinates that did not parse
+}
+
+type Polygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+//==============================================================================================================================
+//	 Transfer - A change of ownership. A declared value needs a salt, see the sale prices of the chaincode.
+//==============================================================================================================================
+type Transfer struct {
+	RecipientNationalID string
+	RecipientMSP        string // the organisation of the current owner when empty
+	RecipientIDType     string // OWNER_CR for a company, a national ID when empty
+	DeclaredValue       float64
+	Salt                string
+}
+
+//==============================================================================================================================
+//	 CallOption - Sets a transient field of a change.
+//==============================================================================================================================
+type CallOption func(transient map[string][]byte)
+
+//==============================================================================================================================
+//	 WithIdempotencyKey - Makes a retried change with the same key return the result of the first instead of running
+//						  again.
+//==============================================================================================================================
+func WithIdempotencyKey(key string) CallOption {
+	return func(transient map[string][]byte) {
+		transient["idempotency_key"] = []byte(key)
+	}
+}
+
+//==============================================================================================================================
+//	 WithExpectedVersion - Rejects the change with VERSION_CONFLICT unless the bond is still at the version given.
+//==============================================================================================================================
+func WithExpectedVersion(version int) CallOption {
+	return func(transient map[string][]byte) {
+		transient["expected_version"] = []byte(strconv.Itoa(version))
+	}
+}
+
+//==============================================================================================================================
+//	 BondRegistryClient - The client, safe for concurrent use if its contract is.
+//==============================================================================================================================
+type BondRegistryClient struct {
+	contract Contract
+}
+
+//==============================================================================================================================
+//	 New - Returns a client calling the contract.
+//==============================================================================================================================
+func New(contract Contract) *BondRegistryClient {
+	return &BondRegistryClient{contract: contract}
+}
+
+//==============================================================================================================================
+//	 CreateBond - Registers the bond and returns its ID, generated by the chaincode when the bond has none. The owner
+//				  is passed in the transient map.
+//==============================================================================================================================
+func (c *BondRegistryClient) CreateBond(ctx context.Context, b Bond, options ...CallOption) (string, error) {
+
+	args, transient, err := create_args(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := c.submit(ctx, "create_bond", transient, options, args...)
+
+	return string(payload), err
+}
+
+//==============================================================================================================================
+//	 MigrateLegacyBond - Creates the bond as migrated from the legacy deed in the batch and returns its ID. Admin only.
+//==============================================================================================================================
+func (c *BondRegistryClient) MigrateLegacyBond(ctx context.Context, batchID string, deedNumber string, b Bond, options ...CallOption) (string, error) {
+
+	args, transient, err := create_args(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := c.submit(ctx, "migrate_legacy_bond", transient, options, append([]string{batchID, deedNumber}, args...)...)
+
+	return string(payload), err
+}
+
+//==============================================================================================================================
+//	 create_args - Returns the arguments and transient map of create_bond for the bond.
+//==============================================================================================================================
+func create_args(b Bond) ([]string, map[string][]byte, error) {
+
+	boundary := ""
+
+	if b.Boundary != nil {
+		bytes, err := json.Marshal(b.Boundary)
+		if err != nil {
+			return nil, nil, err
+		}
+		boundary = string(bytes)
+	}
+
+	area := ""
+
+	if b.Area.Value != 0 {
+		area = strconv.FormatFloat(b.Area.Value, 'f', -1, 64) + " " + b.Area.Unit
+	}
+
+	transient := map[string][]byte{"owner_national_id": []byte(b.OwnerNationalID)}
+
+	args := []string{
+		b.ID,
+		b.RealEstateID,
+		"",
+		b.Status,
+		area,
+		strconv.FormatFloat(b.Coordinates.Long, 'f', -1, 64),
+		strconv.FormatFloat(b.Coordinates.Lat, 'f', -1, 64),
+		boundary,
+		b.DistrictCode,
+		"",
+		b.Street,
+		b.OwnerIDType,
+	}
+
+	return args, transient, nil
+}
+
+//==============================================================================================================================
+//	 TransferOwnership - Transfers the bond to the recipient. The recipient and declared value are passed in the
+//						 transient map.
+//==============================================================================================================================
+func (c *BondRegistryClient) TransferOwnership(ctx context.Context, realEstateID string, t Transfer, options ...CallOption) error {
+
+	transient := map[string][]byte{"recipient_national_id": []byte(t.RecipientNationalID)}
+
+	if t.DeclaredValue > 0 {
+		transient["declared_value"] = []byte(strconv.FormatFloat(t.DeclaredValue, 'f', -1, 64))
+		transient["salt"] = []byte(t.Salt)
+	}
+
+	_, err := c.submit(ctx, "tranfer_bond", transient, options, realEstateID, "", "", t.RecipientMSP, t.RecipientIDType)
+
+	return err
+}
+
+//==============================================================================================================================
+//	 GetBond - Returns the bond with the realEstateID.
+//==============================================================================================================================
+func (c *BondRegistryClient) GetBond(ctx context.Context, realEstateID string) (Bond, error) {
+
+	var b Bond
+
+	err := c.evaluate(ctx, &b, "get_bond_details", realEstateID)
+
+	return b, err
+}
+
+//==============================================================================================================================
+//	 QueryByOwner - Returns every bond held by the owner with the national ID.
+//==============================================================================================================================
+func (c *BondRegistryClient) QueryByOwner(ctx context.Context, nationalID string) ([]Bond, error) {
+
+	var bonds []Bond
+
+	err := c.evaluate(ctx, &bonds, "get_bonds_by_owner", nationalID)
+
+	return bonds, err
+}
+
+//==============================================================================================================================
+//	 evaluate - Queries the function and decodes the data of its response into v.
+//==============================================================================================================================
+func (c *BondRegistryClient) evaluate(ctx context.Context, v interface{}, function string, args ...string) error {
+
+	payload, err := call(ctx, function, func() ([]byte, error) {
+		return c.contract.Evaluate(function, nil, args...)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(payload, v)
+}
+
+//==============================================================================================================================
+//	 submit - Submits the function with the transient fields and call options given and returns the data of its
+//			  response.
+//==============================================================================================================================
+func (c *BondRegistryClient) submit(ctx context.Context, function string, transient map[string][]byte, options []CallOption, args ...string) ([]byte, error) {
+
+	for _, option := range options {
+		option(transient)
+	}
+
+	return call(ctx, function, func() ([]byte, error) {
+		return c.contract.Submit(function, transient, args...)
+	})
+}
+
+//==============================================================================================================================
+//	 call - Runs the call of the function unless the context is done, and unwraps its response. The SDK calls cannot be
+//			cancelled, so a transaction abandoned when the context is done may still commit.
+//==============================================================================================================================
+func call(ctx context.Context, function string, run func() ([]byte, error)) ([]byte, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		payload []byte
+		err     error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		payload, err := run()
+		done <- result{payload, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			return nil, from_error(function, r.err)
+		}
+		return unwrap(r.payload), nil
+	}
+}
+
+//==============================================================================================================================
+//	 Audit_Record - A change made to a bond, see get_audit_log.
+//==============================================================================================================================
+type Audit_Record struct {
+	RealEstateID string `json:"real_estate_id"`
+	Function     string `json:"function"`
+	Actor        string `json:"actor"`
+	TxID         string `json:"txid"`
+	Timestamp    string `json:"timestamp"`
+	BeforeHash   string `json:"before_hash,omitempty"`
+	AfterHash    string `json:"after_hash"`
+}
+
+//==============================================================================================================================
+//	 Exported_Bond and Export_Page - A page of export_bonds. Bookmark is empty once the last page has been returned.
+//==============================================================================================================================
+type Exported_Bond struct {
+	Bond    Bond     `json:"bond"`
+	Indexes []string `json:"indexes"`
+	Hash    string   `json:"hash"`
+}
+
+type Export_Page struct {
+	Bonds    []Exported_Bond `json:"bonds"`
+	Bookmark string          `json:"bookmark"`
+}
+
+//==============================================================================================================================
+//	 History - Returns the changes made to the bond, oldest first. Needs an admin identity.
+//==============================================================================================================================
+func (c *BondRegistryClient) History(ctx context.Context, realEstateID string) ([]Audit_Record, error) {
+
+	var records []Audit_Record
+
+	err := c.evaluate(ctx, &records, "get_audit_log", realEstateID)
+
+	return records, err
+}
+
+//==============================================================================================================================
+//	 ExportBonds - Returns up to pageSize bonds following the bookmark of the previous page, from the first bond when
+//				   the bookmark is empty.
+//==============================================================================================================================
+func (c *BondRegistryClient) ExportBonds(ctx context.Context, pageSize int, bookmark string) (Export_Page, error) {
+
+	var page Export_Page
+
+	err := c.evaluate(ctx, &page, "export_bonds", strconv.Itoa(pageSize), bookmark)
+
+	return page, err
+}
+
+//==============================================================================================================================
+//	 Registry_Checksum - The Merkle root over every bond, see get_registry_checksum.
+//==============================================================================================================================
+type Registry_Checksum struct {
+	Algorithm  string `json:"algorithm"`
+	TotalBonds int    `json:"total_bonds"`
+	MerkleRoot string `json:"merkle_root"`
+}
+
+//==============================================================================================================================
+//	 RegistryChecksum - Returns the Merkle root over every bond on the ledger, to check an off-chain copy against.
+//==============================================================================================================================
+func (c *BondRegistryClient) RegistryChecksum(ctx context.Context) (Registry_Checksum, error) {
+
+	var checksum Registry_Checksum
+
+	err := c.evaluate(ctx, &checksum, "get_registry_checksum")
+
+	return checksum, err
+}
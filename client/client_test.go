@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+//==============================================================================================================================
+//	 fake_contract - Records the last call and answers with the payload or error given.
+//==============================================================================================================================
+type fake_contract struct {
+	function  string
+	transient map[string][]byte
+	args      []string
+
+	payload string
+	err     error
+}
+
+func (f *fake_contract) Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return f.Submit(function, transient, args...)
+}
+
+func (f *fake_contract) Submit(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	f.function, f.transient, f.args = function, transient, args
+
+	return []byte(f.payload), f.err
+}
+
+func TestCreateBond(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":"","data":"bond-1"}`}
+
+	b := Bond{
+		RealEstateID:    "1232.21",
+		OwnerNationalID: "1000000001",
+		Status:          "built",
+		Area:            Land_Area{Value: 500, Unit: "m2"},
+		Coordinates:     Coordinates{Long: 46.6753, Lat: 24.7136},
+		DistrictCode:    "RUH-01",
+	}
+
+	id, err := New(f).CreateBond(context.Background(), b, WithIdempotencyKey("key-1"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "bond-1" {
+		t.Errorf("Expected ID bond-1, got %q", id)
+	}
+
+	args := []string{"", "1232.21", "", "built", "500 m2", "46.6753", "24.7136", "", "RUH-01", "", "", ""}
+
+	if f.function != "create_bond" || !reflect.DeepEqual(f.args, args) {
+		t.Errorf("Unexpected call %s %q", f.function, f.args)
+	}
+
+	if string(f.transient["owner_national_id"]) != "1000000001" || string(f.transient["idempotency_key"]) != "key-1" {
+		t.Errorf("Unexpected transient map %q", f.transient)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":""}`}
+
+	transfer := Transfer{RecipientNationalID: "1000000002", DeclaredValue: 750000, Salt: "s"}
+
+	err := New(f).TransferOwnership(context.Background(), "1232.21", transfer, WithExpectedVersion(3))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "tranfer_bond" || !reflect.DeepEqual(f.args, []string{"1232.21", "", "", "", ""}) {
+		t.Errorf("Unexpected call %s %q", f.function, f.args)
+	}
+
+	expected := map[string]string{"recipient_national_id": "1000000002", "declared_value": "750000", "salt": "s", "expected_version": "3"}
+
+	for k, v := range expected {
+		if string(f.transient[k]) != v {
+			t.Errorf("Expected transient %s %q, got %q", k, v, f.transient[k])
+		}
+	}
+}
+
+func TestQueryByOwner(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":"","data":[{"real_estate_id":"1232.21","version":2}]}`}
+
+	bonds, err := New(f).QueryByOwner(context.Background(), "1000000001")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "get_bonds_by_owner" || len(bonds) != 1 || bonds[0].RealEstateID != "1232.21" || bonds[0].Version != 2 {
+		t.Errorf("Unexpected result of %s: %+v", f.function, bonds)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+
+	f := &fake_contract{err: errors.New(`transaction returned with failure: {"status":500,"code":"BOND_NOT_FOUND","message":"No bond 9"}`)}
+
+	_, err := New(f).GetBond(context.Background(), "9")
+
+	if ErrorCode(err) != CODE_BOND_NOT_FOUND {
+		t.Errorf("Expected %s, got %v", CODE_BOND_NOT_FOUND, err)
+	}
+
+	f.err = errors.New("no peers available")
+
+	_, err = New(f).GetBond(context.Background(), "9")
+
+	if ErrorCode(err) != CODE_ERROR || err != f.err {
+		t.Errorf("Expected the SDK error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = New(f).GetBond(ctx, "9")
+
+	if err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+)
+
+//==============================================================================================================================
+//	 Errors - A transaction the chaincode rejects fails with an *Error carrying the code of its response envelope, so
+//			  callers can act on the code rather than the wording of the message, e.g.
+//
+//		if client.ErrorCode(err) == client.CODE_BOND_NOT_FOUND { ... }
+//
+//			  Errors that never reached the chaincode, such as no peer answering, are returned as the SDK gave them.
+//==============================================================================================================================
+
+const CODE_OK = "OK"
+const CODE_ERROR = "ERROR"
+const CODE_BOND_NOT_FOUND = "BOND_NOT_FOUND"
+const CODE_BOND_EXISTS = "BOND_EXISTS"
+const CODE_NOT_AUTHORIZED = "NOT_AUTHORIZED"
+const CODE_INVALID_STATE = "INVALID_STATE"
+const CODE_VERSION_CONFLICT = "VERSION_CONFLICT"
+const CODE_INVALID_ARGUMENT = "INVALID_ARGUMENT"
+const CODE_UNKNOWN_FUNCTION = "UNKNOWN_FUNCTION"
+const CODE_LEDGER_ERROR = "LEDGER_ERROR"
+const CODE_CORRUPT_RECORD = "CORRUPT_RECORD"
+
+//==============================================================================================================================
+//	 Envelope - The response envelope every function of the chaincode answers with.
+//==============================================================================================================================
+type Envelope struct {
+	Status  int32           `json:"status"`
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Error - A response of the chaincode that is not OK.
+//==============================================================================================================================
+type Error struct {
+	Function string
+	Code     string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return e.Function + ": " + e.Code + ": " + e.Message
+}
+
+//==============================================================================================================================
+//	 ErrorCode - Returns the code of an error returned by the client, ERROR for errors that are not from the chaincode.
+//==============================================================================================================================
+func ErrorCode(err error) string {
+
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+
+	return CODE_ERROR
+}
+
+//==============================================================================================================================
+//	 unwrap - Returns the data of a successful response. A payload that is not an envelope is returned as it is.
+//==============================================================================================================================
+func unwrap(payload []byte) []byte {
+
+	var e Envelope
+
+	if json.Unmarshal(payload, &e) != nil || e.Code == "" {
+		return payload
+	}
+
+	var s string
+
+	if json.Unmarshal(e.Data, &s) == nil {
+		return []byte(s) // a payload that was not JSON, such as the ID returned by create_bond
+	}
+
+	return e.Data
+}
+
+//==============================================================================================================================
+//	 from_error - Converts the error of a rejected transaction into an *Error when it carries the envelope of the
+//				  chaincode. The SDK puts the message of the response into its own error text.
+//==============================================================================================================================
+func from_error(function string, err error) error {
+
+	message := err.Error()
+
+	if s, ok := status.FromError(err); ok {
+		message = s.Message
+	}
+
+	i := strings.Index(message, "{")
+
+	if i < 0 {
+		return err
+	}
+
+	var e Envelope
+
+	if json.NewDecoder(strings.NewReader(message[i:])).Decode(&e) != nil || e.Code == "" {
+		return err
+	}
+
+	return &Error{Function: function, Code: e.Code, Message: e.Message}
+}
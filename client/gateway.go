@@ -0,0 +1,53 @@
+package client
+
+import (
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Gateway_Contract - A Contract calling the chaincode through the gateway of the Fabric SDK.
+//==============================================================================================================================
+type Gateway_Contract struct {
+	contract *gateway.Contract
+}
+
+//==============================================================================================================================
+//	 FromGateway - Returns the contract of the SDK as a Contract of the client.
+//==============================================================================================================================
+func FromGateway(contract *gateway.Contract) *Gateway_Contract {
+	return &Gateway_Contract{contract: contract}
+}
+
+func (g *Gateway_Contract) Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	tx, err := g.transaction(function, transient)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Evaluate(args...)
+}
+
+func (g *Gateway_Contract) Submit(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	tx, err := g.transaction(function, transient)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Submit(args...)
+}
+
+//==============================================================================================================================
+//	 transaction - Returns a transaction of the function carrying the transient fields given.
+//==============================================================================================================================
+func (g *Gateway_Contract) transaction(function string, transient map[string][]byte) (*gateway.Transaction, error) {
+
+	if len(transient) == 0 {
+		return g.contract.CreateTransaction(function)
+	}
+
+	return g.contract.CreateTransaction(function, gateway.WithTransient(transient))
+}
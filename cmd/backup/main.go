@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Registry Backup - Takes signed snapshots of every bond and verifies them, see snapshot.go.
+//
+//		snapshot	Pages through export_bonds into a snapshot in -dir, signed with -key. Exits with 1 when bonds changed
+//					while it was taken, so a scheduler can retry; the snapshot is kept either way.
+//		verify		Checks the snapshot of -manifest was signed by -pubkey and is intact. With -ledger its Merkle root is
+//					also compared with get_registry_checksum, which matches while no bond has changed since. Exits with
+//					1 on any mismatch.
+//
+//		backup -mode snapshot -dir /backups -key signer.pem
+//		backup -mode verify -manifest /backups/<sha256>.manifest.json -pubkey signer-cert.pem -ledger
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 connect - Connects as the identity given, returning the registry client and a function closing the connection.
+//==============================================================================================================================
+func connect(profile string, walletPath string, identity string, channel string, chaincode string) (*client.BondRegistryClient, func()) {
+
+	wallet, err := gateway.NewFileSystemWallet(walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", walletPath, err)
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(config.FromFile(profile)), gateway.WithIdentity(wallet, identity))
+
+	if err != nil {
+		log.Fatalf("Error connecting as %s: %s", identity, err)
+	}
+
+	network, err := gw.GetNetwork(channel)
+
+	if err != nil {
+		log.Fatalf("Error joining channel %s: %s", channel, err)
+	}
+
+	return client.New(client.FromGateway(network.GetContract(chaincode))), gw.Close
+}
+
+//==============================================================================================================================
+//	 main - Runs the mode given.
+//==============================================================================================================================
+func main() {
+
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet")
+	identity := flag.String("identity", "backup", "label of the identity in the wallet to read as, export_bonds needs an admin")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	mode := flag.String("mode", "snapshot", "snapshot or verify")
+	dir := flag.String("dir", ".", "directory to write the snapshot to")
+	keyPath := flag.String("key", "", "PEM ECDSA private key signing the snapshot")
+	pageSize := flag.Int("page-size", 500, "bonds to fetch per query")
+	manifestPath := flag.String("manifest", "", "manifest of the snapshot to verify")
+	publicKeyPath := flag.String("pubkey", "", "PEM ECDSA public key or certificate of the signer")
+	ledger := flag.Bool("ledger", false, "also compare the snapshot with the ledger")
+	timeout := flag.Duration("timeout", 30*time.Minute, "time allowed for the snapshot or verification")
+
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch *mode {
+	case "snapshot":
+
+		key, err := read_private_key(*keyPath)
+
+		if err != nil {
+			log.Fatalf("Error reading key: %s", err)
+		}
+
+		registry, disconnect := connect(*profile, *walletPath, *identity, *channel, *chaincode)
+		defer disconnect()
+
+		m, err := take_snapshot(ctx, registry, *dir, key, *pageSize)
+
+		if err != nil {
+			log.Fatalf("Error taking snapshot: %s", err)
+		}
+
+		json.NewEncoder(os.Stdout).Encode(m)
+
+		if !m.Consistent {
+			log.Printf("Snapshot root %s does not match ledger root %s, bonds changed while it was taken", m.MerkleRoot, m.LedgerRoot)
+			disconnect()
+			os.Exit(1)
+		}
+
+	case "verify":
+
+		public, err := read_public_key(*publicKeyPath)
+
+		if err != nil {
+			log.Fatalf("Error reading public key: %s", err)
+		}
+
+		v, err := verify_snapshot(*manifestPath, public)
+
+		if err != nil {
+			log.Fatalf("Error verifying snapshot: %s", err)
+		}
+
+		if *ledger {
+
+			registry, disconnect := connect(*profile, *walletPath, *identity, *channel, *chaincode)
+
+			checksum, err := registry.RegistryChecksum(ctx)
+
+			disconnect()
+
+			if err != nil {
+				log.Fatalf("Error reading registry checksum: %s", err)
+			}
+
+			matches := checksum.MerkleRoot == v.Manifest.MerkleRoot
+
+			v.LedgerRoot, v.LedgerMatches = checksum.MerkleRoot, &matches
+		}
+
+		json.NewEncoder(os.Stdout).Encode(v)
+
+		if !v.Valid || (v.LedgerMatches != nil && !*v.LedgerMatches) {
+			os.Exit(1)
+		}
+
+	default:
+		log.Fatalf("Unknown mode %s, expecting snapshot or verify", *mode)
+	}
+}
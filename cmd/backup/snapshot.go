@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 Snapshots - A snapshot is three files named by the sha256 of its content, so a snapshot cannot be changed without
+//				 its name no longer matching:
+//
+//		<sha256>.jsonl			every bond as export_bonds returned it, one per line
+//		<sha256>.manifest.json	what was taken and when, with the Merkle root of the bonds
+//		<sha256>.manifest.sig	base64 ECDSA signature over the sha256 of the manifest file
+//
+//				 The Merkle root is recomputed from the bonds on verification, so a snapshot can be checked against
+//				 get_registry_checksum as well as against its own manifest.
+//==============================================================================================================================
+
+const SNAPSHOT_SCHEMA = "registry-snapshot"
+const SNAPSHOT_SCHEMA_VERSION = 1
+
+//==============================================================================================================================
+//	 Manifest - The description of a snapshot. Consistent is whether the ledger checksum read once every page was
+//				exported matched the bonds exported, false when bonds changed while the snapshot was taken.
+//==============================================================================================================================
+type Manifest struct {
+	Schema        string `json:"schema"`
+	SchemaVersion int    `json:"schema_version"`
+	Snapshot      string `json:"snapshot"` // name of the .jsonl file
+	ContentSHA256 string `json:"content_sha256"`
+	TakenAt       string `json:"taken_at"`
+	TotalBonds    int    `json:"total_bonds"`
+	MerkleRoot    string `json:"merkle_root"`
+	LedgerRoot    string `json:"ledger_root"`
+	Consistent    bool   `json:"consistent"`
+}
+
+//==============================================================================================================================
+//	 take_snapshot - Writes a snapshot of every bond into the directory, signed with the key. Returns its manifest.
+//==============================================================================================================================
+func take_snapshot(ctx context.Context, registry *client.BondRegistryClient, dir string, key *ecdsa.PrivateKey, pageSize int) (Manifest, error) {
+
+	m := Manifest{Schema: SNAPSHOT_SCHEMA, SchemaVersion: SNAPSHOT_SCHEMA_VERSION, TakenAt: time.Now().UTC().Format("2006-01-02T15:04:05Z")}
+
+	temp, err := ioutil.TempFile(dir, ".snapshot-")
+
+	if err != nil {
+		return m, err
+	}
+
+	defer os.Remove(temp.Name()) // fails harmlessly once renamed
+
+	content := sha256.New()
+	out := bufio.NewWriter(io.MultiWriter(temp, content))
+	encoder := json.NewEncoder(out)
+	hashes := make(map[string]string)
+	bookmark := ""
+
+	for {
+
+		page, err := registry.ExportBonds(ctx, pageSize, bookmark)
+
+		if err != nil {
+			temp.Close()
+			return m, err
+		}
+
+		for _, e := range page.Bonds {
+
+			if err := encoder.Encode(e); err != nil {
+				temp.Close()
+				return m, err
+			}
+
+			hashes[e.Bond.RealEstateID] = e.Hash
+		}
+
+		if page.Bookmark == "" {
+			break
+		}
+
+		bookmark = page.Bookmark
+	}
+
+	if err := out.Flush(); err != nil {
+		temp.Close()
+		return m, err
+	}
+
+	if err := temp.Close(); err != nil {
+		return m, err
+	}
+
+	m.TotalBonds = len(hashes)
+
+	m.MerkleRoot, err = client.MerkleRoot(hashes)
+
+	if err != nil {
+		return m, err
+	}
+
+	checksum, err := registry.RegistryChecksum(ctx)
+
+	if err != nil {
+		return m, err
+	}
+
+	m.LedgerRoot = checksum.MerkleRoot
+	m.Consistent = m.LedgerRoot == m.MerkleRoot
+	m.ContentSHA256 = hex.EncodeToString(content.Sum(nil))
+	m.Snapshot = m.ContentSHA256 + ".jsonl"
+
+	err = os.Rename(temp.Name(), filepath.Join(dir, m.Snapshot))
+
+	if err != nil {
+		return m, err
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+
+	if err != nil {
+		return m, err
+	}
+
+	signature, err := sign(key, manifest)
+
+	if err != nil {
+		return m, err
+	}
+
+	base := filepath.Join(dir, m.ContentSHA256)
+
+	err = ioutil.WriteFile(base+".manifest.json", manifest, 0644)
+
+	if err != nil {
+		return m, err
+	}
+
+	return m, ioutil.WriteFile(base+".manifest.sig", []byte(signature+"\n"), 0644)
+}
+
+//==============================================================================================================================
+//	 Verification - The result of verifying a snapshot. LedgerMatches is only set when it was compared with the ledger.
+//==============================================================================================================================
+type Verification struct {
+	Manifest      Manifest `json:"manifest"`
+	Valid         bool     `json:"valid"`
+	Problems      []string `json:"problems,omitempty"`
+	LedgerRoot    string   `json:"ledger_root,omitempty"`
+	LedgerMatches *bool    `json:"ledger_matches,omitempty"`
+}
+
+//==============================================================================================================================
+//	 verify_snapshot - Checks the signature of the manifest, the content of the snapshot against the manifest and every
+//					   bond against its hash. An error is only returned when the files cannot be read; what is wrong
+//					   with a snapshot is listed in its problems.
+//==============================================================================================================================
+func verify_snapshot(manifestPath string, public *ecdsa.PublicKey) (Verification, error) {
+
+	var v Verification
+
+	manifest, err := ioutil.ReadFile(manifestPath)
+
+	if err != nil {
+		return v, err
+	}
+
+	signature, err := ioutil.ReadFile(strings.TrimSuffix(manifestPath, ".json") + ".sig")
+
+	if err != nil {
+		return v, err
+	}
+
+	if !verify(public, manifest, strings.TrimSpace(string(signature))) {
+		v.Problems = append(v.Problems, "signature does not match the manifest")
+	}
+
+	err = json.Unmarshal(manifest, &v.Manifest)
+
+	if err != nil {
+		return v, errors.New("Invalid manifest " + manifestPath + ": " + err.Error())
+	}
+
+	m := v.Manifest
+
+	if m.Snapshot != m.ContentSHA256+".jsonl" || filepath.Base(manifestPath) != m.ContentSHA256+".manifest.json" {
+		v.Problems = append(v.Problems, "file names do not match the content hash of the manifest")
+	}
+
+	file, err := os.Open(filepath.Join(filepath.Dir(manifestPath), m.Snapshot))
+
+	if err != nil {
+		return v, err
+	}
+
+	defer file.Close()
+
+	content := sha256.New()
+	decoder := json.NewDecoder(io.TeeReader(file, content))
+	hashes := make(map[string]string)
+
+	for {
+
+		var e client.Exported_Bond
+
+		err := decoder.Decode(&e)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return v, errors.New("Invalid snapshot " + m.Snapshot + ": " + err.Error())
+		}
+
+		hash, err := client.BondHash(e.Bond)
+
+		if err != nil {
+			return v, err
+		}
+
+		if hash != e.Hash {
+			v.Problems = append(v.Problems, "bond "+e.Bond.RealEstateID+" does not match its hash")
+		}
+
+		hashes[e.Bond.RealEstateID] = e.Hash
+	}
+
+	io.Copy(content, file) // trailing whitespace the decoder left unread
+
+	if hex.EncodeToString(content.Sum(nil)) != m.ContentSHA256 {
+		v.Problems = append(v.Problems, "content does not match content_sha256 of the manifest")
+	}
+
+	root, err := client.MerkleRoot(hashes)
+
+	if err != nil {
+		return v, err
+	}
+
+	if len(hashes) != m.TotalBonds || root != m.MerkleRoot {
+		v.Problems = append(v.Problems, "bonds do not match the total_bonds and merkle_root of the manifest")
+	}
+
+	v.Valid = len(v.Problems) == 0
+
+	return v, nil
+}
+
+//==============================================================================================================================
+//	 ECDSA_Signature - The ASN.1 form of an ECDSA signature, as Fabric and OpenSSL write them.
+//==============================================================================================================================
+type ECDSA_Signature struct {
+	R, S *big.Int
+}
+
+//==============================================================================================================================
+//	 sign - Returns the base64 signature of the sha256 of the data.
+//==============================================================================================================================
+func sign(key *ecdsa.PrivateKey, data []byte) (string, error) {
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+
+	if err != nil {
+		return "", err
+	}
+
+	der, err := asn1.Marshal(ECDSA_Signature{r, s})
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+//==============================================================================================================================
+//	 verify - Returns whether the base64 signature is of the sha256 of the data.
+//==============================================================================================================================
+func verify(public *ecdsa.PublicKey, data []byte, signature string) bool {
+
+	der, err := base64.StdEncoding.DecodeString(signature)
+
+	if err != nil {
+		return false
+	}
+
+	var sig ECDSA_Signature
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil || sig.R == nil || sig.S == nil {
+		return false
+	}
+
+	digest := sha256.Sum256(data)
+
+	return ecdsa.Verify(public, digest[:], sig.R, sig.S)
+}
+
+//==============================================================================================================================
+//	 read_private_key - Reads an ECDSA private key from a PEM file in PKCS#8 or SEC 1 form, such as the key of a Fabric
+//						identity.
+//==============================================================================================================================
+func read_private_key(path string) (*ecdsa.PrivateKey, error) {
+
+	block, err := read_pem(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, errors.New("Invalid private key " + path + ": " + err.Error())
+	}
+
+	key, ok := parsed.(*ecdsa.PrivateKey)
+
+	if !ok {
+		return nil, errors.New("Private key " + path + " is not an ECDSA key")
+	}
+
+	return key, nil
+}
+
+//==============================================================================================================================
+//	 read_public_key - Reads an ECDSA public key from a PEM file holding the key or a certificate for it.
+//==============================================================================================================================
+func read_public_key(path string) (*ecdsa.PublicKey, error) {
+
+	block, err := read_pem(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+
+	if block.Type == "CERTIFICATE" {
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+
+		if err != nil {
+			return nil, errors.New("Invalid certificate " + path + ": " + err.Error())
+		}
+
+		parsed = cert.PublicKey
+
+	} else {
+
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+
+		if err != nil {
+			return nil, errors.New("Invalid public key " + path + ": " + err.Error())
+		}
+	}
+
+	key, ok := parsed.(*ecdsa.PublicKey)
+
+	if !ok {
+		return nil, errors.New("Public key " + path + " is not an ECDSA key")
+	}
+
+	return key, nil
+}
+
+func read_pem(path string) (*pem.Block, error) {
+
+	bytes, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bytes)
+
+	if block == nil {
+		return nil, errors.New("No PEM block in " + path)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 fake_registry - Answers export_bonds with the bonds in pages of one and get_registry_checksum with their root.
+//==============================================================================================================================
+type fake_registry struct {
+	bonds []client.Exported_Bond
+}
+
+func (f *fake_registry) Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	if function == "get_registry_checksum" {
+
+		hashes := make(map[string]string)
+
+		for _, e := range f.bonds {
+			hashes[e.Bond.RealEstateID] = e.Hash
+		}
+
+		root, _ := client.MerkleRoot(hashes)
+
+		return json.Marshal(client.Registry_Checksum{Algorithm: "sha256-merkle", TotalBonds: len(f.bonds), MerkleRoot: root})
+	}
+
+	page := client.Export_Page{Bonds: f.bonds[:1]}
+
+	if args[1] == "" {
+		page.Bookmark = "next"
+	} else {
+		page.Bonds = f.bonds[1:]
+	}
+
+	return json.Marshal(page)
+}
+
+func (f *fake_registry) Submit(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return nil, nil
+}
+
+func TestSnapshot(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "snapshot")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	fake := &fake_registry{}
+
+	for _, id := range []string{"1232.21", "1232.22"} {
+		b := client.Bond{ID: "bond-" + id, RealEstateID: id, OwnerNationalID: "1000000001", Status: "flat"}
+		hash, _ := client.BondHash(b)
+		fake.bonds = append(fake.bonds, client.Exported_Bond{Bond: b, Hash: hash})
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	m, err := take_snapshot(context.Background(), client.New(fake), dir, key, 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Consistent || m.TotalBonds != 2 {
+		t.Fatalf("Unexpected manifest %+v", m)
+	}
+
+	manifest := filepath.Join(dir, m.ContentSHA256+".manifest.json")
+
+	v, err := verify_snapshot(manifest, &key.PublicKey)
+
+	if err != nil || !v.Valid {
+		t.Fatalf("Expected the snapshot to verify, got %+v %v", v, err)
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if v, _ := verify_snapshot(manifest, &other.PublicKey); v.Valid {
+		t.Errorf("Expected a snapshot signed by another key to fail")
+	}
+
+	content, _ := ioutil.ReadFile(filepath.Join(dir, m.Snapshot))
+	content = bytes.Replace(content, []byte(`"status":"flat"`), []byte(`"status":"built"`), 1)
+
+	ioutil.WriteFile(filepath.Join(dir, m.Snapshot), content, 0644)
+
+	if v, err := verify_snapshot(manifest, &key.PublicKey); err != nil || v.Valid || len(v.Problems) != 2 {
+		t.Errorf("Expected a changed snapshot to fail, got %+v", v)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//==============================================================================================================================
+//	 Checkpoint - The block the bridge resumes from. Every event of the blocks before it has been published, those of
+//				  the block itself may only partly have been, so it is replayed whole and consumers see its events at
+//				  least once.
+//==============================================================================================================================
+type Checkpoint struct {
+	Block uint64 `json:"block"`
+}
+
+//==============================================================================================================================
+//	 read_checkpoint - Returns the checkpoint saved at the path, found false when none has been saved yet.
+//==============================================================================================================================
+func read_checkpoint(path string) (c Checkpoint, found bool, err error) {
+
+	bytes, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return c, false, nil
+	}
+
+	if err != nil {
+		return c, false, err
+	}
+
+	err = json.Unmarshal(bytes, &c)
+
+	return c, err == nil, err
+}
+
+//==============================================================================================================================
+//	 write_checkpoint - Saves the checkpoint at the path, replacing the previous one in a single rename so a crash never
+//						leaves a partly written file.
+//==============================================================================================================================
+func write_checkpoint(path string, c Checkpoint) error {
+
+	bytes, err := json.Marshal(c)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(bytes)
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "eventbridge")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+
+	_, found, err := read_checkpoint(path)
+
+	if err != nil || found {
+		t.Fatalf("Expected no checkpoint, got found %v, error %v", found, err)
+	}
+
+	for _, block := range []uint64{0, 42} {
+
+		err = write_checkpoint(path, Checkpoint{Block: block})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, found, err := read_checkpoint(path)
+
+		if err != nil || !found || c.Block != block {
+			t.Errorf("Expected block %d, got %+v, found %v, error %v", block, c, found, err)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+
+	if len(files) != 1 {
+		t.Errorf("Expected only the checkpoint to be left, got %d files", len(files))
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient/seek"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+)
+
+//==============================================================================================================================
+//	 Bond Event Bridge - Republishes the events of the chaincode to Kafka or NATS, see sinks.go. Events are read from
+//						 the block delivery service of a peer starting at the saved checkpoint, and an event is only
+//						 passed over once the broker has stored it, so a restart after a crash or an outage of the
+//						 broker replays rather than loses events.
+//
+//		eventbridge -sink kafka -brokers kafka-1:9092,kafka-2:9092 -checkpoint /var/lib/eventbridge/checkpoint.json
+//		eventbridge -sink nats -nats-url nats://nats:4222 -nats-credentials bridge.creds
+//==============================================================================================================================
+
+// Events republished, a regular expression over the event names of the chaincode
+const EVENT_FILTER = "BondCreated|BondTransferred"
+
+// Wait before retrying a publish the broker did not acknowledge, doubling up to the maximum
+const RETRY_BACKOFF = time.Second
+const MAX_RETRY_BACKOFF = 30 * time.Second
+
+//==============================================================================================================================
+//	 publish - Publishes the message, retrying until the broker acknowledges it or the bridge is stopped.
+//==============================================================================================================================
+func publish(sink Sink, m Bridge_Message, stop <-chan os.Signal) bool {
+
+	backoff := RETRY_BACKOFF
+
+	for {
+
+		err := sink.Publish(m)
+
+		if err == nil {
+			return true
+		}
+
+		log.Printf("Error publishing %s of %s, retrying in %s: %s", m.EventType, m.TxID, backoff, err)
+
+		select {
+		case <-stop:
+			return false
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > MAX_RETRY_BACKOFF {
+			backoff = MAX_RETRY_BACKOFF
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 main - Bridges events until stopped or the event stream fails.
+//==============================================================================================================================
+func main() {
+
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	user := flag.String("user", "User1", "user of the organisation to read blocks as")
+	org := flag.String("org", "Org1", "organisation of the user")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	checkpointPath := flag.String("checkpoint", "checkpoint.json", "file holding the block to resume from")
+	sinkName := flag.String("sink", "kafka", "kafka or nats")
+	brokers := flag.String("brokers", "localhost:9092", "comma separated Kafka brokers")
+	natsURL := flag.String("nats-url", "nats://localhost:4222", "NATS server")
+	natsCredentials := flag.String("nats-credentials", "", "NATS credentials file")
+	prefix := flag.String("prefix", "bonds", "prefix of the topics or subjects events are published to")
+
+	flag.Parse()
+
+	var sink Sink
+	var err error
+
+	switch *sinkName {
+	case "kafka":
+		sink, err = new_kafka_sink(*brokers, *prefix)
+	case "nats":
+		sink, err = new_nats_sink(*natsURL, *natsCredentials, *prefix)
+	default:
+		log.Fatalf("Unknown sink %s, expecting kafka or nats", *sinkName)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer sink.Close()
+
+	checkpoint, found, err := read_checkpoint(*checkpointPath)
+
+	if err != nil {
+		log.Fatalf("Error reading checkpoint %s: %s", *checkpointPath, err)
+	}
+
+	sdk, err := fabsdk.New(config.FromFile(*profile))
+
+	if err != nil {
+		log.Fatalf("Error loading connection profile %s: %s", *profile, err)
+	}
+
+	defer sdk.Close()
+
+	options := []event.ClientOption{event.WithBlockEvents(), event.WithSeekType(seek.Oldest)}
+
+	if found {
+		options = []event.ClientOption{event.WithBlockEvents(), event.WithSeekType(seek.FromBlock), event.WithBlockNum(checkpoint.Block)}
+		log.Printf("Resuming from block %d", checkpoint.Block)
+	} else {
+		log.Printf("No checkpoint at %s, starting from the first block", *checkpointPath)
+	}
+
+	client, err := event.New(sdk.ChannelContext(*channel, fabsdk.WithUser(*user), fabsdk.WithOrg(*org)), options...)
+
+	if err != nil {
+		log.Fatalf("Error connecting to the event service of %s: %s", *channel, err)
+	}
+
+	registration, events, err := client.RegisterChaincodeEvent(*chaincode, EVENT_FILTER)
+
+	if err != nil {
+		log.Fatalf("Error registering for events: %s", err)
+	}
+
+	defer client.Unregister(registration)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+
+		select {
+		case <-stop:
+			log.Printf("Stopped at block %d", checkpoint.Block)
+			return
+		case e, ok := <-events:
+
+			if !ok {
+				log.Printf("Event stream closed at block %d", checkpoint.Block)
+				return
+			}
+
+			var envelope struct {
+				BondID string `json:"bond_id"`
+			}
+
+			if err := json.Unmarshal(e.Payload, &envelope); err != nil {
+				log.Printf("Skipping %s event of %s: %s", e.EventName, e.TxID, err)
+				continue
+			}
+
+			m := Bridge_Message{EventType: e.EventName, BondID: envelope.BondID, TxID: e.TxID, BlockNumber: e.BlockNumber, Event: e.Payload}
+
+			if !publish(sink, m, stop) {
+				log.Printf("Stopped at block %d", checkpoint.Block)
+				return
+			}
+
+			if e.BlockNumber > checkpoint.Block || !found {
+
+				checkpoint, found = Checkpoint{Block: e.BlockNumber}, true
+
+				err = write_checkpoint(*checkpointPath, checkpoint)
+
+				if err != nil {
+					log.Printf("Error saving checkpoint %d: %s", checkpoint.Block, err) // replays further back on restart
+				}
+			}
+		}
+	}
+}
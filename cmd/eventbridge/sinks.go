@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/nats-io/nats.go"
+)
+
+//==============================================================================================================================
+//	 Sinks - Where events are republished. An event goes to the topic, or subject, named by the prefix and its event
+//			 type, e.g. bonds.BondTransferred. Publish returns once the broker has stored the event.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Bridge_Message - The body of a republished event, the envelope the chaincode emitted and where it was committed.
+//					  Consumers drop repeats of the same txid, see Checkpoint.
+//==============================================================================================================================
+type Bridge_Message struct {
+	EventType   string          `json:"event_type"`
+	BondID      string          `json:"bond_id"`
+	TxID        string          `json:"txid"`
+	BlockNumber uint64          `json:"block_number"`
+	Event       json.RawMessage `json:"event"`
+}
+
+type Sink interface {
+	Publish(m Bridge_Message) error
+	Close()
+}
+
+//==============================================================================================================================
+//	 Kafka_Sink - Publishes to Kafka keyed by bond so the events of a bond stay in order on one partition.
+//==============================================================================================================================
+type Kafka_Sink struct {
+	producer sarama.SyncProducer
+	prefix   string
+}
+
+func new_kafka_sink(brokers string, prefix string) (*Kafka_Sink, error) {
+
+	config := sarama.NewConfig()
+	config.ClientID = "bond-event-bridge"
+	config.Version = sarama.V2_0_0_0
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Idempotent = true
+	config.Net.MaxOpenRequests = 1 // required by idempotent producers
+	config.Producer.Retry.Max = 10
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(strings.Split(brokers, ","), config)
+
+	if err != nil {
+		return nil, errors.New("Error connecting to Kafka " + brokers + ": " + err.Error())
+	}
+
+	return &Kafka_Sink{producer: producer, prefix: prefix}, nil
+}
+
+func (k *Kafka_Sink) Publish(m Bridge_Message) error {
+
+	bytes, err := json.Marshal(m)
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: k.prefix + "." + m.EventType,
+		Key:   sarama.StringEncoder(m.BondID),
+		Value: sarama.ByteEncoder(bytes),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("txid"), Value: []byte(m.TxID)},
+			{Key: []byte("block_number"), Value: []byte(strconv.FormatUint(m.BlockNumber, 10))},
+		},
+	})
+
+	return err
+}
+
+func (k *Kafka_Sink) Close() {
+	k.producer.Close()
+}
+
+//==============================================================================================================================
+//	 NATS_Sink - Publishes to a JetStream stream, which acknowledges stored messages and drops a repeated txid within
+//				 its duplicate window.
+//==============================================================================================================================
+type NATS_Sink struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+func new_nats_sink(url string, credentials string, prefix string) (*NATS_Sink, error) {
+
+	options := []nats.Option{nats.Name("bond-event-bridge"), nats.MaxReconnects(-1)}
+
+	if credentials != "" {
+		options = append(options, nats.UserCredentials(credentials))
+	}
+
+	conn, err := nats.Connect(url, options...)
+
+	if err != nil {
+		return nil, errors.New("Error connecting to NATS " + url + ": " + err.Error())
+	}
+
+	js, err := conn.JetStream()
+
+	if err != nil {
+		conn.Close()
+		return nil, errors.New("Error opening JetStream: " + err.Error())
+	}
+
+	return &NATS_Sink{conn: conn, js: js, prefix: prefix}, nil
+}
+
+func (n *NATS_Sink) Publish(m Bridge_Message) error {
+
+	bytes, err := json.Marshal(m)
+
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(n.prefix + "." + m.EventType)
+	msg.Data = bytes
+	msg.Header.Set("Bond-Id", m.BondID)
+
+	_, err = n.js.PublishMsg(msg, nats.MsgId(m.TxID), nats.AckWait(10*time.Second))
+
+	return err
+}
+
+func (n *NATS_Sink) Close() {
+	n.conn.Drain()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+//==============================================================================================================================
+//	 Topics - Every event is published on the topic of its bond and on the topic of each owner it concerns, the new
+//			  owner of a created bond and both parties of a transfer.
+//
+//		bond:1232.21		owner:1000000001
+//==============================================================================================================================
+
+const BOND_TOPIC = "bond:"
+const OWNER_TOPIC = "owner:"
+
+// Messages buffered for a subscriber before it is dropped as too slow
+const SUBSCRIBER_BUFFER = 64
+
+//==============================================================================================================================
+//	 Event_Envelope - The JSON body of every chaincode event, see events.go of the chaincode.
+//==============================================================================================================================
+type Event_Envelope struct {
+	EventType     string          `json:"event_type"`
+	SchemaVersion int             `json:"schema_version"`
+	BondID        string          `json:"bond_id"`
+	Actor         string          `json:"actor"`
+	TxID          string          `json:"txid"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+//==============================================================================================================================
+//	 Relay_Message - A message sent to subscribers, the event as the chaincode emitted it and the block holding it.
+//==============================================================================================================================
+type Relay_Message struct {
+	Topic       string          `json:"topic"`
+	BlockNumber uint64          `json:"block_number"`
+	Event       json.RawMessage `json:"event"`
+}
+
+//==============================================================================================================================
+//	 event_topics - Returns the topics the event is published on.
+//==============================================================================================================================
+func event_topics(e Event_Envelope) []string {
+
+	topics := []string{BOND_TOPIC + e.BondID}
+
+	var owners struct {
+		Owner string `json:"owner_national_id"` // BondCreated carries the bond
+		From  string `json:"from"`              // BondTransferred carries the transfer record
+		To    string `json:"to"`
+	}
+
+	json.Unmarshal(e.Payload, &owners)
+
+	for _, owner := range []string{owners.Owner, owners.From, owners.To} {
+		if owner != "" {
+			topics = append(topics, OWNER_TOPIC+owner)
+		}
+	}
+
+	return topics
+}
+
+//==============================================================================================================================
+//	 Subscriber - A connection listening on some topics. Send is closed when the hub drops it.
+//==============================================================================================================================
+type Subscriber struct {
+	Send   chan []byte
+	topics map[string]bool
+}
+
+func new_subscriber() *Subscriber {
+	return &Subscriber{Send: make(chan []byte, SUBSCRIBER_BUFFER), topics: make(map[string]bool)}
+}
+
+//==============================================================================================================================
+//	 Hub - The subscribers of each topic.
+//==============================================================================================================================
+type Hub struct {
+	mutex  sync.Mutex
+	topics map[string]map[*Subscriber]bool
+}
+
+func new_hub() *Hub {
+	return &Hub{topics: make(map[string]map[*Subscriber]bool)}
+}
+
+//==============================================================================================================================
+//	 subscribe - Adds the subscriber to the topic.
+//==============================================================================================================================
+func (h *Hub) subscribe(s *Subscriber, topic string) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscriber]bool)
+	}
+
+	h.topics[topic][s] = true
+	s.topics[topic] = true
+}
+
+//==============================================================================================================================
+//	 unsubscribe - Removes the subscriber from the topic.
+//==============================================================================================================================
+func (h *Hub) unsubscribe(s *Subscriber, topic string) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.remove(s, topic)
+}
+
+//==============================================================================================================================
+//	 drop - Removes the subscriber from every topic and closes its Send channel. Dropping it twice does nothing.
+//==============================================================================================================================
+func (h *Hub) drop(s *Subscriber) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.drop_locked(s)
+}
+
+func (h *Hub) drop_locked(s *Subscriber) {
+
+	if s.topics == nil {
+		return
+	}
+
+	for topic := range s.topics {
+		h.remove(s, topic)
+	}
+
+	s.topics = nil
+
+	close(s.Send)
+}
+
+func (h *Hub) remove(s *Subscriber, topic string) {
+
+	delete(h.topics[topic], s)
+	delete(s.topics, topic)
+
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+//==============================================================================================================================
+//	 publish - Sends the event to the subscribers of each of its topics. A subscriber too slow to keep up is dropped
+//			   rather than holding up the others.
+//==============================================================================================================================
+func (h *Hub) publish(e Event_Envelope, raw []byte, blockNumber uint64) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, topic := range event_topics(e) {
+
+		if len(h.topics[topic]) == 0 {
+			continue
+		}
+
+		bytes, err := json.Marshal(Relay_Message{Topic: topic, BlockNumber: blockNumber, Event: raw})
+
+		if err != nil {
+			continue
+		}
+
+		for s := range h.topics[topic] {
+			select {
+			case s.Send <- bytes:
+			default:
+				h.drop_locked(s)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEventTopics(t *testing.T) {
+
+	created := Event_Envelope{EventType: "BondCreated", BondID: "1232.21", Payload: json.RawMessage(`{"real_estate_id":"1232.21","owner_national_id":"1"}`)}
+	transferred := Event_Envelope{EventType: "BondTransferred", BondID: "1232.21", Payload: json.RawMessage(`{"from":"1","to":"2"}`)}
+
+	if topics := event_topics(created); !reflect.DeepEqual(topics, []string{"bond:1232.21", "owner:1"}) {
+		t.Errorf("Unexpected topics of BondCreated %q", topics)
+	}
+
+	if topics := event_topics(transferred); !reflect.DeepEqual(topics, []string{"bond:1232.21", "owner:1", "owner:2"}) {
+		t.Errorf("Unexpected topics of BondTransferred %q", topics)
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+
+	hub := new_hub()
+
+	bond, owner, other := new_subscriber(), new_subscriber(), new_subscriber()
+
+	hub.subscribe(bond, "bond:1232.21")
+	hub.subscribe(owner, "owner:2")
+	hub.subscribe(other, "bond:1232.22")
+
+	raw := []byte(`{"event_type":"BondTransferred","bond_id":"1232.21","payload":{"from":"1","to":"2"}}`)
+
+	var e Event_Envelope
+
+	json.Unmarshal(raw, &e)
+
+	hub.publish(e, raw, 7)
+
+	for s, topic := range map[*Subscriber]string{bond: "bond:1232.21", owner: "owner:2"} {
+
+		var m Relay_Message
+
+		if len(s.Send) != 1 || json.Unmarshal(<-s.Send, &m) != nil || m.Topic != topic || m.BlockNumber != 7 {
+			t.Errorf("Expected one message on %s, got %+v", topic, m)
+		}
+	}
+
+	if len(other.Send) != 0 {
+		t.Errorf("Unexpected message for another bond")
+	}
+
+	for i := 0; i <= SUBSCRIBER_BUFFER; i++ {
+		hub.publish(e, raw, 8)
+	}
+
+	if _, ok := hub.topics["bond:1232.21"][bond]; ok {
+		t.Errorf("Expected a subscriber that does not keep up to be dropped")
+	}
+
+	hub.drop(bond) // dropping twice does nothing
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Bond Event Relay - Listens for the BondCreated and BondTransferred events of the chaincode and fans them out over
+//						WebSocket to real-time UIs, each connection receiving the topics it subscribed to, see hub.go.
+//						Topics are given as query parameters when connecting and can be changed with subscribe and
+//						unsubscribe messages. Owner topics carry national IDs so, as with the REST gateway, callers
+//						authenticate with a TLS client certificate issued by the client CA.
+//
+//		wss://relay:8444/events?topic=bond:1232.21&topic=owner:1000000001
+//		{"action":"subscribe","topic":"bond:1232.22"}
+//==============================================================================================================================
+
+// Events relayed, a regular expression over the event names of the chaincode
+const EVENT_FILTER = "BondCreated|BondTransferred"
+
+// Keepalive of the connections, a connection silent for PONG_WAIT is closed
+const PING_PERIOD = 30 * time.Second
+const PONG_WAIT = 60 * time.Second
+const WRITE_WAIT = 10 * time.Second
+
+// Largest message accepted from a subscriber
+const MAX_MESSAGE_SIZE = 1024
+
+//==============================================================================================================================
+//	 Subscription_Request - A message from a subscriber changing its topics.
+//==============================================================================================================================
+type Subscription_Request struct {
+	Action string `json:"action"` // subscribe or unsubscribe
+	Topic  string `json:"topic"`
+}
+
+//==============================================================================================================================
+//	 valid_topic - Returns whether the topic is one events are published on.
+//==============================================================================================================================
+func valid_topic(topic string) bool {
+	return (strings.HasPrefix(topic, BOND_TOPIC) && len(topic) > len(BOND_TOPIC)) || (strings.HasPrefix(topic, OWNER_TOPIC) && len(topic) > len(OWNER_TOPIC))
+}
+
+//==============================================================================================================================
+//	 serve_subscriber - Upgrades the request to a WebSocket and relays the events of its topics until either side
+//						closes it.
+//==============================================================================================================================
+func serve_subscriber(hub *Hub, upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request) {
+
+	topics := r.URL.Query()["topic"]
+
+	for _, topic := range topics {
+		if !valid_topic(topic) {
+			http.Error(w, "Invalid topic "+topic, http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+
+	if err != nil {
+		return // the upgrader has answered the request
+	}
+
+	s := new_subscriber()
+
+	for _, topic := range topics {
+		hub.subscribe(s, topic)
+	}
+
+	go write_messages(conn, s)
+
+	conn.SetReadLimit(MAX_MESSAGE_SIZE)
+	conn.SetReadDeadline(time.Now().Add(PONG_WAIT))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(PONG_WAIT))
+	})
+
+	for {
+
+		var req Subscription_Request
+
+		err := conn.ReadJSON(&req)
+
+		if err != nil {
+			break
+		}
+
+		if !valid_topic(req.Topic) {
+			continue
+		}
+
+		if req.Action == "subscribe" {
+			hub.subscribe(s, req.Topic)
+		} else if req.Action == "unsubscribe" {
+			hub.unsubscribe(s, req.Topic)
+		}
+	}
+
+	hub.drop(s)
+}
+
+//==============================================================================================================================
+//	 write_messages - Writes the messages of the subscriber to its connection and pings it, closing the connection once
+//					  the hub drops the subscriber.
+//==============================================================================================================================
+func write_messages(conn *websocket.Conn, s *Subscriber) {
+
+	ticker := time.NewTicker(PING_PERIOD)
+
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-s.Send:
+			conn.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Too slow"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 main - Relays the events until the connection to the network fails.
+//==============================================================================================================================
+func main() {
+
+	listen := flag.String("listen", ":8444", "address to serve WebSocket connections on")
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet")
+	identity := flag.String("identity", "relay", "label of the identity in the wallet to listen as")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	tlsCert := flag.String("tls-cert", "server.pem", "certificate of the relay")
+	tlsKey := flag.String("tls-key", "server.key", "private key of the relay")
+	clientCA := flag.String("client-ca", "client-ca.pem", "CA issuing the client certificates subscribers authenticate with")
+	origins := flag.String("origins", "", "comma separated origins allowed to connect from a browser, any when empty")
+
+	flag.Parse()
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", *walletPath, err)
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(config.FromFile(*profile)), gateway.WithIdentity(wallet, *identity))
+
+	if err != nil {
+		log.Fatalf("Error connecting as %s: %s", *identity, err)
+	}
+
+	defer gw.Close()
+
+	network, err := gw.GetNetwork(*channel)
+
+	if err != nil {
+		log.Fatalf("Error joining channel %s: %s", *channel, err)
+	}
+
+	contract := network.GetContract(*chaincode)
+
+	registration, events, err := contract.RegisterEvent(EVENT_FILTER)
+
+	if err != nil {
+		log.Fatalf("Error registering for events: %s", err)
+	}
+
+	defer contract.Unregister(registration)
+
+	hub := new_hub()
+
+	go func() {
+
+		for event := range events {
+
+			var e Event_Envelope
+
+			if err := json.Unmarshal(event.Payload, &e); err != nil {
+				log.Printf("Skipping %s event of %s: %s", event.EventName, event.TxID, err)
+				continue
+			}
+
+			hub.publish(e, event.Payload, event.BlockNumber)
+		}
+
+		log.Fatal("Event stream closed")
+	}()
+
+	allowed := make(map[string]bool)
+
+	for _, origin := range strings.Split(*origins, ",") {
+		if origin != "" {
+			allowed[origin] = true
+		}
+	}
+
+	upgrader := &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return len(allowed) == 0 || allowed[r.Header.Get("Origin")]
+		},
+	}
+
+	pem, err := ioutil.ReadFile(*clientCA)
+
+	if err != nil {
+		log.Fatalf("Error reading client CA %s: %s", *clientCA, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatalf("No certificates in client CA %s", *clientCA)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		serve_subscriber(hub, upgrader, w, r)
+	})
+
+	server := &http.Server{
+		Addr:      *listen,
+		Handler:   mux,
+		TLSConfig: &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert},
+	}
+
+	log.Printf("Relaying %s/%s events on %s", *channel, *chaincode, *listen)
+
+	log.Fatal(server.ListenAndServeTLS(*tlsCert, *tlsKey))
+}
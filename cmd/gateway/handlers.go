@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+// Largest request body accepted, a bond with a detailed boundary is well under it
+const MAX_BODY_SIZE = 1 << 20
+
+// HTTP status answering each error code of the chaincode, any other code is a bad gateway
+var STATUS_BY_CODE = map[string]int{
+	"BOND_NOT_FOUND":   http.StatusNotFound,
+	"BOND_EXISTS":      http.StatusConflict,
+	"NOT_AUTHORIZED":   http.StatusForbidden,
+	"INVALID_STATE":    http.StatusConflict,
+	"VERSION_CONFLICT": http.StatusPreconditionFailed,
+	"INVALID_ARGUMENT": http.StatusBadRequest,
+	"UNKNOWN_FUNCTION": http.StatusNotFound,
+}
+
+//==============================================================================================================================
+//	 Envelope - The response envelope of the chaincode.
+//==============================================================================================================================
+type Envelope struct {
+	Status  int32           `json:"status"`
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Create_Request - The body of POST /bonds. The owner is passed to the chaincode in the transient map rather than as
+//					  an argument of the transaction, it is still stored with the bond, see transient.go of the chaincode.
+//==============================================================================================================================
+type Create_Request struct {
+	ID              string          `json:"id"` // generated by the chaincode when empty
+	RealEstateID    string          `json:"real_estate_id"`
+	OwnerNationalID string          `json:"owner_national_id"`
+	Status          string          `json:"status"`
+	Area            string          `json:"area"` // e.g. 500 m2
+	Long            *float64        `json:"long"` // required, a missing coordinate is not taken as 0
+	Lat             *float64        `json:"lat"`
+	Boundary        json.RawMessage `json:"boundary,omitempty"` // GeoJSON polygon
+	DistrictCode    string          `json:"district_code,omitempty"`
+	Street          string          `json:"street,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Transfer_Request - The body of POST /bonds/{id}/transfer. The recipient and price go in the transient map, the salt
+//						is required with a declared value, see put_sale_price.
+//==============================================================================================================================
+type Transfer_Request struct {
+	RecipientNationalID string  `json:"recipient_national_id"`
+	RecipientMSP        string  `json:"recipient_msp,omitempty"`
+	DeclaredValue       float64 `json:"declared_value,omitempty"`
+	Salt                string  `json:"salt,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Function_Request - The body of POST /functions/{name}, the arguments of the function in order.
+//==============================================================================================================================
+type Function_Request struct {
+	Args []string `json:"args"`
+}
+
+//==============================================================================================================================
+//	 ServeHTTP - Routes the request to its handler as the identity of the caller. The Idempotency-Key and If-Match
+//				 headers are passed to the chaincode as the idempotency_key and expected_version of the change.
+//==============================================================================================================================
+func (s *Gateway_Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path == "/openapi.json" && r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.spec)
+		return
+	}
+
+	label, ok := caller(r)
+
+	if !ok {
+		write_error(w, http.StatusUnauthorized, "NOT_AUTHORIZED", "A client certificate is required")
+		return
+	}
+
+	c, err := s.contract(label)
+
+	if err != nil {
+		write_error(w, http.StatusForbidden, "NOT_AUTHORIZED", err.Error())
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(path) == 1 && path[0] == "bonds" && r.Method == http.MethodPost:
+		s.create_bond(w, r, c)
+	case len(path) == 2 && path[0] == "bonds" && r.Method == http.MethodGet:
+		s.get_bond(w, r, c, path[1])
+	case len(path) == 3 && path[0] == "bonds" && path[2] == "transfer" && r.Method == http.MethodPost:
+		s.transfer_bond(w, r, c, path[1])
+	case len(path) == 2 && path[0] == "functions" && r.Method == http.MethodPost:
+		s.call_function(w, r, c, path[1])
+	default:
+		write_error(w, http.StatusNotFound, "UNKNOWN_FUNCTION", "No route for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+//==============================================================================================================================
+//	 create_bond - POST /bonds
+//==============================================================================================================================
+func (s *Gateway_Server) create_bond(w http.ResponseWriter, r *http.Request, c *gateway.Contract) {
+
+	var req Create_Request
+
+	if !read_body(w, r, &req) {
+		return
+	}
+
+	if req.Long == nil || req.Lat == nil {
+		write_error(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid request body: long and lat are required")
+		return
+	}
+
+	args := []string{
+		req.ID,
+		req.RealEstateID,
+		"", // owner, from the transient map
+		req.Status,
+		req.Area,
+		strconv.FormatFloat(*req.Long, 'f', -1, 64),
+		strconv.FormatFloat(*req.Lat, 'f', -1, 64),
+		string(req.Boundary),
+		req.DistrictCode,
+		"",
+		req.Street,
+	}
+
+	transient := change_transient(r)
+	transient["owner_national_id"] = []byte(req.OwnerNationalID)
+
+	s.submit(w, c, "create_bond", transient, args)
+}
+
+//==============================================================================================================================
+//	 transfer_bond - POST /bonds/{id}/transfer
+//==============================================================================================================================
+func (s *Gateway_Server) transfer_bond(w http.ResponseWriter, r *http.Request, c *gateway.Contract, realEstateID string) {
+
+	var req Transfer_Request
+
+	if !read_body(w, r, &req) {
+		return
+	}
+
+	transient := change_transient(r)
+	transient["recipient_national_id"] = []byte(req.RecipientNationalID)
+
+	if req.DeclaredValue > 0 {
+		transient["declared_value"] = []byte(strconv.FormatFloat(req.DeclaredValue, 'f', -1, 64))
+		transient["salt"] = []byte(req.Salt)
+	}
+
+	s.submit(w, c, "tranfer_bond", transient, []string{realEstateID, "", "", req.RecipientMSP})
+}
+
+//==============================================================================================================================
+//	 get_bond - GET /bonds/{id}
+//==============================================================================================================================
+func (s *Gateway_Server) get_bond(w http.ResponseWriter, r *http.Request, c *gateway.Contract, realEstateID string) {
+
+	payload, err := c.EvaluateTransaction("get_bond_details", realEstateID)
+
+	write_result(w, payload, err)
+}
+
+//==============================================================================================================================
+//	 call_function - POST /functions/{name}. Functions that write are submitted as transactions, the others evaluated.
+//==============================================================================================================================
+func (s *Gateway_Server) call_function(w http.ResponseWriter, r *http.Request, c *gateway.Contract, name string) {
+
+	f, ok := s.functions[name]
+
+	if !ok {
+		write_error(w, http.StatusNotFound, "UNKNOWN_FUNCTION", "No function "+name)
+		return
+	}
+
+	var req Function_Request
+
+	if !read_body(w, r, &req) {
+		return
+	}
+
+	if f.Writes {
+		s.submit(w, c, name, change_transient(r), req.Args)
+		return
+	}
+
+	payload, err := c.EvaluateTransaction(name, req.Args...)
+
+	write_result(w, payload, err)
+}
+
+//==============================================================================================================================
+//	 submit - Submits the transaction with the transient fields given and writes its result.
+//==============================================================================================================================
+func (s *Gateway_Server) submit(w http.ResponseWriter, c *gateway.Contract, function string, transient map[string][]byte, args []string) {
+
+	tx, err := c.CreateTransaction(function, gateway.WithTransient(transient))
+
+	if err != nil {
+		write_error(w, http.StatusInternalServerError, "ERROR", "Error creating transaction: "+err.Error())
+		return
+	}
+
+	payload, err := tx.Submit(args...)
+
+	write_result(w, payload, err)
+}
+
+//==============================================================================================================================
+//	 change_transient - Returns the transient fields taken from the headers of a request changing a bond.
+//==============================================================================================================================
+func change_transient(r *http.Request) map[string][]byte {
+
+	transient := make(map[string][]byte)
+
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		transient["idempotency_key"] = []byte(key)
+	}
+
+	if version := strings.Trim(r.Header.Get("If-Match"), `"`); version != "" {
+		transient["expected_version"] = []byte(version)
+	}
+
+	return transient
+}
+
+//==============================================================================================================================
+//	 read_body - Decodes the JSON body of the request into v, writing the error response if it is not valid.
+//==============================================================================================================================
+func read_body(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+
+	if err != nil {
+		write_error(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid request body: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+//==============================================================================================================================
+//	 write_result - Writes the envelope returned by the chaincode, or carried by the error of a rejected transaction,
+//					with the HTTP status matching its code.
+//==============================================================================================================================
+func write_result(w http.ResponseWriter, payload []byte, err error) {
+
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(payload)
+		return
+	}
+
+	message := err.Error()
+
+	if s, ok := status.FromError(err); ok {
+		message = s.Message
+	}
+
+	var e Envelope
+
+	if i := strings.Index(message, "{"); i < 0 || json.NewDecoder(strings.NewReader(message[i:])).Decode(&e) != nil || e.Code == "" {
+		write_error(w, http.StatusBadGateway, "ERROR", message) // not an answer of the chaincode, e.g. no peer reachable
+		return
+	}
+
+	code, ok := STATUS_BY_CODE[e.Code]
+
+	if !ok {
+		code = http.StatusBadGateway
+	}
+
+	write_error(w, code, e.Code, e.Message)
+}
+
+//==============================================================================================================================
+//	 write_error - Writes an error envelope with the HTTP status given.
+//==============================================================================================================================
+func write_error(w http.ResponseWriter, httpStatus int, code string, message string) {
+
+	bytes, _ := json.Marshal(Envelope{Status: int32(httpStatus), Code: code, Message: message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(bytes)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateBondRequiresCoordinates(t *testing.T) {
+
+	bodies := []string{
+		`{"real_estate_id":"1232.21","owner_national_id":"1000000001","status":"built"}`,
+		`{"real_estate_id":"1232.21","owner_national_id":"1000000001","status":"built","long":46.6753}`,
+		`{"real_estate_id":"1232.21","owner_national_id":"1000000001","status":"built","lat":24.7136}`,
+	}
+
+	for _, body := range bodies {
+
+		w := httptest.NewRecorder()
+
+		// Rejected before the contract is called
+		new(Gateway_Server).create_bond(w, httptest.NewRequest(http.MethodPost, "/bonds", strings.NewReader(body)), nil)
+
+		var e Envelope
+
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid response %s", err)
+		}
+
+		if w.Code != http.StatusBadRequest || e.Code != "INVALID_ARGUMENT" {
+			t.Errorf("Expected 400 INVALID_ARGUMENT for %s, got %d %s", body, w.Code, e.Code)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Bond Registry Gateway - Exposes the chaincode as a REST API for applications that do not use a Fabric SDK. Callers
+//							 authenticate with a TLS client certificate issued by the client CA and transactions are
+//							 submitted as the identity in the wallet labelled with its common name, so the chaincode
+//							 sees, checks the role of and audits the caller rather than the gateway. Responses are the
+//							 envelope returned by the chaincode, see response.go.
+//
+//		POST /bonds					create_bond
+//		POST /bonds/{id}/transfer	tranfer_bond
+//		GET	 /bonds/{id}			get_bond_details
+//		POST /functions/{name}		any function of the chaincode, as listed by list_functions at startup
+//		GET	 /openapi.json			the OpenAPI description of these routes, see openapi.go
+//
+//		gateway -profile connection.yaml -wallet ./wallet -identity gateway -tls-cert server.pem -tls-key server.key -client-ca ca.pem
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Gateway_Server - The HTTP handler, holding a contract per caller identity once it has been used.
+//==============================================================================================================================
+type Gateway_Server struct {
+	config    core.ConfigProvider
+	wallet    *gateway.Wallet
+	channel   string
+	chaincode string
+
+	functions map[string]Function_Description // from list_functions
+	spec      []byte                          // served at /openapi.json
+
+	mutex     sync.Mutex
+	contracts map[string]*gateway.Contract
+}
+
+//==============================================================================================================================
+//	 contract - Returns the chaincode as seen by the wallet identity with the label given, connecting on first use.
+//==============================================================================================================================
+func (s *Gateway_Server) contract(label string) (*gateway.Contract, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c, ok := s.contracts[label]; ok {
+		return c, nil
+	}
+
+	if !s.wallet.Exists(label) {
+		return nil, errors.New("No identity for " + label + " in the wallet")
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(s.config), gateway.WithIdentity(s.wallet, label))
+
+	if err != nil {
+		return nil, errors.New("Error connecting as " + label + ": " + err.Error())
+	}
+
+	network, err := gw.GetNetwork(s.channel)
+
+	if err != nil {
+		gw.Close()
+		return nil, errors.New("Error joining channel " + s.channel + ": " + err.Error())
+	}
+
+	c := network.GetContract(s.chaincode)
+
+	s.contracts[label] = c
+
+	return c, nil
+}
+
+//==============================================================================================================================
+//	 load_functions - Reads the functions of the chaincode with list_functions as the wallet identity given and builds
+//					  the OpenAPI description from them.
+//==============================================================================================================================
+func (s *Gateway_Server) load_functions(label string) error {
+
+	c, err := s.contract(label)
+
+	if err != nil {
+		return err
+	}
+
+	payload, err := c.EvaluateTransaction("list_functions")
+
+	if err != nil {
+		return errors.New("Error listing functions: " + err.Error())
+	}
+
+	var e Envelope
+
+	err = json.Unmarshal(payload, &e)
+
+	if err != nil {
+		return errors.New("Invalid response of list_functions: " + err.Error())
+	}
+
+	var functions []Function_Description
+
+	err = json.Unmarshal(e.Data, &functions)
+
+	if err != nil {
+		return errors.New("Invalid functions in the response of list_functions: " + err.Error())
+	}
+
+	s.spec, err = openapi_spec(functions)
+
+	if err != nil {
+		return errors.New("Error building the OpenAPI description: " + err.Error())
+	}
+
+	for _, f := range functions {
+		s.functions[f.Name] = f
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 caller - Returns the common name of the verified client certificate of the request.
+//==============================================================================================================================
+func caller(r *http.Request) (string, bool) {
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+
+	return name, name != ""
+}
+
+//==============================================================================================================================
+//	 main - Serves the gateway until it fails.
+//==============================================================================================================================
+func main() {
+
+	listen := flag.String("listen", ":8443", "address to serve HTTPS on")
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet holding the callers' identities")
+	identity := flag.String("identity", "gateway", "wallet identity the functions of the chaincode are listed as at startup")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	tlsCert := flag.String("tls-cert", "server.pem", "certificate of the gateway")
+	tlsKey := flag.String("tls-key", "server.key", "private key of the gateway")
+	clientCA := flag.String("client-ca", "client-ca.pem", "CA issuing the client certificates callers authenticate with")
+
+	flag.Parse()
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", *walletPath, err)
+	}
+
+	pem, err := ioutil.ReadFile(*clientCA)
+
+	if err != nil {
+		log.Fatalf("Error reading client CA %s: %s", *clientCA, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatalf("No certificates in client CA %s", *clientCA)
+	}
+
+	s := &Gateway_Server{
+		config:    config.FromFile(*profile),
+		wallet:    wallet,
+		channel:   *channel,
+		chaincode: *chaincode,
+		functions: make(map[string]Function_Description),
+		contracts: make(map[string]*gateway.Contract),
+	}
+
+	err = s.load_functions(*identity)
+
+	if err != nil {
+		log.Fatalf("Error loading the functions of %s: %s", *chaincode, err)
+	}
+
+	server := &http.Server{
+		Addr:      *listen,
+		Handler:   s,
+		TLSConfig: &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert},
+	}
+
+	log.Printf("Serving %s/%s on %s", *channel, *chaincode, *listen)
+
+	log.Fatal(server.ListenAndServeTLS(*tlsCert, *tlsKey))
+}
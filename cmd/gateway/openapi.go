@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 OpenAPI - The description of the gateway served at GET /openapi.json for client generators. OPENAPI_BASE holds the
+//			   routes with request bodies of their own, see handlers.go. openapi_spec adds a POST /functions/{name}
+//			   route for every function the chaincode reports in list_functions at startup, so the description
+//			   follows the deployed chaincode.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Arg_Description - An argument in the response of list_functions. Type is string, integer, number or JSON.
+//==============================================================================================================================
+type Arg_Description struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+}
+
+//==============================================================================================================================
+//	 Function_Description - A function in the response of list_functions. Role is empty for functions any caller may
+//							call, Writes is set for those submitted as transactions.
+//==============================================================================================================================
+type Function_Description struct {
+	Name     string            `json:"name"`
+	Args     []Arg_Description `json:"args"`
+	Variadic bool              `json:"variadic"`
+	Role     string            `json:"role"`
+	Writes   bool              `json:"writes"`
+}
+
+// Error responses of a function route
+var FUNCTION_ERRORS = []string{"400", "403", "404", "409", "412", "502"}
+
+const OPENAPI_BASE = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Bond Registry Gateway",
+    "version": "1.0.0",
+    "description": "REST access to the bond registry chaincode. Callers authenticate with a TLS client certificate and act as the wallet identity labelled with its common name."
+  },
+  "paths": {
+    "/bonds": {
+      "post": {
+        "operationId": "createBond",
+        "summary": "create_bond",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateRequest"}}}},
+        "responses": {
+          "200": {"description": "The ID of the bond", "content": {"application/json": {"schema": {"allOf": [{"$ref": "#/components/schemas/Envelope"}, {"properties": {"data": {"type": "string"}}}]}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/bonds/{id}": {
+      "get": {
+        "operationId": "getBond",
+        "summary": "get_bond_details",
+        "parameters": [{"$ref": "#/components/parameters/RealEstateID"}],
+        "responses": {
+          "200": {"description": "The bond", "content": {"application/json": {"schema": {"allOf": [{"$ref": "#/components/schemas/Envelope"}, {"properties": {"data": {"$ref": "#/components/schemas/Bond"}}}]}}}},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/bonds/{id}/transfer": {
+      "post": {
+        "operationId": "transferBond",
+        "summary": "tranfer_bond",
+        "parameters": [
+          {"$ref": "#/components/parameters/RealEstateID"},
+          {"$ref": "#/components/parameters/IdempotencyKey"},
+          {"name": "If-Match", "in": "header", "description": "Version the bond must still be at, see expected_version", "schema": {"type": "string"}}
+        ],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TransferRequest"}}}},
+        "responses": {
+          "200": {"description": "The bond was transferred", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Envelope"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "412": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "RealEstateID": {"name": "id", "in": "path", "required": true, "description": "blueprint_number.realestate_number, e.g. 1232.21", "schema": {"type": "string"}},
+      "IdempotencyKey": {"name": "Idempotency-Key", "in": "header", "description": "A retry with the same key returns the result of the first call", "schema": {"type": "string", "maxLength": 128}}
+    },
+    "responses": {
+      "Error": {"description": "The chaincode rejected the call", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Envelope"}}}}
+    },
+    "schemas": {
+      "Envelope": {
+        "type": "object",
+        "required": ["status", "code", "message"],
+        "properties": {
+          "status": {"type": "integer", "format": "int32"},
+          "code": {"type": "string", "enum": ["OK", "ERROR", "BOND_NOT_FOUND", "BOND_EXISTS", "NOT_AUTHORIZED", "INVALID_STATE", "VERSION_CONFLICT", "INVALID_ARGUMENT", "UNKNOWN_FUNCTION"]},
+          "message": {"type": "string"},
+          "data": {}
+        }
+      },
+      "CreateRequest": {
+        "type": "object",
+        "required": ["real_estate_id", "owner_national_id", "status", "long", "lat"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "description": "Generated by the chaincode when empty"},
+          "real_estate_id": {"type": "string"},
+          "owner_national_id": {"type": "string"},
+          "status": {"type": "string", "enum": ["flat", "built"]},
+          "area": {"type": "string", "example": "500 m2"},
+          "long": {"type": "number"},
+          "lat": {"type": "number"},
+          "boundary": {"$ref": "#/components/schemas/Polygon"},
+          "district_code": {"type": "string"},
+          "street": {"type": "string"}
+        }
+      },
+      "TransferRequest": {
+        "type": "object",
+        "required": ["recipient_national_id"],
+        "additionalProperties": false,
+        "properties": {
+          "recipient_national_id": {"type": "string"},
+          "recipient_msp": {"type": "string", "description": "The organisation of the current owner when empty"},
+          "declared_value": {"type": "number"},
+          "salt": {"type": "string", "description": "Required with a declared value"}
+        }
+      },
+      "Bond": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "real_estate_id": {"type": "string"},
+          "owner_national_id": {"type": "string"},
+          "owner_msp": {"type": "string"},
+          "status": {"type": "string"},
+          "area": {"type": "object", "properties": {"value": {"type": "number"}, "unit": {"type": "string"}}},
+          "coordinates": {"type": "object", "properties": {"long": {"type": "number"}, "lat": {"type": "number"}}},
+          "boundary": {"$ref": "#/components/schemas/Polygon"},
+          "geohash": {"type": "string"},
+          "created_at": {"type": "string"},
+          "updated_at": {"type": "string"},
+          "created_tx": {"type": "string"},
+          "last_modified_tx": {"type": "string"},
+          "district_code": {"type": "string"},
+          "city_code": {"type": "string"},
+          "street": {"type": "string"},
+          "exported_to": {"type": "string"},
+          "token_id": {"type": "string"},
+          "schema_version": {"type": "integer"},
+          "version": {"type": "integer"}
+        }
+      },
+      "FunctionRequest": {
+        "type": "object",
+        "required": ["args"],
+        "additionalProperties": false,
+        "properties": {
+          "args": {"type": "array", "items": {"type": "string"}, "description": "The arguments of the function in order, numbers and JSON as text"}
+        }
+      },
+      "Polygon": {
+        "type": "object",
+        "required": ["type", "coordinates"],
+        "properties": {
+          "type": {"type": "string", "enum": ["Polygon"]},
+          "coordinates": {"type": "array", "items": {"type": "array", "items": {"type": "array", "items": {"type": "number"}, "minItems": 2, "maxItems": 2}}}
+        }
+      }
+    }
+  }
+}
+`
+
+//==============================================================================================================================
+//	 openapi_spec - Returns OPENAPI_BASE with the route of each function added.
+//==============================================================================================================================
+func openapi_spec(functions []Function_Description) ([]byte, error) {
+
+	var spec map[string]interface{}
+
+	err := json.Unmarshal([]byte(OPENAPI_BASE), &spec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paths := spec["paths"].(map[string]interface{})
+
+	for _, f := range functions {
+		paths["/functions/"+f.Name] = map[string]interface{}{"post": function_operation(f)}
+	}
+
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+//==============================================================================================================================
+//	 function_operation - Returns the OpenAPI operation of the function's route. The arguments are described in order
+//						  as the body carries them, with how many the function takes.
+//==============================================================================================================================
+func function_operation(f Function_Description) map[string]interface{} {
+
+	var names []string
+	required := 0
+
+	for _, a := range f.Args {
+
+		names = append(names, a.Name+" ("+a.Type+")")
+
+		if !a.Optional {
+			required++
+		}
+	}
+
+	description := "No arguments."
+
+	if len(names) > 0 {
+		description = "Arguments: " + strings.Join(names, ", ") + "."
+	}
+
+	if f.Variadic {
+		description += " Takes further arguments."
+	}
+
+	if f.Role != "" {
+		description += " Requires the " + f.Role + " role."
+	}
+
+	args := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "minItems": required}
+
+	if !f.Variadic {
+		args["maxItems"] = len(f.Args)
+	}
+
+	body := map[string]interface{}{
+		"allOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/FunctionRequest"},
+			map[string]interface{}{"properties": map[string]interface{}{"args": args}},
+		},
+	}
+
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{"description": "The response of " + f.Name, "content": map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Envelope"}}}},
+	}
+
+	for _, code := range FUNCTION_ERRORS {
+		responses[code] = map[string]interface{}{"$ref": "#/components/responses/Error"}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": f.Name,
+		"summary":     f.Name,
+		"description": description,
+		"requestBody": map[string]interface{}{"required": true, "content": map[string]interface{}{"application/json": map[string]interface{}{"schema": body}}},
+		"responses":   responses,
+	}
+
+	if f.Writes {
+		operation["parameters"] = []interface{}{
+			map[string]interface{}{"$ref": "#/components/parameters/IdempotencyKey"},
+			map[string]interface{}{"name": "If-Match", "in": "header", "description": "Version the bond must still be at, see expected_version", "schema": map[string]interface{}{"type": "string"}},
+		}
+	}
+
+	return operation
+}
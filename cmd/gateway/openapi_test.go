@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+
+	functions := []Function_Description{
+		{Name: "get_bonds", Args: []Arg_Description{}},
+		{Name: "change_address", Args: []Arg_Description{{Name: "realEstateID", Type: "string"}, {Name: "districtCode", Type: "string"}, {Name: "street", Type: "string", Optional: true}}, Writes: true},
+		{Name: "create_bond", Args: []Arg_Description{{Name: "bond JSON object or id", Type: "string"}}, Variadic: true, Writes: true},
+	}
+
+	bytes, err := openapi_spec(functions)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			OperationID string        `json:"operationId"`
+			Description string        `json:"description"`
+			Parameters  []interface{} `json:"parameters"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema struct {
+						AllOf []struct {
+							Properties struct {
+								Args struct {
+									MinItems int  `json:"minItems"`
+									MaxItems *int `json:"maxItems"`
+								} `json:"args"`
+							} `json:"properties"`
+						} `json:"allOf"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+		} `json:"paths"`
+	}
+
+	err = json.Unmarshal(bytes, &spec)
+
+	if err != nil {
+		t.Fatalf("Invalid spec %s", err)
+	}
+
+	if len(spec.Paths) != 6 || spec.Paths["/bonds/{id}"]["get"].OperationID != "getBond" {
+		t.Fatalf("Unexpected paths %v", spec.Paths)
+	}
+
+	change := spec.Paths["/functions/change_address"]["post"]
+	args := change.RequestBody.Content["application/json"].Schema.AllOf[1].Properties.Args
+
+	if change.OperationID != "change_address" || len(change.Parameters) != 2 || args.MinItems != 2 || args.MaxItems == nil || *args.MaxItems != 3 {
+		t.Errorf("Unexpected change_address operation %+v", change)
+	}
+
+	if change.Description != "Arguments: realEstateID (string), districtCode (string), street (string)." {
+		t.Errorf("Unexpected description %q", change.Description)
+	}
+
+	create := spec.Paths["/functions/create_bond"]["post"]
+
+	if create.RequestBody.Content["application/json"].Schema.AllOf[1].Properties.Args.MaxItems != nil {
+		t.Errorf("Expected no limit on the arguments of a variadic function")
+	}
+
+	if len(spec.Paths["/functions/get_bonds"]["post"].Parameters) != 0 {
+		t.Errorf("Expected no change headers on a query")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/alfaifiisa/learn-chaincode/cmd/grpcserver/registrypb"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+//==============================================================================================================================
+//	 Bond Registry gRPC Server - Serves the BondRegistry service of registry.proto for systems that are not Fabric
+//								 aware. Callers authenticate with a TLS client certificate issued by the client CA and
+//								 calls are passed on to the chaincode as the identity in the wallet labelled with its
+//								 common name, as the REST gateway in cmd/gateway does.
+//
+//		grpcserver -profile connection.yaml -wallet ./wallet -tls-cert server.pem -tls-key server.key -client-ca ca.pem
+//==============================================================================================================================
+
+// Largest request accepted, a bond with a detailed boundary is well under it
+const MAX_MESSAGE_SIZE = 1 << 20
+
+//==============================================================================================================================
+//	 main - Serves the service until it fails.
+//==============================================================================================================================
+func main() {
+
+	listen := flag.String("listen", ":9443", "address to serve gRPC on")
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet holding the callers' identities")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	tlsCert := flag.String("tls-cert", "server.pem", "certificate of the server")
+	tlsKey := flag.String("tls-key", "server.key", "private key of the server")
+	clientCA := flag.String("client-ca", "client-ca.pem", "CA issuing the client certificates callers authenticate with")
+
+	flag.Parse()
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", *walletPath, err)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+
+	if err != nil {
+		log.Fatalf("Error loading server certificate %s: %s", *tlsCert, err)
+	}
+
+	pem, err := ioutil.ReadFile(*clientCA)
+
+	if err != nil {
+		log.Fatalf("Error reading client CA %s: %s", *clientCA, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatalf("No certificates in client CA %s", *clientCA)
+	}
+
+	s := &Registry_Server{
+		config:    config.FromFile(*profile),
+		wallet:    wallet,
+		channel:   *channel,
+		chaincode: *chaincode,
+		clients:   make(map[string]*client.BondRegistryClient),
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)), grpc.MaxRecvMsgSize(MAX_MESSAGE_SIZE))
+
+	registrypb.RegisterBondRegistryServer(server, s)
+
+	listener, err := net.Listen("tcp", *listen)
+
+	if err != nil {
+		log.Fatalf("Error listening on %s: %s", *listen, err)
+	}
+
+	log.Printf("Serving %s/%s on %s", *channel, *chaincode, *listen)
+
+	log.Fatal(server.Serve(listener))
+}
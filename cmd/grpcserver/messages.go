@@ -0,0 +1,177 @@
+package main
+
+import (
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/alfaifiisa/learn-chaincode/cmd/grpcserver/registrypb"
+	"github.com/alfaifiisa/learn-chaincode/finished/bondpb"
+)
+
+//==============================================================================================================================
+//	 Messages - Conversions between the bonds of the client and the messages of registry.proto, generated in registrypb.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 bond_message - Returns the Bond message of the bond. Empty area and coordinates are left out as the chaincode does.
+//==============================================================================================================================
+func bond_message(b client.Bond) *bondpb.Bond {
+
+	m := &bondpb.Bond{
+		Id:              b.ID,
+		RealEstateId:    b.RealEstateID,
+		OwnerNationalId: b.OwnerNationalID,
+		OwnerMsp:        b.OwnerMSP,
+		Status:          b.Status,
+		Geohash:         b.Geohash,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
+		CreatedTx:       b.CreatedTx,
+		LastModifiedTx:  b.LastModifiedTx,
+		DistrictCode:    b.DistrictCode,
+		CityCode:        b.CityCode,
+		Street:          b.Street,
+		ExportedTo:      b.ExportedTo,
+		SchemaVersion:   int64(b.SchemaVersion),
+		Version:         int64(b.Version),
+		TokenId:         b.TokenID,
+		OwnerIdType:     b.OwnerIDType,
+	}
+
+	if b.Area != (client.Land_Area{}) {
+		m.Area = &bondpb.LandArea{Value: b.Area.Value, Unit: b.Area.Unit}
+	}
+
+	if b.Coordinates != (client.Coordinates{}) {
+		m.Coordinates = &bondpb.Coordinates{Long: b.Coordinates.Long, Lat: b.Coordinates.Lat, Unknown: b.Coordinates.Unknown}
+	}
+
+	if b.Boundary != nil {
+
+		m.Boundary = &bondpb.Polygon{Type: b.Boundary.Type}
+
+		for _, ring := range b.Boundary.Coordinates {
+
+			r := &bondpb.Ring{}
+
+			for _, position := range ring {
+				r.Positions = append(r.Positions, position[0], position[1])
+			}
+
+			m.Boundary.Rings = append(m.Boundary.Rings, r)
+		}
+	}
+
+	if b.Provenance != nil {
+		m.Provenance = &bondpb.Provenance{
+			Origin:           b.Provenance.Origin,
+			LegacyDeedNumber: b.Provenance.LegacyDeedNumber,
+			MigrationBatch:   b.Provenance.MigrationBatch,
+			MigratedAt:       b.Provenance.MigratedAt,
+			MigratedBy:       b.Provenance.MigratedBy,
+		}
+	}
+
+	return m
+}
+
+//==============================================================================================================================
+//	 bond_from_message - Returns the bond of a Bond message, the zero bond when there is none.
+//==============================================================================================================================
+func bond_from_message(m *bondpb.Bond) client.Bond {
+
+	if m == nil {
+		return client.Bond{}
+	}
+
+	b := client.Bond{
+		ID:              m.Id,
+		RealEstateID:    m.RealEstateId,
+		OwnerNationalID: m.OwnerNationalId,
+		OwnerMSP:        m.OwnerMsp,
+		Status:          m.Status,
+		Geohash:         m.Geohash,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+		CreatedTx:       m.CreatedTx,
+		LastModifiedTx:  m.LastModifiedTx,
+		DistrictCode:    m.DistrictCode,
+		CityCode:        m.CityCode,
+		Street:          m.Street,
+		ExportedTo:      m.ExportedTo,
+		SchemaVersion:   int(m.SchemaVersion),
+		Version:         int(m.Version),
+		TokenID:         m.TokenId,
+		OwnerIDType:     m.OwnerIdType,
+	}
+
+	if m.Area != nil {
+		b.Area = client.Land_Area{Value: m.Area.Value, Unit: m.Area.Unit}
+	}
+
+	if m.Coordinates != nil {
+		b.Coordinates = client.Coordinates{Long: m.Coordinates.Long, Lat: m.Coordinates.Lat, Unknown: m.Coordinates.Unknown}
+	}
+
+	if m.Boundary != nil {
+
+		b.Boundary = &client.Polygon{Type: m.Boundary.Type}
+
+		for _, r := range m.Boundary.Rings {
+
+			var ring [][2]float64
+
+			for i := 0; i+1 < len(r.Positions); i += 2 {
+				ring = append(ring, [2]float64{r.Positions[i], r.Positions[i+1]})
+			}
+
+			b.Boundary.Coordinates = append(b.Boundary.Coordinates, ring)
+		}
+	}
+
+	if m.Provenance != nil {
+		b.Provenance = &client.Provenance{
+			Origin:           m.Provenance.Origin,
+			LegacyDeedNumber: m.Provenance.LegacyDeedNumber,
+			MigrationBatch:   m.Provenance.MigrationBatch,
+			MigratedAt:       m.Provenance.MigratedAt,
+			MigratedBy:       m.Provenance.MigratedBy,
+		}
+	}
+
+	return b
+}
+
+//==============================================================================================================================
+//	 bond_list - Returns the BondList message of the bonds.
+//==============================================================================================================================
+func bond_list(bonds []client.Bond) *registrypb.BondList {
+
+	list := &registrypb.BondList{}
+
+	for _, b := range bonds {
+		list.Bonds = append(list.Bonds, bond_message(b))
+	}
+
+	return list
+}
+
+//==============================================================================================================================
+//	 audit_log - Returns the AuditLog message of the records.
+//==============================================================================================================================
+func audit_log(records []client.Audit_Record) *registrypb.AuditLog {
+
+	audit := &registrypb.AuditLog{}
+
+	for _, r := range records {
+		audit.Records = append(audit.Records, &registrypb.AuditRecord{
+			RealEstateId: r.RealEstateID,
+			Function:     r.Function,
+			Actor:        r.Actor,
+			Txid:         r.TxID,
+			Timestamp:    r.Timestamp,
+			BeforeHash:   r.BeforeHash,
+			AfterHash:    r.AfterHash,
+		})
+	}
+
+	return audit
+}
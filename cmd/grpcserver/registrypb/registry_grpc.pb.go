@@ -0,0 +1,278 @@
+// The bond registry as a gRPC service, served by cmd/grpcserver for systems that do not use a Fabric SDK. Each call is
+// passed on to the chaincode as the identity of the caller, see main.go. registrypb is generated from this file, with
+// the Bond message taken from finished/bondpb; regenerate it after any change:
+//
+//   protoc -I . -I ../../finished --go_out=registrypb --go_opt=paths=source_relative \
+//     --go-grpc_out=registrypb --go-grpc_opt=paths=source_relative registry.proto
+//
+// Field numbers must never be reused; append new fields with new numbers. Clients generate their stubs from this
+// file and bond.proto in the same way.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: registry.proto
+
+package registrypb
+
+import (
+	context "context"
+	bondpb "github.com/alfaifiisa/learn-chaincode/finished/bondpb"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BondRegistry_CreateBond_FullMethodName        = "/registry.BondRegistry/CreateBond"
+	BondRegistry_TransferOwnership_FullMethodName = "/registry.BondRegistry/TransferOwnership"
+	BondRegistry_GetBond_FullMethodName           = "/registry.BondRegistry/GetBond"
+	BondRegistry_QueryByOwner_FullMethodName      = "/registry.BondRegistry/QueryByOwner"
+	BondRegistry_GetHistory_FullMethodName        = "/registry.BondRegistry/GetHistory"
+)
+
+// BondRegistryClient is the client API for BondRegistry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BondRegistryClient interface {
+	// create_bond, returning the ID of the bond
+	CreateBond(ctx context.Context, in *CreateBondRequest, opts ...grpc.CallOption) (*CreateBondResponse, error)
+	// tranfer_bond
+	TransferOwnership(ctx context.Context, in *TransferOwnershipRequest, opts ...grpc.CallOption) (*TransferOwnershipResponse, error)
+	// get_bond_details
+	GetBond(ctx context.Context, in *GetBondRequest, opts ...grpc.CallOption) (*bondpb.Bond, error)
+	// get_bonds_by_owner
+	QueryByOwner(ctx context.Context, in *QueryByOwnerRequest, opts ...grpc.CallOption) (*BondList, error)
+	// get_audit_log, for admin identities
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*AuditLog, error)
+}
+
+type bondRegistryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBondRegistryClient(cc grpc.ClientConnInterface) BondRegistryClient {
+	return &bondRegistryClient{cc}
+}
+
+func (c *bondRegistryClient) CreateBond(ctx context.Context, in *CreateBondRequest, opts ...grpc.CallOption) (*CreateBondResponse, error) {
+	out := new(CreateBondResponse)
+	err := c.cc.Invoke(ctx, BondRegistry_CreateBond_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bondRegistryClient) TransferOwnership(ctx context.Context, in *TransferOwnershipRequest, opts ...grpc.CallOption) (*TransferOwnershipResponse, error) {
+	out := new(TransferOwnershipResponse)
+	err := c.cc.Invoke(ctx, BondRegistry_TransferOwnership_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bondRegistryClient) GetBond(ctx context.Context, in *GetBondRequest, opts ...grpc.CallOption) (*bondpb.Bond, error) {
+	out := new(bondpb.Bond)
+	err := c.cc.Invoke(ctx, BondRegistry_GetBond_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bondRegistryClient) QueryByOwner(ctx context.Context, in *QueryByOwnerRequest, opts ...grpc.CallOption) (*BondList, error) {
+	out := new(BondList)
+	err := c.cc.Invoke(ctx, BondRegistry_QueryByOwner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bondRegistryClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*AuditLog, error) {
+	out := new(AuditLog)
+	err := c.cc.Invoke(ctx, BondRegistry_GetHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BondRegistryServer is the server API for BondRegistry service.
+// All implementations must embed UnimplementedBondRegistryServer
+// for forward compatibility
+type BondRegistryServer interface {
+	// create_bond, returning the ID of the bond
+	CreateBond(context.Context, *CreateBondRequest) (*CreateBondResponse, error)
+	// tranfer_bond
+	TransferOwnership(context.Context, *TransferOwnershipRequest) (*TransferOwnershipResponse, error)
+	// get_bond_details
+	GetBond(context.Context, *GetBondRequest) (*bondpb.Bond, error)
+	// get_bonds_by_owner
+	QueryByOwner(context.Context, *QueryByOwnerRequest) (*BondList, error)
+	// get_audit_log, for admin identities
+	GetHistory(context.Context, *GetHistoryRequest) (*AuditLog, error)
+	mustEmbedUnimplementedBondRegistryServer()
+}
+
+// UnimplementedBondRegistryServer must be embedded to have forward compatible implementations.
+type UnimplementedBondRegistryServer struct {
+}
+
+func (UnimplementedBondRegistryServer) CreateBond(context.Context, *CreateBondRequest) (*CreateBondResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBond not implemented")
+}
+func (UnimplementedBondRegistryServer) TransferOwnership(context.Context, *TransferOwnershipRequest) (*TransferOwnershipResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferOwnership not implemented")
+}
+func (UnimplementedBondRegistryServer) GetBond(context.Context, *GetBondRequest) (*bondpb.Bond, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBond not implemented")
+}
+func (UnimplementedBondRegistryServer) QueryByOwner(context.Context, *QueryByOwnerRequest) (*BondList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryByOwner not implemented")
+}
+func (UnimplementedBondRegistryServer) GetHistory(context.Context, *GetHistoryRequest) (*AuditLog, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedBondRegistryServer) mustEmbedUnimplementedBondRegistryServer() {}
+
+// UnsafeBondRegistryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BondRegistryServer will
+// result in compilation errors.
+type UnsafeBondRegistryServer interface {
+	mustEmbedUnimplementedBondRegistryServer()
+}
+
+func RegisterBondRegistryServer(s grpc.ServiceRegistrar, srv BondRegistryServer) {
+	s.RegisterService(&BondRegistry_ServiceDesc, srv)
+}
+
+func _BondRegistry_CreateBond_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBondRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BondRegistryServer).CreateBond(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BondRegistry_CreateBond_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BondRegistryServer).CreateBond(ctx, req.(*CreateBondRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BondRegistry_TransferOwnership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferOwnershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BondRegistryServer).TransferOwnership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BondRegistry_TransferOwnership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BondRegistryServer).TransferOwnership(ctx, req.(*TransferOwnershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BondRegistry_GetBond_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBondRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BondRegistryServer).GetBond(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BondRegistry_GetBond_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BondRegistryServer).GetBond(ctx, req.(*GetBondRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BondRegistry_QueryByOwner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryByOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BondRegistryServer).QueryByOwner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BondRegistry_QueryByOwner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BondRegistryServer).QueryByOwner(ctx, req.(*QueryByOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BondRegistry_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BondRegistryServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BondRegistry_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BondRegistryServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BondRegistry_ServiceDesc is the grpc.ServiceDesc for BondRegistry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BondRegistry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "registry.BondRegistry",
+	HandlerType: (*BondRegistryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateBond",
+			Handler:    _BondRegistry_CreateBond_Handler,
+		},
+		{
+			MethodName: "TransferOwnership",
+			Handler:    _BondRegistry_TransferOwnership_Handler,
+		},
+		{
+			MethodName: "GetBond",
+			Handler:    _BondRegistry_GetBond_Handler,
+		},
+		{
+			MethodName: "QueryByOwner",
+			Handler:    _BondRegistry_QueryByOwner_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _BondRegistry_GetHistory_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "registry.proto",
+}
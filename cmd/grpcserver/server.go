@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/alfaifiisa/learn-chaincode/cmd/grpcserver/registrypb"
+	"github.com/alfaifiisa/learn-chaincode/finished/bondpb"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC status answering each error code of the chaincode, any other code is unavailable
+var STATUS_BY_CODE = map[string]codes.Code{
+	client.CODE_BOND_NOT_FOUND:   codes.NotFound,
+	client.CODE_BOND_EXISTS:      codes.AlreadyExists,
+	client.CODE_NOT_AUTHORIZED:   codes.PermissionDenied,
+	client.CODE_INVALID_STATE:    codes.FailedPrecondition,
+	client.CODE_VERSION_CONFLICT: codes.Aborted,
+	client.CODE_INVALID_ARGUMENT: codes.InvalidArgument,
+	client.CODE_UNKNOWN_FUNCTION: codes.Unimplemented,
+}
+
+//==============================================================================================================================
+//	 Registry_Server - The BondRegistry service of registry.proto, holding a client per caller identity once it has been
+//					   used.
+//==============================================================================================================================
+type Registry_Server struct {
+	registrypb.UnimplementedBondRegistryServer
+
+	config    core.ConfigProvider
+	wallet    *gateway.Wallet
+	channel   string
+	chaincode string
+
+	mutex   sync.Mutex
+	clients map[string]*client.BondRegistryClient
+}
+
+//==============================================================================================================================
+//	 registry - Returns the client acting as the wallet identity labelled with the common name of the verified client
+//				certificate of the call, connecting on first use.
+//==============================================================================================================================
+func (s *Registry_Server) registry(ctx context.Context) (*client.BondRegistryClient, error) {
+
+	p, ok := peer.FromContext(ctx)
+
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "A client certificate is required")
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "A client certificate is required")
+	}
+
+	label := info.State.VerifiedChains[0][0].Subject.CommonName
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c, ok := s.clients[label]; ok {
+		return c, nil
+	}
+
+	if label == "" || !s.wallet.Exists(label) {
+		return nil, status.Error(codes.PermissionDenied, "No identity for "+label+" in the wallet")
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(s.config), gateway.WithIdentity(s.wallet, label))
+
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "Error connecting as "+label+": "+err.Error())
+	}
+
+	network, err := gw.GetNetwork(s.channel)
+
+	if err != nil {
+		gw.Close()
+		return nil, status.Error(codes.Unavailable, "Error joining channel "+s.channel+": "+err.Error())
+	}
+
+	c := client.New(client.FromGateway(network.GetContract(s.chaincode)))
+
+	s.clients[label] = c
+
+	return c, nil
+}
+
+//==============================================================================================================================
+//	 grpc_error - Returns the gRPC status of an error of the client. The code of the chaincode starts the message.
+//==============================================================================================================================
+func grpc_error(err error) error {
+
+	if err == context.Canceled {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	if err == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	e, ok := err.(*client.Error)
+
+	if !ok {
+		return status.Error(codes.Unavailable, err.Error()) // not an answer of the chaincode, e.g. no peer reachable
+	}
+
+	code, ok := STATUS_BY_CODE[e.Code]
+
+	if !ok {
+		code = codes.Unavailable
+	}
+
+	return status.Error(code, e.Code+" "+e.Message)
+}
+
+//==============================================================================================================================
+//	 change_options - Returns the call options of a change.
+//==============================================================================================================================
+func change_options(idempotencyKey string, expectedVersion *int64) []client.CallOption {
+
+	var options []client.CallOption
+
+	if idempotencyKey != "" {
+		options = append(options, client.WithIdempotencyKey(idempotencyKey))
+	}
+
+	if expectedVersion != nil {
+		options = append(options, client.WithExpectedVersion(int(*expectedVersion)))
+	}
+
+	return options
+}
+
+func (s *Registry_Server) CreateBond(ctx context.Context, req *registrypb.CreateBondRequest) (*registrypb.CreateBondResponse, error) {
+
+	registry, err := s.registry(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := registry.CreateBond(ctx, bond_from_message(req.Bond), change_options(req.IdempotencyKey, nil)...)
+
+	if err != nil {
+		return nil, grpc_error(err)
+	}
+
+	return &registrypb.CreateBondResponse{Id: id}, nil
+}
+
+func (s *Registry_Server) TransferOwnership(ctx context.Context, req *registrypb.TransferOwnershipRequest) (*registrypb.TransferOwnershipResponse, error) {
+
+	registry, err := s.registry(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := client.Transfer{
+		RecipientNationalID: req.RecipientNationalId,
+		RecipientMSP:        req.RecipientMsp,
+		DeclaredValue:       req.DeclaredValue,
+		Salt:                req.Salt,
+	}
+
+	err = registry.TransferOwnership(ctx, req.RealEstateId, transfer, change_options(req.IdempotencyKey, req.ExpectedVersion)...)
+
+	if err != nil {
+		return nil, grpc_error(err)
+	}
+
+	return &registrypb.TransferOwnershipResponse{}, nil
+}
+
+func (s *Registry_Server) GetBond(ctx context.Context, req *registrypb.GetBondRequest) (*bondpb.Bond, error) {
+
+	registry, err := s.registry(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := registry.GetBond(ctx, req.RealEstateId)
+
+	if err != nil {
+		return nil, grpc_error(err)
+	}
+
+	return bond_message(b), nil
+}
+
+func (s *Registry_Server) QueryByOwner(ctx context.Context, req *registrypb.QueryByOwnerRequest) (*registrypb.BondList, error) {
+
+	registry, err := s.registry(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds, err := registry.QueryByOwner(ctx, req.OwnerNationalId)
+
+	if err != nil {
+		return nil, grpc_error(err)
+	}
+
+	return bond_list(bonds), nil
+}
+
+func (s *Registry_Server) GetHistory(ctx context.Context, req *registrypb.GetHistoryRequest) (*registrypb.AuditLog, error) {
+
+	registry, err := s.registry(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := registry.History(ctx, req.RealEstateId)
+
+	if err != nil {
+		return nil, grpc_error(err)
+	}
+
+	return audit_log(records), nil
+}
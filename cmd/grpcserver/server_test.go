@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/alfaifiisa/learn-chaincode/cmd/grpcserver/registrypb"
+	"github.com/alfaifiisa/learn-chaincode/finished/bondpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+//==============================================================================================================================
+//	 fake_contract - Records the last call and answers with the payload or error given.
+//==============================================================================================================================
+type fake_contract struct {
+	function  string
+	transient map[string][]byte
+	args      []string
+
+	payload string
+	err     error
+}
+
+func (f *fake_contract) Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return f.Submit(function, transient, args...)
+}
+
+func (f *fake_contract) Submit(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	f.function, f.transient, f.args = function, transient, args
+
+	return []byte(f.payload), f.err
+}
+
+//==============================================================================================================================
+//	 certificate - Returns a certificate for the common name signed by the parent, self-signed when parent is nil.
+//==============================================================================================================================
+func certificate(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer, signer := template, interface{}(key)
+
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		issuer, signer = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+//==============================================================================================================================
+//	 serve - Serves the registry over TLS in memory, calling the contract as the identity clerk, and returns a client
+//			 authenticated with the certificate of clerk.
+//==============================================================================================================================
+func serve(t *testing.T, contract *fake_contract) registrypb.BondRegistryClient {
+
+	ca := certificate(t, "ca", nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	s := &Registry_Server{clients: map[string]*client.BondRegistryClient{"clerk": client.New(contract)}}
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{certificate(t, "localhost", &ca)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+
+	registrypb.RegisterBondRegistryServer(server, s)
+
+	listener := bufconn.Listen(1 << 20)
+
+	go server.Serve(listener)
+
+	t.Cleanup(server.Stop)
+
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{certificate(t, "clerk", &ca)},
+		RootCAs:      pool,
+		ServerName:   "localhost",
+	})
+
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(dial), grpc.WithTransportCredentials(creds))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return registrypb.NewBondRegistryClient(conn)
+}
+
+func TestCreateBond(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":"","data":"bond-1"}`}
+
+	req := &registrypb.CreateBondRequest{
+		Bond: &bondpb.Bond{
+			RealEstateId:    "1232.21",
+			OwnerNationalId: "1000000001",
+			Status:          "built",
+			Area:            &bondpb.LandArea{Value: 500, Unit: "m2"},
+			Coordinates:     &bondpb.Coordinates{Long: 46.6753, Lat: 24.7136},
+		},
+		IdempotencyKey: "key-1",
+	}
+
+	resp, err := serve(t, f).CreateBond(context.Background(), req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Id != "bond-1" {
+		t.Errorf("Expected ID bond-1, got %q", resp.Id)
+	}
+
+	if f.function != "create_bond" || f.args[1] != "1232.21" || f.args[4] != "500 m2" {
+		t.Errorf("Unexpected call %s %q", f.function, f.args)
+	}
+
+	if string(f.transient["owner_national_id"]) != "1000000001" || string(f.transient["idempotency_key"]) != "key-1" {
+		t.Errorf("Unexpected transient map %q", f.transient)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":""}`}
+
+	version := int64(0)
+
+	req := &registrypb.TransferOwnershipRequest{RealEstateId: "1232.21", RecipientNationalId: "1000000002", ExpectedVersion: &version}
+
+	_, err := serve(t, f).TransferOwnership(context.Background(), req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "tranfer_bond" || f.args[0] != "1232.21" {
+		t.Errorf("Unexpected call %s %q", f.function, f.args)
+	}
+
+	// A version of 0 is still given, the field is optional in registry.proto
+	if string(f.transient["expected_version"]) != "0" || string(f.transient["recipient_national_id"]) != "1000000002" {
+		t.Errorf("Unexpected transient map %q", f.transient)
+	}
+}
+
+func TestGetBond(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":"","data":{"id":"bond-1","real_estate_id":"1232.21",` +
+		`"owner_national_id":"1000000001","status":"built","area":{"value":500,"unit":"m2"},"coordinates":{"long":46.6753,"lat":24.7136},` +
+		`"boundary":{"type":"Polygon","coordinates":[[[46.67,24.71],[46.68,24.71],[46.68,24.72],[46.67,24.71]]]},` +
+		`"geohash":"th3hs","updated_at":"2024-01-01T00:00:00Z","provenance":{"origin":"legacy","legacy_deed_number":"LD-77"},` +
+		`"schema_version":3,"version":2}}`}
+
+	expected := client.Bond{
+		ID:              "bond-1",
+		RealEstateID:    "1232.21",
+		OwnerNationalID: "1000000001",
+		Status:          "built",
+		Area:            client.Land_Area{Value: 500, Unit: "m2"},
+		Coordinates:     client.Coordinates{Long: 46.6753, Lat: 24.7136},
+		Boundary:        &client.Polygon{Type: "Polygon", Coordinates: [][][2]float64{{{46.67, 24.71}, {46.68, 24.71}, {46.68, 24.72}, {46.67, 24.71}}}},
+		Geohash:         "th3hs",
+		UpdatedAt:       "2024-01-01T00:00:00Z",
+		Provenance:      &client.Provenance{Origin: "legacy", LegacyDeedNumber: "LD-77"},
+		SchemaVersion:   3,
+		Version:         2,
+	}
+
+	b, err := serve(t, f).GetBond(context.Background(), &registrypb.GetBondRequest{RealEstateId: "1232.21"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "get_bond_details" || !reflect.DeepEqual(f.args, []string{"1232.21"}) {
+		t.Errorf("Unexpected call %s %q", f.function, f.args)
+	}
+
+	if !reflect.DeepEqual(bond_from_message(b), expected) {
+		t.Errorf("Expected %+v, got %+v", expected, bond_from_message(b))
+	}
+}
+
+func TestQueryByOwnerAndHistory(t *testing.T) {
+
+	f := &fake_contract{payload: `{"status":200,"code":"OK","message":"","data":[{"real_estate_id":"1232.21"},{"real_estate_id":"1232.22"}]}`}
+
+	registry := serve(t, f)
+
+	list, err := registry.QueryByOwner(context.Background(), &registrypb.QueryByOwnerRequest{OwnerNationalId: "1000000001"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "get_bonds_by_owner" || len(list.Bonds) != 2 || list.Bonds[1].RealEstateId != "1232.22" {
+		t.Errorf("Unexpected bonds %v from %s", list.Bonds, f.function)
+	}
+
+	f.payload = `{"status":200,"code":"OK","message":"","data":[{"real_estate_id":"1232.21","function":"create_bond","txid":"tx1","after_hash":"ab"}]}`
+
+	log, err := registry.GetHistory(context.Background(), &registrypb.GetHistoryRequest{RealEstateId: "1232.21"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.function != "get_audit_log" || len(log.Records) != 1 || log.Records[0].Txid != "tx1" || log.Records[0].AfterHash != "ab" {
+		t.Errorf("Unexpected records %v from %s", log.Records, f.function)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+
+	f := &fake_contract{err: errors.New(`chaincode error {"status":404,"code":"BOND_NOT_FOUND","message":"Bond 1232.99 not found"}`)}
+
+	registry := serve(t, f)
+
+	_, err := registry.GetBond(context.Background(), &registrypb.GetBondRequest{RealEstateId: "1232.99"})
+
+	if s := status.Convert(err); s.Code() != codes.NotFound || s.Message() != "BOND_NOT_FOUND Bond 1232.99 not found" {
+		t.Errorf("Unexpected status %v", s)
+	}
+
+	f.err = errors.New("no peer answered")
+
+	_, err = registry.GetBond(context.Background(), &registrypb.GetBondRequest{RealEstateId: "1232.99"})
+
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable, got %v", err)
+	}
+}
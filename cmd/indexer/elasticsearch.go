@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+// Bonds read per request when listing the hashes of the index
+const ELASTICSEARCH_PAGE_SIZE = 1000
+
+//==============================================================================================================================
+//	 Elasticsearch_Store - Keeps each bond as a document of the index, identified by its realEstateID, with its
+//						   coordinates mapped as a geo point for spatial queries. Talks to the REST API directly.
+//==============================================================================================================================
+type Elasticsearch_Store struct {
+	url   string
+	index string
+	http  *http.Client
+}
+
+const ELASTICSEARCH_MAPPING = `{
+  "mappings": {
+    "properties": {
+      "real_estate_id": {"type": "keyword"},
+      "owner_national_id": {"type": "keyword"},
+      "status": {"type": "keyword"},
+      "district_code": {"type": "keyword"},
+      "city_code": {"type": "keyword"},
+      "geohash": {"type": "keyword"},
+      "location": {"type": "geo_point"},
+      "boundary": {"type": "geo_shape"},
+      "updated_at": {"type": "date"},
+      "hash": {"type": "keyword"}
+    }
+  }
+}`
+
+//==============================================================================================================================
+//	 Elasticsearch_Document - A bond as indexed.
+//==============================================================================================================================
+type Elasticsearch_Document struct {
+	client.Bond
+	Location [2]float64 `json:"location"` // long, lat
+	Hash     string     `json:"hash"`
+}
+
+//==============================================================================================================================
+//	 new_elasticsearch_store - Returns the store of the index, creating the index if it does not exist yet.
+//==============================================================================================================================
+func new_elasticsearch_store(baseURL string, index string) (*Elasticsearch_Store, error) {
+
+	e := &Elasticsearch_Store{url: strings.TrimRight(baseURL, "/"), index: index, http: &http.Client{Timeout: 30 * time.Second}}
+
+	status, body, err := e.request(http.MethodHead, "/"+index, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusNotFound {
+
+		status, body, err = e.request(http.MethodPut, "/"+index, strings.NewReader(ELASTICSEARCH_MAPPING))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if status >= 300 {
+		return nil, errors.New("Error creating index " + index + ": " + string(body))
+	}
+
+	return e, nil
+}
+
+//==============================================================================================================================
+//	 request - Sends a request to the cluster and returns the status and body of its response.
+//==============================================================================================================================
+func (e *Elasticsearch_Store) request(method string, path string, body io.Reader) (int, []byte, error) {
+
+	req, err := http.NewRequest(method, e.url+path, body)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.http.Do(req)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+
+	return res.StatusCode, data, err
+}
+
+func (e *Elasticsearch_Store) Upsert(b client.Bond, hash string) error {
+
+	data, err := json.Marshal(Elasticsearch_Document{Bond: b, Location: [2]float64{b.Coordinates.Long, b.Coordinates.Lat}, Hash: hash})
+
+	if err != nil {
+		return err
+	}
+
+	status, body, err := e.request(http.MethodPut, "/"+e.index+"/_doc/"+url.PathEscape(b.RealEstateID), bytes.NewReader(data))
+
+	if err == nil && status >= 300 {
+		err = errors.New("Error indexing bond " + b.RealEstateID + ": " + string(body))
+	}
+
+	return err
+}
+
+func (e *Elasticsearch_Store) Delete(realEstateID string) error {
+
+	status, body, err := e.request(http.MethodDelete, "/"+e.index+"/_doc/"+url.PathEscape(realEstateID), nil)
+
+	if err == nil && status >= 300 && status != http.StatusNotFound {
+		err = errors.New("Error deleting bond " + realEstateID + ": " + string(body))
+	}
+
+	return err
+}
+
+//==============================================================================================================================
+//	 Hashes - Pages through the index in realEstateID order with search_after.
+//==============================================================================================================================
+func (e *Elasticsearch_Store) Hashes() (map[string]string, error) {
+
+	hashes := make(map[string]string)
+
+	var after []interface{}
+
+	for {
+
+		query := map[string]interface{}{
+			"size":    ELASTICSEARCH_PAGE_SIZE,
+			"_source": []string{"hash"},
+			"sort":    []map[string]string{{"real_estate_id": "asc"}},
+		}
+
+		if after != nil {
+			query["search_after"] = after
+		}
+
+		data, err := json.Marshal(query)
+
+		if err != nil {
+			return nil, err
+		}
+
+		status, body, err := e.request(http.MethodPost, "/"+e.index+"/_search", bytes.NewReader(data))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if status >= 300 {
+			return nil, errors.New("Error searching index " + e.index + ": " + string(body))
+		}
+
+		var result struct {
+			Hits struct {
+				Hits []struct {
+					ID     string `json:"_id"`
+					Source struct {
+						Hash string `json:"hash"`
+					} `json:"_source"`
+					Sort []interface{} `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, err
+		}
+
+		for _, hit := range result.Hits.Hits {
+			hashes[hit.ID] = hit.Source.Hash
+			after = hit.Sort
+		}
+
+		if len(result.Hits.Hits) < ELASTICSEARCH_PAGE_SIZE {
+			return hashes, nil
+		}
+	}
+}
+
+func (e *Elasticsearch_Store) Close() error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Bond Indexer - Mirrors the bonds into PostgreSQL or Elasticsearch for analytical queries the chaincode is not built
+//					for, see store.go.
+//
+//		sync		Reconciles the store with the ledger, then follows the bond events of committed blocks, writing
+//					each bond they touch. Changes that emit no event, such as a new address, are picked up by the
+//					reconciliation repeated every -reconcile-every.
+//		reconcile	Reconciles once and prints the result, exiting with 1 unless the Merkle root of the store matches
+//					get_registry_checksum. With -dry-run the store is compared but not changed.
+//
+//		indexer -store postgres -postgres-dsn "postgres://indexer@db/registry?sslmode=require"
+//		indexer -mode reconcile -dry-run -store elasticsearch -es-url https://es:9200
+//==============================================================================================================================
+
+// Events that change a bond, a regular expression over the event names of the chaincode
+const EVENT_FILTER = "BondCreated|BondsCreated|BondTransferred"
+
+// Time allowed for a single reconciliation
+const RECONCILE_TIMEOUT = 30 * time.Minute
+
+//==============================================================================================================================
+//	 run_reconcile - Reconciles and logs the result.
+//==============================================================================================================================
+func run_reconcile(registry *client.BondRegistryClient, store Store, dryRun bool) (Reconciliation, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), RECONCILE_TIMEOUT)
+	defer cancel()
+
+	r, err := reconcile(ctx, registry, store, dryRun)
+
+	if err != nil {
+		return r, err
+	}
+
+	if r.Matches {
+		log.Printf("Reconciled %d bonds, %d written, %d deleted, root %s", r.TotalBonds, r.Upserted, r.Deleted, r.StoreRoot)
+	} else {
+		log.Printf("Reconciled %d bonds, %d written, %d deleted, root %s does not match ledger root %s of %d bonds", r.TotalBonds, r.Upserted, r.Deleted, r.StoreRoot, r.LedgerRoot, r.LedgerBonds)
+	}
+
+	return r, nil
+}
+
+//==============================================================================================================================
+//	 main - Runs the mode given.
+//==============================================================================================================================
+func main() {
+
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet")
+	identity := flag.String("identity", "indexer", "label of the identity in the wallet to read as, export_bonds needs an admin")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	mode := flag.String("mode", "sync", "sync or reconcile")
+	dryRun := flag.Bool("dry-run", false, "compare the store with the ledger without changing it, reconcile mode only")
+	reconcileEvery := flag.Duration("reconcile-every", time.Hour, "interval between reconciliations in sync mode")
+	storeName := flag.String("store", "postgres", "postgres or elasticsearch")
+	postgresDSN := flag.String("postgres-dsn", "postgres://localhost/registry?sslmode=disable", "PostgreSQL connection string")
+	esURL := flag.String("es-url", "http://localhost:9200", "Elasticsearch cluster")
+	esIndex := flag.String("es-index", "bonds", "Elasticsearch index")
+
+	flag.Parse()
+
+	var store Store
+	var err error
+
+	switch *storeName {
+	case "postgres":
+		store, err = new_postgres_store(*postgresDSN)
+	case "elasticsearch":
+		store, err = new_elasticsearch_store(*esURL, *esIndex)
+	default:
+		log.Fatalf("Unknown store %s, expecting postgres or elasticsearch", *storeName)
+	}
+
+	if err != nil {
+		log.Fatalf("Error opening %s store: %s", *storeName, err)
+	}
+
+	defer store.Close()
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", *walletPath, err)
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(config.FromFile(*profile)), gateway.WithIdentity(wallet, *identity))
+
+	if err != nil {
+		log.Fatalf("Error connecting as %s: %s", *identity, err)
+	}
+
+	defer gw.Close()
+
+	network, err := gw.GetNetwork(*channel)
+
+	if err != nil {
+		log.Fatalf("Error joining channel %s: %s", *channel, err)
+	}
+
+	contract := network.GetContract(*chaincode)
+	registry := client.New(client.FromGateway(contract))
+
+	if *mode == "reconcile" {
+
+		r, err := run_reconcile(registry, store, *dryRun)
+
+		if err != nil {
+			log.Fatalf("Error reconciling: %s", err)
+		}
+
+		json.NewEncoder(os.Stdout).Encode(r)
+
+		if !r.Matches {
+			store.Close()
+			gw.Close()
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if *mode != "sync" {
+		log.Fatalf("Unknown mode %s, expecting sync or reconcile", *mode)
+	}
+
+	// Register before reconciling so no event committed meanwhile is missed, they are applied once it is done
+	registration, events, err := contract.RegisterEvent(EVENT_FILTER)
+
+	if err != nil {
+		log.Fatalf("Error registering for events: %s", err)
+	}
+
+	defer contract.Unregister(registration)
+
+	if _, err := run_reconcile(registry, store, false); err != nil {
+		log.Fatalf("Error reconciling: %s", err)
+	}
+
+	ticker := time.NewTicker(*reconcileEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				log.Printf("Event stream closed")
+				return
+			}
+			if err := apply_event(context.Background(), registry, store, e.Payload); err != nil {
+				log.Printf("Error applying %s event of %s, left to the next reconciliation: %s", e.EventName, e.TxID, err)
+			}
+		case <-ticker.C:
+			if _, err := run_reconcile(registry, store, false); err != nil {
+				log.Printf("Error reconciling: %s", err)
+			}
+		}
+	}
+}
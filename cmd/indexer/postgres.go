@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	_ "github.com/lib/pq"
+)
+
+//==============================================================================================================================
+//	 Postgres_Store - Keeps the bonds in a table with a column for each field analytical queries filter or group on
+//					  and the whole bond as JSONB for the rest.
+//==============================================================================================================================
+type Postgres_Store struct {
+	db *sql.DB
+}
+
+const POSTGRES_SCHEMA = `
+CREATE TABLE IF NOT EXISTS bonds (
+	real_estate_id    TEXT PRIMARY KEY,
+	owner_national_id TEXT NOT NULL,
+	status            TEXT NOT NULL,
+	district_code     TEXT NOT NULL,
+	city_code         TEXT NOT NULL,
+	geohash           TEXT NOT NULL,
+	long              DOUBLE PRECISION NOT NULL,
+	lat               DOUBLE PRECISION NOT NULL,
+	area_value        DOUBLE PRECISION NOT NULL,
+	area_unit         TEXT NOT NULL,
+	updated_at        TEXT NOT NULL,
+	version           INTEGER NOT NULL,
+	bond              JSONB NOT NULL,
+	hash              TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS bonds_owner ON bonds (owner_national_id);
+CREATE INDEX IF NOT EXISTS bonds_district ON bonds (city_code, district_code);
+`
+
+const POSTGRES_UPSERT = `
+INSERT INTO bonds (real_estate_id, owner_national_id, status, district_code, city_code, geohash, long, lat, area_value, area_unit, updated_at, version, bond, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+ON CONFLICT (real_estate_id) DO UPDATE SET
+	owner_national_id = EXCLUDED.owner_national_id, status = EXCLUDED.status, district_code = EXCLUDED.district_code,
+	city_code = EXCLUDED.city_code, geohash = EXCLUDED.geohash, long = EXCLUDED.long, lat = EXCLUDED.lat,
+	area_value = EXCLUDED.area_value, area_unit = EXCLUDED.area_unit, updated_at = EXCLUDED.updated_at,
+	version = EXCLUDED.version, bond = EXCLUDED.bond, hash = EXCLUDED.hash
+`
+
+//==============================================================================================================================
+//	 new_postgres_store - Connects to the database and creates the table if it does not exist yet.
+//==============================================================================================================================
+func new_postgres_store(dsn string) (*Postgres_Store, error) {
+
+	db, err := sql.Open("postgres", dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(POSTGRES_SCHEMA)
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Postgres_Store{db: db}, nil
+}
+
+func (p *Postgres_Store) Upsert(b client.Bond, hash string) error {
+
+	bytes, err := json.Marshal(b)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(POSTGRES_UPSERT, b.RealEstateID, b.OwnerNationalID, b.Status, b.DistrictCode, b.CityCode, b.Geohash,
+		b.Coordinates.Long, b.Coordinates.Lat, b.Area.Value, b.Area.Unit, b.UpdatedAt, b.Version, string(bytes), hash)
+
+	return err
+}
+
+func (p *Postgres_Store) Delete(realEstateID string) error {
+
+	_, err := p.db.Exec("DELETE FROM bonds WHERE real_estate_id = $1", realEstateID)
+
+	return err
+}
+
+func (p *Postgres_Store) Hashes() (map[string]string, error) {
+
+	rows, err := p.db.Query("SELECT real_estate_id, hash FROM bonds")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+
+	for rows.Next() {
+
+		var id, hash string
+
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+
+		hashes[id] = hash
+	}
+
+	return hashes, rows.Err()
+}
+
+func (p *Postgres_Store) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 Store - An off-chain copy of the registry. Each bond is kept with its hash, the leaf get_registry_checksum uses for
+//			 it, see client.BondHash, so the copy can be checked against the ledger without reading it back in full.
+//==============================================================================================================================
+type Store interface {
+	Upsert(b client.Bond, hash string) error
+	Delete(realEstateID string) error
+	Hashes() (map[string]string, error) // hash of every stored bond by realEstateID
+	Close() error
+}
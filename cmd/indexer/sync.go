@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+// Bonds read per export_bonds query while reconciling
+const EXPORT_PAGE_SIZE = 500
+
+//==============================================================================================================================
+//	 Reconciliation - The result of comparing the store with the ledger.
+//==============================================================================================================================
+type Reconciliation struct {
+	Upserted    int    `json:"upserted"`
+	Deleted     int    `json:"deleted"`
+	TotalBonds  int    `json:"total_bonds"`
+	StoreRoot   string `json:"store_root"`
+	LedgerRoot  string `json:"ledger_root"`
+	LedgerBonds int    `json:"ledger_bonds"`
+	Matches     bool   `json:"matches"`
+}
+
+//==============================================================================================================================
+//	 reconcile - Brings the store in line with the ledger, writing the bonds whose hash differs from the one stored and
+//				 deleting those no longer on the ledger, then checks the Merkle root of the store against
+//				 get_registry_checksum. With dryRun the store is only compared. The roots can differ without either
+//				 being wrong when bonds change while the reconciliation runs; the next one settles it.
+//==============================================================================================================================
+func reconcile(ctx context.Context, registry *client.BondRegistryClient, store Store, dryRun bool) (Reconciliation, error) {
+
+	var r Reconciliation
+
+	stored, err := store.Hashes()
+
+	if err != nil {
+		return r, err
+	}
+
+	ledger := make(map[string]string, len(stored))
+	bookmark := ""
+
+	for {
+
+		page, err := registry.ExportBonds(ctx, EXPORT_PAGE_SIZE, bookmark)
+
+		if err != nil {
+			return r, err
+		}
+
+		for _, e := range page.Bonds {
+
+			ledger[e.Bond.RealEstateID] = e.Hash
+
+			if stored[e.Bond.RealEstateID] == e.Hash {
+				continue
+			}
+
+			r.Upserted++
+
+			if !dryRun {
+				if err := store.Upsert(e.Bond, e.Hash); err != nil {
+					return r, err
+				}
+			}
+		}
+
+		if page.Bookmark == "" {
+			break
+		}
+
+		bookmark = page.Bookmark
+	}
+
+	for id := range stored {
+
+		if _, ok := ledger[id]; ok {
+			continue
+		}
+
+		r.Deleted++
+
+		if !dryRun {
+			if err := store.Delete(id); err != nil {
+				return r, err
+			}
+		}
+	}
+
+	if !dryRun {
+		stored = ledger
+	}
+
+	r.TotalBonds = len(stored)
+
+	r.StoreRoot, err = client.MerkleRoot(stored)
+
+	if err != nil {
+		return r, err
+	}
+
+	checksum, err := registry.RegistryChecksum(ctx)
+
+	if err != nil {
+		return r, err
+	}
+
+	r.LedgerRoot, r.LedgerBonds = checksum.MerkleRoot, checksum.TotalBonds
+	r.Matches = r.StoreRoot == r.LedgerRoot
+
+	return r, nil
+}
+
+//==============================================================================================================================
+//	 apply_event - Writes the bonds of a BondCreated, BondsCreated or BondTransferred event to the store as the ledger now
+//				   holds them.
+//==============================================================================================================================
+func apply_event(ctx context.Context, registry *client.BondRegistryClient, store Store, payload []byte) error {
+
+	var envelope struct {
+		EventType string          `json:"event_type"`
+		BondID    string          `json:"bond_id"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+
+	err := json.Unmarshal(payload, &envelope)
+
+	if err != nil {
+		return err
+	}
+
+	ids := []string{envelope.BondID}
+
+	if envelope.EventType == "BondsCreated" {
+
+		var bonds []client.Bond
+
+		err = json.Unmarshal(envelope.Payload, &bonds)
+
+		if err != nil {
+			return err
+		}
+
+		ids = ids[:0]
+
+		for _, b := range bonds {
+			ids = append(ids, b.RealEstateID)
+		}
+	}
+
+	for _, id := range ids {
+
+		b, err := registry.GetBond(ctx, id)
+
+		if client.ErrorCode(err) == client.CODE_BOND_NOT_FOUND {
+			log.Printf("Bond %s of %s event no longer exists", id, envelope.EventType)
+			if err := store.Delete(id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		hash, err := client.BondHash(b)
+
+		if err != nil {
+			return err
+		}
+
+		err = store.Upsert(b, hash)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+type memory_store map[string]string
+
+func (m memory_store) Upsert(b client.Bond, hash string) error { m[b.RealEstateID] = hash; return nil }
+func (m memory_store) Delete(id string) error                  { delete(m, id); return nil }
+func (m memory_store) Hashes() (map[string]string, error) {
+	copy := make(map[string]string)
+	for k, v := range m {
+		copy[k] = v
+	}
+	return copy, nil
+}
+func (m memory_store) Close() error { return nil }
+
+//==============================================================================================================================
+//	 ledger_contract - Answers export_bonds with a single page of the bonds and get_registry_checksum with their root.
+//==============================================================================================================================
+type ledger_contract struct {
+	page client.Export_Page
+}
+
+func (l *ledger_contract) Evaluate(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+
+	if function == "export_bonds" {
+		return json.Marshal(l.page)
+	}
+
+	hashes := make(map[string]string)
+
+	for _, e := range l.page.Bonds {
+		hashes[e.Bond.RealEstateID] = e.Hash
+	}
+
+	root, _ := client.MerkleRoot(hashes)
+
+	return json.Marshal(client.Registry_Checksum{Algorithm: "sha256", TotalBonds: len(hashes), MerkleRoot: root})
+}
+
+func (l *ledger_contract) Submit(function string, transient map[string][]byte, args ...string) ([]byte, error) {
+	return l.Evaluate(function, transient, args...)
+}
+
+func TestReconcile(t *testing.T) {
+
+	ledger := &ledger_contract{}
+
+	for _, id := range []string{"1232.1", "1232.2", "1232.3"} {
+
+		b := client.Bond{RealEstateID: id, OwnerNationalID: "1"}
+		hash, _ := client.BondHash(b)
+
+		ledger.page.Bonds = append(ledger.page.Bonds, client.Exported_Bond{Bond: b, Hash: hash})
+	}
+
+	store := memory_store{"1232.1": ledger.page.Bonds[0].Hash, "1232.2": "00", "1232.9": "ff"}
+	registry := client.New(ledger)
+
+	r, err := reconcile(context.Background(), registry, store, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Upserted != 2 || r.Deleted != 1 || r.Matches || store["1232.2"] != "00" {
+		t.Errorf("Unexpected dry run %+v, store %v", r, store)
+	}
+
+	r, err = reconcile(context.Background(), registry, store, false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Upserted != 2 || r.Deleted != 1 || !r.Matches || r.TotalBonds != 3 || len(store) != 3 {
+		t.Errorf("Unexpected reconciliation %+v, store %v", r, store)
+	}
+
+	r, _ = reconcile(context.Background(), registry, store, false)
+
+	if r.Upserted != 0 || r.Deleted != 0 || !r.Matches {
+		t.Errorf("Expected nothing left to reconcile, got %+v", r)
+	}
+}
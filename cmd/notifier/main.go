@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 Bond Notifier - Calls the webhooks of the configuration file on the events of the chaincode they list, see
+//					 webhooks.go. Any event the chaincode emits can be listed; today those are BondCreated and
+//					 BondTransferred.
+//
+//		{"webhooks": [
+//			{"url": "https://crm.example/hooks/bonds", "events": ["BondCreated", "BondTransferred"], "secret_env": "CRM_SECRET"},
+//			{"url": "https://sms.example/hooks/olaya", "events": ["BondTransferred"], "districts": ["OLAYA"], "min_severity": "alert"}
+//		]}
+//
+//		CRM_SECRET=... notifier -config webhooks.json
+//==============================================================================================================================
+
+func main() {
+
+	configPath := flag.String("config", "webhooks.json", "configuration file of the webhooks")
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet")
+	identity := flag.String("identity", "notifier", "label of the identity in the wallet to listen as")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+
+	flag.Parse()
+
+	c, err := load_config(*configPath)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(c.Webhooks) == 0 {
+		log.Fatalf("No webhooks in %s", *configPath)
+	}
+
+	var dispatchers []*Dispatcher
+
+	for _, w := range c.Webhooks {
+		d := new_dispatcher(w)
+		dispatchers = append(dispatchers, d)
+		go d.run()
+	}
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		log.Fatalf("Error opening wallet %s: %s", *walletPath, err)
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(config.FromFile(*profile)), gateway.WithIdentity(wallet, *identity))
+
+	if err != nil {
+		log.Fatalf("Error connecting as %s: %s", *identity, err)
+	}
+
+	defer gw.Close()
+
+	network, err := gw.GetNetwork(*channel)
+
+	if err != nil {
+		log.Fatalf("Error joining channel %s: %s", *channel, err)
+	}
+
+	contract := network.GetContract(*chaincode)
+
+	registration, events, err := contract.RegisterEvent(c.event_filter())
+
+	if err != nil {
+		log.Fatalf("Error registering for events: %s", err)
+	}
+
+	defer contract.Unregister(registration)
+
+	log.Printf("Notifying %d webhooks of %s/%s events", len(dispatchers), *channel, *chaincode)
+
+	for e := range events {
+
+		delivery := new_delivery(e.TxID, e.EventName, e.Payload)
+
+		for _, d := range dispatchers {
+			if d.wants(e.EventName) && d.routes(delivery.Routing) {
+				d.enqueue(delivery)
+			}
+		}
+	}
+
+	log.Printf("Event stream closed")
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//==============================================================================================================================
+//	 Webhooks - Each configured webhook receives the events of the types it lists as a POST of the event envelope the
+//				chaincode emitted. When it has a secret the request is signed so the receiver can check it came from the
+//				notifier and was not replayed:
+//
+//		X-Registry-Timestamp: 1700000000
+//		X-Registry-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+//				A delivery answered with anything but a 2xx status is retried with a growing backoff, up to the
+//				attempts configured.
+//
+//				A webhook can further narrow its events by the routing the chaincode adds to them, to the districts
+//				it lists and to events of min_severity or above. The severity is also sent as X-Registry-Severity.
+//==============================================================================================================================
+
+const SIGNATURE_HEADER = "X-Registry-Signature"
+const TIMESTAMP_HEADER = "X-Registry-Timestamp"
+const EVENT_HEADER = "X-Registry-Event"
+const DELIVERY_HEADER = "X-Registry-Delivery"
+const SEVERITY_HEADER = "X-Registry-Severity"
+
+// Severities of the chaincode's events, from the least urgent
+var SEVERITIES = []string{"info", "notice", "alert"}
+
+// Defaults of a webhook that does not set them
+const DEFAULT_MAX_ATTEMPTS = 5
+const DEFAULT_TIMEOUT = 10 * time.Second
+const RETRY_BACKOFF = 2 * time.Second
+
+// Deliveries queued for a webhook before new events for it are dropped
+const QUEUE_SIZE = 1000
+
+//==============================================================================================================================
+//	 Webhook - A webhook of the configuration file. The secret is read from the environment variable named by SecretEnv
+//			   so it is not kept in the file.
+//==============================================================================================================================
+type Webhook struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"` // e.g. BondCreated, BondTransferred
+	SecretEnv   string   `json:"secret_env,omitempty"`
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`      // e.g. 5s
+	Districts   []string `json:"districts,omitempty"`    // district codes, every district when empty
+	MinSeverity string   `json:"min_severity,omitempty"` // e.g. alert, every severity when empty
+
+	secret  []byte
+	timeout time.Duration
+}
+
+type Notifier_Config struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+//==============================================================================================================================
+//	 load_config - Reads the configuration file, resolving the secrets and defaults of its webhooks.
+//==============================================================================================================================
+func load_config(path string) (Notifier_Config, error) {
+
+	var c Notifier_Config
+
+	bytes, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(bytes, &c)
+
+	if err != nil {
+		return c, errors.New("Invalid configuration " + path + ": " + err.Error())
+	}
+
+	for i := range c.Webhooks {
+
+		w := &c.Webhooks[i]
+
+		if !strings.HasPrefix(w.URL, "https://") && !strings.HasPrefix(w.URL, "http://") {
+			return c, errors.New("Invalid URL of webhook " + strconv.Itoa(i) + ": " + w.URL)
+		}
+
+		if len(w.Events) == 0 {
+			return c, errors.New("No events for webhook " + w.URL)
+		}
+
+		if w.SecretEnv != "" {
+
+			secret := os.Getenv(w.SecretEnv)
+
+			if secret == "" {
+				return c, errors.New("Secret " + w.SecretEnv + " of webhook " + w.URL + " is not set")
+			}
+
+			w.secret = []byte(secret)
+		}
+
+		if w.MaxAttempts <= 0 {
+			w.MaxAttempts = DEFAULT_MAX_ATTEMPTS
+		}
+
+		if w.MinSeverity != "" && severity_rank(w.MinSeverity) < 0 {
+			return c, errors.New("Invalid min_severity of webhook " + w.URL + ": " + w.MinSeverity)
+		}
+
+		w.timeout = DEFAULT_TIMEOUT
+
+		if w.Timeout != "" {
+			w.timeout, err = time.ParseDuration(w.Timeout)
+			if err != nil || w.timeout <= 0 {
+				return c, errors.New("Invalid timeout of webhook " + w.URL + ": " + w.Timeout)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+//==============================================================================================================================
+//	 event_filter - Returns the regular expression matching the names of every event a webhook listens for.
+//==============================================================================================================================
+func (c Notifier_Config) event_filter() string {
+
+	seen := make(map[string]bool)
+
+	var names []string
+
+	for _, w := range c.Webhooks {
+		for _, event := range w.Events {
+			if !seen[event] {
+				seen[event] = true
+				names = append(names, regexp.QuoteMeta(event))
+			}
+		}
+	}
+
+	return "^(" + strings.Join(names, "|") + ")$"
+}
+
+//==============================================================================================================================
+//	 severity_rank - Returns the rank of the severity in SEVERITIES, -1 for an unknown severity.
+//==============================================================================================================================
+func severity_rank(severity string) int {
+
+	for i, s := range SEVERITIES {
+		if s == severity {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//==============================================================================================================================
+//	 sign - Returns the signature of the body sent at the timestamp.
+//==============================================================================================================================
+func sign(secret []byte, timestamp string, body []byte) string {
+
+	mac := hmac.New(sha256.New, secret)
+
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//==============================================================================================================================
+//	 Delivery - An event to deliver. ID is the same on every attempt, the receiver can drop repeats of it.
+//==============================================================================================================================
+type Delivery struct {
+	ID      string
+	Event   string
+	Body    []byte
+	Routing Routing
+}
+
+//==============================================================================================================================
+//	 Routing - The routing of an event envelope, see events.go of the chaincode. Events emitted before the chaincode
+//			   added it have none.
+//==============================================================================================================================
+type Routing struct {
+	OwnerHashes []string `json:"owner_hashes"`
+	Districts   []string `json:"districts"`
+	Severity    string   `json:"severity"`
+}
+
+//==============================================================================================================================
+//	 new_delivery - Returns the delivery of the event, reading the routing of its envelope.
+//==============================================================================================================================
+func new_delivery(txID string, event string, body []byte) Delivery {
+
+	var envelope struct {
+		Routing Routing `json:"routing"`
+	}
+
+	json.Unmarshal(body, &envelope)
+
+	return Delivery{ID: txID + "/" + event, Event: event, Body: body, Routing: envelope.Routing}
+}
+
+//==============================================================================================================================
+//	 Dispatcher - Delivers the events of a webhook in order on its own queue, so a slow receiver only holds up itself.
+//==============================================================================================================================
+type Dispatcher struct {
+	webhook Webhook
+	queue   chan Delivery
+	http    *http.Client
+	backoff time.Duration
+}
+
+func new_dispatcher(w Webhook) *Dispatcher {
+	return &Dispatcher{webhook: w, queue: make(chan Delivery, QUEUE_SIZE), http: &http.Client{Timeout: w.timeout}, backoff: RETRY_BACKOFF}
+}
+
+//==============================================================================================================================
+//	 wants - Returns whether the webhook listens for the event.
+//==============================================================================================================================
+func (d *Dispatcher) wants(event string) bool {
+
+	for _, e := range d.webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 routes - Returns whether the routing of an event is within the districts and severity of the webhook. An event
+//			  without routing only passes a webhook that does not narrow its events.
+//==============================================================================================================================
+func (d *Dispatcher) routes(r Routing) bool {
+
+	if d.webhook.MinSeverity != "" && severity_rank(r.Severity) < severity_rank(d.webhook.MinSeverity) {
+		return false
+	}
+
+	if len(d.webhook.Districts) == 0 {
+		return true
+	}
+
+	for _, district := range r.Districts {
+		for _, wanted := range d.webhook.Districts {
+			if district == wanted {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 enqueue - Queues the delivery, dropping it when the queue of the webhook is full.
+//==============================================================================================================================
+func (d *Dispatcher) enqueue(delivery Delivery) {
+
+	select {
+	case d.queue <- delivery:
+	default:
+		log.Printf("Queue of %s is full, dropping %s %s", d.webhook.URL, delivery.Event, delivery.ID)
+	}
+}
+
+//==============================================================================================================================
+//	 run - Delivers the queued events until the queue is closed.
+//==============================================================================================================================
+func (d *Dispatcher) run() {
+
+	for delivery := range d.queue {
+
+		backoff := d.backoff
+
+		for attempt := 1; ; attempt++ {
+
+			err := d.post(delivery)
+
+			if err == nil {
+				break
+			}
+
+			if attempt >= d.webhook.MaxAttempts {
+				log.Printf("Giving up on %s %s to %s after %d attempts: %s", delivery.Event, delivery.ID, d.webhook.URL, attempt, err)
+				break
+			}
+
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 post - Makes one attempt at the delivery.
+//==============================================================================================================================
+func (d *Dispatcher) post(delivery Delivery) error {
+
+	req, err := http.NewRequest(http.MethodPost, d.webhook.URL, bytes.NewReader(delivery.Body))
+
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EVENT_HEADER, delivery.Event)
+	req.Header.Set(DELIVERY_HEADER, delivery.ID)
+	req.Header.Set(TIMESTAMP_HEADER, timestamp)
+
+	if delivery.Routing.Severity != "" {
+		req.Header.Set(SEVERITY_HEADER, delivery.Routing.Severity)
+	}
+
+	if d.webhook.secret != nil {
+		req.Header.Set(SIGNATURE_HEADER, sign(d.webhook.secret, timestamp, delivery.Body))
+	}
+
+	res, err := d.http.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New("Status " + res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDelivery(t *testing.T) {
+
+	received := make(chan *http.Request, 10)
+	failures := 1
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.Header.Get(SIGNATURE_HEADER) != sign([]byte("secret"), r.Header.Get(TIMESTAMP_HEADER), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		received <- r
+	}))
+
+	defer server.Close()
+
+	d := new_dispatcher(Webhook{URL: server.URL, Events: []string{"BondCreated"}, MaxAttempts: 3, secret: []byte("secret"), timeout: time.Second})
+	d.backoff = time.Millisecond
+
+	if d.wants("BondTransferred") || !d.wants("BondCreated") {
+		t.Errorf("Expected the webhook to want BondCreated only")
+	}
+
+	d.enqueue(Delivery{ID: "tx1/BondCreated", Event: "BondCreated", Body: []byte(`{"bond_id":"1232.21"}`)})
+	close(d.queue)
+	d.run()
+
+	select {
+	case r := <-received:
+		if r.Header.Get(DELIVERY_HEADER) != "tx1/BondCreated" || r.Header.Get(EVENT_HEADER) != "BondCreated" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+	default:
+		t.Fatalf("Expected the delivery to be retried until it succeeded")
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+
+	c := Notifier_Config{Webhooks: []Webhook{{Events: []string{"BondCreated", "BondTransferred"}}, {Events: []string{"BondCreated"}}}}
+
+	if filter := c.event_filter(); filter != "^(BondCreated|BondTransferred)$" {
+		t.Errorf("Unexpected filter %s", filter)
+	}
+}
+
+func TestRouting(t *testing.T) {
+
+	delivery := new_delivery("tx1", "BondTransferred", []byte(`{"event_type":"BondTransferred","routing":{"owner_hashes":[],"districts":["OLAYA"],"severity":"alert"}}`))
+
+	if delivery.ID != "tx1/BondTransferred" || delivery.Routing.Severity != "alert" {
+		t.Fatalf("Unexpected delivery %+v", delivery)
+	}
+
+	tests := []struct {
+		webhook Webhook
+		routes  bool
+	}{
+		{Webhook{}, true},
+		{Webhook{Districts: []string{"OLAYA"}, MinSeverity: "notice"}, true},
+		{Webhook{Districts: []string{"MALAZ"}}, false},
+		{Webhook{MinSeverity: "alert"}, true},
+	}
+
+	for _, test := range tests {
+		if routes := new_dispatcher(test.webhook).routes(delivery.Routing); routes != test.routes {
+			t.Errorf("Expected routes %t for %+v", test.routes, test.webhook)
+		}
+	}
+
+	if new_dispatcher(Webhook{MinSeverity: "notice"}).routes(Routing{Severity: "info"}) {
+		t.Errorf("Expected an info event to be below notice")
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/alfaifiisa/learn-chaincode/exchange"
+)
+
+//==============================================================================================================================
+//	 parse - Parses the flags of a subcommand and returns its arguments, failing unless there are count of them.
+//==============================================================================================================================
+func parse(flags *flag.FlagSet, args []string, count int, names string) ([]string, error) {
+
+	err := flags.Parse(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.NArg() != count {
+		return nil, errors.New("Expecting " + names)
+	}
+
+	return flags.Args(), nil
+}
+
+//==============================================================================================================================
+//	 change_options - Adds the flags common to the commands changing a bond and returns a function reading them.
+//==============================================================================================================================
+func change_options(flags *flag.FlagSet) func() []client.CallOption {
+
+	key := flags.String("idempotency-key", "", "key making a retry of the command return the result of the first run")
+	version := flags.Int("expected-version", -1, "version the bond must still be at")
+
+	return func() []client.CallOption {
+
+		var options []client.CallOption
+
+		if *key != "" {
+			options = append(options, client.WithIdempotencyKey(*key))
+		}
+
+		if *version >= 0 {
+			options = append(options, client.WithExpectedVersion(*version))
+		}
+
+		return options
+	}
+}
+
+//==============================================================================================================================
+//	 create_command - create [flags]
+//==============================================================================================================================
+func create_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+
+	id := flags.String("id", "", "ID of the bond, generated by the chaincode when empty")
+	realEstateID := flags.String("real-estate-id", "", "blueprint_number.realestate_number, e.g. 1232.21")
+	owner := flags.String("owner", "", "national ID of the owner")
+	status := flags.String("status", "", "flat or built")
+	area := flags.Float64("area", 0, "area of the parcel")
+	unit := flags.String("unit", "m2", "unit of the area")
+	long := flags.Float64("long", 0, "longitude")
+	lat := flags.Float64("lat", 0, "latitude")
+	boundary := flags.String("boundary", "", "file holding the GeoJSON polygon of the parcel")
+	district := flags.String("district", "", "code of the district")
+	street := flags.String("street", "", "street of the bond")
+	options := change_options(flags)
+
+	_, err := parse(flags, args, 0, "no arguments")
+
+	if err != nil {
+		return err
+	}
+
+	b := client.Bond{
+		ID:              *id,
+		RealEstateID:    *realEstateID,
+		OwnerNationalID: *owner,
+		Status:          *status,
+		Area:            client.Land_Area{Value: *area, Unit: *unit},
+		Coordinates:     client.Coordinates{Long: *long, Lat: *lat},
+		DistrictCode:    *district,
+		Street:          *street,
+	}
+
+	if *boundary != "" {
+
+		bytes, err := ioutil.ReadFile(*boundary)
+
+		if err != nil {
+			return err
+		}
+
+		b.Boundary = &client.Polygon{}
+
+		err = json.Unmarshal(bytes, b.Boundary)
+
+		if err != nil {
+			return errors.New("Invalid boundary in " + *boundary + ": " + err.Error())
+		}
+	}
+
+	created, err := registry.CreateBond(ctx, b, options()...)
+
+	if err != nil {
+		return err
+	}
+
+	return print_json(map[string]string{"id": created})
+}
+
+//==============================================================================================================================
+//	 transfer_command - transfer [flags] realEstateID
+//==============================================================================================================================
+func transfer_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("transfer", flag.ExitOnError)
+
+	recipient := flags.String("recipient", "", "national ID of the new owner")
+	msp := flags.String("msp", "", "organisation of the new owner, that of the current owner when empty")
+	value := flags.Float64("value", 0, "declared value of the sale")
+	salt := flags.String("salt", "", "salt of the declared value, required with -value")
+	options := change_options(flags)
+
+	args, err := parse(flags, args, 1, "realEstateID")
+
+	if err != nil {
+		return err
+	}
+
+	transfer := client.Transfer{RecipientNationalID: *recipient, RecipientMSP: *msp, DeclaredValue: *value, Salt: *salt}
+
+	return registry.TransferOwnership(ctx, args[0], transfer, options()...)
+}
+
+//==============================================================================================================================
+//	 query_command - query realEstateID | query -owner nationalID
+//==============================================================================================================================
+func query_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+
+	owner := flags.String("owner", "", "national ID of the owner whose bonds to list")
+
+	err := flags.Parse(args)
+
+	if err != nil {
+		return err
+	}
+
+	if *owner != "" && flags.NArg() == 0 {
+
+		bonds, err := registry.QueryByOwner(ctx, *owner)
+
+		if err != nil {
+			return err
+		}
+
+		return print_json(bonds)
+	}
+
+	if *owner != "" || flags.NArg() != 1 {
+		return errors.New("Expecting realEstateID or -owner")
+	}
+
+	b, err := registry.GetBond(ctx, flags.Arg(0))
+
+	if err != nil {
+		return err
+	}
+
+	return print_json(b)
+}
+
+//==============================================================================================================================
+//	 history_command - history realEstateID
+//==============================================================================================================================
+func history_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+
+	args, err := parse(flags, args, 1, "realEstateID")
+
+	if err != nil {
+		return err
+	}
+
+	records, err := registry.History(ctx, args[0])
+
+	if err != nil {
+		return err
+	}
+
+	return print_json(records)
+}
+
+//==============================================================================================================================
+//	 export_command - export [-page-size n] [-format f], writing each bond as a line of JSON, or every bond as a batch of
+//					  the national registry exchange schema in JSON or XML, or the bonds of a district or range of
+//					  change dates as the real estate authority's property records, see the exchange package.
+//==============================================================================================================================
+func export_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+
+	pageSize := flags.Int("page-size", 500, "bonds to fetch per query")
+	format := flags.String("format", "jsonl", "jsonl, national-json, national-xml or rega")
+	batchID := flags.String("batch-id", "", "ID of the national registry batch")
+	district := flags.String("district", "", "district code of the bonds of a rega export")
+	from := flags.String("from", "", "rega export of bonds changed on or after the date, YYYY-MM-DD")
+	to := flags.String("to", "", "rega export of bonds changed before the date, YYYY-MM-DD")
+	licenses := flags.String("licenses", "", "CSV file of realEstateID,licence references of a rega export")
+
+	_, err := parse(flags, args, 0, "no arguments")
+
+	if err != nil {
+		return err
+	}
+
+	var write func(e client.Exported_Bond) error
+	var finish func() error
+
+	switch *format {
+	case "jsonl":
+		encoder := json.NewEncoder(os.Stdout)
+		write = func(e client.Exported_Bond) error {
+			return encoder.Encode(e)
+		}
+	case "national-json", "national-xml":
+		national, err := exchange.NewNationalWriter(os.Stdout, strings.TrimPrefix(*format, "national-"), *batchID)
+		if err != nil {
+			return err
+		}
+		write = func(e client.Exported_Bond) error {
+			return national.Write(e.Bond)
+		}
+		finish = national.Close
+	case "rega":
+		rega, err := rega_writer(*district, *from, *to, *licenses)
+		if err != nil {
+			return err
+		}
+		write = func(e client.Exported_Bond) error {
+			return rega.Write(e.Bond)
+		}
+		finish = rega.Close
+	default:
+		return errors.New("Unknown format " + *format)
+	}
+
+	bookmark := ""
+
+	for {
+
+		page, err := registry.ExportBonds(ctx, *pageSize, bookmark)
+
+		if err != nil {
+			return err
+		}
+
+		for _, e := range page.Bonds {
+			if err := write(e); err != nil {
+				return err
+			}
+		}
+
+		if page.Bookmark == "" {
+			break
+		}
+
+		bookmark = page.Bookmark
+	}
+
+	if finish != nil {
+		return finish()
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 rega_writer - Returns the writer of a rega export of the flags given.
+//==============================================================================================================================
+func rega_writer(district string, from string, to string, licensesFile string) (*exchange.REGAWriter, error) {
+
+	filter := exchange.REGAFilter{DistrictCode: district}
+
+	var err error
+
+	if from != "" {
+		if filter.From, err = time.Parse("2006-01-02", from); err != nil {
+			return nil, errors.New("Invalid -from date " + from)
+		}
+	}
+
+	if to != "" {
+		if filter.To, err = time.Parse("2006-01-02", to); err != nil {
+			return nil, errors.New("Invalid -to date " + to)
+		}
+	}
+
+	licenses := make(map[string]string)
+
+	if licensesFile != "" {
+
+		file, err := os.Open(licensesFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		if licenses, err = exchange.ReadLicenses(file); err != nil {
+			return nil, errors.New("Invalid licences " + licensesFile + ": " + err.Error())
+		}
+	}
+
+	return exchange.NewREGAWriter(os.Stdout, filter, licenses)
+}
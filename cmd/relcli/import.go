@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 Import - Loads a CSV of legacy deeds, one bond per row, named by a header row:
+//
+//		real_estate_id,owner_national_id,status,area,unit,longitude,latitude,district_code,street
+//		1232.21,1000000001,built,500,m2,46.6753,24.7136,RUH-01,King Fahd Road
+//
+//			 The id column is optional, the chaincode generates the ID of a bond without one. Every row is checked before
+//			 any is created and the rows that fail are left out; the rest are created one transaction each, keyed by
+//			 their realEstateID so a rerun of the same file does not create them twice. The report lists every row
+//			 that was not created and why. The -timeout of relcli bounds the whole import.
+//
+//			 With -batch the rows are migrated into the batch instead, each recording the deed of its
+//			 legacy_deed_number column, see legacy.go of the chaincode.
+//==============================================================================================================================
+
+const ROW_INVALID = "invalid"
+const ROW_FAILED = "failed"
+const ROW_VALID = "valid" // of a dry run
+
+// Columns every file must have
+var REQUIRED_COLUMNS = []string{"real_estate_id", "owner_national_id", "status", "longitude", "latitude"}
+
+var REAL_ESTATE_ID = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+var NATIONAL_ID = regexp.MustCompile(`^[0-9]{10}$`)
+
+//==============================================================================================================================
+//	 Row_Report - The outcome of a row. Row counts from 1 at the header, the line a spreadsheet shows it on.
+//==============================================================================================================================
+type Row_Report struct {
+	Row          int      `json:"row"`
+	RealEstateID string   `json:"real_estate_id,omitempty"`
+	Result       string   `json:"result"`
+	Code         string   `json:"code,omitempty"` // of a row the chaincode rejected
+	Errors       []string `json:"errors,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Import_Report - The outcome of a file. Rows lists only the rows that were not created, or every row of a dry run.
+//==============================================================================================================================
+type Import_Report struct {
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Invalid int          `json:"invalid"`
+	Failed  int          `json:"failed"`
+	Rows    []Row_Report `json:"rows,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Import_Row - A row of the file with the bond it holds, or the reasons it holds none.
+//==============================================================================================================================
+type Import_Row struct {
+	Row        int
+	Bond       client.Bond
+	DeedNumber string // legacy deed of a migrated row
+	Errors     []string
+}
+
+//==============================================================================================================================
+//	 read_rows - Reads and checks the rows of the file. An error is only returned for a file that cannot be read at all,
+//				 a bad row is returned with its errors.
+//==============================================================================================================================
+func read_rows(r io.Reader) ([]Import_Row, error) {
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, errors.New("Error reading header: " + err.Error())
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range REQUIRED_COLUMNS {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.New("Missing column " + name)
+		}
+	}
+
+	var rows []Import_Row
+
+	seen := make(map[string]int)
+
+	for line := 2; ; line++ {
+
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, err
+			}
+			rows = append(rows, Import_Row{Row: line, Errors: []string{err.Error()}})
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := check_row(line, field)
+
+		if first, ok := seen[row.Bond.RealEstateID]; ok && row.Bond.RealEstateID != "" {
+			row.Errors = append(row.Errors, "realEstateID repeats row "+strconv.Itoa(first))
+		} else {
+			seen[row.Bond.RealEstateID] = line
+		}
+
+		rows = append(rows, row)
+	}
+}
+
+//==============================================================================================================================
+//	 check_row - Builds the bond of a row, collecting every problem with it rather than stopping at the first.
+//==============================================================================================================================
+func check_row(line int, field func(name string) string) Import_Row {
+
+	row := Import_Row{Row: line, DeedNumber: field("legacy_deed_number")}
+
+	b := client.Bond{
+		ID:              field("id"),
+		RealEstateID:    field("real_estate_id"),
+		OwnerNationalID: field("owner_national_id"),
+		Status:          field("status"),
+		DistrictCode:    field("district_code"),
+		Street:          field("street"),
+	}
+
+	if !REAL_ESTATE_ID.MatchString(b.RealEstateID) {
+		row.Errors = append(row.Errors, "real_estate_id must be blueprint_number.realestate_number, got \""+b.RealEstateID+"\"")
+	}
+
+	if !NATIONAL_ID.MatchString(b.OwnerNationalID) {
+		row.Errors = append(row.Errors, "owner_national_id must be 10 digits, got \""+b.OwnerNationalID+"\"")
+	}
+
+	if b.Status != "flat" && b.Status != "built" {
+		row.Errors = append(row.Errors, "status must be flat or built, got \""+b.Status+"\"")
+	}
+
+	var err error
+
+	if b.Coordinates.Long, err = strconv.ParseFloat(field("longitude"), 64); err != nil || b.Coordinates.Long < -180 || b.Coordinates.Long > 180 {
+		row.Errors = append(row.Errors, "longitude must be a number from -180 to 180, got \""+field("longitude")+"\"")
+	}
+
+	if b.Coordinates.Lat, err = strconv.ParseFloat(field("latitude"), 64); err != nil || b.Coordinates.Lat < -90 || b.Coordinates.Lat > 90 {
+		row.Errors = append(row.Errors, "latitude must be a number from -90 to 90, got \""+field("latitude")+"\"")
+	}
+
+	if area := field("area"); area != "" {
+
+		b.Area.Value, err = strconv.ParseFloat(area, 64)
+
+		if err != nil || b.Area.Value <= 0 {
+			row.Errors = append(row.Errors, "area must be a positive number, got \""+area+"\"")
+		}
+
+		b.Area.Unit = field("unit")
+
+		if b.Area.Unit == "" {
+			b.Area.Unit = "m2"
+		}
+	}
+
+	row.Bond = b
+
+	return row
+}
+
+//==============================================================================================================================
+//	 import_rows - Creates the bonds of the valid rows, or migrates them into the batch when one is given, and reports
+//				   on every row.
+//==============================================================================================================================
+func import_rows(ctx context.Context, registry *client.BondRegistryClient, rows []Import_Row, batch string, dryRun bool) Import_Report {
+
+	report := Import_Report{Total: len(rows)}
+
+	for _, row := range rows {
+
+		r := Row_Report{Row: row.Row, RealEstateID: row.Bond.RealEstateID}
+
+		if batch != "" && row.DeedNumber == "" {
+			row.Errors = append(row.Errors, "legacy_deed_number is required to migrate a row")
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			r.Result, r.Errors = ROW_INVALID, row.Errors
+			report.Invalid++
+		case dryRun:
+			r.Result = ROW_VALID
+		default:
+			var err error
+			if batch != "" {
+				_, err = registry.MigrateLegacyBond(ctx, batch, row.DeedNumber, row.Bond, client.WithIdempotencyKey("migrate:"+row.DeedNumber))
+			} else {
+				_, err = registry.CreateBond(ctx, row.Bond, client.WithIdempotencyKey("import:"+row.Bond.RealEstateID))
+			}
+			if err != nil {
+				r.Result, r.Code, r.Errors = ROW_FAILED, client.ErrorCode(err), []string{err.Error()}
+				report.Failed++
+				break
+			}
+			report.Created++
+			continue
+		}
+
+		report.Rows = append(report.Rows, r)
+	}
+
+	return report
+}
+
+//==============================================================================================================================
+//	 import_command - import [-dry-run] [-batch id] file.csv, failing when any row was not created.
+//==============================================================================================================================
+func import_command(ctx context.Context, registry *client.BondRegistryClient, args []string) error {
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+
+	dryRun := flags.Bool("dry-run", false, "check the rows without creating any bonds")
+	batch := flags.String("batch", "", "migrate the rows into the legacy migration batch")
+
+	args, err := parse(flags, args, 1, "the CSV file")
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	rows, err := read_rows(file)
+
+	if err != nil {
+		return errors.New("Invalid CSV " + args[0] + ": " + err.Error())
+	}
+
+	report := import_rows(ctx, registry, rows, *batch, *dryRun)
+
+	err = print_json(report)
+
+	if err != nil {
+		return err
+	}
+
+	if report.Invalid+report.Failed > 0 {
+		return errors.New(strconv.Itoa(report.Invalid+report.Failed) + " of " + strconv.Itoa(report.Total) + " rows not imported")
+	}
+
+	return nil
+}
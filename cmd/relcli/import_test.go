@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadRows(t *testing.T) {
+
+	file := "real_estate_id,owner_national_id,status,area,longitude,latitude\n" +
+		"1232.21,1000000001,built,500,46.67,24.71\n" +
+		"1232,12345,villa,-3,46.67,95\n" +
+		"1232.21,1000000002,flat,,46.67,24.71\n"
+
+	rows, err := read_rows(strings.NewReader(file))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+
+	if len(rows[0].Errors) != 0 || rows[0].Bond.Area.Unit != "m2" || rows[0].Bond.Coordinates.Lat != 24.71 {
+		t.Errorf("Unexpected first row %+v", rows[0])
+	}
+
+	if rows[1].Row != 3 || len(rows[1].Errors) != 5 {
+		t.Errorf("Expected every problem of row 3 to be reported, got %v", rows[1].Errors)
+	}
+
+	if len(rows[2].Errors) != 1 || !strings.Contains(rows[2].Errors[0], "repeats row 2") {
+		t.Errorf("Expected the repeated realEstateID to be reported, got %v", rows[2].Errors)
+	}
+
+	report := import_rows(context.Background(), nil, rows, "", true)
+
+	if report.Total != 3 || report.Invalid != 2 || report.Created != 0 || len(report.Rows) != 3 || report.Rows[0].Result != ROW_VALID {
+		t.Errorf("Unexpected dry run report %+v", report)
+	}
+
+	report = import_rows(context.Background(), nil, rows[:1], "B-2024-01", true)
+
+	if report.Invalid != 1 || !strings.Contains(report.Rows[0].Errors[0], "legacy_deed_number") {
+		t.Errorf("Expected a migrated row without a legacy deed to be invalid, got %+v", report)
+	}
+
+	if _, err := read_rows(strings.NewReader("real_estate_id,status\n")); err == nil {
+		t.Errorf("Expected a file missing columns to be rejected")
+	}
+}
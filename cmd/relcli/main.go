@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+//==============================================================================================================================
+//	 relcli - Manages bonds from the command line as an identity of a wallet, so operators do not have to hand-craft
+//			  invoke payloads. Results are written to stdout as JSON, errors to stderr with the code returned by the
+//			  chaincode.
+//
+//		relcli [connection flags] create -real-estate-id 1232.21 -owner 1000000001 -status built -area 500 ...
+//		relcli [connection flags] transfer -recipient 1000000002 [-value 750000 -salt s] 1232.21
+//		relcli [connection flags] query 1232.21 | query -owner 1000000001
+//		relcli [connection flags] history 1232.21
+//		relcli [connection flags] export [-page-size 500] > bonds.jsonl
+//		relcli [connection flags] export -format national-xml -batch-id 2024-06 > batch.xml
+//		relcli [connection flags] export -format rega -district RUH-01 -from 2024-01-01 -licenses licenses.csv > rega.json
+//		relcli [connection flags] import [-dry-run] deeds.csv > report.json
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Command - A subcommand, run with the arguments following its name.
+//==============================================================================================================================
+type Command struct {
+	Usage string
+	Run   func(ctx context.Context, registry *client.BondRegistryClient, args []string) error
+}
+
+var COMMANDS = map[string]Command{
+	"create":   {"create a bond", create_command},
+	"transfer": {"transfer a bond to a new owner", transfer_command},
+	"query":    {"show a bond, or the bonds of an owner", query_command},
+	"history":  {"show the changes made to a bond", history_command},
+	"export":   {"write every bond as a line of JSON", export_command},
+	"import":   {"create the bonds of a CSV of legacy deeds", import_command},
+}
+
+//==============================================================================================================================
+//	 usage - Prints the flags and subcommands.
+//==============================================================================================================================
+func usage() {
+
+	fmt.Fprintln(os.Stderr, "Usage: relcli [flags] command [command flags] [arguments]")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	for _, name := range []string{"create", "transfer", "query", "history", "export", "import"} {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, COMMANDS[name].Usage)
+	}
+}
+
+//==============================================================================================================================
+//	 print_json - Writes v to stdout as indented JSON.
+//==============================================================================================================================
+func print_json(v interface{}) error {
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+//==============================================================================================================================
+//	 main - Connects as the identity given and runs the subcommand.
+//==============================================================================================================================
+func main() {
+
+	profile := flag.String("profile", "connection.yaml", "connection profile of the network")
+	walletPath := flag.String("wallet", "wallet", "directory of the wallet")
+	identity := flag.String("identity", "admin", "label of the identity in the wallet to act as")
+	channel := flag.String("channel", "mychannel", "channel the chaincode is instantiated on")
+	chaincode := flag.String("chaincode", "learn-chaincode", "name of the chaincode")
+	timeout := flag.Duration("timeout", 2*time.Minute, "time to wait for the command")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, ok := COMMANDS[flag.Arg(0)]
+
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown command "+flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	wallet, err := gateway.NewFileSystemWallet(*walletPath)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening wallet %s: %s\n", *walletPath, err)
+		os.Exit(1)
+	}
+
+	gw, err := gateway.Connect(gateway.WithConfig(config.FromFile(*profile)), gateway.WithIdentity(wallet, *identity))
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting as %s: %s\n", *identity, err)
+		os.Exit(1)
+	}
+
+	defer gw.Close()
+
+	network, err := gw.GetNetwork(*channel)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error joining channel %s: %s\n", *channel, err)
+		os.Exit(1)
+	}
+
+	registry := client.New(client.FromGateway(network.GetContract(*chaincode)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	err = command.Run(ctx, registry, flag.Args()[1:])
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", flag.Arg(0), err)
+		cancel()
+		gw.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,239 @@
+package exchange
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 National Registry Exchange - Bonds as the records of a batch submitted to the national land registry, in JSON or
+//								  XML. The mapping from a bond to a record is kept in national_record alone so a change
+//								  of the exchange schema touches one place; raise NATIONAL_SCHEMA_VERSION with it.
+//
+//		w, _ := exchange.NewNationalWriter(os.Stdout, exchange.FORMAT_XML, "batch-2024-06")
+//		w.Write(bond) ...
+//		w.Close()
+//==============================================================================================================================
+
+const NATIONAL_SCHEMA = "national-land-registry-exchange"
+const NATIONAL_SCHEMA_VERSION = "1.0"
+
+const FORMAT_JSON = "json"
+const FORMAT_XML = "xml"
+
+//==============================================================================================================================
+//	 National_Record - A deed of the exchange schema.
+//==============================================================================================================================
+type National_Record struct {
+	DeedNumber     string           `json:"deed_number" xml:"DeedNumber"`
+	PlanNumber     string           `json:"plan_number" xml:"PlanNumber"`
+	ParcelNumber   string           `json:"parcel_number" xml:"ParcelNumber"`
+	Owner          National_Owner   `json:"owner" xml:"Owner"`
+	PropertyStatus string           `json:"property_status" xml:"PropertyStatus"`
+	Area           National_Area    `json:"area" xml:"Area"`
+	Location       National_Point   `json:"location" xml:"Location"`
+	BoundaryWKT    string           `json:"boundary_wkt,omitempty" xml:"BoundaryWKT,omitempty"`
+	Address        National_Address `json:"address" xml:"Address"`
+	RegisteredAt   string           `json:"registered_at,omitempty" xml:"RegisteredAt,omitempty"`
+	LastUpdatedAt  string           `json:"last_updated_at" xml:"LastUpdatedAt"`
+	LedgerTxID     string           `json:"ledger_txid,omitempty" xml:"LedgerTxID,omitempty"`
+	LedgerVersion  int              `json:"ledger_version" xml:"LedgerVersion"`
+}
+
+type National_Owner struct {
+	IDType string `json:"id_type" xml:"IDType,attr"`
+	ID     string `json:"id" xml:",chardata"`
+}
+
+type National_Area struct {
+	Unit  string  `json:"unit" xml:"unit,attr"`
+	Value float64 `json:"value" xml:",chardata"`
+}
+
+type National_Point struct {
+	Latitude  float64 `json:"latitude" xml:"Latitude"`
+	Longitude float64 `json:"longitude" xml:"Longitude"`
+}
+
+type National_Address struct {
+	CityCode     string `json:"city_code,omitempty" xml:"CityCode,omitempty"`
+	DistrictCode string `json:"district_code,omitempty" xml:"DistrictCode,omitempty"`
+	Street       string `json:"street,omitempty" xml:"Street,omitempty"`
+}
+
+//==============================================================================================================================
+//	 national_record - Maps the bond to its record. The realEstateID is blueprint_number.realestate_number, the plan and
+//					   parcel numbers of the record.
+//==============================================================================================================================
+func national_record(b client.Bond) National_Record {
+
+	plan, parcel := b.RealEstateID, ""
+
+	if i := strings.Index(b.RealEstateID, "."); i >= 0 {
+		plan, parcel = b.RealEstateID[:i], b.RealEstateID[i+1:]
+	}
+
+	return National_Record{
+		DeedNumber:     b.ID,
+		PlanNumber:     plan,
+		ParcelNumber:   parcel,
+		Owner:          National_Owner{IDType: "NationalID", ID: b.OwnerNationalID},
+		PropertyStatus: b.Status,
+		Area:           National_Area{Unit: b.Area.Unit, Value: b.Area.Value},
+		Location:       National_Point{Latitude: b.Coordinates.Lat, Longitude: b.Coordinates.Long},
+		BoundaryWKT:    polygon_wkt(b.Boundary),
+		Address:        National_Address{CityCode: b.CityCode, DistrictCode: b.DistrictCode, Street: b.Street},
+		RegisteredAt:   b.CreatedAt,
+		LastUpdatedAt:  b.UpdatedAt,
+		LedgerTxID:     b.LastModifiedTx,
+		LedgerVersion:  b.Version,
+	}
+}
+
+//==============================================================================================================================
+//	 polygon_wkt - Returns the polygon as Well-Known Text, empty for no polygon.
+//==============================================================================================================================
+func polygon_wkt(p *client.Polygon) string {
+
+	if p == nil || len(p.Coordinates) == 0 {
+		return ""
+	}
+
+	var rings []string
+
+	for _, ring := range p.Coordinates {
+
+		var positions []string
+
+		for _, position := range ring {
+			positions = append(positions, strconv.FormatFloat(position[0], 'f', -1, 64)+" "+strconv.FormatFloat(position[1], 'f', -1, 64))
+		}
+
+		rings = append(rings, "("+strings.Join(positions, ", ")+")")
+	}
+
+	return "POLYGON (" + strings.Join(rings, ", ") + ")"
+}
+
+//==============================================================================================================================
+//	 NationalWriter - Streams a batch of records, so a batch of the whole registry is never held in memory.
+//==============================================================================================================================
+type NationalWriter struct {
+	w       io.Writer
+	format  string
+	xml     *xml.Encoder
+	records int
+}
+
+//==============================================================================================================================
+//	 NewNationalWriter - Writes the header of the batch in the format given.
+//==============================================================================================================================
+func NewNationalWriter(w io.Writer, format string, batchID string) (*NationalWriter, error) {
+
+	n := &NationalWriter{w: w, format: format}
+	generated := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	switch format {
+	case FORMAT_JSON:
+
+		header, err := json.Marshal(map[string]string{"schema": NATIONAL_SCHEMA, "schema_version": NATIONAL_SCHEMA_VERSION, "batch_id": batchID, "generated_at": generated})
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.WriteString(w, string(header[:len(header)-1])+`,"records":[`)
+
+		return n, err
+
+	case FORMAT_XML:
+
+		n.xml = xml.NewEncoder(w)
+		n.xml.Indent("", "  ")
+
+		start := xml.StartElement{
+			Name: xml.Name{Local: "RegistryBatch"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "schema"}, Value: NATIONAL_SCHEMA},
+				{Name: xml.Name{Local: "schemaVersion"}, Value: NATIONAL_SCHEMA_VERSION},
+				{Name: xml.Name{Local: "batchID"}, Value: batchID},
+				{Name: xml.Name{Local: "generatedAt"}, Value: generated},
+			},
+		}
+
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return nil, err
+		}
+
+		return n, n.xml.EncodeToken(start)
+	}
+
+	return nil, errors.New("Unknown format " + format + ", expecting " + FORMAT_JSON + " or " + FORMAT_XML)
+}
+
+//==============================================================================================================================
+//	 Write - Writes the record of the bond.
+//==============================================================================================================================
+func (n *NationalWriter) Write(b client.Bond) error {
+
+	r := national_record(b)
+
+	if n.format == FORMAT_XML {
+		n.records++
+		return n.xml.EncodeElement(r, xml.StartElement{Name: xml.Name{Local: "Deed"}})
+	}
+
+	bytes, err := json.Marshal(r)
+
+	if err != nil {
+		return err
+	}
+
+	if n.records > 0 {
+		bytes = append([]byte{','}, bytes...)
+	}
+
+	n.records++
+
+	_, err = n.w.Write(bytes)
+
+	return err
+}
+
+//==============================================================================================================================
+//	 Close - Writes the end of the batch, with the number of records for the receiver to check it is complete.
+//==============================================================================================================================
+func (n *NationalWriter) Close() error {
+
+	if n.format == FORMAT_XML {
+
+		count := xml.StartElement{Name: xml.Name{Local: "RecordCount"}}
+
+		if err := n.xml.EncodeElement(n.records, count); err != nil {
+			return err
+		}
+
+		if err := n.xml.EncodeToken(xml.EndElement{Name: xml.Name{Local: "RegistryBatch"}}); err != nil {
+			return err
+		}
+
+		if err := n.xml.Flush(); err != nil {
+			return err
+		}
+
+		_, err := io.WriteString(n.w, "\n")
+
+		return err
+	}
+
+	_, err := io.WriteString(n.w, `],"record_count":`+strconv.Itoa(n.records)+"}\n")
+
+	return err
+}
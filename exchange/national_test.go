@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+var TEST_BONDS = []client.Bond{
+	{
+		ID:              "bond-1",
+		RealEstateID:    "1232.21",
+		OwnerNationalID: "1000000001",
+		Status:          "built",
+		Area:            client.Land_Area{Value: 500, Unit: "m2"},
+		Coordinates:     client.Coordinates{Long: 46.6753, Lat: 24.7136},
+		Boundary:        &client.Polygon{Type: "Polygon", Coordinates: [][][2]float64{{{46.67, 24.71}, {46.68, 24.71}, {46.67, 24.71}}}},
+		DistrictCode:    "RUH-01",
+		CityCode:        "RUH",
+		UpdatedAt:       "2024-01-02T03:04:05Z",
+		Version:         2,
+	},
+	{ID: "bond-2", RealEstateID: "1232.22", OwnerNationalID: "1000000002", Status: "flat", UpdatedAt: "2024-01-03T00:00:00Z"},
+}
+
+func TestNationalJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	w, err := NewNationalWriter(&buf, FORMAT_JSON, "batch-1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range TEST_BONDS {
+		if err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var batch struct {
+		Schema      string            `json:"schema"`
+		BatchID     string            `json:"batch_id"`
+		Records     []National_Record `json:"records"`
+		RecordCount int               `json:"record_count"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &batch); err != nil {
+		t.Fatalf("Invalid JSON batch %s: %s", buf.String(), err)
+	}
+
+	if batch.Schema != NATIONAL_SCHEMA || batch.BatchID != "batch-1" || batch.RecordCount != 2 || len(batch.Records) != 2 {
+		t.Fatalf("Unexpected batch %+v", batch)
+	}
+
+	r := batch.Records[0]
+
+	if r.PlanNumber != "1232" || r.ParcelNumber != "21" || r.Owner.ID != "1000000001" || r.Location.Latitude != 24.7136 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+
+	if r.BoundaryWKT != "POLYGON ((46.67 24.71, 46.68 24.71, 46.67 24.71))" {
+		t.Errorf("Unexpected boundary %s", r.BoundaryWKT)
+	}
+}
+
+func TestNationalXML(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	w, err := NewNationalWriter(&buf, FORMAT_XML, "batch-1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range TEST_BONDS {
+		w.Write(b)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var batch struct {
+		BatchID     string            `xml:"batchID,attr"`
+		Deeds       []National_Record `xml:"Deed"`
+		RecordCount int               `xml:"RecordCount"`
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), &batch); err != nil {
+		t.Fatalf("Invalid XML batch %s: %s", buf.String(), err)
+	}
+
+	if batch.BatchID != "batch-1" || batch.RecordCount != 2 || len(batch.Deeds) != 2 || batch.Deeds[1].ParcelNumber != "22" || batch.Deeds[0].Owner.IDType != "NationalID" {
+		t.Errorf("Unexpected batch %+v", batch)
+	}
+
+	if _, err := NewNationalWriter(&buf, "csv", "batch-1"); err == nil {
+		t.Errorf("Expected an unknown format to be rejected")
+	}
+}
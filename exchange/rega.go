@@ -0,0 +1,208 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alfaifiisa/learn-chaincode/client"
+)
+
+//==============================================================================================================================
+//	 Real Estate Authority Export - Bonds as the property records of the authority's published schema, limited to a
+//									district and a range of last change dates when asked. The ledger holds no licence
+//									references, they are joined in from a CSV file of realEstateID,licence pairs kept
+//									by the operator, see ReadLicenses.
+//==============================================================================================================================
+
+const REGA_SCHEMA = "rega-property-records"
+const REGA_SCHEMA_VERSION = "1.0"
+
+// Property classification code of each bond status, bonds of any other status are UNCLASSIFIED
+var REGA_CLASSIFICATION = map[string]string{
+	"flat":  "RES-APT",
+	"built": "RES-BLD",
+}
+
+const REGA_UNCLASSIFIED = "UNCLASSIFIED"
+
+//==============================================================================================================================
+//	 REGA_Record - A property of the authority's schema.
+//==============================================================================================================================
+type REGA_Record struct {
+	PropertyID         string  `json:"property_id"`
+	DeedNumber         string  `json:"deed_number"`
+	ClassificationCode string  `json:"classification_code"`
+	LicenseReference   string  `json:"license_reference,omitempty"`
+	OwnerID            string  `json:"owner_id"`
+	AreaSquareMetres   float64 `json:"area_sqm"`
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	CityCode           string  `json:"city_code,omitempty"`
+	DistrictCode       string  `json:"district_code,omitempty"`
+	LastUpdatedAt      string  `json:"last_updated_at"`
+}
+
+//==============================================================================================================================
+//	 REGAFilter - Selects the bonds exported. Empty fields select every bond.
+//==============================================================================================================================
+type REGAFilter struct {
+	DistrictCode string    `json:"district_code,omitempty"`
+	From         time.Time `json:"from,omitempty"` // last changed at or after
+	To           time.Time `json:"to,omitempty"`   // last changed before
+}
+
+//==============================================================================================================================
+//	 Matches - Returns whether the filter selects the bond. A bond with no change date is only selected without a
+//			   date range.
+//==============================================================================================================================
+func (f REGAFilter) Matches(b client.Bond) bool {
+
+	if f.DistrictCode != "" && b.DistrictCode != f.DistrictCode {
+		return false
+	}
+
+	if f.From.IsZero() && f.To.IsZero() {
+		return true
+	}
+
+	updated, err := time.Parse(time.RFC3339, b.UpdatedAt)
+
+	if err != nil {
+		return false
+	}
+
+	return (f.From.IsZero() || !updated.Before(f.From)) && (f.To.IsZero() || updated.Before(f.To))
+}
+
+//==============================================================================================================================
+//	 ReadLicenses - Reads the licence references of a CSV file of realEstateID,licence rows. A first row of
+//					real_estate_id,license is taken as a header.
+//==============================================================================================================================
+func ReadLicenses(r io.Reader) (map[string]string, error) {
+
+	licenses := make(map[string]string)
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+
+	for line := 1; ; line++ {
+
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			return licenses, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 1 && strings.EqualFold(row[0], "real_estate_id") {
+			continue
+		}
+
+		if _, ok := licenses[row[0]]; ok {
+			return nil, errors.New("Duplicate licence of " + row[0] + " on line " + strconv.Itoa(line))
+		}
+
+		licenses[row[0]] = strings.TrimSpace(row[1])
+	}
+}
+
+//==============================================================================================================================
+//	 rega_record - Maps the bond to its record.
+//==============================================================================================================================
+func rega_record(b client.Bond, licenses map[string]string) REGA_Record {
+
+	code, ok := REGA_CLASSIFICATION[b.Status]
+
+	if !ok {
+		code = REGA_UNCLASSIFIED
+	}
+
+	return REGA_Record{
+		PropertyID:         b.RealEstateID,
+		DeedNumber:         b.ID,
+		ClassificationCode: code,
+		LicenseReference:   licenses[b.RealEstateID],
+		OwnerID:            b.OwnerNationalID,
+		AreaSquareMetres:   b.Area.Value, // the chaincode normalises areas to square metres
+		Latitude:           b.Coordinates.Lat,
+		Longitude:          b.Coordinates.Long,
+		CityCode:           b.CityCode,
+		DistrictCode:       b.DistrictCode,
+		LastUpdatedAt:      b.UpdatedAt,
+	}
+}
+
+//==============================================================================================================================
+//	 REGAWriter - Streams the records of the bonds the filter selects as a JSON document.
+//==============================================================================================================================
+type REGAWriter struct {
+	w        io.Writer
+	filter   REGAFilter
+	licenses map[string]string
+	records  int
+}
+
+//==============================================================================================================================
+//	 NewREGAWriter - Writes the header of the document, recording the filter it was made with.
+//==============================================================================================================================
+func NewREGAWriter(w io.Writer, filter REGAFilter, licenses map[string]string) (*REGAWriter, error) {
+
+	header, err := json.Marshal(struct {
+		Schema        string     `json:"schema"`
+		SchemaVersion string     `json:"schema_version"`
+		GeneratedAt   string     `json:"generated_at"`
+		Filter        REGAFilter `json:"filter"`
+	}{REGA_SCHEMA, REGA_SCHEMA_VERSION, time.Now().UTC().Format("2006-01-02T15:04:05Z"), filter})
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.WriteString(w, string(header[:len(header)-1])+`,"records":[`)
+
+	return &REGAWriter{w: w, filter: filter, licenses: licenses}, err
+}
+
+//==============================================================================================================================
+//	 Write - Writes the record of the bond if the filter selects it.
+//==============================================================================================================================
+func (r *REGAWriter) Write(b client.Bond) error {
+
+	if !r.filter.Matches(b) {
+		return nil
+	}
+
+	bytes, err := json.Marshal(rega_record(b, r.licenses))
+
+	if err != nil {
+		return err
+	}
+
+	if r.records > 0 {
+		bytes = append([]byte{','}, bytes...)
+	}
+
+	r.records++
+
+	_, err = r.w.Write(bytes)
+
+	return err
+}
+
+//==============================================================================================================================
+//	 Close - Writes the end of the document with the number of records.
+//==============================================================================================================================
+func (r *REGAWriter) Close() error {
+
+	_, err := io.WriteString(r.w, `],"record_count":`+strconv.Itoa(r.records)+"}\n")
+
+	return err
+}
@@ -0,0 +1,79 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestREGAExport(t *testing.T) {
+
+	licenses, err := ReadLicenses(strings.NewReader("real_estate_id,license\n1232.21,LIC-778\n"))
+
+	if err != nil || licenses["1232.21"] != "LIC-778" {
+		t.Fatalf("Unexpected licences %v %v", licenses, err)
+	}
+
+	var buf bytes.Buffer
+
+	filter := REGAFilter{DistrictCode: "RUH-01", From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+
+	w, err := NewREGAWriter(&buf, filter, licenses)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range TEST_BONDS {
+		if err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var document struct {
+		Schema      string        `json:"schema"`
+		Records     []REGA_Record `json:"records"`
+		RecordCount int           `json:"record_count"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid JSON document %s: %s", buf.String(), err)
+	}
+
+	if document.Schema != REGA_SCHEMA || document.RecordCount != 1 || len(document.Records) != 1 {
+		t.Fatalf("Expected the bond of RUH-01 alone, got %s", buf.String())
+	}
+
+	r := document.Records[0]
+
+	if r.PropertyID != "1232.21" || r.ClassificationCode != "RES-BLD" || r.LicenseReference != "LIC-778" || r.AreaSquareMetres != 500 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+}
+
+func TestREGAFilter(t *testing.T) {
+
+	b := TEST_BONDS[1]
+
+	if !(REGAFilter{}).Matches(b) {
+		t.Errorf("Expected an empty filter to select every bond")
+	}
+
+	if (REGAFilter{To: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}).Matches(b) {
+		t.Errorf("Expected the end of the range to be excluded")
+	}
+
+	if !(REGAFilter{From: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}).Matches(b) {
+		t.Errorf("Expected the start of the range to be included")
+	}
+
+	if _, err := ReadLicenses(strings.NewReader("1232.21,A\n1232.21,B\n")); err == nil {
+		t.Errorf("Expected a duplicate licence to be rejected")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Address Functions - Bonds with a registered district are indexed by city, district and street so they can be
+//						 searched by address e.g. address~RUH~OLAYA~king fahd road~1232.21. Streets are stored as
+//						 entered and indexed in lower case with single spaces so searches ignore case and spacing.
+//						 A composite index is used rather than CouchDB rich queries because bonds may be stored as
+//						 protobuf, see protobuf.go, which CouchDB cannot query, and peers on LevelDB have no rich
+//						 queries at all. The range query serves the same searches on either state database.
+//==============================================================================================================================
+
+const ADDRESS_INDEX = "address"
+
+//==============================================================================================================================
+//	 parse_street - Trims the street name and checks it can be used in an index key.
+//==============================================================================================================================
+func parse_street(s string) (string, error) {
+
+	s = strings.TrimSpace(s)
+
+	if strings.Contains(s, INDEX_SEPARATOR) {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "Invalid street, must not contain "+INDEX_SEPARATOR)
+	}
+
+	return s, nil
+}
+
+//==============================================================================================================================
+//	 normalize_street - Returns the form of the street name used in the address index.
+//==============================================================================================================================
+func normalize_street(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+//==============================================================================================================================
+//	 address_attributes - Returns the attributes of the bond's address index entry.
+//==============================================================================================================================
+func address_attributes(b Bond) []string {
+	return []string{b.CityCode, b.DistrictCode, normalize_street(b.Street), b.RealEstateID}
+}
+
+//==============================================================================================================================
+//	 move_address_index - Replaces the address index entry of the bond as it was with the entry for the bond as it is
+//						  now. Bonds without a district have no entry.
+//==============================================================================================================================
+func (t *SimpleChaincode) move_address_index(stub shim.ChaincodeStubInterface, previous Bond, b Bond) error {
+
+	before, after := address_attributes(previous), address_attributes(b)
+
+	if strings.Join(before, INDEX_SEPARATOR) == strings.Join(after, INDEX_SEPARATOR) {
+		return nil
+	}
+
+	if previous.CityCode != "" {
+		err := t.del_index(stub, ADDRESS_INDEX, before...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.CityCode == "" {
+		return nil
+	}
+
+	return t.put_index(stub, ADDRESS_INDEX, after...)
+}
+
+//=================================================================================================================================
+//	 change_address - Assigns the bond to a registered district and sets its street. An empty street clears it.
+//=================================================================================================================================
+func (t *SimpleChaincode) change_address(stub shim.ChaincodeStubInterface, b Bond, districtCode string, street string) ([]byte, error) {
+
+	d, err := t.lookup_district(stub, districtCode)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_ADDRESS", err)
+	}
+
+	var problems Field_Errors
+
+	problems.check_street("street", street)
+
+	err = problems.to_error("CHANGE_ADDRESS", "address")
+
+	if err != nil {
+		return nil, err
+	}
+
+	street, _ = parse_street(street)
+
+	previous := b
+
+	b.DistrictCode = d.Code
+	b.CityCode = d.CityCode
+	b.Street = street
+
+	err = t.move_address_index(stub, previous, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_ADDRESS: Error updating address index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating address index", err)
+	}
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_ADDRESS: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 change_district - Assigns the bond to a registered district, keeping its street.
+//=================================================================================================================================
+func (t *SimpleChaincode) change_district(stub shim.ChaincodeStubInterface, b Bond, code string) ([]byte, error) {
+
+	d, err := t.lookup_district(stub, code)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_DISTRICT", err)
+	}
+
+	previous := b
+
+	b.DistrictCode = d.Code
+	b.CityCode = d.CityCode
+
+	err = t.move_address_index(stub, previous, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_DISTRICT: Error updating address index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating address index", err)
+	}
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_DISTRICT: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 search_by_address - Returns the bonds in the city, optionally narrowed to a district and to streets starting with
+//						 the street given. Matching ignores case and spacing of the street name.
+//=================================================================================================================================
+func (t *SimpleChaincode) search_by_address(stub shim.ChaincodeStubInterface, cityCode string, districtCode string, street string) ([]byte, error) {
+
+	if cityCode == "" {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "SEARCH_BY_ADDRESS: City is required")
+	}
+
+	street = normalize_street(street)
+	prefix := cityCode + INDEX_SEPARATOR
+
+	if districtCode != "" {
+		prefix += districtCode + INDEX_SEPARATOR + street // without a district the street is filtered below
+	}
+
+	ids, err := t.scan_index(stub, ADDRESS_INDEX, prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := t.retrieve_listed_bonds(stub, "SEARCH_BY_ADDRESS", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds := []Bond{}
+
+	for _, b := range found {
+
+		if strings.HasPrefix(normalize_street(b.Street), street) {
+			bonds = append(bonds, b)
+		}
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "SEARCH_BY_ADDRESS: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
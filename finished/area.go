@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 Land_Area - The area of a parcel. Areas are normalised to square metres when they are parsed so they can be compared,
+//				 filtered and summed, the unit is stored alongside the value to keep records self describing.
+//==============================================================================================================================
+type Land_Area struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+const AREA_UNIT = "m2"
+
+// Accepted area units and how many square metres each is worth
+var AREA_UNITS = map[string]float64{
+	"":         1,
+	"m2":       1,
+	"m²":       1,
+	"sqm":      1,
+	"ha":       10000,
+	"hectare":  10000,
+	"hectares": 10000,
+	"km2":      1000000,
+	"km²":      1000000,
+}
+
+//==============================================================================================================================
+//	 parse_area - Parses an area such as "500", "500 m2" or "1.5 ha" into square metres.
+//==============================================================================================================================
+func parse_area(s string) (Land_Area, error) {
+
+	s = strings.TrimSpace(strings.ToLower(s))
+
+	i := 0
+	for i < len(s) && strings.IndexByte("0123456789.+-e", s[i]) >= 0 {
+		i++
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return Land_Area{}, coded_error(CODE_INVALID_ARGUMENT, "Invalid area "+s)
+	}
+
+	factor, ok := AREA_UNITS[strings.TrimSpace(s[i:])]
+
+	if !ok {
+		return Land_Area{}, coded_error(CODE_INVALID_ARGUMENT, "Unknown area unit "+strings.TrimSpace(s[i:]))
+	}
+
+	if value <= 0 {
+		return Land_Area{}, coded_error(CODE_INVALID_ARGUMENT, "Area must be positive")
+	}
+
+	return Land_Area{Value: value * factor, Unit: AREA_UNIT}, nil
+}
+
+//==============================================================================================================================
+//	 String - Formats the area for messages e.g. 500.00 m2
+//==============================================================================================================================
+func (a Land_Area) String() string {
+	return strconv.FormatFloat(a.Value, 'f', 2, 64) + " " + a.Unit
+}
+
+//==============================================================================================================================
+//	 UnmarshalJSON - Reads both the typed form and the free text string older bonds were stored with. Legacy text that
+//					 is not a valid area is read as an unknown (zero) area rather than failing the whole record.
+//==============================================================================================================================
+func (a *Land_Area) UnmarshalJSON(data []byte) error {
+
+	var legacy string
+
+	if json.Unmarshal(data, &legacy) == nil {
+		parsed, err := parse_area(legacy)
+		if err != nil {
+			parsed = Land_Area{Unit: AREA_UNIT}
+		}
+		*a = parsed
+		return nil
+	}
+
+	type typed Land_Area // avoids recursing into this method
+
+	return json.Unmarshal(data, (*typed)(a))
+}
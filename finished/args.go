@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 Argument Specs - The arguments every function takes, given by its entry in the registry, see registry.go. They
+//					  are checked by Invoke before the call is routed so no function reads an argument that was not
+//					  passed. A spec names the arguments in order; optional ones may be left out or passed empty, and
+//					  typed ones are checked to convert. Functions taking a variable list, such as create_bond, check
+//					  the arguments after the spec themselves.
+//==============================================================================================================================
+
+// Types of argument, checked unless the argument is an empty optional one
+const ARG_STRING = ""
+const ARG_INT = "integer"
+const ARG_NUMBER = "number"
+const ARG_JSON = "JSON"
+
+//==============================================================================================================================
+//	 Arg - An argument of a function.
+//==============================================================================================================================
+type Arg struct {
+	Name     string
+	Type     string
+	Optional bool // optional arguments follow the required ones
+}
+
+//==============================================================================================================================
+//	 Arg_Spec - The arguments of a function. Variadic functions accept more arguments than Args names.
+//==============================================================================================================================
+type Arg_Spec struct {
+	Args     []Arg
+	Variadic bool
+}
+
+func required_arg(name string, kind string) Arg {
+	return Arg{Name: name, Type: kind}
+}
+
+func optional_arg(name string, kind string) Arg {
+	return Arg{Name: name, Type: kind, Optional: true}
+}
+
+var REAL_ESTATE_ID_ARG = required_arg("realEstateID", ARG_STRING)
+var PAGE_ARGS = []Arg{required_arg("pageSize", ARG_INT), required_arg("bookmark", ARG_STRING)}
+
+//==============================================================================================================================
+//	 expecting - Describes the arguments of the spec for messages, e.g. realEstateID, status and optionally expand.
+//==============================================================================================================================
+func (s Arg_Spec) expecting() string {
+
+	var names, optionals []string
+
+	for _, a := range s.Args {
+		if a.Optional {
+			optionals = append(optionals, a.Name)
+		} else {
+			names = append(names, a.Name)
+		}
+	}
+
+	if len(names) == 0 && len(optionals) == 0 {
+		return "no arguments"
+	}
+
+	text := strings.Join(names, ", ")
+
+	if len(optionals) > 0 {
+		if text != "" {
+			text += " and "
+		}
+		text += "optionally " + strings.Join(optionals, ", ")
+	}
+
+	return text
+}
+
+//==============================================================================================================================
+//	 check_arg - Checks the value converts to the type of the argument.
+//==============================================================================================================================
+func check_arg(a Arg, value string) error {
+
+	if value == "" && a.Optional {
+		return nil
+	}
+
+	ok := true
+
+	switch a.Type {
+	case ARG_INT:
+		_, err := strconv.Atoi(value)
+		ok = err == nil
+	case ARG_NUMBER:
+		v, err := strconv.ParseFloat(value, 64)
+		ok = err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+	case ARG_JSON:
+		ok = json.Valid([]byte(value))
+	}
+
+	if !ok {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid "+a.Name+" "+value+", expecting "+article(a.Type)+" "+a.Type)
+	}
+
+	return nil
+}
+
+func article(kind string) string {
+
+	if strings.IndexByte("aeiou", kind[0]) >= 0 {
+		return "an"
+	}
+
+	return "a"
+}
+
+//==============================================================================================================================
+//	 check_args - Checks the arguments against the spec of the function in the registry. Functions not in the
+//				  registry are left to invoke, which rejects them as unknown.
+//==============================================================================================================================
+func check_args(function string, args []string) error {
+
+	entry, ok := FUNCTIONS[function]
+
+	if !ok {
+		return nil
+	}
+
+	spec := entry.Args
+
+	least := 0
+
+	for _, a := range spec.Args {
+		if !a.Optional {
+			least++
+		}
+	}
+
+	if len(args) < least || (len(args) > len(spec.Args) && !spec.Variadic) {
+		return coded_error(CODE_INVALID_ARGUMENT, strings.ToUpper(function)+": Incorrect number of arguments. Expecting "+spec.expecting())
+	}
+
+	for i, a := range spec.Args {
+
+		if i == len(args) {
+			break
+		}
+
+		err := check_arg(a, args[i])
+
+		if err != nil {
+			return prefix_error(strings.ToUpper(function), err)
+		}
+	}
+
+	return nil
+}
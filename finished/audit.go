@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Audit Log - Every change saved to a bond is recorded under audit~realEstateID~timestamp~txid, holding who made it,
+//				 with which function, and the hashes of the bond before and after. Records are only ever added. An
+//				 index entry audit_log~timestamp~txid~realEstateID lets the whole log be paged through in time order.
+//				 A transaction changing a bond more than once leaves one record, from the bond as committed before it
+//				 to the bond as it finally saved it.
+//==============================================================================================================================
+
+const AUDIT_PREFIX = "audit"
+const AUDIT_INDEX = "audit_log"
+
+//==============================================================================================================================
+//	 Audit_Record - Defines the structure of an audit record. BeforeHash is empty for the record creating the bond, the
+//					hashes are those get_registry_checksum uses.
+//==============================================================================================================================
+type Audit_Record struct {
+	RealEstateID string `json:"real_estate_id"`
+	Function     string `json:"function"`
+	Actor        string `json:"actor"`
+	TxID         string `json:"txid"`
+	Timestamp    string `json:"timestamp"`
+	BeforeHash   string `json:"before_hash,omitempty"`
+	AfterHash    string `json:"after_hash"`
+}
+
+//==============================================================================================================================
+//	 Audit_Page - The response of get_audit_records. Bookmark is empty once the last page has been returned.
+//==============================================================================================================================
+type Audit_Page struct {
+	Records  []Audit_Record `json:"records"`
+	Bookmark string         `json:"bookmark"`
+}
+
+//==============================================================================================================================
+//	 audit_key - Returns the ledger key of the audit record.
+//==============================================================================================================================
+func audit_key(realEstateID string, timestamp string, txID string) string {
+	return index_key(AUDIT_PREFIX, realEstateID, timestamp, txID)
+}
+
+//==============================================================================================================================
+//	 record_audit - Stores the audit record of the current transaction saving the bond after, found tells whether
+//					before was stored already.
+//==============================================================================================================================
+func (t *SimpleChaincode) record_audit(stub shim.ChaincodeStubInterface, before Bond, found bool, after Bond) error {
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_AUDIT: Error reading caller identity: %s", err)
+		return wrap_error(CODE_NOT_AUTHORIZED, "Error reading caller identity", err)
+	}
+
+	function, _ := stub.GetFunctionAndParameters()
+
+	r := Audit_Record{RealEstateID: after.RealEstateID, Function: function, Actor: actor, TxID: stub.GetTxID(), Timestamp: now.Format(TIME_LAYOUT)}
+
+	if found {
+
+		hash, err := bond_hash(before)
+
+		if err != nil {
+			return err
+		}
+
+		r.BeforeHash = hex.EncodeToString(hash)
+	}
+
+	hash, err := bond_hash(after)
+
+	if err != nil {
+		return err
+	}
+
+	r.AfterHash = hex.EncodeToString(hash)
+
+	bytes, err := json.Marshal(r)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_AUDIT: Error converting audit record: %s", err)
+		return wrap_error(CODE_ERROR, "Error converting audit record", err)
+	}
+
+	err = stub.PutState(audit_key(r.RealEstateID, r.Timestamp, r.TxID), bytes)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_AUDIT: Error storing audit record: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing audit record "+audit_key(r.RealEstateID, r.Timestamp, r.TxID), err)
+	}
+
+	return t.put_index(stub, AUDIT_INDEX, r.Timestamp, r.TxID, r.RealEstateID)
+}
+
+//==============================================================================================================================
+//	 read_audit_record - Returns the audit record stored under the key.
+//==============================================================================================================================
+func (t *SimpleChaincode) read_audit_record(stub shim.ChaincodeStubInterface, key string) (Audit_Record, error) {
+
+	var r Audit_Record
+
+	bytes, err := stub.GetState(key)
+
+	if err != nil {
+		log_errorf(stub, "READ_AUDIT_RECORD: Error retrieving audit record %s: %s", key, err)
+		return r, wrap_error(CODE_LEDGER_ERROR, "Error retrieving audit record "+key, err)
+	}
+
+	err = json.Unmarshal(bytes, &r)
+
+	if err != nil {
+		return r, errors.New("Corrupt audit record " + key)
+	}
+
+	return r, nil
+}
+
+//=================================================================================================================================
+//	 get_audit_log - Admin function returning the audit records of the bond, oldest first.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_audit_log(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_AUDIT_LOG", err)
+	}
+
+	prefix := index_key(AUDIT_PREFIX, realEstateID, "")
+
+	iter, err := stub.GetStateByRange(prefix, prefix+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_AUDIT_LOG: Error querying audit records: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "GET_AUDIT_LOG: Error querying audit records", err)
+	}
+
+	defer iter.Close()
+
+	records := []Audit_Record{}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_AUDIT_LOG: Error reading audit records: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "GET_AUDIT_LOG: Error reading audit records", err)
+		}
+
+		var r Audit_Record
+
+		err = json.Unmarshal(kv.Value, &r)
+
+		if err != nil {
+			return nil, errors.New("GET_AUDIT_LOG: Corrupt audit record " + kv.Key)
+		}
+
+		records = append(records, r)
+	}
+
+	return json.Marshal(records)
+}
+
+//=================================================================================================================================
+//	 get_audit_records - Admin function returning up to pageSize audit records of every bond in time order, starting
+//						 after the bookmark returned with the previous page. An empty bookmark starts from the oldest.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_audit_records(stub shim.ChaincodeStubInterface, pageSize int, bookmark string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_AUDIT_RECORDS", err)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_AUDIT_RECORDS", err)
+	}
+
+	if pageSize < 1 || pageSize > c.MaxPageSize {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_AUDIT_RECORDS: Page size must be between 1 and "+strconv.Itoa(c.MaxPageSize))
+	}
+
+	after, err := decode_bookmark("get_audit_records", bookmark)
+
+	if err != nil {
+		return nil, prefix_error("GET_AUDIT_RECORDS", err)
+	}
+
+	start := AUDIT_INDEX + INDEX_SEPARATOR
+
+	if after != "" {
+		start = after + "\x00"
+	}
+
+	iter, err := stub.GetStateByRange(start, AUDIT_INDEX+INDEX_SEPARATOR+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_AUDIT_RECORDS: Error querying audit log: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "GET_AUDIT_RECORDS: Error querying audit log", err)
+	}
+
+	defer iter.Close()
+
+	page := Audit_Page{Records: []Audit_Record{}}
+	last := ""
+
+	for iter.HasNext() {
+
+		if len(page.Records) == pageSize {
+			page.Bookmark = encode_bookmark("get_audit_records", last)
+			break
+		}
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_AUDIT_RECORDS: Error reading audit log: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "GET_AUDIT_RECORDS: Error reading audit log", err)
+		}
+
+		attributes := strings.SplitN(kv.Key, INDEX_SEPARATOR, 4) // audit_log, timestamp, txid, realEstateID
+
+		if len(attributes) != 4 {
+			return nil, errors.New("GET_AUDIT_RECORDS: Invalid audit log entry " + kv.Key)
+		}
+
+		r, err := t.read_audit_record(stub, audit_key(attributes[3], attributes[1], attributes[2]))
+
+		if err != nil {
+			return nil, prefix_error("GET_AUDIT_RECORDS", err)
+		}
+
+		page.Records = append(page.Records, r)
+		last = kv.Key
+	}
+
+	bytes, err := json.Marshal(page)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_AUDIT_RECORDS: Error converting audit records", err)
+	}
+
+	return bytes, nil
+}
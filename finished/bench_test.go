@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+//==============================================================================================================================
+//	 Benchmarks - The listing queries against a registry of bench_bonds bonds, 100,000 unless set with -bonds, held by
+//				  BENCH_OWNERS owners. The registry is built once, through create_bonds_bulk, and shared by the
+//				  benchmarks, which only read it. Run them with e.g.
+//
+//		go test -run XXX -bench . -benchtime 10x
+//		go test -run XXX -bench GetBondsByOwner -bonds 10000
+//==============================================================================================================================
+
+var bench_bonds = flag.Int("bonds", 100000, "bonds registered for the benchmarks")
+
+// Owners the bonds are spread over, so each holds bench_bonds / BENCH_OWNERS of them
+const BENCH_OWNERS = 1000
+
+// Bonds per row of the grid they are laid out on, 0.0005 degrees or about 50 m apart
+const BENCH_ROW = 400
+
+var bench_once sync.Once
+var bench_registry *harness
+var bench_middle string // bookmark of the export page halfway through the registry
+
+//==============================================================================================================================
+//	 bench_record - Returns the nth bond of the benchmark registry.
+//==============================================================================================================================
+func bench_record(n int) Bulk_Bond {
+	return Bulk_Bond{
+		RealEstateID:    strconv.Itoa(9000+n/1000) + "." + strconv.Itoa(1+n%1000),
+		OwnerNationalID: owner_fixture(1 + n%BENCH_OWNERS).NationalID,
+		Status:          "flat",
+		Area:            "500",
+		Longitude:       46.5 + float64(n%BENCH_ROW)*0.0005,
+		Latitude:        24.5 + float64(n/BENCH_ROW)*0.0005,
+		DistrictCode:    TEST_DISTRICT,
+		Street:          "King Fahd Road",
+	}
+}
+
+//==============================================================================================================================
+//	 bench_harness - Returns the harness of the benchmark registry, building it on first use, with the timer reset.
+//==============================================================================================================================
+func bench_harness(b *testing.B) *harness {
+
+	bench_once.Do(func() {
+
+		h := new_harness(b)
+
+		h.seed_reference_data()
+
+		for start := 0; start < *bench_bonds; start += MAX_BULK_BONDS {
+
+			var records []Bulk_Bond
+
+			for n := start; n < start+MAX_BULK_BONDS && n < *bench_bonds; n++ {
+				records = append(records, bench_record(n))
+			}
+
+			batch, _ := json.Marshal(records)
+
+			h.must("create_bonds_bulk", string(batch), BULK_ATOMIC)
+		}
+
+		var page Export_Page
+
+		for n := 0; n < *bench_bonds/2; n += MAX_PAGE_SIZE {
+			decode(b, h.must("export_bonds", strconv.Itoa(MAX_PAGE_SIZE), page.Bookmark), &page)
+		}
+
+		bench_registry, bench_middle = h, page.Bookmark
+	})
+
+	if bench_registry == nil {
+		b.Fatal("the benchmark registry could not be built")
+	}
+
+	bench_registry.t = b
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	return bench_registry
+}
+
+func BenchmarkGetBondDetails(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("get_bond_details", bench_record(i%*bench_bonds).RealEstateID)
+	}
+}
+
+func BenchmarkGetBonds(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("get_bonds")
+	}
+}
+
+func BenchmarkGetBondsByOwner(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("get_bonds_by_owner", owner_fixture(1+i%BENCH_OWNERS).NationalID)
+	}
+}
+
+func BenchmarkGetOwnerSummary(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("get_owner_summary", owner_fixture(1+i%BENCH_OWNERS).NationalID)
+	}
+}
+
+func BenchmarkExportBondsFirstPage(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("export_bonds", "100", "")
+	}
+}
+
+func BenchmarkExportBondsMiddlePage(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+		h.must("export_bonds", "100", bench_middle)
+	}
+}
+
+func BenchmarkExportBondsAllPages(b *testing.B) {
+
+	h := bench_harness(b)
+
+	for i := 0; i < b.N; i++ {
+
+		var page Export_Page
+
+		for {
+			decode(b, h.must("export_bonds", strconv.Itoa(MAX_PAGE_SIZE), page.Bookmark), &page)
+			if page.Bookmark == "" {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Blueprints - A realEstateID is the number of the approved subdivision blueprint followed by the number of the
+//				  parcel on it, e.g. 1232.21. Both are checked when a bond is registered, and bonds are indexed by them
+//				  separately under blueprint~<blueprint>~<parcel>~<realEstateID> so every parcel of a blueprint can be
+//				  listed in parcel order, the parcel number being zero padded in the key for that. Bonds registered
+//				  before the format was enforced keep their IDs, and are left out of the index when not in the form.
+//==============================================================================================================================
+
+const BLUEPRINT_INDEX = "blueprint"
+
+const MAX_BLUEPRINT_NUMBER = 999999
+const MAX_PARCEL_NUMBER = 99999
+
+// Digits, a dot and digits. Leading zeros are allowed, 1232.021 being parcel 21 as for the duplicate checks
+var REAL_ESTATE_ID = regexp.MustCompile(`^([0-9]{1,9})\.([0-9]{1,9})$`)
+
+//==============================================================================================================================
+//	 split_real_estate_id - Returns the blueprint and parcel numbers of the realEstateID, and whether it is in the
+//							blueprint.parcel form. The numbers are not checked against their ranges.
+//==============================================================================================================================
+func split_real_estate_id(realEstateID string) (int, int, bool) {
+
+	m := REAL_ESTATE_ID.FindStringSubmatch(realEstateID)
+
+	if m == nil {
+		return 0, 0, false
+	}
+
+	blueprint, _ := strconv.Atoi(m[1]) // at most nine digits
+	parcel, _ := strconv.Atoi(m[2])
+
+	return blueprint, parcel, true
+}
+
+//==============================================================================================================================
+//	 blueprint_attributes - Returns the attributes of the bond's blueprint index entry, none for a realEstateID not in
+//							the blueprint.parcel form.
+//==============================================================================================================================
+func blueprint_attributes(realEstateID string) ([]string, bool) {
+
+	blueprint, parcel, ok := split_real_estate_id(realEstateID)
+
+	if !ok {
+		return nil, false
+	}
+
+	padded := strconv.Itoa(parcel)
+
+	for len(padded) < len(strconv.Itoa(MAX_PARCEL_NUMBER)) {
+		padded = "0" + padded
+	}
+
+	return []string{strconv.Itoa(blueprint), padded, realEstateID}, true
+}
+
+//==============================================================================================================================
+//	 check_real_estate_id - Checks a required realEstateID is a blueprint number and a parcel number within range.
+//==============================================================================================================================
+func (e *Field_Errors) check_real_estate_id(field string, realEstateID string) {
+
+	if !e.required(field, realEstateID) {
+		return
+	}
+
+	blueprint, parcel, ok := split_real_estate_id(realEstateID)
+
+	if !ok {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid realEstateID "+realEstateID+", expecting blueprint.parcel numbers e.g. 1232.21")
+		return
+	}
+
+	if blueprint < 1 || blueprint > MAX_BLUEPRINT_NUMBER {
+		e.add(field, FIELD_OUT_OF_RANGE, "Blueprint number out of range "+realEstateID+", expecting 1 to "+strconv.Itoa(MAX_BLUEPRINT_NUMBER))
+	}
+
+	if parcel < 1 || parcel > MAX_PARCEL_NUMBER {
+		e.add(field, FIELD_OUT_OF_RANGE, "Parcel number out of range "+realEstateID+", expecting 1 to "+strconv.Itoa(MAX_PARCEL_NUMBER))
+	}
+}
+
+//=================================================================================================================================
+//	 get_bonds_by_blueprint - Returns every bond on the blueprint as a JSON array, in parcel order.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bonds_by_blueprint(stub shim.ChaincodeStubInterface, blueprint string) ([]byte, error) {
+
+	number, err := strconv.Atoi(blueprint)
+
+	if err != nil || number < 1 || number > MAX_BLUEPRINT_NUMBER {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_BONDS_BY_BLUEPRINT: Invalid blueprint number "+blueprint+", expecting 1 to "+strconv.Itoa(MAX_BLUEPRINT_NUMBER))
+	}
+
+	ids, err := t.scan_index(stub, BLUEPRINT_INDEX, strconv.Itoa(number)+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, prefix_error("GET_BONDS_BY_BLUEPRINT", err)
+	}
+
+	bonds, err := t.retrieve_listed_bonds(stub, "GET_BONDS_BY_BLUEPRINT", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_BONDS_BY_BLUEPRINT: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Bond Records - create_bond also takes its arguments as a single JSON object, the Bulk_Bond record of
+//					create_bonds_bulk, e.g. {"real_estate_id":"1232.21","owner_national_id":"1000000001",
+//					"status":"built","area":"500","longitude":46.67,"latitude":24.71}. Fields the record does not have
+//					are rejected rather than ignored, and the record is then checked like the arguments, see
+//					validation.go.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 decode_bond_record - Decodes the JSON object of a record, rejecting unknown fields and fields of the wrong type.
+//==============================================================================================================================
+func decode_bond_record(s string) (Bulk_Bond, Field_Errors) {
+
+	var r Bulk_Bond
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&r)
+
+	if err == nil && decoder.More() {
+		err = errors.New("expecting a single JSON object")
+	}
+
+	if err == nil {
+		return r, nil
+	}
+
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		return r, Field_Errors{{Field: e.Field, Code: FIELD_INVALID_TYPE, Message: "expecting a " + e.Type.String() + ", got a " + e.Value}}
+	}
+
+	if message := err.Error(); strings.HasPrefix(message, "json: unknown field ") {
+		field, _ := strconv.Unquote(strings.TrimPrefix(message, "json: unknown field "))
+		return r, Field_Errors{{Field: field, Code: FIELD_UNKNOWN, Message: "unknown field"}}
+	}
+
+	return r, Field_Errors{{Code: FIELD_INVALID_FORMAT, Message: err.Error()}}
+}
+
+//=================================================================================================================================
+//	 create_bond_record - Creates the bond of the JSON object. Returns the bond ID, or an INVALID_ARGUMENT error listing
+//						  the problems with its fields.
+//=================================================================================================================================
+func (t *SimpleChaincode) create_bond_record(stub shim.ChaincodeStubInterface, s string) ([]byte, error) {
+
+	r, problems := decode_bond_record(s)
+
+	if problems != nil {
+		return nil, problems.to_error("CREATE_BOND", "bond")
+	}
+
+	return t.new_bond(stub, r.args(), nil)
+}
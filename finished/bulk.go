@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Bulk Creation - create_bonds_bulk registers a JSON array of bonds in one transaction, each through create_bond so
+//					 every record is checked as a single create would be. By default the valid records are created and
+//					 the response reports the result of each; with "atomic" any failed record fails the transaction
+//					 and nothing is created.
+//
+//					 A record's writes are staged and only kept when the whole record succeeds, so a record failing
+//					 half way leaves nothing behind. Later records read the staged writes of earlier ones, catching
+//					 a realEstateID or parcel repeated within the batch. Range scans read only the ledger, so the
+//					 distance and overlap checks do not compare records of the same batch with each other.
+//
+//					 Fabric keeps one event per transaction: a bulk create emits a single BondsCreated event whose
+//					 payload is the list of bonds created.
+//==============================================================================================================================
+
+const BULK_ATOMIC = "atomic"
+
+// Most records accepted in one call, keeping the transaction and its read-write set a reasonable size
+const MAX_BULK_BONDS = 100
+
+//==============================================================================================================================
+//	 Bulk_Bond - A record of the array, the arguments of create_bond as JSON. Also the JSON object create_bond takes.
+//==============================================================================================================================
+type Bulk_Bond struct {
+	ID              string          `json:"id,omitempty"` // generated when empty
+	RealEstateID    string          `json:"real_estate_id"`
+	OwnerNationalID string          `json:"owner_national_id"`
+	Status          string          `json:"status"`
+	Area            string          `json:"area,omitempty"` // e.g. "500" or "1.5 ha", see parse_area
+	Longitude       float64         `json:"longitude"`
+	Latitude        float64         `json:"latitude"`
+	Boundary        json.RawMessage `json:"boundary,omitempty"` // GeoJSON polygon
+	DistrictCode    string          `json:"district_code,omitempty"`
+	Street          string          `json:"street,omitempty"`
+	Force           bool            `json:"force,omitempty"`         // skip the duplicate check, regulator only
+	OwnerIDType     string          `json:"owner_id_type,omitempty"` // cr for a company, see owner_id.go
+}
+
+//==============================================================================================================================
+//	 args - Returns the create_bond arguments of the record.
+//==============================================================================================================================
+func (r Bulk_Bond) args() []string {
+
+	force := ""
+
+	if r.Force {
+		force = "force"
+	}
+
+	return []string{
+		r.ID,
+		r.RealEstateID,
+		r.OwnerNationalID,
+		r.Status,
+		r.Area,
+		strconv.FormatFloat(r.Longitude, 'f', -1, 64),
+		strconv.FormatFloat(r.Latitude, 'f', -1, 64),
+		string(r.Boundary),
+		r.DistrictCode,
+		force,
+		r.Street,
+		r.OwnerIDType,
+	}
+}
+
+//==============================================================================================================================
+//	 Bulk_Result - The outcome of a record, by its position in the array. Code and Message are set for a failed record.
+//==============================================================================================================================
+type Bulk_Result struct {
+	Index        int    `json:"index"`
+	RealEstateID string `json:"real_estate_id"`
+	ID           string `json:"id,omitempty"`
+	Code         string `json:"code"`
+	Message      string `json:"message,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Bulk_Response - The response of create_bonds_bulk.
+//==============================================================================================================================
+type Bulk_Response struct {
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []Bulk_Result `json:"results"`
+}
+
+//==============================================================================================================================
+//	 create_bonds_bulk - Creates the bonds of the JSON array, atomically when mode is BULK_ATOMIC.
+//==============================================================================================================================
+func (t *SimpleChaincode) create_bonds_bulk(stub shim.ChaincodeStubInterface, records string, mode string) ([]byte, error) {
+
+	if mode != "" && mode != BULK_ATOMIC {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "CREATE_BONDS_BULK: Unknown mode "+mode+", expecting "+BULK_ATOMIC+" or nothing")
+	}
+
+	var bonds []Bulk_Bond
+
+	err := json.Unmarshal([]byte(records), &bonds)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "CREATE_BONDS_BULK: Invalid JSON array of bonds: "+err.Error())
+	}
+
+	if len(bonds) == 0 || len(bonds) > MAX_BULK_BONDS {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "CREATE_BONDS_BULK: Expecting 1 to "+strconv.Itoa(MAX_BULK_BONDS)+" bonds, got "+strconv.Itoa(len(bonds)))
+	}
+
+	batch := new_staged_stub(stub)
+	parcels := make(map[string]int)
+	response := Bulk_Response{Results: []Bulk_Result{}}
+	created := []Bond{}
+
+	for i, r := range bonds {
+
+		result := Bulk_Result{Index: i, RealEstateID: r.RealEstateID, Code: CODE_OK}
+
+		b, err := t.create_staged_bond(batch, r, parcels)
+
+		if err != nil {
+
+			if mode == BULK_ATOMIC {
+				return nil, prefix_error("CREATE_BONDS_BULK: Bond "+strconv.Itoa(i)+" ("+r.RealEstateID+")", err)
+			}
+
+			log_infof(stub, "CREATE_BONDS_BULK: Bond %d (%s) rejected: %s", i, r.RealEstateID, err)
+
+			result.Code, result.Message = error_code(err), err.Error()
+			response.Failed++
+
+		} else {
+
+			parcels[normalize_parcel(b.RealEstateID)] = i
+			result.ID = b.ID
+			created = append(created, b)
+			response.Created++
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	err = batch.commit()
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BONDS_BULK: Error writing staged changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "CREATE_BONDS_BULK: Error writing staged changes", err)
+	}
+
+	if len(created) > 0 {
+
+		var owners, districts []string
+
+		for _, b := range created {
+			owners = append(owners, b.OwnerNationalID)
+			districts = append(districts, b.DistrictCode)
+		}
+
+		err = t.emit_event(stub, BONDS_CREATED_EVENT, "", created, event_routing(owners, districts))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bytes, err := json.Marshal(response)
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BONDS_BULK: Error converting response: %s", err)
+		return nil, wrap_error(CODE_ERROR, "CREATE_BONDS_BULK: Error converting response", err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 create_staged_bond - Creates the bond of the record on a stage of its own, merging it into the batch only if it
+//						  succeeds. Returns the bond as created.
+//==============================================================================================================================
+func (t *SimpleChaincode) create_staged_bond(batch *staged_stub, r Bulk_Bond, parcels map[string]int) (Bond, error) {
+
+	var b Bond
+
+	if first, ok := parcels[normalize_parcel(r.RealEstateID)]; ok && !r.Force {
+		return b, coded_error(CODE_BOND_EXISTS, "Same parcel as bond "+strconv.Itoa(first)+" of the batch")
+	}
+
+	stage := new_staged_stub(batch)
+
+	_, err := t.create_bond(stage, r.args())
+
+	if err != nil {
+		return b, err
+	}
+
+	var event struct {
+		Payload Bond `json:"payload"`
+	}
+
+	err = json.Unmarshal(stage.events[BOND_CREATED_EVENT], &event)
+
+	if err != nil {
+		return b, errors.New("Error reading the created bond: " + err.Error())
+	}
+
+	return event.Payload, stage.commit()
+}
+
+//==============================================================================================================================
+//	 staged_stub - A stub holding back the state changes and events made through it until commit, reading its own
+//				   changes before those of the stub it wraps. Everything else, range scans included, goes to the
+//				   wrapped stub.
+//==============================================================================================================================
+type staged_stub struct {
+	shim.ChaincodeStubInterface
+	writes     map[string][]byte // nil for a deleted key
+	parameters map[string][]byte // key level endorsement policies
+	events     map[string][]byte
+}
+
+func new_staged_stub(stub shim.ChaincodeStubInterface) *staged_stub {
+	return &staged_stub{ChaincodeStubInterface: stub, writes: make(map[string][]byte), parameters: make(map[string][]byte), events: make(map[string][]byte)}
+}
+
+func (s *staged_stub) GetState(key string) ([]byte, error) {
+
+	if value, ok := s.writes[key]; ok {
+		return value, nil
+	}
+
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+func (s *staged_stub) PutState(key string, value []byte) error {
+
+	if key == "" || value == nil {
+		return s.ChaincodeStubInterface.PutState(key, value) // let the wrapped stub reject it
+	}
+
+	s.writes[key] = value
+
+	return nil
+}
+
+func (s *staged_stub) DelState(key string) error {
+
+	s.writes[key] = nil
+
+	return nil
+}
+
+func (s *staged_stub) SetStateValidationParameter(key string, ep []byte) error {
+
+	s.parameters[key] = ep
+
+	return nil
+}
+
+func (s *staged_stub) SetEvent(name string, payload []byte) error {
+
+	s.events[name] = payload
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 commit - Makes the staged state changes on the wrapped stub in key order, so every peer writes them in the same
+//			  order. Staged events are dropped, the caller emits its own.
+//==============================================================================================================================
+func (s *staged_stub) commit() error {
+
+	keys := make([]string, 0, len(s.writes))
+
+	for key := range s.writes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		var err error
+
+		if s.writes[key] == nil {
+			err = s.ChaincodeStubInterface.DelState(key)
+		} else {
+			err = s.ChaincodeStubInterface.PutState(key, s.writes[key])
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	keys = keys[:0]
+
+	for key := range s.parameters {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := s.ChaincodeStubInterface.SetStateValidationParameter(key, s.parameters[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
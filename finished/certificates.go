@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Ownership Certificates - A statement that an owner held a bond at a version of it, issued by
+//							  get_ownership_certificate for the owner to present to a bank or buyer. The certificate
+//							  is JSON and its signature an HMAC over those exact bytes with the verification key of
+//							  verification.go, so only the regulator's peers can issue or verify one. The certificate
+//							  stays genuine for good; verify_ownership_certificate says whether it still describes the
+//							  bond or has been superseded by a later change.
+//==============================================================================================================================
+
+// Domain of certificate signatures, so a certificate can never pass as a QR verification payload or the other way round
+const CERTIFICATE_SIGNATURE_DOMAIN = "ownership_certificate."
+
+//==============================================================================================================================
+//	 Ownership_Certificate - The certified facts of the bond.
+//==============================================================================================================================
+type Ownership_Certificate struct {
+	RealEstateID    string    `json:"real_estate_id"`
+	BondID          string    `json:"bond_id"`
+	OwnerNationalID string    `json:"owner_national_id"`
+	Status          string    `json:"status"`
+	Area            Land_Area `json:"area"`
+	BondVersion     int       `json:"bond_version"`
+	IssuedAt        string    `json:"issued_at"`
+	IssuedTx        string    `json:"issued_tx"`
+	KeyID           string    `json:"key_id"`
+}
+
+//==============================================================================================================================
+//	 Signed_Certificate - The response of get_ownership_certificate. Certificate is the JSON the signature is over and
+//						  must be presented unchanged.
+//==============================================================================================================================
+type Signed_Certificate struct {
+	Certificate string `json:"certificate"`
+	Signature   string `json:"signature"`
+}
+
+//==============================================================================================================================
+//	 Certificate_Verification - The response of verify_ownership_certificate, Result being one of the results of
+//								verification.go.
+//==============================================================================================================================
+type Certificate_Verification struct {
+	Result         string                 `json:"result"`
+	Reason         string                 `json:"reason,omitempty"`
+	Certificate    *Ownership_Certificate `json:"certificate,omitempty"`
+	CurrentVersion int                    `json:"current_version,omitempty"`
+	OwnerCurrent   bool                   `json:"owner_current"`
+}
+
+func certificate_signature(key []byte, certificate string) string {
+	return base64.RawURLEncoding.EncodeToString(payload_mac(key, CERTIFICATE_SIGNATURE_DOMAIN+certificate))
+}
+
+//=================================================================================================================================
+//	 get_ownership_certificate - Returns a signed certificate of the bond as it is now.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_ownership_certificate(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("GET_OWNERSHIP_CERTIFICATE", err)
+	}
+
+	id, key, err := t.verification_key(stub, "")
+
+	if err != nil {
+		return nil, prefix_error("GET_OWNERSHIP_CERTIFICATE", err)
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_OWNERSHIP_CERTIFICATE", err)
+	}
+
+	certificate, err := json.Marshal(Ownership_Certificate{
+		RealEstateID:    b.RealEstateID,
+		BondID:          b.ID,
+		OwnerNationalID: b.OwnerNationalID,
+		Status:          b.Status.String(),
+		Area:            b.Area,
+		BondVersion:     b.Version,
+		IssuedAt:        now.Format(TIME_LAYOUT),
+		IssuedTx:        stub.GetTxID(),
+		KeyID:           id,
+	})
+
+	if err != nil {
+		log_errorf(stub, "GET_OWNERSHIP_CERTIFICATE: Error converting certificate: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_OWNERSHIP_CERTIFICATE: Error converting certificate", err)
+	}
+
+	bytes, err := json.Marshal(Signed_Certificate{Certificate: string(certificate), Signature: certificate_signature(key, string(certificate))})
+
+	if err != nil {
+		log_errorf(stub, "GET_OWNERSHIP_CERTIFICATE: Error converting response: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_OWNERSHIP_CERTIFICATE: Error converting response", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 verify_ownership_certificate - Checks the certificate was issued by the registry and compares it with the bond as
+//									it is now. A certificate that does not verify is reported as invalid, not as an
+//									error.
+//=================================================================================================================================
+func (t *SimpleChaincode) verify_ownership_certificate(stub shim.ChaincodeStubInterface, certificate string, signature string) ([]byte, error) {
+
+	v, err := t.check_ownership_certificate(stub, certificate, signature)
+
+	if err != nil {
+		return nil, prefix_error("VERIFY_OWNERSHIP_CERTIFICATE", err)
+	}
+
+	bytes, err := json.Marshal(v)
+
+	if err != nil {
+		log_errorf(stub, "VERIFY_OWNERSHIP_CERTIFICATE: Error converting result: %s", err)
+		return nil, wrap_error(CODE_ERROR, "VERIFY_OWNERSHIP_CERTIFICATE: Error converting result", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 check_ownership_certificate - Returns the result of verifying the certificate. An error is only returned when the
+//								   peer cannot tell, such as a peer without the keys.
+//=================================================================================================================================
+func (t *SimpleChaincode) check_ownership_certificate(stub shim.ChaincodeStubInterface, certificate string, signature string) (Certificate_Verification, error) {
+
+	v := Certificate_Verification{Result: VERIFICATION_INVALID}
+
+	var c Ownership_Certificate
+
+	if json.Unmarshal([]byte(certificate), &c) != nil || c.RealEstateID == "" || c.KeyID == "" {
+		v.Reason = "malformed certificate"
+		return v, nil
+	}
+
+	_, key, err := t.verification_key(stub, c.KeyID)
+
+	if error_code(err) == CODE_INVALID_STATE {
+		if _, _, current := t.verification_key(stub, ""); current == nil {
+			v.Reason = "unknown signing key" // this peer holds the keys, none has the ID
+			return v, nil
+		}
+	}
+
+	if err != nil {
+		return v, err
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(certificate_signature(key, certificate))) {
+		v.Reason = "signature does not match"
+		return v, nil
+	}
+
+	v.Certificate = &c
+	v.Result = VERIFICATION_SUPERSEDED
+
+	b, found, err := t.get_stored_bond(stub, c.RealEstateID)
+
+	if err != nil {
+		return v, err
+	}
+
+	if !found {
+		v.Reason = "bond no longer exists"
+		return v, nil
+	}
+
+	v.CurrentVersion = b.Version
+	v.OwnerCurrent = b.OwnerNationalID == c.OwnerNationalID
+
+	switch {
+	case !v.OwnerCurrent:
+		v.Reason = "bond transferred since the certificate was issued"
+	case b.Version != c.BondVersion:
+		v.Reason = "bond changed since the certificate was issued"
+	default:
+		v.Result = VERIFICATION_VALID
+	}
+
+	return v, nil
+}
@@ -3,9 +3,10 @@ package main
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
 )
 
 var logger = shim.NewLogger("CLDChaincode")
@@ -46,298 +47,583 @@ type SimpleChaincode struct {
 //==============================================================================================================================
 
 type Bond struct {
-	ID              string `json:"id"`
-	RealEstateID    string `json:"real_estate_id"`    // blueprint_number.readestate_number ex: 1232.21
-	OwnerNationalID string `json:"owner_national_id"` // national_id
-	Status          string `json:"status"`            // flat, built
-	Area            string `json:"area"`              // example:
-	Coordinates     struct {
-		Long string `json:"long"`
-		Lat  string `json:"lat"`
-	} `json:"coordinates"`
-	Borders struct {
-		North string `json:"north"`
-		South string `json:"south"`
-		East  string `json:"east"`
-		West  string `json:"west"`
-	} `json:"borders"`
+	ID              string        `json:"id"`
+	RealEstateID    string        `json:"real_estate_id"`          // blueprint_number.readestate_number ex: 1232.21
+	OwnerNationalID string        `json:"owner_national_id"`       // national_id, or CR number of a company
+	OwnerIDType     Owner_ID_Type `json:"owner_id_type,omitempty"` // cr for a company, see owner_id.go
+	OwnerMSP        string        `json:"owner_msp,omitempty"`     // organisation endorsing for the owner, see set_bond_endorsement
+	Status          Bond_Status   `json:"status"`                  // flat, built, see status.go
+	Area            Land_Area     `json:"area"`                    // normalised to square metres
+	Coordinates     Coordinates   `json:"coordinates"`
+	Boundary        *Polygon      `json:"boundary,omitempty"`         // GeoJSON polygon of the parcel outline
+	Geohash         string        `json:"geohash"`                    // kept in step with coordinates, see update_geohash
+	CreatedAt       string        `json:"created_at,omitempty"`       // transaction time of create_bond, unknown for older bonds
+	UpdatedAt       string        `json:"updated_at"`                 // transaction time of the last change, see touch_bond
+	CreatedTx       string        `json:"created_tx,omitempty"`       // transaction of create_bond
+	LastModifiedTx  string        `json:"last_modified_tx,omitempty"` // transaction of the last change, see touch_bond
+	DistrictCode    string        `json:"district_code,omitempty"`    // registered district, see add_district
+	CityCode        string        `json:"city_code,omitempty"`        // city of the district
+	Street          string        `json:"street,omitempty"`
+	ExportedTo      string        `json:"exported_to,omitempty"` // channel the bond is locked for, see export_bond
+	TokenID         string        `json:"token_id,omitempty"`    // token the bond is locked for, see wrap_bond
+	Provenance      *Provenance   `json:"provenance,omitempty"`  // of a bond migrated from the legacy registry, see legacy.go
+	SchemaVersion   int           `json:"schema_version"`        // see upgrade_bond
+	Version         int           `json:"version"`               // number of times the bond has been saved, see check_version
+
+	upgraded bool // read in an older schema version and not saved since
 }
 
 //==============================================================================================================================
-//	V5C Holder - Defines the structure that holds all the v5cIDs for vehicles that have been created.
-//				Used as an index when querying all vehicles.
+//	Bond Existence - The response of bond_exists.
 //==============================================================================================================================
 
-type Bond_Holder struct {
-	BondIDs []string `json:"bond_ids"`
+type Bond_Existence struct {
+	RealEstateID string `json:"real_estate_id"`
+	Exists       bool   `json:"exists"`
 }
 
-//=============================================================================================================
-//	User_and_eCert - Struct for storing the JSON of a user and their ecert
+//==============================================================================================================================
+//	Bond Holder - Defines the structure of the bondIDs record, the list of realEstateIDs that bonds were once listed
+//				  from. Only read now, see get_legacy_bond_ids.
 //==============================================================================================================================
 
-type User_and_eCert struct {
-	Identity string `json:"identity"`
-	ECert    string `json:"ecert"`
+type Bond_Holder struct {
+	BondIDs []string `json:"bond_ids"`
 }
 
 //==============================================================================================================================
-//	Init Function - Called when the user deploys the chaincode
+//	Init Function - Called when the chaincode is instantiated and again on every upgrade. A JSON configuration passed
+//					is applied on top of the stored one, see Config.
 //==============================================================================================================================
-func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 
 	//Args
 	//				0
-	//			peer_address
+	//			configuration (optional)
 
-	var bondIDs Bond_Holder
+	_, args := stub.GetFunctionAndParameters()
 
-	bytes, err := json.Marshal(bondIDs)
+	if len(args) > 0 && args[0] != "" {
 
-	if err != nil {
-		return nil, errors.New("Error creating RealEstateBond_Holder record")
-	}
+		c, err := t.load_config(stub)
+
+		if err != nil {
+			return failure(CODE_ERROR, "INIT: "+err.Error())
+		}
 
-	err = stub.PutState("bondIDs", bytes)
-	// TODO: modify the cert for users.
-	/*for i := 0; i < len(args); i = i + 2 {
-		t.add_ecert(stub, args[i], args[i+1])
-	}*/
+		c, err = parse_config(c, args[0])
 
-	return nil, nil
+		if err != nil {
+			return failure(CODE_ERROR, "INIT: "+err.Error())
+		}
+
+		err = t.put_config(stub, c)
+
+		if err != nil {
+			return failure(CODE_ERROR, "INIT: "+err.Error())
+		}
+	}
+
+	return success(nil)
 }
 
 //==============================================================================================================================
 //	 General Functions
 //==============================================================================================================================
-//	 get_ecert - Takes the name passed and calls out to the REST API for HyperLedger to retrieve the ecert
-//				 for that user. Returns the ecert as retrived including html encoding.
+//	 check_affiliation - Returns the role of the caller, read from the role attribute of the caller's eCert.
 //==============================================================================================================================
-func (t *SimpleChaincode) get_ecert(stub shim.ChaincodeStubInterface, name string) ([]byte, error) {
+func (t *SimpleChaincode) check_affiliation(stub shim.ChaincodeStubInterface) (string, error) {
 
-	ecert, err := stub.GetState(name)
+	role, found, err := cid.GetAttributeValue(stub, "role")
 
 	if err != nil {
-		return nil, errors.New("Couldn't retrieve ecert for user " + name)
+		return "", coded_error(CODE_NOT_AUTHORIZED, "Couldn't get attribute 'role'. Error: "+err.Error())
 	}
 
-	return ecert, nil
+	if !found {
+		return "", coded_error(CODE_NOT_AUTHORIZED, "Couldn't get attribute 'role'. Error: the caller's certificate has no role attribute")
+	}
+
+	return role, nil
 }
 
 //==============================================================================================================================
-//	 add_ecert - Adds a new ecert and user pair to the table of ecerts
+//	 check_admin - Returns an error unless the caller is the regulator, the only role allowed to run admin functions.
 //==============================================================================================================================
+func (t *SimpleChaincode) check_admin(stub shim.ChaincodeStubInterface) error {
 
-func (t *SimpleChaincode) add_ecert(stub shim.ChaincodeStubInterface, name string, ecert string) ([]byte, error) {
+	role, err := t.check_affiliation(stub)
 
-	err := stub.PutState(name, []byte(ecert))
+	if err != nil {
+		log_errorf(stub, "CHECK_ADMIN: Error retrieving caller role: %s", err)
+		return coded_error(CODE_NOT_AUTHORIZED, "Error retrieving caller role")
+	}
 
-	if err == nil {
-		return nil, errors.New("Error storing eCert for user " + name + " identity: " + ecert)
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
 	}
 
-	return nil, nil
+	if role != c.AdminRole {
+		return coded_error(CODE_NOT_AUTHORIZED, "Permission denied, admin functions are restricted to "+c.AdminRole)
+	}
 
+	return nil
 }
 
-//==============================================================================================================================
-//	 check_affiliation - Takes an ecert as a string, decodes it to remove html encoding then parses it and checks the
-// 				  		certificates common name. The affiliation is stored as part of the common name.
-//==============================================================================================================================
-
 //==============================================================================================================================
 //	 get_caller_data - Calls the get_ecert and check_role functions and returns the ecert and role for the
 //					 name passed.
 //==============================================================================================================================
 //==============================================================================================================================
-//	 retrieve_v5c - Gets the state of the data at v5cID in the ledger then converts it from the stored
-//					JSON into the Vehicle struct for use in the contract. Returns the Vehcile struct.
-//					Returns empty v if it errors.
+//	 retrieve_bond - Gets the bond stored for the realEstateID and converts it into the Bond struct for use in the
+//					 contract. Returns an empty bond and a BOND_NOT_FOUND error if there is none.
 //==============================================================================================================================
 
 func (t *SimpleChaincode) retrieve_bond(stub shim.ChaincodeStubInterface, ReadEstateID string) (Bond, error) {
-	var b Bond
-	bytes, err := stub.GetState(ReadEstateID)
+
+	b, found, err := t.get_stored_bond(stub, ReadEstateID)
 
 	if err != nil {
-		fmt.Printf("RETRIEVE_V5C: Failed to invoke vehicle_code: %s", err)
-		return b, errors.New("RETRIEVE_V5C: Error retrieving bond with realEstateID = " + ReadEstateID)
+		log_errorf(stub, "RETRIEVE_BOND: %s", err)
+		return b, prefix_error("RETRIEVE_BOND", err)
 	}
-	err = json.Unmarshal(bytes, &b)
+
+	if !found {
+		return b, prefix_error("RETRIEVE_BOND", bond_not_found(ReadEstateID))
+	}
+
+	return upgrade_bond(b), nil
+}
+
+//==============================================================================================================================
+// save_changes - Writes to the ledger the Bond struct passed in a JSON format, stamping it with the time of the
+//				  transaction, counting up its version and recording the change in the audit log. Uses the shim
+//				  file's method 'PutState'. Returns the bond as written.
+//==============================================================================================================================
+func (t *SimpleChaincode) save_changes(stub shim.ChaincodeStubInterface, b Bond) (Bond, error) {
+
+	previous, found, err := t.get_stored_bond(stub, b.RealEstateID)
+
+	if err != nil {
+		return b, err
+	}
+
+	b, err = t.touch_bond(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "SAVE_CHANGES: Error updating modified index: %s", err)
+		return b, wrap_error(CODE_ERROR, "Error updating modified index", err)
+	}
+
+	b.Version = previous.Version + 1 // a bond saved twice in one transaction counts once
+
+	err = t.put_bond(stub, b)
+
+	if err != nil {
+		return b, err
+	}
+
+	err = t.record_audit(stub, previous, found, b)
 
 	if err != nil {
-		fmt.Printf("RETRIEVE_BOND: Corrupt vehicle record "+string(bytes)+": %s", err)
-		return b, errors.New("RETRIEVE_BOND: Corrupt bond record" + string(bytes))
+		return b, err
 	}
 
 	return b, nil
 }
 
 //==============================================================================================================================
-// save_changes - Writes to the ledger the Vehicle struct passed in a JSON format. Uses the shim file's
-//				  method 'PutState'.
+// put_bond - Writes the Bond struct to the ledger as it is, in the configured state encoding, without touching its
+//			  modification time. Bonds upgraded from an older schema version have their index entries written too,
+//			  and are moved off the bare realEstateID key they were stored under before version 3.
 //==============================================================================================================================
-func (t *SimpleChaincode) save_changes(stub shim.ChaincodeStubInterface, b Bond) (bool, error) {
+func (t *SimpleChaincode) put_bond(stub shim.ChaincodeStubInterface, b Bond) error {
 
-	bytes, err := json.Marshal(b)
+	if b.upgraded {
+
+		for _, key := range bond_index_keys(b) {
+
+			err := stub.PutState(key, INDEX_VALUE)
+
+			if err != nil {
+				log_errorf(stub, "PUT_BOND: Error storing index entry %s: %s", key, err)
+				return wrap_error(CODE_LEDGER_ERROR, "Error storing index entry "+key, err)
+			}
+		}
+
+		err := stub.DelState(b.RealEstateID)
+
+		if err != nil {
+			log_errorf(stub, "PUT_BOND: Error removing legacy bond record: %s", err)
+			return wrap_error(CODE_LEDGER_ERROR, "Error removing legacy bond record "+b.RealEstateID, err)
+		}
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	bytes, err := marshal_bond(b, c.StateEncoding)
 
 	if err != nil {
-		fmt.Printf("SAVE_CHANGES: Error converting bond record: %s", err)
-		return false, errors.New("Error converting bond record")
+		log_errorf(stub, "PUT_BOND: Error converting bond record: %s", err)
+		return wrap_error(CODE_ERROR, "Error converting bond record", err)
 	}
 
-	err = stub.PutState(b.RealEstateID, bytes)
+	err = stub.PutState(bond_key(b.RealEstateID), bytes)
 
 	if err != nil {
-		fmt.Printf("SAVE_CHANGES: Error storing bond record: %s", err)
-		return false, errors.New("Error storing bond record")
+		log_errorf(stub, "PUT_BOND: Error storing bond record: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing bond record "+bond_key(b.RealEstateID), err)
 	}
 
-	return true, nil
+	return nil
 }
 
 //==============================================================================================================================
 //	 Router Functions
 //==============================================================================================================================
-//	Invoke - Called on chaincode invoke. Takes a function name passed and calls that function. Converts some
-//		  initial arguments passed to other things for use in the called function e.g. name -> ecert
+//	Invoke - Called on every transaction, queries included. Reads the function name and its arguments from the
+//			 transaction and, unless the function is not in the registry, passes them through the middleware, see
+//			 middleware.go, to invoke by way of invoke_once, turning their result into the peer response.
+//			 Callers of an organisation registered to a tenant run on the tenant's keys, see tenants.go.
 //==============================================================================================================================
-func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 
-	if function == "create_bond" {
-		return t.create_bond(stub, args)
-	} else if function == "ping" {
-		return t.ping(stub)
-	} else if function == "tranfer_bond" { // If the function is not a create then there must be a car so we need to retrieve the car.
-		bond, err := t.retrieve_bond(stub, args[0])
-		if err != nil {
-			return nil, errors.New("cannot find bond by given realestateID")
-		}
-		b, err := t.transfer_ownership(stub, bond, args[1])
+	function, args := stub.GetFunctionAndParameters()
 
-		if err != nil {
-			fmt.Printf("INVOKE: Error retrieving v5c: %s", err)
-			return nil, errors.New("Error retrieving v5c")
-		}
-		return b, nil
+	stub, err := t.tenant_stub_for(stub)
 
-	} else if function == "change_realestate_status" {
-		bond, err := t.retrieve_bond(stub, args[0])
-		if err != nil {
-			return nil, errors.New("cannot find bond by given realestateID")
+	if err != nil {
+		log_warningf(stub, "INVOKE: Rejected with %s: %s", error_code(err), err)
+		return failure(error_code(err), err.Error())
+	}
+
+	entry, ok := FUNCTIONS[function]
+
+	if !ok {
+		err = unknown_function(function)
+		log_warningf(stub, "INVOKE: Rejected with %s: %s", error_code(err), err)
+		return failure(error_code(err), err.Error())
+	}
+
+	handler := pipeline(function, entry, func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+		return t.invoke_once(stub, function, args)
+	})
+
+	bytes, err := handler(t, stub, args)
+
+	if err != nil {
+
+		if error_code(err) == CODE_CORRUPT_RECORD {
+			log_errorf(stub, "INVOKE: %s hit a corrupt record: %s", function, err)
 		}
-		return t.change_bond_status(stub, bond, args[1])
 
+		return failure(error_code(err), err.Error())
+	}
+
+	return success(bytes)
+}
+
+//==============================================================================================================================
+//	invoke - Takes a function name passed and calls its handler in the registry, see registry.go. The handlers
+//			 convert the arguments passed for use in the called function e.g. realEstateID -> bond.
+//==============================================================================================================================
+func (t *SimpleChaincode) invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+
+	entry, ok := FUNCTIONS[function]
+
+	if !ok {
+		return nil, unknown_function(function)
 	}
 
-	return nil, errors.New("Received unknown function invocation " + function)
+	return entry.Handler(t, stub, args)
 }
 
 //=================================================================================================================================
-//	Query - Called on chaincode query. Takes a function name passed and calls that function. Passes the
-//  		initial arguments passed are passed on to the called function.
+//	 tranfer_bond - Transfers the bond to the recipient, passed as an argument or in the transient data along with the
+//					declared value of the sale.
 //=================================================================================================================================
-func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) tranfer_bond(stub shim.ChaincodeStubInterface, bond Bond, args []string) ([]byte, error) {
+
+	recipient, err := arg_or_transient(stub, args, 1, TRANSIENT_RECIPIENT)
+
+	if err != nil {
+		return nil, prefix_error("TRANFER_BOND", err)
+	}
+
+	value := optional_value(args, 2)
+
+	if value == "" {
+		value, _, err = transient_field(stub, TRANSIENT_DECLARED_VALUE)
 
-	if function == "get_bond_details" {
-		if len(args) != 1 {
-			fmt.Printf("Incorrect number of arguments passed")
-			return []byte("error"), errors.New("QUERY: Incorrect number of arguments passed")
-		}
-		b, err := t.retrieve_bond(stub, args[0])
 		if err != nil {
-			fmt.Printf("QUERY: Error retrieving v5c: %s", err)
-			return nil, errors.New("QUERY: Error retrieving v5c " + err.Error())
+			return nil, prefix_error("TRANFER_BOND", err)
 		}
-		return t.get_bond_details(stub, b)
-	} else if function == "check_unique_real_estate_id" {
-		return t.check_unique_read_estate_id(stub, args[0])
-	} else if function == "get_bonds" {
-		return t.get_bonds(stub)
-	} else if function == "get_ecert" {
-		return t.get_ecert(stub, args[0])
-	} else if function == "ping" {
-		return t.ping(stub)
 	}
 
-	return nil, errors.New("Received unknown function invocation " + function)
+	err = validate_transfer(recipient, optional_value(args, 4), value).to_error("TRANFER_BOND", "transfer")
 
-}
+	if err != nil {
+		return nil, err
+	}
 
-//=================================================================================================================================
-//	 Ping Function
-//=================================================================================================================================
-//	 Pings the peer to keep the connection alive
-//=================================================================================================================================
-func (t *SimpleChaincode) ping(stub shim.ChaincodeStubInterface) ([]byte, error) {
-	return []byte("Hello, world!"), nil
+	declared_value := 0.0
+
+	if value != "" {
+		values, err := parse_floats([]string{value}, "declared value", 1)
+
+		if err != nil || values[0] < 0 {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, "Invalid declared value "+value)
+		}
+
+		declared_value = values[0]
+	}
+
+	err = t.use_identity_check(stub, bond.RealEstateID, recipient)
+
+	if err != nil {
+		return nil, prefix_error("TRANFER_BOND", err)
+	}
+
+	kind, _ := parse_owner_id_type(optional_value(args, 4))
+
+	bond.OwnerIDType = kind.stored() // saved with the new owner by transfer_ownership
+
+	b, err := t.transfer_ownership(stub, bond, recipient, optional_value(args, 3), declared_value)
+
+	if err != nil {
+		log_errorf(stub, "INVOKE: Error transferring bond: %s", err)
+		return nil, err
+	}
+
+	return b, nil
 }
 
 //=================================================================================================================================
 //	 Create Function
 //=================================================================================================================================
 //	 Create Vehicle - Creates the initial JSON for the vehcile and then saves it to the ledger.
+//					  Returns the bond ID, generated when the caller leaves it empty. A single argument is the bond
+//					  as a JSON object, see bond_record.go.
 //=================================================================================================================================
 func (t *SimpleChaincode) create_bond(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 
-	fmt.Println("inside create_bond", args)
+	if len(args) == 1 {
+		return t.create_bond_record(stub, args[0])
+	}
+
+	if len(args) < 7 {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "CREATE_BOND: Incorrect number of arguments. Expecting a JSON object or id, realEstateID, nationalID, status, area, long, lat and optionally boundary, district, force, street, owner ID type")
+	}
+
+	return t.new_bond(stub, args, nil)
+}
+
+//=================================================================================================================================
+//	 new_bond - Creates the bond of the create_bond arguments. A bond migrated from the legacy registry carries its
+//				provenance and is not checked against its neighbours or its declared area, see legacy.go.
+//=================================================================================================================================
+func (t *SimpleChaincode) new_bond(stub shim.ChaincodeStubInterface, args []string, provenance *Provenance) ([]byte, error) {
+
+	log_debugf(stub, "CREATE_BOND: %s", args[1]) // the real estate ID only, the owner may be a positional argument
+
+	err := t.validate_bond(stub, args).to_error("CREATE_BOND", "bond")
+
+	if err != nil {
+		return nil, err
+	}
 
 	var b Bond
 
+	owner, err := arg_or_transient(stub, args, 2, TRANSIENT_OWNER)
+
+	if err != nil {
+		return nil, prefix_error("CREATE_BOND", err)
+	}
+
 	b.ID = args[0]
 	b.RealEstateID = args[1]
-	b.OwnerNationalID = args[2]
-	b.Status = args[3]
-	b.Area = args[4]
-	b.Coordinates.Long = args[5]
-	b.Coordinates.Lat = args[6]
-	b.Borders.North = args[7]
-	b.Borders.South = args[8]
-	b.Borders.East = args[9]
-	b.Borders.West = args[10]
+	b.OwnerNationalID = owner
+	b.Status = Bond_Status(args[3])
+
+	if len(args) > 11 {
+		kind, _ := parse_owner_id_type(args[11]) // checked by validate_bond
+		b.OwnerIDType = kind.stored()
+	}
+
+	if b.ID == "" {
+		b.ID = generate_bond_id(stub.GetTxID(), b.RealEstateID)
+	}
+
+	b.OwnerMSP, err = caller_msp(stub)
+
+	if err != nil {
+		return nil, prefix_error("CREATE_BOND", err)
+	}
+
+	if args[4] != "" {
+		area, err := parse_area(args[4])
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+		b.Area = area
+	}
+
+	coordinates, err := parse_coordinates(args[5], args[6])
+
+	if err != nil {
+		return nil, prefix_error("CREATE_BOND", err)
+	}
 
-	record, err := stub.GetState(b.RealEstateID) // If not an error then a record exists so cant create a new car with this V5cID as it must be unique
+	b.Coordinates = coordinates
 
-	if record != nil {
-		return nil, errors.New("Bond already exists")
+	if len(args) > 7 && args[7] != "" {
+		polygon, err := parse_boundary(args[7])
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+		b.Boundary = polygon
 	}
 
-	_, err = t.save_changes(stub, b)
+	if len(args) > 8 && args[8] != "" {
+		d, err := t.lookup_district(stub, args[8])
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+		b.DistrictCode = d.Code
+		b.CityCode = d.CityCode
+	}
+
+	if len(args) > 10 {
+		street, err := parse_street(args[10])
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+		b.Street = street
+	}
+
+	if provenance == nil {
+		b, err = check_area(b)
+	} else {
+		b.Provenance = provenance
+	}
 
 	if err != nil {
-		fmt.Printf("CREATE_BOND: Error saving changes: %s", err)
-		return nil, errors.New("Error saving changes")
+		return nil, prefix_error("CREATE_BOND", err)
 	}
 
-	bytes, err := stub.GetState("bondIDs")
+	_, exists, err := t.get_stored_bond(stub, b.RealEstateID)
 
 	if err != nil {
-		return nil, errors.New("Unable to get bondIDs")
+		return nil, prefix_error("CREATE_BOND", err)
 	}
 
-	var bondIDs Bond_Holder
+	if exists {
+		return nil, coded_error(CODE_BOND_EXISTS, "Bond already exists")
+	}
+
+	if provenance == nil {
+
+		err = t.check_duplicates(stub, b, len(args) > 9 && args[9] == "force")
+
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+
+		err = t.check_overlaps(stub, b)
+
+		if err != nil {
+			return nil, prefix_error("CREATE_BOND", err)
+		}
+	}
 
-	err = json.Unmarshal(bytes, &bondIDs)
+	b, err = t.update_geohash(stub, b)
 
 	if err != nil {
-		return nil, errors.New("Corrupt Bond_Holder record")
+		log_errorf(stub, "CREATE_BOND: Error updating geohash index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating geohash index", err)
 	}
 
-	bondIDs.BondIDs = append(bondIDs.BondIDs, b.RealEstateID)
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("CREATE_BOND", err)
+	}
 
-	bytes, err = json.Marshal(bondIDs)
+	b.CreatedAt = now.Format(TIME_LAYOUT)
+	b.CreatedTx = stub.GetTxID()
+	b.SchemaVersion = SCHEMA_VERSION
+
+	b, err = t.save_changes(stub, b)
 
 	if err != nil {
-		fmt.Print("Error creating V5C_Holder record")
+		log_errorf(stub, "CREATE_BOND: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
 	}
 
-	err = stub.PutState("bondIDs", bytes)
+	err = t.set_bond_endorsement(stub, b)
 
 	if err != nil {
-		return nil, errors.New("Unable to put the state")
+		return nil, prefix_error("CREATE_BOND", err)
 	}
 
-	return nil, nil
+	err = t.move_index(stub, OWNER_INDEX, b.RealEstateID, "", b.OwnerNationalID)
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BOND: Error updating owner index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating owner index", err)
+	}
+
+	err = t.move_index(stub, STATUS_INDEX, b.RealEstateID, "", b.Status.String())
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BOND: Error updating status index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating status index", err)
+	}
+
+	err = t.put_index(stub, PARCEL_INDEX, normalize_parcel(b.RealEstateID), b.RealEstateID)
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BOND: Error updating parcel index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating parcel index", err)
+	}
+
+	if attributes, ok := blueprint_attributes(b.RealEstateID); ok {
+
+		err = t.put_index(stub, BLUEPRINT_INDEX, attributes...)
+
+		if err != nil {
+			log_errorf(stub, "CREATE_BOND: Error updating blueprint index: %s", err)
+			return nil, wrap_error(CODE_ERROR, "Error updating blueprint index", err)
+		}
+	}
+
+	err = t.move_address_index(stub, Bond{}, b)
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BOND: Error updating address index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating address index", err)
+	}
+
+	err = t.move_boundary_index(stub, Bond{}, b)
+
+	if err != nil {
+		log_errorf(stub, "CREATE_BOND: Error updating boundary index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating boundary index", err)
+	}
+
+	err = t.emit_event(stub, BOND_CREATED_EVENT, b.RealEstateID, b, event_routing([]string{b.OwnerNationalID}, []string{b.DistrictCode}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(b.ID), nil
 
 }
 
@@ -346,15 +632,52 @@ func (t *SimpleChaincode) create_bond(stub shim.ChaincodeStubInterface, args []s
 //=================================================================================================================================
 //	 authority_to_manufacturer
 //=================================================================================================================================
-func (t *SimpleChaincode) transfer_ownership(stub shim.ChaincodeStubInterface, b Bond, recipient_national_id string) ([]byte, error) {
+func (t *SimpleChaincode) transfer_ownership(stub shim.ChaincodeStubInterface, b Bond, recipient_national_id string, recipient_msp string, declared_value float64) ([]byte, error) {
+
+	tr := Transfer_Record{RealEstateID: b.RealEstateID, DistrictCode: b.DistrictCode, From: b.OwnerNationalID, To: recipient_national_id, DeclaredValue: declared_value}
 
 	b.OwnerNationalID = recipient_national_id // then make the owner the new owner
 
+	if recipient_msp != "" {
+		b.OwnerMSP = recipient_msp // otherwise the recipient is with the same organisation
+	}
+
 	_, err := t.save_changes(stub, b) // Write new state
 
 	if err != nil {
-		fmt.Printf("AUTHORITY_TO_MANUFACTURER: Error saving changes: %s", err)
-		return nil, errors.New("Error saving changes")
+		log_errorf(stub, "CHANGE_BOND_STATUS: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	err = t.set_bond_endorsement(stub, b)
+
+	if err != nil {
+		return nil, prefix_error("TRANSFER_OWNERSHIP", err)
+	}
+
+	err = t.move_index(stub, OWNER_INDEX, b.RealEstateID, tr.From, tr.To)
+
+	if err != nil {
+		log_errorf(stub, "TRANSFER_OWNERSHIP: Error updating owner index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating owner index", err)
+	}
+
+	tr, err = t.record_transfer(stub, tr)
+
+	if err != nil {
+		return nil, prefix_error("TRANSFER_OWNERSHIP", err)
+	}
+
+	err = t.flag_sale_price(stub, b, tr, declared_value)
+
+	if err != nil {
+		return nil, prefix_error("TRANSFER_OWNERSHIP", err)
+	}
+
+	err = t.emit_event(stub, BOND_TRANSFERRED_EVENT, b.RealEstateID, tr, event_routing([]string{tr.From, tr.To}, []string{tr.DistrictCode}))
+
+	if err != nil {
+		return nil, err
 	}
 
 	return nil, nil // We are Done
@@ -362,13 +685,30 @@ func (t *SimpleChaincode) transfer_ownership(stub shim.ChaincodeStubInterface, b
 }
 func (t *SimpleChaincode) change_bond_status(stub shim.ChaincodeStubInterface, b Bond, newStatus string) ([]byte, error) {
 
-	b.Status = newStatus // then make the owner the new owner
+	var problems Field_Errors
 
-	_, err := t.save_changes(stub, b) // Write new state
+	problems.check_status("status", newStatus)
+
+	err := problems.to_error("CHANGE_BOND_STATUS", "status")
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.move_index(stub, STATUS_INDEX, b.RealEstateID, b.Status.String(), newStatus)
 
 	if err != nil {
-		fmt.Printf("AUTHORITY_TO_MANUFACTURER: Error saving changes: %s", err)
-		return nil, errors.New("Error saving changes")
+		log_errorf(stub, "CHANGE_BOND_STATUS: Error updating status index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating status index", err)
+	}
+
+	b.Status = Bond_Status(newStatus) // then make the owner the new owner
+
+	_, err = t.save_changes(stub, b) // Write new state
+
+	if err != nil {
+		log_errorf(stub, "AUTHORITY_TO_MANUFACTURER: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
 	}
 
 	return nil, nil // We are Done
@@ -381,69 +721,120 @@ func (t *SimpleChaincode) get_bond_details(stub shim.ChaincodeStubInterface, b B
 	bytes, err := json.Marshal(b)
 
 	if err != nil {
-		return nil, errors.New("GET_VEHICLE_DETAILS: Invalid vehicle object")
+		return nil, errors.New("GET_BOND_DETAILS: Invalid bond object")
 	}
 	return bytes, nil
 }
 
 //=================================================================================================================================
-//	 get_vehicles
+//	 get_expanded_bond_details - Returns the bond along with the names of its district and city.
 //=================================================================================================================================
+func (t *SimpleChaincode) get_expanded_bond_details(stub shim.ChaincodeStubInterface, b Bond) ([]byte, error) {
 
-func (t *SimpleChaincode) get_bonds(stub shim.ChaincodeStubInterface) ([]byte, error) {
-	bytes, err := stub.GetState("bondIDs")
+	details := Bond_Details{Bond: b}
 
-	if err != nil {
-		return nil, errors.New("Unable to get bondIDs")
-	}
+	if b.DistrictCode != "" {
 
-	var bondIDs Bond_Holder
+		d, err := t.lookup_district(stub, b.DistrictCode)
 
-	err = json.Unmarshal(bytes, &bondIDs)
+		if err != nil {
+			return nil, prefix_error("GET_BOND_DETAILS", err)
+		}
+
+		details.District = &d
+	}
+
+	bytes, err := json.Marshal(details)
 
 	if err != nil {
-		return nil, errors.New("Corrupt Bond_Holder")
+		return nil, errors.New("GET_BOND_DETAILS: Invalid bond object")
 	}
 
-	result := "["
+	return bytes, nil
+}
 
-	var temp []byte
-	var b Bond
+//=================================================================================================================================
+//	 get_bonds - Returns every bond as a JSON array, marshalled in one go so the output is always well formed. Bonds
+//				 that cannot be read are logged and left out rather than failing the whole listing, see
+//				 get_skipped_bonds.
+//=================================================================================================================================
 
-	for _, v5c := range bondIDs.BondIDs {
+func (t *SimpleChaincode) get_bonds(stub shim.ChaincodeStubInterface) ([]byte, error) {
 
-		b, err = t.retrieve_bond(stub, v5c)
+	bonds := []Bond{}
 
-		if err != nil {
-			return nil, errors.New("Failed to retrieve bondIDs")
-		}
+	err := t.scan_bonds(stub, func(b Bond) error {
+		bonds = append(bonds, b)
+		return nil
+	}, func(s Skipped_Bond) error {
+		log_warningf(stub, "GET_BONDS: Skipping %s: %s", s.RealEstateID, s.Message)
+		return nil
+	})
 
-		temp, err = t.get_bond_details(stub, b)
+	if err != nil {
+		return nil, prefix_error("GET_BONDS", err)
+	}
 
-		if err == nil {
-			result += string(temp) + ","
-		}
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_BONDS: Error converting bond records", err)
 	}
 
-	if len(result) == 1 {
-		result = "[]"
-	} else {
-		result = result[:len(result)-1] + "]"
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 bond_exists - Returns whether a bond is stored for the realEstateID, as {"real_estate_id":"1232.21","exists":true}.
+//				   A missing bond is an answer rather than an error, a record that cannot be read is an error.
+//=================================================================================================================================
+func (t *SimpleChaincode) bond_exists(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	_, found, err := t.get_stored_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("BOND_EXISTS", err)
 	}
 
-	return []byte(result), nil
+	return json.Marshal(Bond_Existence{RealEstateID: realEstateID, Exists: found})
 }
 
 //=================================================================================================================================
-//	 check_unique_v5c
+//	 Uniqueness_Check - The response of check_unique_real_estate_id. ExistingOwner is the owner_routing_hash of the
+//						owner of the bond holding the realEstateID, the same hash events are routed by, so a client can
+//						tell whether the number is taken by its own party without the national ID being disclosed.
 //=================================================================================================================================
-func (t *SimpleChaincode) check_unique_read_estate_id(stub shim.ChaincodeStubInterface, readEstateID string) ([]byte, error) {
-	_, err := t.retrieve_bond(stub, readEstateID)
-	if err == nil {
-		return []byte("false"), errors.New("ReadEstateID is not unique")
-	} else {
-		return []byte("true"), nil
+type Uniqueness_Check struct {
+	RealEstateID  string `json:"real_estate_id"`
+	Unique        bool   `json:"unique"`
+	ExistingOwner string `json:"existing_owner,omitempty"`
+}
+
+//=================================================================================================================================
+//	 check_unique_real_estate_id - Returns whether the realEstateID is free to register. A taken realEstateID is an
+//								   answer rather than an error, a record that cannot be read is an error.
+//=================================================================================================================================
+func (t *SimpleChaincode) check_unique_real_estate_id(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	b, found, err := t.get_stored_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("CHECK_UNIQUE", err)
 	}
+
+	r := Uniqueness_Check{RealEstateID: realEstateID, Unique: !found}
+
+	if found {
+		r.ExistingOwner = owner_routing_hash(b.OwnerNationalID)
+	}
+
+	bytes, err := json.Marshal(r)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "CHECK_UNIQUE: Error converting response", err)
+	}
+
+	return bytes, nil
 }
 
 //=================================================================================================================================
@@ -454,6 +845,6 @@ func main() {
 	err := shim.Start(new(SimpleChaincode))
 
 	if err != nil {
-		fmt.Printf("Error starting Chaincode: %s", err)
+		logger.Errorf("Error starting Chaincode: %s", err)
 	}
 }
@@ -0,0 +1,2274 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+)
+
+//==============================================================================================================================
+//	 Route Tests - One case per outcome of every function the Invoke and query routers accept. Each case runs on a
+//				   fresh registry holding TEST_CITY, TEST_DISTRICT and two bonds:
+//
+//		1232.1 - held by owner 1, registered at King Fahd Road in TEST_DISTRICT.
+//		1232.2 - held by owner 2, with a boundary and no district.
+//==============================================================================================================================
+
+type route_test struct {
+	name     string
+	setup    func(h *harness)
+	function string
+	args     []string
+	err      string // part of the expected error, empty when the call should succeed
+	check    func(t *testing.T, h *harness, payload []byte)
+}
+
+//==============================================================================================================================
+//	 seeded_harness - Returns a harness holding the reference data and bonds the route tests start from.
+//==============================================================================================================================
+func seeded_harness(t testing.TB) *harness {
+
+	h := new_harness(t)
+
+	h.seed_reference_data()
+	h.create(bond_fixture(1).in(TEST_DISTRICT, "King Fahd Road"), bond_fixture(2).owned_by(owner_fixture(2)).bounded(0.001))
+
+	return h
+}
+
+//==============================================================================================================================
+//	 enable - Turns a feature flag on.
+//==============================================================================================================================
+func enable(feature string) func(h *harness) {
+	return func(h *harness) {
+		h.must("set_config", `{"features":{"`+feature+`":true}}`)
+	}
+}
+
+//==============================================================================================================================
+//	 with_geofence - Limits bonds to a box around the test bonds in Riyadh.
+//==============================================================================================================================
+func with_geofence(h *harness) {
+	h.must("set_config", `{"geofence":[{"name":"Riyadh","boundary":{"type":"Polygon","coordinates":[[[46,24],[47.5,24],[47.5,25.5],[46,25.5],[46,24]]]}}]}`)
+}
+
+//==============================================================================================================================
+//	 with_tokens - Enables token_wrapping with a fake token chaincode on the channel, returned to inspect its tokens.
+//==============================================================================================================================
+func with_tokens(h *harness) *shim.MockStub {
+
+	tokens := shim.NewMockStub("tokens", new(fake_token))
+
+	h.stub.MockPeerChaincode("tokens", tokens)
+
+	h.must("set_config", `{"token_chaincode":"tokens","features":{"`+FEATURE_TOKEN_WRAPPING+`":true}}`)
+
+	return tokens
+}
+
+//==============================================================================================================================
+//	 run_route_tests - Runs each case on its own seeded harness.
+//==============================================================================================================================
+func run_route_tests(t *testing.T, tests []route_test) {
+
+	for _, tc := range tests {
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			h := seeded_harness(t)
+
+			if tc.setup != nil {
+				tc.setup(h)
+			}
+
+			if tc.err != "" {
+				h.fails(tc.err, tc.function, tc.args...)
+				return
+			}
+
+			payload := h.must(tc.function, tc.args...)
+
+			if tc.check != nil {
+				tc.check(t, h, payload)
+			}
+		})
+	}
+}
+
+//==============================================================================================================================
+//	 expect_event - Checks the last transaction emitted the event and returns its envelope.
+//==============================================================================================================================
+func expect_event(t testing.TB, h *harness, name string) Event_Envelope {
+
+	t.Helper()
+
+	var envelope Event_Envelope
+
+	for _, e := range h.events {
+		if e.EventName == name {
+			decode(t, e.Payload, &envelope)
+			return envelope
+		}
+	}
+
+	t.Fatalf("no %s event in %v", name, h.events)
+
+	return envelope
+}
+
+//==============================================================================================================================
+//	 link - Lets the harness call the chaincode of the other harness as if it was deployed on the channel given.
+//==============================================================================================================================
+func (h *harness) link(other *harness, channel string) {
+
+	other.stub.ChannelID = channel
+
+	h.stub.MockPeerChaincode("registry/"+channel, other.stub)
+}
+
+func TestInvokeRoutes(t *testing.T) {
+
+	duplicate := bond_fixture(1) // a second registration of the same parcel under another number
+	duplicate.RealEstateID = "1232.99"
+
+	unnumbered := bond_fixture(3) // left to create_bond to number
+	unnumbered.ID = ""
+
+	run_route_tests(t, []route_test{
+		{
+			name:     "create_bond",
+			function: "create_bond",
+			args:     bond_fixture(3).owned_by(owner_fixture(3)).args(),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				expect_event(t, h, BOND_CREATED_EVENT)
+				b := h.bond("1232.3")
+				if b.OwnerNationalID != owner_fixture(3).NationalID || b.OwnerMSP != REGULATOR_MSP || b.SchemaVersion != SCHEMA_VERSION {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+				if _, shared := h.stub.State[BOND_LIST_KEY]; shared {
+					t.Fatalf("create_bond wrote the shared bondIDs key")
+				}
+			},
+		},
+		{
+			name: "create_bond retried with idempotency key",
+			setup: func(h *harness) {
+				h.with_transient(map[string]string{TRANSIENT_IDEMPOTENCY_KEY: "create-3"})
+				h.must("create_bond", unnumbered.args()...)
+			},
+			function: "create_bond",
+			args:     unnumbered.args(),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if id := h.bond("1232.3").ID; id != string(payload) {
+					t.Fatalf("retry returned %s for bond %s", payload, id)
+				}
+			},
+		},
+		{
+			name:     "create_bond generated ID",
+			function: "create_bond",
+			args:     unnumbered.args(),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				want := generate_bond_id("tx"+strconv.Itoa(h.tx), "1232.3")
+				if id := h.bond("1232.3").ID; id != string(payload) || id != want {
+					t.Fatalf("unexpected ID %s, returned %s", id, payload)
+				}
+			},
+		},
+		{
+			name:     "create_bond JSON object",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner_national_id":"` + owner_fixture(9).NationalID + `","status":"built","area":"0.05 ha","longitude":46.69,"latitude":24.70,"district_code":"` + TEST_DISTRICT + `"}`},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.9"); b.ID != string(payload) || b.Area.Value != 500 || b.DistrictCode != TEST_DISTRICT {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+			},
+		},
+		{
+			name:     "create_bond JSON object unknown field",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner":"x"}`},
+			err:      `[{"field":"owner","code":"UNKNOWN_FIELD","message":"unknown field"}]`,
+		},
+		{
+			name:     "create_bond missing arguments",
+			function: "create_bond",
+			args:     []string{"bond9", "1232.9"},
+			err:      "Incorrect number of arguments",
+		},
+		{
+			name:     "create_bond existing",
+			function: "create_bond",
+			args:     bond_fixture(1).args(),
+			err:      "Bond already exists",
+		},
+		{
+			name:     "create_bond duplicate location",
+			function: "create_bond",
+			args:     duplicate.args(),
+			err:      "1232.1",
+		},
+		{
+			name:     "create_bond invalid national ID",
+			function: "create_bond",
+			args:     bond_fixture(3).owned_by(Owner_Fixture{NationalID: "A12"}).args(),
+			err:      `{"field":"owner_national_id","code":"INVALID_FORMAT"`,
+		},
+		{
+			name:     "create_bond unknown status",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner_national_id":"` + owner_fixture(9).NationalID + `","status":"Built","longitude":46.69,"latitude":24.70}`},
+			err:      `{"field":"status","code":"INVALID_FORMAT","message":"Unknown status Built, expecting one of flat, built"}`,
+		},
+		{
+			name:     "create_bond invalid realEstateID",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232-9","owner_national_id":"` + owner_fixture(9).NationalID + `","status":"flat","longitude":46.69,"latitude":24.70}`},
+			err:      `{"field":"real_estate_id","code":"INVALID_FORMAT","message":"Invalid realEstateID 1232-9, expecting blueprint.parcel numbers e.g. 1232.21"}`,
+		},
+		{
+			name:     "create_bond parcel number out of range",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.0","owner_national_id":"` + owner_fixture(9).NationalID + `","status":"flat","longitude":46.69,"latitude":24.70}`},
+			err:      `{"field":"real_estate_id","code":"OUT_OF_RANGE","message":"Parcel number out of range 1232.0, expecting 1 to 99999"}`,
+		},
+		{
+			name:     "create_bond company",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner_national_id":"` + COMPANY_CR + `","owner_id_type":"cr","status":"built","longitude":46.69,"latitude":24.70}`},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.9"); b.OwnerNationalID != COMPANY_CR || b.OwnerIDType != OWNER_CR {
+					t.Fatalf("unexpected owner %+v", b)
+				}
+				if b := h.bond("1232.1"); b.OwnerIDType != "" || b.owner_id_type() != OWNER_NATIONAL_ID {
+					t.Fatalf("unexpected owner ID type of a person %+v", b)
+				}
+			},
+		},
+		{
+			name:     "create_bond invalid CR number",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner_national_id":"9010123456","owner_id_type":"cr","status":"built","longitude":46.69,"latitude":24.70}`},
+			err:      `{"field":"owner_national_id","code":"INVALID_FORMAT","message":"Invalid commercial registration number 9010123456, expecting 10 digits starting with 1 to 7"}`,
+		},
+		{
+			name:     "create_bond unknown owner ID type",
+			function: "create_bond",
+			args:     []string{`{"real_estate_id":"1232.9","owner_national_id":"` + owner_fixture(9).NationalID + `","owner_id_type":"passport","status":"built","longitude":46.69,"latitude":24.70}`},
+			err:      `{"field":"owner_id_type","code":"INVALID_FORMAT","message":"Unknown owner ID type passport, expecting national_id or cr"}`,
+		},
+		{
+			name:     "create_bond unknown district",
+			function: "create_bond",
+			args:     bond_fixture(3).in("NOWHERE", "").args(),
+			err:      "NOWHERE",
+		},
+		{
+			name:     "migrate_legacy_bond duplicate location",
+			function: "migrate_legacy_bond",
+			args:     append([]string{"B-2024-01", "LD-77"}, duplicate.args()...),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var b Bond
+				decode(t, h.must("get_bond_by_legacy_deed", "LD-77"), &b)
+				if b.RealEstateID != "1232.99" || b.Provenance == nil || b.Provenance.Origin != PROVENANCE_MIGRATED || b.Provenance.MigrationBatch != "B-2024-01" {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+				var batch []Bond
+				decode(t, h.must("get_migration_batch", "B-2024-01"), &batch)
+				if len(batch) != 1 || batch[0].ID != b.ID {
+					t.Fatalf("unexpected batch %+v", batch)
+				}
+				h.fails("was migrated as 1232.99", "migrate_legacy_bond", append([]string{"B-2024-02", "LD-77"}, bond_fixture(4).args()...)...)
+				if h.bond("1232.1").Provenance != nil {
+					t.Fatalf("bond 1232.1 was not migrated")
+				}
+			},
+		},
+		{
+			name:     "health",
+			function: "health",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var health Health
+				decode(t, payload, &health)
+				if health.Status != "ok" || health.ChaincodeVersion != CHAINCODE_VERSION || health.SchemaVersion != SCHEMA_VERSION || health.Bonds != 2 || health.Channel != TEST_CHANNEL || len(health.ConfigHash) != 64 {
+					t.Fatalf("unexpected health %+v", health)
+				}
+				h.must("set_config", `{"max_page_size":10}`)
+				var changed Health
+				decode(t, h.must("health"), &changed)
+				if changed.ConfigHash == health.ConfigHash {
+					t.Fatal("config hash did not change with the configuration")
+				}
+			},
+		},
+		{
+			name:     "tranfer_bond",
+			setup:    func(h *harness) { h.with_transient(map[string]string{SALE_PRICE_SALT: "pepper"}) },
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID, "750000", owner_fixture(2).MSP},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				r := expect_event(t, h, BOND_TRANSFERRED_EVENT).Routing
+				owners := []string{owner_routing_hash(owner_fixture(1).NationalID), owner_routing_hash(owner_fixture(2).NationalID)}
+				sort.Strings(owners)
+				if r.Severity != SEVERITY_ALERT || !reflect.DeepEqual(r.OwnerHashes, owners) || !reflect.DeepEqual(r.Districts, []string{TEST_DISTRICT}) {
+					t.Fatalf("unexpected routing %+v", r)
+				}
+				b := h.bond("1232.1")
+				if b.OwnerNationalID != owner_fixture(2).NationalID || b.OwnerMSP != owner_fixture(2).MSP {
+					t.Fatalf("bond not transferred %+v", b)
+				}
+			},
+		},
+		{
+			name: "tranfer_bond retried with idempotency key",
+			setup: func(h *harness) {
+				h.with_transient(map[string]string{TRANSIENT_IDEMPOTENCY_KEY: "transfer-1"})
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+			},
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if len(h.events) != 0 {
+					t.Fatalf("retry emitted %d events", len(h.events))
+				}
+				transfers, _ := new(SimpleChaincode).get_transfers(h.stub, "2000-01-01", "2100-01-01")
+				if len(transfers) != 1 {
+					t.Fatalf("retry recorded %d transfers", len(transfers))
+				}
+			},
+		},
+		{
+			name: "create_bond reusing idempotency key",
+			setup: func(h *harness) {
+				h.with_transient(map[string]string{TRANSIENT_IDEMPOTENCY_KEY: "transfer-1"}).must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+			},
+			function: "create_bond",
+			args:     bond_fixture(3).args(),
+			err:      "was used for tranfer_bond",
+		},
+		{
+			name:     "tranfer_bond price without salt",
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID, "750000"},
+			err:      SALE_PRICE_SALT,
+		},
+		{
+			name:     "tranfer_bond field errors",
+			function: "tranfer_bond",
+			args:     []string{"1232.1", "12345", "-5"},
+			err:      `[{"field":"recipient_national_id","code":"INVALID_FORMAT","message":"Invalid national ID 12345, expecting 10 digits starting with 1 or 2"},{"field":"declared_value","code":"OUT_OF_RANGE"`,
+		},
+		{
+			name:     "tranfer_bond to a company",
+			function: "tranfer_bond",
+			args:     []string{"1232.1", COMPANY_CR, "", "", string(OWNER_CR)},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.1"); b.OwnerNationalID != COMPANY_CR || b.OwnerIDType != OWNER_CR {
+					t.Fatalf("bond not transferred to the company %+v", b)
+				}
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+				if b := h.bond("1232.1"); b.OwnerIDType != "" {
+					t.Fatalf("owner ID type kept after a transfer to a person %+v", b)
+				}
+			},
+		},
+		{
+			name:     "request_identity_check resident",
+			function: "request_identity_check",
+			args:     []string{"1232.1", "2000000001"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c Identity_Check
+				decode(t, payload, &c)
+				if c.IDType != OWNER_NATIONAL_ID || !c.Foreign {
+					t.Fatalf("unexpected check %+v", c)
+				}
+			},
+		},
+		{
+			name:     "request_identity_check company",
+			function: "request_identity_check",
+			args:     []string{"1232.1", COMPANY_CR, string(OWNER_CR)},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c Identity_Check
+				decode(t, payload, &c)
+				if c.IDType != OWNER_CR || c.Foreign {
+					t.Fatalf("unexpected check %+v", c)
+				}
+			},
+		},
+		{
+			name:     "tranfer_bond unknown bond",
+			function: "tranfer_bond",
+			args:     []string{"1232.9", owner_fixture(2).NationalID},
+			err:      "No bond with realEstateID",
+		},
+		{
+			name:     "change_realestate_status expected version",
+			setup:    func(h *harness) { h.with_transient(map[string]string{TRANSIENT_EXPECTED_VERSION: "1"}) },
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "built"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.1"); b.Version != 2 {
+					t.Fatalf("unexpected version %d", b.Version)
+				}
+			},
+		},
+		{
+			name:     "change_realestate_status stale version",
+			setup:    func(h *harness) { h.with_transient(map[string]string{TRANSIENT_EXPECTED_VERSION: "0"}) },
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "built"},
+			err:      "is at version 1, not 0",
+		},
+		{
+			name:     "change_realestate_status without version",
+			setup:    enable(FEATURE_STRICT_VERSIONING),
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "built"},
+			err:      "Missing " + TRANSIENT_EXPECTED_VERSION,
+		},
+		{
+			name:     "change_realestate_status invalid status",
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "villa~sold"},
+			err:      `{"field":"status","code":"INVALID_FORMAT"`,
+		},
+		{
+			name:     "change_realestate_status unknown status",
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "villa"},
+			err:      "Unknown status villa, expecting one of flat, built",
+		},
+		{
+			name:     "change_realestate_status",
+			function: "change_realestate_status",
+			args:     []string{"1232.1", "built"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if h.bond("1232.1").Status != STATUS_BUILT {
+					t.Fatal("status not changed")
+				}
+			},
+		},
+		{
+			name:     "change_coordinates",
+			function: "change_coordinates",
+			args:     []string{"1232.1", "46.7", "24.8"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if c := h.bond("1232.1").Coordinates; c.Long != 46.7 || c.Lat != 24.8 {
+					t.Fatalf("coordinates not changed %+v", c)
+				}
+			},
+		},
+		{
+			name:     "change_coordinates out of range",
+			function: "change_coordinates",
+			args:     []string{"1232.1", "46.7", "91"},
+			err:      "91",
+		},
+		{
+			name:     "change_coordinates outside geofence",
+			setup:    with_geofence,
+			function: "change_coordinates",
+			args:     []string{"1232.1", "39.2", "21.5"},
+			err:      "CHANGE_COORDINATES: Position long 39.2, lat 21.5 is outside the geofence (Riyadh)",
+		},
+		{
+			name:     "create_bond inside geofence",
+			setup:    with_geofence,
+			function: "create_bond",
+			args:     bond_fixture(3).args(),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				h.bond("1232.3")
+			},
+		},
+		{
+			name:     "create_bond swapped coordinates",
+			setup:    with_geofence,
+			function: "create_bond",
+			args:     bond_fixture(3).at("24.70", "46.63").args(),
+			err:      `{"field":"longitude","code":"OUT_OF_RANGE","message":"Position long 24.7, lat 46.63 is outside the geofence (Riyadh), the longitude and latitude look swapped"}`,
+		},
+		{
+			name:     "set_config geofence open ring",
+			function: "set_config",
+			args:     []string{`{"geofence":[{"name":"Riyadh","boundary":{"type":"Polygon","coordinates":[[[46,24],[47.5,24],[47.5,25.5],[46,25.5]]]}}]}`},
+			err:      "Invalid configuration, geofence region Riyadh: Invalid boundary",
+		},
+		{
+			name:     "add_city",
+			function: "add_city",
+			args:     []string{"JED", "Jeddah"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var cities []City
+				decode(t, h.must("get_cities"), &cities)
+				if len(cities) != 2 {
+					t.Fatalf("unexpected cities %+v", cities)
+				}
+			},
+		},
+		{
+			name:     "add_district",
+			function: "add_district",
+			args:     []string{TEST_CITY, "MALAZ", "Malaz"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d District
+				decode(t, h.must("get_district", "MALAZ"), &d)
+				if d.CityCode != TEST_CITY {
+					t.Fatalf("unexpected district %+v", d)
+				}
+			},
+		},
+		{
+			name:     "add_city invalid code and name",
+			function: "add_city",
+			args:     []string{"JED~2", "Jeddah\x00"},
+			err:      `[{"field":"code","code":"INVALID_FORMAT","message":"Invalid JED~2, expecting letters, digits and _ -"},{"field":"name","code":"INVALID_FORMAT","message":"Invalid character '\\x00'"}]`,
+		},
+		{
+			name:     "add_district Arabic name",
+			function: "add_district",
+			args:     []string{TEST_CITY, "MALAZ", "الملز"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d District
+				decode(t, h.must("get_district", "MALAZ"), &d)
+				if d.Name != "الملز" {
+					t.Fatalf("unexpected district %+v", d)
+				}
+			},
+		},
+		{
+			name:     "add_district unknown city",
+			function: "add_district",
+			args:     []string{"JED", "RAWDAH", "Rawdah"},
+			err:      "JED",
+		},
+		{
+			name:     "change_address street too long",
+			function: "change_address",
+			args:     []string{"1232.2", TEST_DISTRICT, strings.Repeat("Tahlia ", 20)},
+			err:      `[{"field":"street","code":"OUT_OF_RANGE","message":"Longer than 128 characters"}]`,
+		},
+		{
+			name:     "payload too large",
+			setup:    func(h *harness) { h.must("set_config", `{"max_payload_size":1024}`) },
+			function: "change_boundary",
+			args:     []string{"1232.1", strings.Repeat(" ", 1024)},
+			err:      "exceeds max_payload_size of 1024",
+		},
+		{
+			name:     "change_address",
+			function: "change_address",
+			args:     []string{"1232.2", TEST_DISTRICT, "Tahlia Street"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.2"); b.CityCode != TEST_CITY || b.Street != "Tahlia Street" {
+					t.Fatalf("address not changed %+v", b)
+				}
+			},
+		},
+		{
+			name: "change_district",
+			setup: func(h *harness) {
+				h.must("add_district", TEST_CITY, "MALAZ", "Malaz")
+			},
+			function: "change_district",
+			args:     []string{"1232.1", "MALAZ"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.1"); b.DistrictCode != "MALAZ" || b.Street != "King Fahd Road" {
+					t.Fatalf("district not changed %+v", b)
+				}
+				var bonds []Bond
+				if decode(t, h.must("search_by_address", TEST_CITY, "MALAZ", "king fahd"), &bonds); len(bonds) != 1 {
+					t.Fatalf("address index not moved %+v", bonds)
+				}
+				h.fails("MISSING", "change_district", "1232.1", "MISSING")
+			},
+		},
+		{
+			name:     "export_bond",
+			setup:    enable(FEATURE_CROSS_CHANNEL),
+			function: "export_bond",
+			args:     []string{"1232.1", "other"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if h.bond("1232.1").ExportedTo != "other" {
+					t.Fatal("bond not exported")
+				}
+				h.fails("exported", "change_realestate_status", "1232.1", "built")
+			},
+		},
+		{
+			name:     "wrap_bond",
+			setup:    func(h *harness) { with_tokens(h) },
+			function: "wrap_bond",
+			args:     []string{"1232.1", "wallet-1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				expect_event(t, h, BOND_WRAPPED_EVENT)
+				var w Token_Wrap
+				decode(t, payload, &w)
+				if w.TokenID != "bond:1232.1" || w.Holder != "wallet-1" || h.bond("1232.1").TokenID != w.TokenID {
+					t.Fatalf("unexpected wrap %+v", w)
+				}
+				h.fails("wrapped as token bond:1232.1", "change_realestate_status", "1232.1", "built")
+				h.fails("wrapped as token bond:1232.1", "wrap_bond", "1232.1", "wallet-2")
+			},
+		},
+		{
+			name: "unwrap_bond",
+			setup: func(h *harness) {
+				tokens := with_tokens(h)
+				h.must("wrap_bond", "1232.1", "wallet-1")
+				tokens.MockInvoke("trade", test_args("transfer", []string{"bond:1232.1", "wallet-2"}))
+			},
+			function: "unwrap_bond",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var w Token_Wrap
+				decode(t, payload, &w)
+				if w.Holder != "wallet-2" || h.bond("1232.1").TokenID != "" {
+					t.Fatalf("unexpected unwrap %+v", w)
+				}
+				h.fails("is not wrapped", "unwrap_bond", "1232.1")
+				h.must("change_realestate_status", "1232.1", "built")
+			},
+		},
+		{
+			name:     "wrap_bond without token chaincode",
+			function: "set_config",
+			args:     []string{`{"features":{"` + FEATURE_TOKEN_WRAPPING + `":true}}`},
+			err:      "token_chaincode is required",
+		},
+		{
+			name:     "register_tenant",
+			function: "register_tenant",
+			args:     []string{"jeddah", "JeddahMSP"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				h.as("clerk", "JeddahMSP", AUTHORITY)
+				var c Config
+				decode(t, h.must("get_config"), &c)
+				if c.RegulatorMSP != "JeddahMSP" {
+					t.Fatalf("unexpected tenant configuration %+v", c)
+				}
+				h.fails("No bond with realEstateID = 1232.1", "get_bond_details", "1232.1")
+				h.must("create_bond", bond_fixture(1).owned_by(owner_fixture(7)).args()...)
+				if e := expect_event(t, h, BOND_CREATED_EVENT); e.Tenant != "jeddah" {
+					t.Fatalf("unexpected event %+v", e)
+				}
+				if b := h.bond("1232.1"); b.OwnerNationalID != owner_fixture(7).NationalID {
+					t.Fatalf("unexpected tenant bond %+v", b)
+				}
+				var bonds []Bond
+				decode(t, h.must("get_bonds_by_owner", owner_fixture(1).NationalID), &bonds)
+				if len(bonds) != 0 {
+					t.Fatalf("tenant sees bonds of the deployment %+v", bonds)
+				}
+				h.fails("tenants are registered by "+REGULATOR_MSP, "register_tenant", "makkah", "MakkahMSP")
+				h.as("regulator", REGULATOR_MSP, AUTHORITY)
+				if b := h.bond("1232.1"); b.OwnerNationalID != owner_fixture(1).NationalID {
+					t.Fatalf("tenant changed the deployment's bond %+v", b)
+				}
+				h.fails("already registered to jeddah", "register_tenant", "other", "JeddahMSP")
+			},
+		},
+		{
+			name:     "export_bond disabled",
+			function: "export_bond",
+			args:     []string{"1232.1", "other"},
+			err:      FEATURE_CROSS_CHANNEL,
+		},
+		{
+			name: "import_bond",
+			setup: func(h *harness) {
+				source := seeded_harness(h.t).create(bond_fixture(9))
+				h.link(source, "land")
+				enable(FEATURE_CROSS_CHANNEL)(source)
+				source.must("export_bond", "1232.9", TEST_CHANNEL)
+				enable(FEATURE_CROSS_CHANNEL)(h)
+			},
+			function: "import_bond",
+			args:     []string{"1232.9", "land", "registry"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var ref Bond_Reference
+				decode(t, h.must("get_bond_reference", "1232.9"), &ref)
+				if ref.SourceChannel != "land" || ref.Bond.ExportedTo != TEST_CHANNEL {
+					t.Fatalf("unexpected reference %+v", ref)
+				}
+			},
+		},
+		{
+			name:     "import_bond local",
+			setup:    enable(FEATURE_CROSS_CHANNEL),
+			function: "import_bond",
+			args:     []string{"1232.1", "land", "registry"},
+			err:      "registered on this channel",
+		},
+		{
+			name:     "release_bond_reference none",
+			setup:    enable(FEATURE_CROSS_CHANNEL),
+			function: "release_bond_reference",
+			args:     []string{"1232.1"},
+			err:      "No active reference",
+		},
+		{
+			name: "reclaim_bond",
+			setup: func(h *harness) {
+				enable(FEATURE_CROSS_CHANNEL)(h)
+				h.must("export_bond", "1232.1", "other")
+				target := new_harness(h.t)
+				enable(FEATURE_CROSS_CHANNEL)(target)
+				h.link(target, "other")
+				target.link(h, TEST_CHANNEL)
+				target.must("import_bond", "1232.1", TEST_CHANNEL, "registry")
+				target.must("release_bond_reference", "1232.1")
+			},
+			function: "reclaim_bond",
+			args:     []string{"1232.1", "registry"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if h.bond("1232.1").ExportedTo != "" {
+					t.Fatal("bond not reclaimed")
+				}
+			},
+		},
+		{
+			name:     "reclaim_bond not exported",
+			setup:    enable(FEATURE_CROSS_CHANNEL),
+			function: "reclaim_bond",
+			args:     []string{"1232.1", "registry"},
+			err:      "is not exported",
+		},
+		{
+			name:     "migrate",
+			function: "migrate",
+			args:     []string{"2", "3"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var p Migration_Progress
+				decode(t, payload, &p)
+				if !p.Done || p.Migrated != 2 {
+					t.Fatalf("unexpected progress %+v", p)
+				}
+			},
+		},
+		{
+			name: "migrate legacy bond",
+			setup: func(h *harness) {
+				h.stub.State["1232.9"] = []byte(`{"id":"bond9","real_estate_id":"1232.9","owner_national_id":"1000000009","status":"flat","schema_version":2}`)
+				h.stub.State[BOND_LIST_KEY] = []byte(`{"bond_ids":["1232.1","1232.9"]}`)
+				var bonds []Bond
+				if decode(h.t, h.must("get_bonds"), &bonds); len(bonds) != 3 {
+					h.t.Fatalf("legacy bond not listed %+v", bonds)
+				}
+			},
+			function: "migrate",
+			args:     []string{"2", "3"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if h.stub.State["1232.9"] != nil || h.stub.State[bond_key("1232.9")] == nil || h.stub.State[BOND_LIST_KEY] != nil {
+					t.Fatalf("legacy bond not moved")
+				}
+				var bonds []Bond
+				if decode(t, h.must("get_bonds"), &bonds); len(bonds) != 3 {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name: "migrate baseline bond with free text coordinates",
+			setup: func(h *harness) {
+				h.stub.State["1232.9"] = []byte(`{"id":"bond9","real_estate_id":"1232.9","owner_national_id":"1000000009","status":"flat","area":"500","coordinates":{"long":"near the mosque","lat":""}}`)
+				h.stub.State[BOND_LIST_KEY] = []byte(`{"bond_ids":["1232.1","1232.9"]}`)
+			},
+			function: "migrate",
+			args:     []string{"1", "3"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var p Migration_Progress
+				if decode(t, payload, &p); !p.Done || p.Migrated != 3 {
+					t.Fatalf("unexpected progress %+v", p)
+				}
+				b := h.bond("1232.9")
+				if b.Coordinates != (Coordinates{Unknown: true}) || b.Geohash != "" || b.Area.Value != 500 {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+				for key := range h.stub.State {
+					if strings.HasPrefix(key, GEOHASH_INDEX+INDEX_SEPARATOR) && strings.HasSuffix(key, INDEX_SEPARATOR+"1232.9") {
+						t.Fatalf("unknown coordinates indexed under %s", key)
+					}
+				}
+			},
+		},
+		{
+			name:     "migrate unknown version",
+			function: "migrate",
+			args:     []string{"3", "4"},
+			err:      "Cannot migrate",
+		},
+		{
+			name:     "set_config",
+			function: "set_config",
+			args:     []string{`{"max_page_size":5}`},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c Config
+				decode(t, h.must("get_config"), &c)
+				if c.MaxPageSize != 5 || c.RegulatorMSP != REGULATOR_MSP {
+					t.Fatalf("unexpected configuration %+v", c)
+				}
+			},
+		},
+		{
+			name:     "set_config protobuf encoding",
+			function: "set_config",
+			args:     []string{`{"state_encoding":"protobuf"}`},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				before := h.bond("1232.2")
+				h.must("change_realestate_status", "1232.2", "built")
+				if stored := h.stub.State[bond_key("1232.2")]; len(stored) == 0 || stored[0] == '{' {
+					t.Fatalf("bond not stored as protobuf %q", stored)
+				}
+				after := h.bond("1232.2")
+				if after.Version != before.Version+1 {
+					t.Fatalf("version %d after saving version %d", after.Version, before.Version)
+				}
+				after.Status, after.UpdatedAt, after.LastModifiedTx, after.Version = before.Status, before.UpdatedAt, before.LastModifiedTx, before.Version
+				if !reflect.DeepEqual(before, after) {
+					t.Fatalf("bond changed by encoding\n%+v\n%+v", before, after)
+				}
+			},
+		},
+		{
+			name:     "set_config invalid log level",
+			function: "set_config",
+			args:     []string{`{"log_level":"VERBOSE"}`},
+			err:      "log_level must be",
+		},
+		{
+			name:     "set_config not admin",
+			setup:    func(h *harness) { h.as("clerk", "Org1MSP", "clerk") },
+			function: "set_config",
+			args:     []string{`{"max_page_size":5}`},
+			err:      "Permission denied",
+		},
+		{
+			name:     "add_ecert",
+			function: "add_ecert",
+			args:     []string{"bob", "bob-ecert"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Ecert_Response
+				decode(t, payload, &r)
+				if r.Registered != 1 || len(r.Overwritten) != 0 {
+					t.Fatalf("unexpected response %+v", r)
+				}
+				if ecert := h.must("get_ecert", "bob"); string(ecert) != "bob-ecert" {
+					t.Fatalf("unexpected ecert %s", ecert)
+				}
+			},
+		},
+		{
+			name:     "add_ecert registered",
+			setup:    func(h *harness) { h.must("add_ecert", "bob", "bob-ecert") },
+			function: "add_ecert",
+			args:     []string{"bob", "other-ecert"},
+			err:      "ADD_ECERT: User 0 (bob): Already registered, pass overwrite",
+		},
+		{
+			name:     "add_ecert overwrite",
+			setup:    func(h *harness) { h.must("add_ecert", "bob", "bob-ecert") },
+			function: "add_ecert",
+			args:     []string{"bob", "new-ecert", ECERT_OVERWRITE},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Ecert_Response
+				decode(t, payload, &r)
+				if r.Registered != 1 || len(r.Overwritten) != 1 || string(h.must("get_ecert", "bob")) != "new-ecert" {
+					t.Fatalf("ecert not replaced %+v", r)
+				}
+			},
+		},
+		{
+			name:     "add_ecert empty",
+			function: "add_ecert",
+			args:     []string{"bob", " "},
+			err:      "Expecting a non-empty ecert",
+		},
+		{
+			name:     "add_ecert not admin",
+			setup:    func(h *harness) { h.as("clerk", "Org1MSP", "clerk") },
+			function: "add_ecert",
+			args:     []string{"bob", "bob-ecert"},
+			err:      "Permission denied",
+		},
+		{
+			name:     "add_ecerts",
+			function: "add_ecerts",
+			args:     []string{`[{"identity":"bob","ecert":"bob-ecert"},{"identity":"alice","ecert":"alice-ecert"}]`},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Ecert_Response
+				decode(t, payload, &r)
+				if r.Registered != 2 || string(h.must("get_ecert", "alice")) != "alice-ecert" {
+					t.Fatalf("unexpected response %+v", r)
+				}
+			},
+		},
+		{
+			name:     "add_ecerts all or none",
+			setup:    func(h *harness) { h.must("add_ecert", "alice", "alice-ecert") },
+			function: "add_ecerts",
+			args:     []string{`[{"identity":"bob","ecert":"bob-ecert"},{"identity":"alice","ecert":"other-ecert"}]`},
+			err:      "ADD_ECERTS: User 1 (alice): Already registered",
+		},
+		{
+			name:     "add_ecerts repeated name",
+			function: "add_ecerts",
+			args:     []string{`[{"identity":"bob","ecert":"bob-ecert"},{"identity":"bob","ecert":"other-ecert"}]`},
+			err:      "Name repeated in the batch",
+		},
+		{
+			name:     "add_ecerts empty",
+			function: "add_ecerts",
+			args:     []string{`[]`},
+			err:      "Expecting 1 to 100 users, got 0",
+		},
+		{
+			name:     "rebuild_indexes",
+			function: "rebuild_indexes",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Rebuild_Result
+				decode(t, payload, &r)
+				if r.Bonds != 2 || r.EntriesWritten != r.EntriesRemoved {
+					t.Fatalf("unexpected result %+v", r)
+				}
+			},
+		},
+		{
+			name:     "change_boundary",
+			function: "change_boundary",
+			args:     []string{"1232.2", bond_fixture(2).bounded(0.002).Boundary},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.2"); b.Boundary == nil || math.Abs(b.Boundary.Coordinates[0][0][0]-46.619) > 1e-9 {
+					t.Fatalf("boundary not changed %+v", b.Boundary)
+				}
+			},
+		},
+		{
+			name:     "change_boundary overlapping",
+			function: "change_boundary",
+			args:     []string{"1232.1", bond_fixture(1).bounded(0.03).Boundary},
+			err:      "overlaps bonds 1232.2",
+		},
+		{
+			name: "create_bond inside a large parcel",
+			setup: func(h *harness) {
+				h.must("create_bond", bond_fixture(5).at("47.00", "24.70").bounded(0.1).args()...)
+			},
+			function: "create_bond",
+			args:     bond_fixture(6).at("46.96", "24.70").bounded(0.002).args(),
+			err:      "overlaps bonds 1232.5",
+		},
+		{
+			name: "change_boundary moves the boundary index",
+			setup: func(h *harness) {
+				h.must("create_bond", bond_fixture(5).at("47.00", "24.70").bounded(0.1).args()...)
+				h.must("change_boundary", "1232.5", bond_fixture(5).at("47.00", "24.70").bounded(0.01).Boundary)
+			},
+			function: "create_bond",
+			args:     bond_fixture(6).at("46.96", "24.70").bounded(0.002).args(),
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.6"); b.Boundary == nil {
+					t.Fatalf("bond not created %+v", b)
+				}
+			},
+		},
+		{
+			name:     "attach_document",
+			function: "attach_document",
+			args:     []string{"1232.1", "deed", strings.Repeat("AB", 32), "https://docs.example/deeds/1232.1.pdf"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var documents []Document
+				decode(t, h.must("get_documents", "1232.1"), &documents)
+				if len(documents) != 1 || documents[0].SHA256 != strings.Repeat("ab", 32) || documents[0].AttachedMSP != REGULATOR_MSP {
+					t.Fatalf("unexpected documents %+v", documents)
+				}
+				h.fails("already attached", "attach_document", "1232.1", "deed", strings.Repeat("ab", 32), "https://docs.example/copy.pdf")
+				var v Document_Verification
+				decode(t, h.must("verify_document", "1232.1", "deed", strings.Repeat("AB", 32)), &v)
+				if !v.Matches || v.Document == nil || v.Registered != 1 {
+					t.Fatalf("unexpected verification %+v", v)
+				}
+				var other Document_Verification
+				decode(t, h.must("verify_document", "1232.1", "deed", strings.Repeat("cd", 32)), &other)
+				if other.Matches || other.Document != nil || other.Registered != 1 {
+					t.Fatalf("unexpected verification of another copy %+v", other)
+				}
+			},
+		},
+		{
+			name:     "attach_document invalid hash",
+			function: "attach_document",
+			args:     []string{"1232.1", "deed", "abc", "https://docs.example/deeds/1232.1.pdf"},
+			err:      "Invalid sha256",
+		},
+		{
+			name:     "attach_document field errors",
+			function: "attach_document",
+			args:     []string{"1232.1", "Deed", "abc", "https://docs.example/" + strings.Repeat("a", MAX_DOCUMENT_URI_LENGTH)},
+			err:      `[{"field":"doc_type","code":"INVALID_FORMAT","message":"Invalid document type Deed, expecting lower case letters, digits and _"},{"field":"sha256","code":"INVALID_FORMAT","message":"Invalid sha256 abc, expecting 64 hex digits"},{"field":"uri","code":"OUT_OF_RANGE","message":"The uri is longer than 2048 characters"}]`,
+		},
+		{
+			name:     "attach_document on IPFS",
+			function: "attach_document",
+			args:     []string{"1232.1", "blueprint", strings.Repeat("ab", 32), "ipfs://QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG/plan.pdf"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d Document
+				decode(t, payload, &d)
+				if d.CID != "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG" {
+					t.Fatalf("unexpected document %+v", d)
+				}
+				h.must("attach_document", "1232.1", "survey", strings.Repeat("ab", 32), raw_cid(strings.Repeat("ab", 32)))
+				h.fails("does not match the digest", "attach_document", "1232.1", "survey", strings.Repeat("cd", 32), raw_cid(strings.Repeat("ab", 32)))
+			},
+		},
+		{
+			name:     "attach_document invalid CID",
+			function: "attach_document",
+			args:     []string{"1232.1", "deed", strings.Repeat("ab", 32), "ipfs://QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbd0"},
+			err:      "Invalid IPFS CID",
+		},
+		{
+			name: "tranfer_bond requiring a deed",
+			setup: func(h *harness) {
+				h.must("set_config", `{"required_documents":{"tranfer_bond":["deed","survey"]}}`)
+				h.must("attach_document", "1232.1", "survey", strings.Repeat("0", 64), "https://docs.example/surveys/1232.1.pdf")
+			},
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID},
+			err:      "lacks the documents tranfer_bond requires: deed",
+		},
+		{
+			name:     "register_document_ca not a CA",
+			function: "register_document_ca",
+			args:     []string{"notary", TEST_PKI.signer},
+			err:      "is not a CA certificate",
+		},
+		{
+			name: "attach_document_signature",
+			setup: func(h *harness) {
+				h.must("register_document_ca", "notary", TEST_PKI.ca)
+				h.must("attach_document", "1232.1", "deed", strings.Repeat("ab", 32), "https://docs.example/deeds/1232.1.pdf")
+				h.must("set_config", `{"required_documents":{"tranfer_bond":["deed"]},"signed_documents":["deed"]}`)
+				h.fails("deed (unsigned)", "tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+			},
+			function: "attach_document_signature",
+			args:     []string{"1232.1", "deed", strings.Repeat("ab", 32), TEST_PKI.sign(strings.Repeat("ab", 32)), TEST_PKI.signer},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d Document
+				decode(t, payload, &d)
+				if len(d.Signatures) != 1 || d.Signatures[0].CA != "notary" || d.Signatures[0].Signer != "CN=Notary 1" {
+					t.Fatalf("unexpected document %+v", d)
+				}
+				h.fails("already signed", "attach_document_signature", "1232.1", "deed", strings.Repeat("ab", 32), TEST_PKI.sign(strings.Repeat("ab", 32)), TEST_PKI.signer)
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+			},
+		},
+		{
+			name: "attach_document_signature of another document",
+			setup: func(h *harness) {
+				h.must("register_document_ca", "notary", TEST_PKI.ca)
+				h.must("attach_document", "1232.1", "deed", strings.Repeat("ab", 32), "https://docs.example/deeds/1232.1.pdf")
+			},
+			function: "attach_document_signature",
+			args:     []string{"1232.1", "deed", strings.Repeat("ab", 32), TEST_PKI.sign(strings.Repeat("cd", 32)), TEST_PKI.signer},
+			err:      "is not of the document",
+		},
+		{
+			name: "attach_document_signature untrusted signer",
+			setup: func(h *harness) {
+				h.must("register_document_ca", "court", TEST_PKI.other_ca)
+				h.must("attach_document", "1232.1", "deed", strings.Repeat("ab", 32), "https://docs.example/deeds/1232.1.pdf")
+			},
+			function: "attach_document_signature",
+			args:     []string{"1232.1", "deed", strings.Repeat("ab", 32), TEST_PKI.sign(strings.Repeat("ab", 32)), TEST_PKI.signer},
+			err:      "does not chain to a registered CA",
+		},
+		{
+			name: "tranfer_bond confirmed by the civil registry",
+			setup: func(h *harness) {
+				enable(FEATURE_IDENTITY_ORACLE)(h)
+				h.fails("No confirmed civil registry check", "tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+				var c Identity_Check
+				decode(h.t, h.must("request_identity_check", "1232.1", owner_fixture(2).NationalID), &c)
+				h.fails("restricted to "+CIVIL_REGISTRY_ORACLE, "post_identity_check", c.ID, CHECK_CONFIRMED, "CR-1")
+				h.as("registry", "CivilRegistryMSP", CIVIL_REGISTRY_ORACLE).must("post_identity_check", c.ID, CHECK_CONFIRMED, "CR-1")
+				h.fails("already confirmed", "post_identity_check", c.ID, CHECK_REJECTED, "CR-2")
+				h.as("regulator", REGULATOR_MSP, AUTHORITY)
+			},
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if b := h.bond("1232.1"); b.OwnerNationalID != owner_fixture(2).NationalID {
+					t.Fatalf("bond not transferred %+v", b)
+				}
+				h.fails("No confirmed civil registry check", "tranfer_bond", "1232.1", owner_fixture(1).NationalID)
+			},
+		},
+		{
+			name: "tranfer_bond rejected by the civil registry",
+			setup: func(h *harness) {
+				enable(FEATURE_IDENTITY_ORACLE)(h)
+				var c Identity_Check
+				decode(h.t, h.must("request_identity_check", "1232.1", owner_fixture(2).NationalID), &c)
+				expect_event(h.t, h, IDENTITY_CHECK_REQUESTED_EVENT)
+				h.as("registry", "CivilRegistryMSP", CIVIL_REGISTRY_ORACLE).must("post_identity_check", c.ID, CHECK_REJECTED, "CR-1")
+				h.as("regulator", REGULATOR_MSP, AUTHORITY)
+			},
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID},
+			err:      "No confirmed civil registry check",
+		},
+		{
+			name: "tranfer_bond below the price index",
+			setup: func(h *harness) {
+				h.fails("restricted to "+PRICE_ORACLE, "post_price_index", TEST_DISTRICT, "1000", "2017-01-01")
+				h.as("market", "MarketDataMSP", PRICE_ORACLE).must("post_price_index", TEST_DISTRICT, "1000", "2017-01-01")
+				h.must("post_price_index", TEST_DISTRICT, "2000", "2099-01-01") // not yet in effect
+				h.as("regulator", REGULATOR_MSP, AUTHORITY).with_transient(map[string]string{SALE_PRICE_SALT: "pepper"})
+			},
+			function: "tranfer_bond",
+			args:     []string{"1232.1", owner_fixture(2).NationalID, "300000"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var index Price_Index
+				decode(t, h.must("get_price_index", TEST_DISTRICT), &index)
+				if index.PricePerSqm != 1000 {
+					t.Fatalf("unexpected index %+v", index)
+				}
+				var flags []Price_Flag
+				decode(t, h.must("get_price_flags", "2017-01-01", "2017-12-31"), &flags)
+				if len(flags) != 1 || flags[0].ExpectedValue != 500000 || flags[0].Shortfall != 0.4 {
+					t.Fatalf("unexpected flags %+v", flags)
+				}
+				h.must("tranfer_bond", "1232.1", owner_fixture(1).NationalID, "400000") // within the threshold
+				decode(t, h.must("get_price_flags", "2017-01-01", "2017-12-31"), &flags)
+				if len(flags) != 1 {
+					t.Fatalf("unexpected flags %+v", flags)
+				}
+			},
+		},
+		{
+			name:     "create_bonds_bulk",
+			function: "create_bonds_bulk",
+			args:     []string{BULK_BONDS},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Bulk_Response
+				decode(t, payload, &r)
+				if r.Created != 1 || r.Failed != 2 || r.Results[0].ID != "bond5" || r.Results[1].Code != CODE_BOND_EXISTS || r.Results[2].Code != CODE_BOND_EXISTS {
+					t.Fatalf("unexpected response %+v", r)
+				}
+				expect_event(t, h, BONDS_CREATED_EVENT)
+				if b := h.bond("1232.5"); b.OwnerNationalID != owner_fixture(5).NationalID || b.Area.Value != 500 {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+			},
+		},
+		{
+			name:     "create_bonds_bulk atomic",
+			function: "create_bonds_bulk",
+			args:     []string{BULK_BONDS, BULK_ATOMIC},
+			err:      "Bond 1 (1232.1): Bond already exists",
+		},
+	})
+}
+
+//==============================================================================================================================
+//	 raw_cid - Returns the base32 CIDv1 of a raw block with the sha256 given.
+//==============================================================================================================================
+func raw_cid(sha256 string) string {
+
+	digest, _ := hex.DecodeString(sha256)
+
+	return "b" + BASE32_LOWER.EncodeToString(append([]byte{1, CODEC_RAW, MULTIHASH_SHA2_256, 32}, digest...))
+}
+
+//==============================================================================================================================
+//	 test_pki - A document CA with a signer it certified, and a second CA certifying no one, all valid at the times of
+//				the mock stub's transactions.
+//==============================================================================================================================
+type test_pki struct {
+	ca       string
+	other_ca string
+	signer   string
+	key      *ecdsa.PrivateKey
+}
+
+var TEST_PKI = new_test_pki()
+
+func new_test_pki() test_pki {
+
+	issue := func(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		if err != nil {
+			panic(err)
+		}
+
+		template.NotBefore, template.NotAfter = time.Unix(1400000000, 0), time.Unix(1600000000, 0)
+
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+
+		if err != nil {
+			panic(err)
+		}
+
+		cert, _ := x509.ParseCertificate(der)
+
+		return cert, key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+
+	authority := func(name string) x509.Certificate {
+		return x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: name}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}
+	}
+
+	caTemplate, otherTemplate := authority("Notaries CA"), authority("Courts CA")
+
+	ca, caKey, caPEM := issue(&caTemplate, nil, nil)
+	_, _, otherPEM := issue(&otherTemplate, nil, nil)
+	_, key, signerPEM := issue(&x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "Notary 1"}, KeyUsage: x509.KeyUsageDigitalSignature}, ca, caKey)
+
+	return test_pki{ca: caPEM, other_ca: otherPEM, signer: signerPEM, key: key}
+}
+
+//==============================================================================================================================
+//	 sign - Returns the base64 ECDSA signature of the signer over the sha256 given.
+//==============================================================================================================================
+func (p test_pki) sign(sha256 string) string {
+
+	digest, _ := hex.DecodeString(sha256)
+
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, digest)
+
+	if err != nil {
+		panic(err)
+	}
+
+	der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+//==============================================================================================================================
+//	 set_verification_key - Sets the key signing verification payloads.
+//==============================================================================================================================
+func set_verification_key(h *harness) {
+	h.with_transient(map[string]string{TRANSIENT_VERIFICATION_KEY: "0123456789abcdef0123456789abcdef"}).must("set_verification_key")
+	h.with_transient(nil)
+}
+
+// A new bond, a bond already registered and the new bond again
+const BULK_BONDS = `[
+	{"id":"bond5","real_estate_id":"1232.5","owner_national_id":"1000000005","status":"built","area":"500","longitude":46.65,"latitude":24.70},
+	{"real_estate_id":"1232.1","owner_national_id":"1000000005","status":"built","longitude":46.75,"latitude":24.70},
+	{"real_estate_id":"1232.5","owner_national_id":"1000000006","status":"built","longitude":46.85,"latitude":24.70}
+]`
+
+func TestQueryRoutes(t *testing.T) {
+
+	run_route_tests(t, []route_test{
+		{
+			name:     "list_functions",
+			function: "list_functions",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var functions []Function_Description
+				decode(t, payload, &functions)
+				if len(functions) != len(FUNCTIONS) {
+					t.Fatalf("listed %d functions of %d", len(functions), len(FUNCTIONS))
+				}
+				for _, f := range functions {
+					if f.Name == "migrate" && (f.Role != ROLE_ADMIN || !f.Writes || len(f.Args) != 3 || f.Args[0].Type != ARG_INT || !f.Args[2].Optional) {
+						t.Fatalf("unexpected description %+v", f)
+					}
+					if f.Name == "get_bond_details" && (f.Role != ROLE_ANY || f.Writes || f.Args[0].Type != "string") {
+						t.Fatalf("unexpected description %+v", f)
+					}
+				}
+			},
+		},
+		{
+			name:     "list_functions too many arguments",
+			function: "list_functions",
+			args:     []string{"all"},
+			err:      "LIST_FUNCTIONS: Incorrect number of arguments. Expecting no arguments",
+		},
+		{
+			name:     "get_bond_details",
+			function: "get_bond_details",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var b Bond
+				decode(t, payload, &b)
+				if b.ID != "bond1" || b.DistrictCode != TEST_DISTRICT {
+					t.Fatalf("unexpected bond %+v", b)
+				}
+			},
+		},
+		{
+			name:     "get_bond_details expand",
+			function: "get_bond_details",
+			args:     []string{"1232.1", "expand"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d Bond_Details
+				decode(t, payload, &d)
+				if d.District == nil || d.District.CityName != "Riyadh" {
+					t.Fatalf("unexpected details %+v", d)
+				}
+			},
+		},
+		{
+			name:     "get_bond_details unknown",
+			function: "get_bond_details",
+			args:     []string{"1232.9"},
+			err:      "1232.9",
+		},
+		{
+			name:     "check_unique_real_estate_id",
+			function: "check_unique_real_estate_id",
+			args:     []string{"1232.9"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Uniqueness_Check
+				decode(t, payload, &r)
+				if r.RealEstateID != "1232.9" || !r.Unique || r.ExistingOwner != "" {
+					t.Fatalf("unexpected result %+v", r)
+				}
+			},
+		},
+		{
+			name:     "check_unique_real_estate_id taken",
+			function: "check_unique_real_estate_id",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var r Uniqueness_Check
+				decode(t, payload, &r)
+				if r.Unique || r.ExistingOwner != owner_routing_hash(owner_fixture(1).NationalID) {
+					t.Fatalf("unexpected result %+v", r)
+				}
+				if strings.Contains(string(payload), owner_fixture(1).NationalID) {
+					t.Fatalf("national ID disclosed in %s", payload)
+				}
+			},
+		},
+		{
+			name:     "check_unique_real_estate_id corrupt record",
+			setup:    func(h *harness) { h.stub.State[bond_key("1232.9")] = []byte("{") },
+			function: "check_unique_real_estate_id",
+			args:     []string{"1232.9"},
+			err:      "Corrupt bond record",
+		},
+		{
+			name:     "bond_exists",
+			function: "bond_exists",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var e Bond_Existence
+				if decode(t, payload, &e); !e.Exists || e.RealEstateID != "1232.1" {
+					t.Fatalf("unexpected existence %+v", e)
+				}
+				if decode(t, h.must("bond_exists", "1232.9"), &e); e.Exists {
+					t.Fatalf("unknown bond exists %+v", e)
+				}
+			},
+		},
+		{
+			name:     "get_bonds",
+			function: "get_bonds",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				decode(t, payload, &bonds)
+				if len(bonds) != 2 {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_in_bbox",
+			function: "get_bonds_in_bbox",
+			args:     []string{"24.69", "46.60", "24.71", "46.615"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				decode(t, payload, &bonds)
+				if len(bonds) != 1 || bonds[0].RealEstateID != "1232.1" {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_in_bbox invalid",
+			function: "get_bonds_in_bbox",
+			args:     []string{"24.69", "46.60"},
+			err:      "minLat",
+		},
+		{
+			name:     "get_nearby_bonds",
+			function: "get_nearby_bonds",
+			args:     []string{"24.70", "46.62", "5000"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var nearby Nearby_Bonds
+				decode(t, payload, &nearby)
+				if len(nearby) != 2 || nearby[0].Bond.RealEstateID != "1232.2" {
+					t.Fatalf("unexpected bonds %+v", nearby)
+				}
+			},
+		},
+		{
+			name: "get_transfer_stats",
+			setup: func(h *harness) {
+				h.with_transient(map[string]string{SALE_PRICE_SALT: "pepper"})
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID, "750000")
+			},
+			function: "get_transfer_stats",
+			args:     []string{"district", "2000-01-01", "2100-01-01"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var s Transfer_Stats
+				decode(t, payload, &s)
+				if s.Count != 1 || s.TotalValue != 750000 || s.Groups[0].Key != TEST_DISTRICT {
+					t.Fatalf("unexpected statistics %+v", s)
+				}
+			},
+		},
+		{
+			name:     "get_transfer_stats unknown grouping",
+			function: "get_transfer_stats",
+			args:     []string{"owner", "2000-01-01", "2100-01-01"},
+			err:      "Unsupported grouping",
+		},
+		{
+			name:     "get_registry_stats",
+			function: "get_registry_stats",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var s Registry_Stats
+				decode(t, payload, &s)
+				if s.TotalBonds != 2 || s.UniqueOwners != 2 || s.BondsByStatus["flat"] != 2 {
+					t.Fatalf("unexpected statistics %+v", s)
+				}
+			},
+		},
+		{
+			name:     "export_bonds",
+			function: "export_bonds",
+			args:     []string{"1", ""},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var page Export_Page
+				decode(t, payload, &page)
+				if len(page.Bonds) != 1 || page.Bookmark == "" {
+					t.Fatalf("unexpected page %+v", page)
+				}
+				decode(t, h.must("export_bonds", "1", page.Bookmark), &page)
+				if len(page.Bonds) != 1 || page.Bonds[0].Bond.RealEstateID != "1232.2" {
+					t.Fatalf("unexpected second page %+v", page)
+				}
+			},
+		},
+		{
+			name: "export_ladm",
+			setup: func(h *harness) {
+				with_tokens(h)
+				h.must("wrap_bond", "1232.2", "wallet-1")
+				h.must("create_bond", bond_fixture(3).owned_by(owner_fixture(2)).args()...)
+			},
+			function: "export_ladm",
+			args:     []string{"2", ""},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var page LADM_Page
+				decode(t, payload, &page)
+				if page.Model != LADM_MODEL || len(page.BAUnits) != 2 || len(page.Parties) != 2 || page.Bookmark == "" {
+					t.Fatalf("unexpected page %+v", page)
+				}
+				if r := page.Rights[0]; r.Type != "ownership" || r.PID != "party:"+owner_fixture(1).NationalID || r.UID != "baunit:1232.1" {
+					t.Fatalf("unexpected right %+v", r)
+				}
+				if len(page.Restrictions) != 1 || page.Restrictions[0].Type != "tokenLock" || page.Restrictions[0].UID != "baunit:1232.2" {
+					t.Fatalf("unexpected restrictions %+v", page.Restrictions)
+				}
+				decode(t, h.must("export_ladm", "2", page.Bookmark), &page)
+				if len(page.BAUnits) != 1 || len(page.Parties) != 1 || page.SpatialUnits[0].Area[0].AreaSize != 500 || page.Bookmark != "" {
+					t.Fatalf("unexpected second page %+v", page)
+				}
+			},
+		},
+		{
+			name: "export_bonds with a legacy bond",
+			setup: func(h *harness) {
+				h.must("create_bond", bond_fixture(3).args()...)
+				h.stub.State["1232.15"] = []byte(`{"id":"bond15","real_estate_id":"1232.15","owner_national_id":"1000000009","status":"flat","schema_version":2}`)
+				h.stub.State[BOND_LIST_KEY] = []byte(`{"bond_ids":["1232.1","1232.15"]}`)
+			},
+			function: "export_bonds",
+			args:     []string{"2", ""},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var ids []string
+				var page Export_Page
+				for decode(t, payload, &page); ; decode(t, h.must("export_bonds", "2", page.Bookmark), &page) {
+					for _, e := range page.Bonds {
+						ids = append(ids, e.Bond.RealEstateID)
+					}
+					if page.Bookmark == "" {
+						break
+					}
+				}
+				if strings.Join(ids, ",") != "1232.1,1232.15,1232.2,1232.3" {
+					t.Fatalf("unexpected bonds %v", ids)
+				}
+			},
+		},
+		{
+			name:     "export_bonds page too large",
+			function: "export_bonds",
+			args:     []string{"100000", ""},
+			err:      "Page size must be between",
+		},
+		{
+			name:     "get_registry_checksum",
+			function: "get_registry_checksum",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c Registry_Checksum
+				decode(t, payload, &c)
+				if c.TotalBonds != 2 || c.MerkleRoot == "" {
+					t.Fatalf("unexpected checksum %+v", c)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_by_owner",
+			function: "get_bonds_by_owner",
+			args:     []string{owner_fixture(2).NationalID},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				if decode(t, payload, &bonds); len(bonds) != 1 || bonds[0].RealEstateID != "1232.2" {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_by_blueprint",
+			function: "get_bonds_by_blueprint",
+			args:     []string{"01232"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				if decode(t, payload, &bonds); len(bonds) != 2 || bonds[0].RealEstateID != "1232.1" || bonds[1].RealEstateID != "1232.2" {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_by_blueprint other blueprint",
+			function: "get_bonds_by_blueprint",
+			args:     []string{"1233"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				if decode(t, payload, &bonds); len(bonds) != 0 {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_by_blueprint invalid",
+			function: "get_bonds_by_blueprint",
+			args:     []string{"1232.1"},
+			err:      "Invalid blueprint number",
+		},
+		{
+			name:     "get_owner_summary",
+			function: "get_owner_summary",
+			args:     []string{owner_fixture(1).NationalID},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var s Owner_Summary
+				decode(t, payload, &s)
+				if s.TotalBonds != 1 || s.TotalArea.Value != 500 {
+					t.Fatalf("unexpected summary %+v", s)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_modified_since",
+			function: "get_bonds_modified_since",
+			args:     []string{"2000-01-01T00:00:00Z"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				decode(t, payload, &bonds)
+				if len(bonds) != 2 {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_bonds_modified_since invalid",
+			function: "get_bonds_modified_since",
+			args:     []string{"yesterday"},
+			err:      "RFC 3339",
+		},
+		{
+			name:     "get_cities",
+			function: "get_cities",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var cities []City
+				decode(t, payload, &cities)
+				if len(cities) != 1 || cities[0].Code != TEST_CITY {
+					t.Fatalf("unexpected cities %+v", cities)
+				}
+			},
+		},
+		{
+			name:     "get_districts",
+			function: "get_districts",
+			args:     []string{TEST_CITY},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var districts []District
+				decode(t, payload, &districts)
+				if len(districts) != 1 || districts[0].Code != TEST_DISTRICT {
+					t.Fatalf("unexpected districts %+v", districts)
+				}
+			},
+		},
+		{
+			name:     "get_district",
+			function: "get_district",
+			args:     []string{TEST_DISTRICT},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var d District
+				decode(t, payload, &d)
+				if d.CityCode != TEST_CITY || d.CityName != "Riyadh" {
+					t.Fatalf("unexpected district %+v", d)
+				}
+			},
+		},
+		{
+			name:     "get_district unknown",
+			function: "get_district",
+			args:     []string{"NOWHERE"},
+			err:      "NOWHERE",
+		},
+		{
+			name:     "search_by_address",
+			function: "search_by_address",
+			args:     []string{TEST_CITY, "", "king  FAHD"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var bonds []Bond
+				decode(t, payload, &bonds)
+				if len(bonds) != 1 || bonds[0].RealEstateID != "1232.1" {
+					t.Fatalf("unexpected bonds %+v", bonds)
+				}
+			},
+		},
+		{
+			name:     "get_district_geojson",
+			setup:    func(h *harness) { h.must("change_address", "1232.2", TEST_DISTRICT, "Olaya Street") },
+			function: "get_district_geojson",
+			args:     []string{TEST_DISTRICT},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c struct {
+					Type     string
+					Features []struct {
+						ID         string
+						Geometry   struct{ Type string }
+						Properties Feature_Properties
+					}
+				}
+				decode(t, payload, &c)
+				if c.Type != "FeatureCollection" || len(c.Features) != 2 {
+					t.Fatalf("unexpected collection %s", payload)
+				}
+				geometries := map[string]string{c.Features[0].ID: c.Features[0].Geometry.Type, c.Features[1].ID: c.Features[1].Geometry.Type}
+				if geometries["1232.1"] != "Point" || geometries["1232.2"] != "Polygon" || c.Features[0].Properties.DistrictCode != TEST_DISTRICT {
+					t.Fatalf("unexpected features %s", payload)
+				}
+				if strings.Contains(string(payload), owner_fixture(1).NationalID) {
+					t.Fatalf("owner exported in %s", payload)
+				}
+			},
+		},
+		{
+			name:     "get_district_geojson unknown district",
+			function: "get_district_geojson",
+			args:     []string{"NOWHERE"},
+			err:      "NOWHERE",
+		},
+		{
+			name:     "get_bond_reference unknown",
+			function: "get_bond_reference",
+			args:     []string{"1232.1"},
+			err:      "No reference",
+		},
+		{
+			name:     "get_config",
+			function: "get_config",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var c Config
+				decode(t, payload, &c)
+				if c.AdminRole != AUTHORITY || c.MaxPageSize != MAX_PAGE_SIZE {
+					t.Fatalf("unexpected configuration %+v", c)
+				}
+			},
+		},
+		{
+			name:     "get_config_changes",
+			setup:    enable(FEATURE_STRICT_ACL),
+			function: "get_config_changes",
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var changes []Config_Change
+				decode(t, payload, &changes)
+				if len(changes) != 1 || !changes[0].Config.Features[FEATURE_STRICT_ACL] {
+					t.Fatalf("unexpected changes %+v", changes)
+				}
+			},
+		},
+		{
+			name:     "get_audit_log",
+			setup:    func(h *harness) { h.must("change_realestate_status", "1232.1", "built") },
+			function: "get_audit_log",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var records []Audit_Record
+				decode(t, payload, &records)
+				if len(records) != 2 || records[0].Function != "create_bond" || records[0].BeforeHash != "" {
+					t.Fatalf("unexpected audit log %+v", records)
+				}
+				if records[1].Function != "change_realestate_status" || records[1].BeforeHash != records[0].AfterHash || records[1].AfterHash == records[1].BeforeHash {
+					t.Fatalf("unexpected audit record %+v", records[1])
+				}
+			},
+		},
+		{
+			name:     "get_audit_records",
+			function: "get_audit_records",
+			args:     []string{"1", ""},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var page Audit_Page
+				decode(t, payload, &page)
+				if len(page.Records) != 1 || page.Records[0].RealEstateID != "1232.1" || page.Bookmark == "" {
+					t.Fatalf("unexpected page %+v", page)
+				}
+				decode(t, h.must("get_audit_records", "1", page.Bookmark), &page)
+				if len(page.Records) != 1 || page.Records[0].RealEstateID != "1232.2" || page.Bookmark != "" {
+					t.Fatalf("unexpected second page %+v", page)
+				}
+			},
+		},
+		{
+			name:     "get_audit_log not admin",
+			setup:    func(h *harness) { h.as("clerk", "Org1MSP", "clerk") },
+			function: "get_audit_log",
+			args:     []string{"1232.1"},
+			err:      "Permission denied",
+		},
+		{
+			name:     "get_verification_payload",
+			setup:    set_verification_key,
+			function: "get_verification_payload",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var issued Issued_Verification
+				decode(t, payload, &issued)
+				var r Verification_Result
+				decode(t, h.must("verify_verification_payload", issued.Payload), &r)
+				if r.Result != VERIFICATION_VALID || r.RealEstateID != "1232.1" || !r.OwnerCurrent {
+					t.Fatalf("unexpected result %+v", r)
+				}
+				decode(t, h.must("verify_verification_payload", issued.Payload+"AAAA"), &r)
+				if r.Result != VERIFICATION_INVALID {
+					t.Fatalf("altered payload verified %+v", r)
+				}
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+				decode(t, h.must("verify_verification_payload", issued.Payload), &r)
+				if r.Result != VERIFICATION_SUPERSEDED || r.BondCurrent || r.OwnerCurrent {
+					t.Fatalf("unexpected result after transfer %+v", r)
+				}
+			},
+		},
+		{
+			name:     "verify_ownership_certificate",
+			setup:    set_verification_key,
+			function: "get_ownership_certificate",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				var signed Signed_Certificate
+				decode(t, payload, &signed)
+				var v Certificate_Verification
+				decode(t, h.must("verify_ownership_certificate", signed.Certificate, signed.Signature), &v)
+				if v.Result != VERIFICATION_VALID || v.Certificate.OwnerNationalID != owner_fixture(1).NationalID {
+					t.Fatalf("unexpected result %+v", v)
+				}
+				forged := strings.Replace(signed.Certificate, owner_fixture(1).NationalID, owner_fixture(9).NationalID, 1)
+				decode(t, h.must("verify_ownership_certificate", forged, signed.Signature), &v)
+				if v.Result != VERIFICATION_INVALID {
+					t.Fatalf("forged certificate verified %+v", v)
+				}
+				h.must("tranfer_bond", "1232.1", owner_fixture(2).NationalID)
+				decode(t, h.must("verify_ownership_certificate", signed.Certificate, signed.Signature), &v)
+				if v.Result != VERIFICATION_SUPERSEDED || v.OwnerCurrent || v.CurrentVersion <= v.Certificate.BondVersion {
+					t.Fatalf("unexpected result after transfer %+v", v)
+				}
+			},
+		},
+		{
+			name:     "get_verification_payload without key",
+			function: "get_verification_payload",
+			args:     []string{"1232.1"},
+			err:      "No verification key has been set",
+		},
+		{
+			name:     "get_ecert unknown",
+			function: "get_ecert",
+			args:     []string{"1232.1"},
+			check: func(t *testing.T, h *harness, payload []byte) {
+				if len(payload) != 0 {
+					t.Fatalf("bond returned as ecert %s", payload)
+				}
+			},
+		},
+		{
+			name:     "unknown function",
+			function: "get_everything",
+			err:      "unknown function invocation get_everything",
+		},
+	})
+}
+
+func TestAddEcertsAllOrNone(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	h.must("add_ecert", "alice", "alice-ecert")
+	h.fails("Already registered", "add_ecerts", `[{"identity":"bob","ecert":"bob-ecert"},{"identity":"alice","ecert":"other-ecert"}]`)
+
+	// the users are checked before any is written, so the failed batch left bob unregistered
+	if ecert := h.must("get_ecert", "bob"); len(ecert) != 0 {
+		t.Fatalf("bob registered by a failed batch: %s", ecert)
+	}
+
+	if ecert := h.must("get_ecert", "alice"); string(ecert) != "alice-ecert" {
+		t.Fatalf("alice's ecert replaced by a failed batch: %s", ecert)
+	}
+}
+
+func TestBondsByBlueprintInParcelOrder(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	// 1232.10 sorts before 1232.2 as a string, the index pads the parcel number to keep them in parcel order
+	h.must("create_bond", bond_fixture(10).args()...)
+
+	var bonds []Bond
+	decode(t, h.must("get_bonds_by_blueprint", "1232"), &bonds)
+
+	var ids []string
+
+	for _, b := range bonds {
+		ids = append(ids, b.RealEstateID)
+	}
+
+	if strings.Join(ids, ",") != "1232.1,1232.2,1232.10" {
+		t.Fatalf("unexpected order %v", ids)
+	}
+}
+
+func TestGeohashCover(t *testing.T) {
+
+	// Boxes reaching past the poles or the antimeridian are cut to the globe, a box of 1e9 degrees once hung
+	cells, err := geohash_cover(-1e9, -1e9, 1e9, 1e9, MAX_SEARCH_CELLS)
+
+	if err != nil || len(cells) == 0 || len(cells) > MAX_SEARCH_CELLS {
+		t.Fatalf("unexpected cover of the whole globe %v %v", cells, err)
+	}
+
+	cells, err = geohash_cover(89.5, 179.5, 95, 185, MAX_SEARCH_CELLS)
+
+	if err != nil || len(cells) == 0 {
+		t.Fatalf("unexpected cover of the corner %v %v", cells, err)
+	}
+
+	for _, cell := range cells {
+		if !strings.HasPrefix(cell, geohash_encode(89.75, 179.75, 1)) {
+			t.Fatalf("cell %s outside the corner", cell)
+		}
+	}
+
+	if cells, err = geohash_cover(91, 0, 95, 1, MAX_SEARCH_CELLS); err != nil || len(cells) != 0 {
+		t.Fatalf("unexpected cover of a box off the globe %v %v", cells, err)
+	}
+}
+
+func TestBondEndorsementPolicy(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	policy_orgs := func(id string) []string {
+
+		ep, err := statebased.NewStateEP(h.stub.EndorsementPolicies[bond_key(id)])
+
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+
+		return ep.ListOrgs()
+	}
+
+	// The owner's organisation is that of the clerk registering the bond
+	h.as("clerk", "JeddahMSP", AUTHORITY).create(bond_fixture(3))
+
+	if orgs := policy_orgs(bond_fixture(3).RealEstateID); !reflect.DeepEqual(orgs, []string{"JeddahMSP", REGULATOR_MSP}) {
+		t.Fatalf("unexpected endorsing organisations %v after create_bond", orgs)
+	}
+
+	recipient := Owner_Fixture{NationalID: owner_fixture(4).NationalID, MSP: "Org2MSP"}
+
+	h.must("tranfer_bond", bond_fixture(3).RealEstateID, recipient.NationalID, "", recipient.MSP)
+
+	if orgs := policy_orgs(bond_fixture(3).RealEstateID); !reflect.DeepEqual(orgs, []string{recipient.MSP, REGULATOR_MSP}) {
+		t.Fatalf("unexpected endorsing organisations %v after tranfer_bond", orgs)
+	}
+}
+
+func TestDanglingEntries(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	h.stub.State[bond_key("1232.8")] = []byte("{")                           // a record that no longer converts
+	h.stub.State[BOND_LIST_KEY] = []byte(`{"bond_ids":["1232.1","1232.9"]}`) // 1232.9 was never stored
+	h.stub.State[index_key(OWNER_INDEX, owner_fixture(1).NationalID, "1232.9")] = INDEX_VALUE
+
+	h.stub.State[index_key(GEOHASH_INDEX, h.bond("1232.1").Geohash[:5]+"zzzz", "1232.9")] = INDEX_VALUE
+
+	var bonds []Bond
+	if decode(t, h.must("get_bonds"), &bonds); len(bonds) != 2 {
+		t.Fatalf("unexpected bonds %+v", bonds)
+	}
+
+	var entries []Skipped_Bond
+	decode(t, h.must("get_skipped_bonds"), &entries)
+
+	var skipped []string
+
+	for _, s := range entries {
+		skipped = append(skipped, s.RealEstateID+":"+s.Code)
+	}
+
+	if strings.Join(skipped, ",") != "1232.8:CORRUPT_RECORD,1232.9:BOND_NOT_FOUND" {
+		t.Fatalf("unexpected skipped entries %+v", entries)
+	}
+
+	if decode(t, h.must("get_bonds_by_owner", owner_fixture(1).NationalID), &bonds); len(bonds) != 1 {
+		t.Fatalf("unexpected owner bonds %+v", bonds)
+	}
+
+	if decode(t, h.must("get_bonds_in_bbox", "-90", "-180", "90", "180"), &bonds); len(bonds) != 2 {
+		t.Fatalf("unexpected bonds in the box %+v", bonds)
+	}
+
+	var stats Registry_Stats
+	if decode(t, h.must("get_registry_stats"), &stats); stats.TotalBonds != 2 || strings.Join(stats.Skipped, ",") != "1232.8,1232.9" {
+		t.Fatalf("unexpected statistics %+v", stats)
+	}
+
+	var checksum Registry_Checksum
+	if decode(t, h.must("get_registry_checksum"), &checksum); checksum.TotalBonds != 2 || strings.Join(checksum.Skipped, ",") != "1232.8,1232.9" {
+		t.Fatalf("unexpected checksum %+v", checksum)
+	}
+
+	var page Export_Page
+	if decode(t, h.must("export_bonds", "10", ""), &page); len(page.Bonds) != 2 || strings.Join(page.Skipped, ",") != "1232.8,1232.9" {
+		t.Fatalf("unexpected export %+v", page)
+	}
+
+	h.as("clerk", "Org1MSP", "clerk").fails("Permission denied", "repair_bond_listing")
+	h.as("regulator", REGULATOR_MSP, AUTHORITY)
+
+	var r Repair_Result
+	decode(t, h.must("repair_bond_listing"), &r)
+
+	if strings.Join(r.Quarantined, ",") != "1232.8" || strings.Join(r.Unlisted, ",") != "1232.9" {
+		t.Fatalf("unexpected repair %+v", r)
+	}
+
+	if string(h.stub.State[index_key(CORRUPT_BOND_PREFIX, "1232.8")]) != "{" || h.stub.State[bond_key("1232.8")] != nil {
+		t.Fatalf("corrupt record not moved aside")
+	}
+
+	if decode(t, h.must("get_skipped_bonds"), &entries); len(entries) != 0 {
+		t.Fatalf("unexpected skipped entries after repair %+v", entries)
+	}
+
+	var rebuilt Rebuild_Result
+	decode(t, h.must("rebuild_indexes"), &rebuilt)
+
+	if rebuilt.Bonds != 2 || len(rebuilt.Skipped) != 0 || h.stub.State[index_key(OWNER_INDEX, owner_fixture(1).NationalID, "1232.9")] != nil {
+		t.Fatalf("dangling owner entry not dropped %+v", rebuilt)
+	}
+}
+
+func TestBondRecordFieldErrors(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	r := h.call("create_bond", `{"real_estate_id":"1232.9","area":"lots","longitude":46.69,"latitude":95,"district_code":"NOWHERE"}`)
+
+	var envelope Response_Envelope
+	decode(t, []byte(r.Message), &envelope)
+
+	listing := envelope.Message[strings.Index(envelope.Message, "["):]
+
+	var problems []Field_Error
+	decode(t, []byte(listing), &problems)
+
+	var fields []string
+
+	for _, p := range problems {
+		fields = append(fields, p.Field+":"+p.Code)
+	}
+
+	if envelope.Code != CODE_INVALID_ARGUMENT || strings.Join(fields, ",") != "owner_national_id:REQUIRED,status:REQUIRED,area:INVALID_FORMAT,latitude:OUT_OF_RANGE,district_code:UNKNOWN_REFERENCE" {
+		t.Fatalf("unexpected field errors %s", envelope.Message)
+	}
+
+	r = h.call("create_bond", `{"real_estate_id":1232.9}`)
+
+	if !strings.Contains(r.Message, `{\"field\":\"real_estate_id\",\"code\":\"INVALID_TYPE\",\"message\":\"expecting a string, got a number\"}`) {
+		t.Fatalf("unexpected type error %s", r.Message)
+	}
+}
+
+func TestBondJSONFieldsUnique(t *testing.T) {
+
+	// encoding/json silently drops fields sharing a name, so a clash would lose both from every stored bond
+	bond := reflect.TypeOf(Bond{})
+	seen := make(map[string]string)
+
+	for i := 0; i < bond.NumField(); i++ {
+
+		name := strings.Split(bond.Field(i).Tag.Get("json"), ",")[0]
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if other, clash := seen[name]; clash {
+			t.Fatalf("%s and %s are both stored as %s", other, bond.Field(i).Name, name)
+		}
+
+		seen[name] = bond.Field(i).Name
+	}
+}
+
+func TestBondMessageWireFormat(t *testing.T) {
+
+	// Written by the encoder that predates bond.proto, records already on the ledger must read the same
+	stored, _ := hex.DecodeString("0a05626f6e64321206313233322e321a0a3130303030303030303222074f7267314d53502a04666c6174320d090000000000407f4012026d323a12098fc2f5285c4f4740113333333333b33840424d0a07506f6c79676f6e12420a40cdcccccccc4c47409a999999999938409a999999995947409a999999999938409a999999995947403333333333b33840cdcccccccc4c47409a999999999938404a057468336873900103980102aa010877616c6c65742d31a2010f0a066c656761637912054c442d3737")
+
+	expected := Bond{ID: "bond2", RealEstateID: "1232.2", OwnerNationalID: "1000000002", OwnerMSP: "Org1MSP", Status: "flat",
+		Area: Land_Area{500, "m2"}, Coordinates: Coordinates{Long: 46.62, Lat: 24.7},
+		Boundary: &Polygon{Type: "Polygon", Coordinates: [][][2]float64{{{46.6, 24.6}, {46.7, 24.6}, {46.7, 24.7}, {46.6, 24.6}}}},
+		Geohash:  "th3hs", SchemaVersion: 3, Version: 2,
+		Provenance: &Provenance{Origin: "legacy", LegacyDeedNumber: "LD-77"}, TokenID: "wallet-1"}
+
+	b, err := unmarshal_bond(stored)
+
+	if err != nil || !reflect.DeepEqual(b, expected) {
+		t.Fatalf("unexpected bond %+v %v", b, err)
+	}
+
+	data, err := marshal_bond(expected, ENCODING_PROTOBUF)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if b, err = unmarshal_bond(data); err != nil || !reflect.DeepEqual(b, expected) {
+		t.Fatalf("bond changed by a round trip %+v %v", b, err)
+	}
+}
+
+func TestArgSpecs(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	// "1" converts to every argument type, so each call gets past the spec into the function
+	for function, entry := range FUNCTIONS {
+		for n := 0; n <= len(entry.Args.Args)+1; n++ {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s with %d arguments panicked: %v", function, n, r)
+					}
+				}()
+				h.call(function, strings.Split(strings.Repeat("1", n), "")...)
+			}()
+		}
+	}
+
+	h.fails("TRANFER_BOND: Incorrect number of arguments. Expecting realEstateID and optionally recipient national ID, declared value, recipient MSP", "tranfer_bond")
+	h.fails("GET_BONDS: Incorrect number of arguments. Expecting no arguments", "get_bonds", "1232.1")
+	h.fails("EXPORT_BONDS: Invalid pageSize ten, expecting an integer", "export_bonds", "ten", "")
+	h.fails("SET_CONFIG: Invalid configuration {, expecting a JSON", "set_config", "{")
+}
+
+func TestFunctionRegistry(t *testing.T) {
+
+	for function, entry := range FUNCTIONS {
+		if entry.Handler == nil {
+			t.Errorf("%s has no handler", function)
+		}
+	}
+
+	h := seeded_harness(t)
+
+	h.fails(`expecting one of ["add_city",`, "get_everything")
+
+	// the registry rejects the caller before the function runs
+	h.as("clerk", "Org1MSP", "clerk")
+	h.fails("Permission denied, admin functions are restricted to "+AUTHORITY, "get_config")
+	h.fails("POST_PRICE_INDEX: Permission denied, restricted to "+PRICE_ORACLE, "post_price_index", TEST_DISTRICT, "1000", "2017-01-01")
+
+	if !FUNCTIONS["create_bond"].Writes || FUNCTIONS["get_bonds"].Writes {
+		t.Fatalf("create_bond should write and get_bonds should not")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	FUNCTIONS["panic"] = Function_Entry{
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			var b *Bond
+			return []byte(b.ID), nil
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("n", ARG_INT)}},
+		Role: ROLE_ADMIN,
+	}
+
+	defer delete(FUNCTIONS, "panic")
+
+	h.fails("INVOKE: Internal error in panic", "panic", "1")
+
+	// the arguments are checked before the role, as in MIDDLEWARE
+	h.as("clerk", "Org1MSP", "clerk")
+	h.fails("PANIC: Invalid n one, expecting an integer", "panic", "one")
+	h.fails("Permission denied", "panic", "1")
+}
+
+func TestResponseEnvelope(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	var ok, failed Response_Envelope
+
+	r := h.call("get_district", TEST_DISTRICT)
+	decode(t, r.Payload, &ok)
+
+	if ok.Status != 200 || ok.Code != CODE_OK || ok.Message != "" || !strings.Contains(string(ok.Data), `"code":"OLAYA"`) {
+		t.Fatalf("unexpected envelope %s", r.Payload)
+	}
+
+	r = h.call("health")
+	decode(t, r.Payload, &ok)
+
+	if !strings.Contains(string(ok.Data), `"status":"ok"`) {
+		t.Fatalf("unexpected envelope %s", r.Payload)
+	}
+
+	r = h.call("get_district", "NOWHERE")
+	decode(t, []byte(r.Message), &failed)
+
+	if r.Status != 500 || failed.Status != 500 || failed.Code != CODE_INVALID_ARGUMENT || !strings.Contains(failed.Message, "NOWHERE") || failed.Data != nil {
+		t.Fatalf("unexpected envelope %s", r.Message)
+	}
+}
+
+//==============================================================================================================================
+//	 TestErrorCodes - Checks failing responses carry the code of the error whatever function prefixes its message.
+//==============================================================================================================================
+func TestErrorCodes(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	h.stub.State[bond_key("1232.8")] = []byte("{") // a record stored but unreadable, unlike 1232.9
+
+	tests := []struct {
+		code     string
+		function string
+		args     []string
+	}{
+		{CODE_BOND_NOT_FOUND, "get_bond_details", []string{"1232.9"}},
+		{CODE_BOND_NOT_FOUND, "tranfer_bond", []string{"1232.9", owner_fixture(2).NationalID}},
+		{CODE_CORRUPT_RECORD, "get_bond_details", []string{"1232.8"}},
+		{CODE_CORRUPT_RECORD, "tranfer_bond", []string{"1232.8", owner_fixture(2).NationalID}},
+		{CODE_CORRUPT_RECORD, "bond_exists", []string{"1232.8"}},
+		{CODE_BOND_EXISTS, "create_bond", bond_fixture(1).args()},
+		{CODE_UNKNOWN_FUNCTION, "no_such_function", nil},
+	}
+
+	for _, test := range tests {
+
+		var failed Response_Envelope
+
+		r := h.call(test.function, test.args...)
+		decode(t, r.Payload, &failed)
+
+		if r.Status == shim.OK || failed.Code != test.code {
+			t.Errorf("%s %v: expecting code %s, got %s", test.function, test.args, test.code, r.Payload)
+		}
+	}
+
+	h.as("owner", "Org1MSP", "")
+
+	var failed Response_Envelope
+
+	decode(t, h.call("set_config", `{}`).Payload, &failed)
+
+	if failed.Code != CODE_NOT_AUTHORIZED {
+		t.Errorf("set_config by an owner: expecting code %s, got %s", CODE_NOT_AUTHORIZED, failed.Code)
+	}
+}
+
+//==============================================================================================================================
+//	 failing_stub - A stub whose ledger reads fail, as when the peer cannot be reached.
+//==============================================================================================================================
+type failing_stub struct {
+	*shim.MockStub
+}
+
+var PEER_UNAVAILABLE = errors.New("peer unavailable")
+
+func (s failing_stub) GetState(key string) ([]byte, error) {
+	return nil, PEER_UNAVAILABLE
+}
+
+func TestWrappedErrors(t *testing.T) {
+
+	h := seeded_harness(t)
+
+	_, err := new(SimpleChaincode).retrieve_bond(failing_stub{h.stub}, "1232.1")
+
+	if error_code(err) != CODE_LEDGER_ERROR || !strings.Contains(err.Error(), "Error retrieving bond 1232.1: peer unavailable") {
+		t.Fatalf("unexpected error %v with code %s", err, error_code(err))
+	}
+
+	err = prefix_error("QUERY", err)
+
+	if e, ok := err.(Chaincode_Error); !ok || e.Code != CODE_LEDGER_ERROR || e.Unwrap() != PEER_UNAVAILABLE {
+		t.Fatalf("prefix_error lost the cause of %v", err)
+	}
+}
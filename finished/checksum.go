@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Registry Checksum - A Merkle root over every bond lets auditors prove an off-chain snapshot matches the ledger. The
+//						 leaves are the sha256 of each bond's JSON, as returned in the hash field of export_bonds, in
+//						 realEstateID order. Each parent is the sha256 of its two children's hashes concatenated and a
+//						 node left without a partner is carried up to the next level unchanged.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Registry_Checksum - The response of get_registry_checksum.
+//==============================================================================================================================
+type Registry_Checksum struct {
+	Algorithm  string   `json:"algorithm"`
+	TotalBonds int      `json:"total_bonds"`
+	MerkleRoot string   `json:"merkle_root"`
+	Skipped    []string `json:"skipped,omitempty"` // bonds left out of the tree, see get_skipped_bonds
+}
+
+//==============================================================================================================================
+//	 bond_hash - Returns the sha256 of the bond's JSON, the Merkle leaf for the bond.
+//==============================================================================================================================
+func bond_hash(b Bond) ([]byte, error) {
+
+	bytes, err := json.Marshal(b)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "Error converting bond record", err)
+	}
+
+	sum := sha256.Sum256(bytes)
+
+	return sum[:], nil
+}
+
+//==============================================================================================================================
+//	 merkle_root - Folds the leaf hashes into their Merkle root. An empty tree has the hash of no data as its root.
+//==============================================================================================================================
+func merkle_root(level [][]byte) []byte {
+
+	if len(level) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+
+	for len(level) > 1 {
+
+		var next [][]byte
+
+		for i := 0; i < len(level); i += 2 {
+
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+
+			sum := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+
+		level = next
+	}
+
+	return level[0]
+}
+
+//=================================================================================================================================
+//	 get_registry_checksum - Returns the Merkle root over every bond on the ledger.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_registry_checksum(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	ids, err := t.get_sorted_bond_ids(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds, skipped, err := t.retrieve_bonds_skipping(stub, "GET_REGISTRY_CHECKSUM", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves [][]byte
+
+	for _, b := range bonds {
+
+		leaf, err := bond_hash(b)
+
+		if err != nil {
+			return nil, prefix_error("GET_REGISTRY_CHECKSUM", err)
+		}
+
+		leaves = append(leaves, leaf)
+	}
+
+	bytes, err := json.Marshal(Registry_Checksum{Algorithm: "sha256", TotalBonds: len(bonds), MerkleRoot: hex.EncodeToString(merkle_root(leaves)), Skipped: skipped})
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_REGISTRY_CHECKSUM: Error converting checksum", err)
+	}
+
+	return bytes, nil
+}
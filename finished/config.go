@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Configuration - Operational parameters are stored on the ledger under CONFIG_KEY so they can be set when the
+//					 chaincode is instantiated or upgraded, e.g. -c '{"Args":["init","{\"regulator_msp\":\"MoJMSP\"}"]}'.
+//					 Parameters missing from the stored configuration take the defaults below.
+//==============================================================================================================================
+
+const CONFIG_KEY = "config"
+
+// Prefix of the audit records of configuration changes, keyed by the time and ID of the transaction
+const CONFIG_CHANGE_PREFIX = "config_change"
+
+//==============================================================================================================================
+//	 Config - The configuration of the chaincode.
+//==============================================================================================================================
+
+type Config struct {
+	RegulatorMSP      string          `json:"regulator_msp"`         // organisation endorsing every bond, see set_bond_endorsement
+	AdminRole         string          `json:"admin_role"`            // role attribute allowed to run admin functions
+	OracleRole        string          `json:"oracle_role"`           // role attribute of the civil registry's oracle, see oracle.go
+	PriceOracleRole   string          `json:"price_oracle_role"`     // role attribute of the market data provider, see price_index.go
+	PriceAlert        float64         `json:"price_alert_threshold"` // fraction below the price index flagged, see flag_sale_price
+	TokenChaincode    string          `json:"token_chaincode"`       // chaincode bonds are wrapped by, see tokens.go
+	DuplicateDistance float64         `json:"duplicate_distance"`    // metres, see find_duplicates
+	MaxPageSize       int             `json:"max_page_size"`         // largest export_bonds page
+	MaxPayloadSize    int             `json:"max_payload_size"`      // bytes of arguments and transient data, see check_payload_size
+	Features          map[string]bool `json:"features,omitempty"`    // see check_features
+	StateEncoding     string          `json:"state_encoding"`        // json or protobuf, see marshal_bond
+	LogLevel          string          `json:"log_level"`             // see set_log_level
+
+	RequiredDocuments map[string][]string `json:"required_documents,omitempty"` // document types by function, see check_required_documents
+	SignedDocuments   []string            `json:"signed_documents,omitempty"`   // document types counted only when signed, see signatures.go
+	Geofence          []Geofence_Region   `json:"geofence,omitempty"`           // regions bonds must lie in, see geofence.go
+}
+
+var DEFAULT_CONFIG = Config{
+	RegulatorMSP:      REGULATOR_MSP,
+	AdminRole:         AUTHORITY,
+	OracleRole:        CIVIL_REGISTRY_ORACLE,
+	PriceOracleRole:   PRICE_ORACLE,
+	PriceAlert:        PRICE_ALERT_THRESHOLD,
+	DuplicateDistance: DUPLICATE_DISTANCE,
+	MaxPageSize:       MAX_PAGE_SIZE,
+	MaxPayloadSize:    MAX_PAYLOAD_SIZE,
+	StateEncoding:     ENCODING_JSON,
+	LogLevel:          DEFAULT_LOG_LEVEL,
+}
+
+//==============================================================================================================================
+//	 Config_Change - The audit record of a change to the configuration.
+//==============================================================================================================================
+type Config_Change struct {
+	Timestamp string `json:"timestamp"`
+	TxID      string `json:"txid"`
+	Actor     string `json:"actor"`
+	Previous  Config `json:"previous"`
+	Config    Config `json:"config"`
+}
+
+//==============================================================================================================================
+//	 validate_config - Checks every parameter of the configuration is usable.
+//==============================================================================================================================
+func validate_config(c Config) error {
+
+	if c.RegulatorMSP == "" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, regulator_msp is required")
+	}
+
+	if c.AdminRole == "" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, admin_role is required")
+	}
+
+	if c.OracleRole == "" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, oracle_role is required")
+	}
+
+	if c.PriceOracleRole == "" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, price_oracle_role is required")
+	}
+
+	if c.PriceAlert < 0 || c.PriceAlert >= 1 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, price_alert_threshold must be from 0 up to 1")
+	}
+
+	if c.DuplicateDistance < 0 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, duplicate_distance must not be negative")
+	}
+
+	if c.MaxPageSize < 1 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, max_page_size must be at least 1")
+	}
+
+	if c.MaxPayloadSize < 1024 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, max_payload_size must be at least 1024")
+	}
+
+	if c.StateEncoding != ENCODING_JSON && c.StateEncoding != ENCODING_PROTOBUF {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, state_encoding must be "+ENCODING_JSON+" or "+ENCODING_PROTOBUF)
+	}
+
+	if _, err := shim.LogLevel(c.LogLevel); err != nil {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, log_level must be DEBUG, INFO, NOTICE, WARNING, ERROR or CRITICAL")
+	}
+
+	if c.Features[FEATURE_TOKEN_WRAPPING] && c.TokenChaincode == "" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, token_chaincode is required for "+FEATURE_TOKEN_WRAPPING)
+	}
+
+	err := validate_required_documents(c.RequiredDocuments, c.SignedDocuments)
+
+	if err != nil {
+		return err
+	}
+
+	err = validate_geofence(c.Geofence)
+
+	if err != nil {
+		return err
+	}
+
+	return validate_features(c.Features)
+}
+
+//==============================================================================================================================
+//	 parse_config - Applies the parameters of the JSON object passed on top of the configuration c. Unknown parameters
+//					are rejected so a misspelt name is not silently ignored.
+//==============================================================================================================================
+func parse_config(c Config, s string) (Config, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&c)
+
+	if err != nil {
+		return c, coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration: "+err.Error())
+	}
+
+	return c, validate_config(c)
+}
+
+//==============================================================================================================================
+//	 load_config - Returns the stored configuration, or the defaults when none has been stored. Sets the level of the
+//				   logger to its log_level as every invocation loads it, see check_features.
+//==============================================================================================================================
+func (t *SimpleChaincode) load_config(stub shim.ChaincodeStubInterface) (Config, error) {
+
+	c := DEFAULT_CONFIG
+
+	data, err := stub.GetState(CONFIG_KEY)
+
+	if err != nil {
+		log_errorf(stub, "LOAD_CONFIG: Error retrieving configuration: %s", err)
+		return c, wrap_error(CODE_LEDGER_ERROR, "Error retrieving configuration "+CONFIG_KEY, err)
+	}
+
+	if data == nil {
+		set_log_level(c.LogLevel)
+		return c, nil
+	}
+
+	err = json.Unmarshal(data, &c)
+
+	if err != nil {
+		return c, errors.New("Corrupt configuration " + string(data))
+	}
+
+	set_log_level(c.LogLevel) // validated when stored
+
+	return c, nil
+}
+
+//==============================================================================================================================
+//	 put_config - Validates and stores the configuration, recording the change from the previous one.
+//==============================================================================================================================
+func (t *SimpleChaincode) put_config(stub shim.ChaincodeStubInterface, c Config) error {
+
+	err := validate_config(c)
+
+	if err != nil {
+		return err
+	}
+
+	previous, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	err = t.record_config_change(stub, previous, c)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c)
+
+	if err != nil {
+		log_errorf(stub, "PUT_CONFIG: Error converting configuration: %s", err)
+		return wrap_error(CODE_ERROR, "Error converting configuration", err)
+	}
+
+	err = stub.PutState(CONFIG_KEY, data)
+
+	if err != nil {
+		log_errorf(stub, "PUT_CONFIG: Error storing configuration: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing configuration "+CONFIG_KEY, err)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 record_config_change - Stores the audit record of the configuration change made by the current transaction.
+//==============================================================================================================================
+func (t *SimpleChaincode) record_config_change(stub shim.ChaincodeStubInterface, previous Config, c Config) error {
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_CONFIG_CHANGE: Error reading caller identity: %s", err)
+		return wrap_error(CODE_NOT_AUTHORIZED, "Error reading caller identity", err)
+	}
+
+	change := Config_Change{Timestamp: now.Format(TIME_LAYOUT), TxID: stub.GetTxID(), Actor: actor, Previous: previous, Config: c}
+
+	data, err := json.Marshal(change)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_CONFIG_CHANGE: Error converting configuration change: %s", err)
+		return wrap_error(CODE_ERROR, "Error converting configuration change", err)
+	}
+
+	err = stub.PutState(index_key(CONFIG_CHANGE_PREFIX, change.Timestamp, change.TxID), data)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_CONFIG_CHANGE: Error storing configuration change: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing configuration change "+index_key(CONFIG_CHANGE_PREFIX, change.Timestamp, change.TxID), err)
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 set_config - Admin function applying the parameters of the JSON object passed to the stored configuration.
+//=================================================================================================================================
+func (t *SimpleChaincode) set_config(stub shim.ChaincodeStubInterface, s string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("SET_CONFIG", err)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("SET_CONFIG", err)
+	}
+
+	c, err = parse_config(c, s)
+
+	if err != nil {
+		return nil, prefix_error("SET_CONFIG", err)
+	}
+
+	err = t.put_config(stub, c)
+
+	if err != nil {
+		return nil, prefix_error("SET_CONFIG", err)
+	}
+
+	return json.Marshal(c)
+}
+
+//=================================================================================================================================
+//	 get_config - Admin function returning the configuration in effect.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_config(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_CONFIG", err)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_CONFIG", err)
+	}
+
+	return json.Marshal(c)
+}
+
+//=================================================================================================================================
+//	 get_config_changes - Admin function returning the audit records of every configuration change, oldest first.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_config_changes(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_CONFIG_CHANGES", err)
+	}
+
+	changes := []Config_Change{}
+
+	err = t.list_references(stub, CONFIG_CHANGE_PREFIX, func(data []byte) error {
+		var change Config_Change
+		err := json.Unmarshal(data, &change)
+		changes = append(changes, change)
+		return err
+	})
+
+	if err != nil {
+		return nil, prefix_error("GET_CONFIG_CHANGES", err)
+	}
+
+	return json.Marshal(changes)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Cross Channel References - Municipalities running on separate channels hand a bond over in two transactions since a
+//								chaincode can only read, not write, another channel:
+//
+//		1. export_bond on the source channel locks the bond for the target channel.
+//		2. import_bond on the target channel reads the locked bond from the source and records a Bond_Reference to it.
+//
+//	 Handing it back works the same way round, release_bond_reference on the target marks the reference released and
+//	 reclaim_bond on the source checks that before unlocking the bond. All four are regulator functions.
+//==============================================================================================================================
+
+const BOND_REFERENCE_PREFIX = "bond_ref"
+
+//==============================================================================================================================
+//	 Bond_Reference - A bond held on another channel, as it was when imported.
+//==============================================================================================================================
+type Bond_Reference struct {
+	RealEstateID    string `json:"real_estate_id"`
+	SourceChannel   string `json:"source_channel"`
+	SourceChaincode string `json:"source_chaincode"`
+	Bond            Bond   `json:"bond"`
+	ImportedTx      string `json:"imported_tx"`
+	Released        bool   `json:"released"`
+}
+
+//==============================================================================================================================
+//	 retrieve_bond_for_update - Retrieves a bond about to be changed, refusing bonds locked by export_bond or wrap_bond
+//								and bonds changed since the caller read them, see check_version.
+//==============================================================================================================================
+func (t *SimpleChaincode) retrieve_bond_for_update(stub shim.ChaincodeStubInterface, realEstateID string) (Bond, error) {
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return b, err
+	}
+
+	if b.ExportedTo != "" {
+		return b, coded_error(CODE_INVALID_STATE, "Bond "+realEstateID+" is exported to channel "+b.ExportedTo+" and cannot be changed")
+	}
+
+	if b.TokenID != "" {
+		return b, coded_error(CODE_INVALID_STATE, "Bond "+realEstateID+" is wrapped as token "+b.TokenID+" and cannot be changed")
+	}
+
+	return b, t.check_version(stub, b)
+}
+
+//=================================================================================================================================
+//	 export_bond - Locks the bond so it can be imported on the target channel.
+//=================================================================================================================================
+func (t *SimpleChaincode) export_bond(stub shim.ChaincodeStubInterface, b Bond, channel string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("EXPORT_BOND", err)
+	}
+
+	if channel == "" || channel == stub.GetChannelID() {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "EXPORT_BOND: Invalid target channel "+channel)
+	}
+
+	b.ExportedTo = channel
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "EXPORT_BOND: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 import_bond - Records a reference to a bond exported to this channel by the source chaincode on the source channel.
+//=================================================================================================================================
+func (t *SimpleChaincode) import_bond(stub shim.ChaincodeStubInterface, realEstateID string, channel string, chaincode string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("IMPORT_BOND", err)
+	}
+
+	_, local, err := t.get_stored_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "IMPORT_BOND: Error checking for a local bond", err)
+	}
+
+	if local {
+		return nil, coded_error(CODE_BOND_EXISTS, "IMPORT_BOND: Bond "+realEstateID+" is registered on this channel")
+	}
+
+	ref, found, err := t.get_reference_record(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("IMPORT_BOND", err)
+	}
+
+	if found && !ref.Released {
+		return nil, coded_error(CODE_INVALID_STATE, "IMPORT_BOND: Bond "+realEstateID+" is already imported")
+	}
+
+	var caller Chaincode_Caller = Chaincode_Target{Name: chaincode, Channel: channel, Attempts: 1}
+
+	bytes, err := caller.Call(stub, "get_bond_details", realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("IMPORT_BOND", err)
+	}
+
+	var b Bond
+
+	err = json.Unmarshal(bytes, &b)
+
+	if err != nil {
+		return nil, errors.New("IMPORT_BOND: Corrupt bond record " + string(bytes))
+	}
+
+	if b.ExportedTo != stub.GetChannelID() {
+		return nil, coded_error(CODE_INVALID_STATE, "IMPORT_BOND: Bond "+realEstateID+" is not exported to this channel")
+	}
+
+	ref = Bond_Reference{
+		RealEstateID:    realEstateID,
+		SourceChannel:   channel,
+		SourceChaincode: chaincode,
+		Bond:            b,
+		ImportedTx:      stub.GetTxID(),
+	}
+
+	err = t.put_reference(stub, BOND_REFERENCE_PREFIX, realEstateID, ref)
+
+	if err != nil {
+		return nil, prefix_error("IMPORT_BOND", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 release_bond_reference - Marks the reference released so the source channel can reclaim the bond.
+//=================================================================================================================================
+func (t *SimpleChaincode) release_bond_reference(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("RELEASE_BOND_REFERENCE", err)
+	}
+
+	ref, found, err := t.get_reference_record(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("RELEASE_BOND_REFERENCE", err)
+	}
+
+	if !found || ref.Released {
+		return nil, coded_error(CODE_INVALID_STATE, "RELEASE_BOND_REFERENCE: No active reference to bond "+realEstateID)
+	}
+
+	ref.Released = true
+
+	err = t.put_reference(stub, BOND_REFERENCE_PREFIX, realEstateID, ref)
+
+	if err != nil {
+		return nil, prefix_error("RELEASE_BOND_REFERENCE", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 reclaim_bond - Unlocks an exported bond once the target chaincode on the target channel has released its reference.
+//=================================================================================================================================
+func (t *SimpleChaincode) reclaim_bond(stub shim.ChaincodeStubInterface, b Bond, chaincode string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("RECLAIM_BOND", err)
+	}
+
+	if b.ExportedTo == "" {
+		return nil, coded_error(CODE_INVALID_STATE, "RECLAIM_BOND: Bond "+b.RealEstateID+" is not exported")
+	}
+
+	var caller Chaincode_Caller = Chaincode_Target{Name: chaincode, Channel: b.ExportedTo, Attempts: 1}
+
+	bytes, err := caller.Call(stub, "get_bond_reference", b.RealEstateID)
+
+	if err != nil {
+		return nil, prefix_error("RECLAIM_BOND", err)
+	}
+
+	var ref Bond_Reference
+
+	err = json.Unmarshal(bytes, &ref)
+
+	if err != nil {
+		return nil, errors.New("RECLAIM_BOND: Corrupt bond reference " + string(bytes))
+	}
+
+	if !ref.Released {
+		return nil, coded_error(CODE_INVALID_STATE, "RECLAIM_BOND: Bond "+b.RealEstateID+" is still referenced on channel "+b.ExportedTo)
+	}
+
+	b.ExportedTo = ""
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "RECLAIM_BOND: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 get_bond_reference - Returns the reference to a bond imported from another channel.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bond_reference(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	ref, found, err := t.get_reference_record(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("GET_BOND_REFERENCE", err)
+	}
+
+	if !found {
+		return nil, coded_error(CODE_BOND_NOT_FOUND, "GET_BOND_REFERENCE: No reference to bond "+realEstateID)
+	}
+
+	return json.Marshal(ref)
+}
+
+//==============================================================================================================================
+//	 get_reference_record - Reads the reference to the bond. Returns false when there is none.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_reference_record(stub shim.ChaincodeStubInterface, realEstateID string) (Bond_Reference, bool, error) {
+
+	var ref Bond_Reference
+
+	found, err := t.get_reference(stub, BOND_REFERENCE_PREFIX, realEstateID, &ref)
+
+	return ref, found, err
+}
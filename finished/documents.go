@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Documents - Deeds, surveys, blueprints and other papers of a bond are kept off-chain and anchored to it by their
+//				 sha256, so a copy can be checked against the ledger. A bond can hold several documents of a type,
+//				 each stored under document~realEstateID~docType~sha256.
+//
+//				 The required_documents of the configuration names, per function changing a bond, the document types
+//				 the bond must hold before the change is allowed, e.g. {"tranfer_bond": ["deed", "survey"]}. Types
+//				 also listed in signed_documents only count when signed, see signatures.go.
+//==============================================================================================================================
+
+const DOCUMENT_PREFIX = "document"
+
+const MAX_DOCUMENT_URI_LENGTH = 2048
+
+var DOCUMENT_TYPE = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+var SHA256_HEX = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Functions that can require documents, each taking the realEstateID as its first argument
+var DOCUMENT_GATED_FUNCTIONS = map[string]bool{
+	"tranfer_bond":             true,
+	"change_realestate_status": true,
+	"change_coordinates":       true,
+	"change_boundary":          true,
+	"change_district":          true,
+	"change_address":           true,
+	"export_bond":              true,
+}
+
+//==============================================================================================================================
+//	 Document - A document anchored to a bond.
+//==============================================================================================================================
+type Document struct {
+	RealEstateID string `json:"real_estate_id"`
+	DocType      string `json:"doc_type"`
+	SHA256       string `json:"sha256"`
+	URI          string `json:"uri"`
+	CID          string `json:"cid,omitempty"` // of a document on IPFS, see ipfs.go
+	AttachedAt   string `json:"attached_at"`
+	AttachedBy   string `json:"attached_by"`
+	AttachedMSP  string `json:"attached_msp"`
+	TxID         string `json:"txid"`
+
+	Signatures []Document_Signature `json:"signatures,omitempty"` // verified PKI signatures, see signatures.go
+}
+
+func document_key(realEstateID string, docType string, sha256 string) string {
+	return index_key(DOCUMENT_PREFIX, realEstateID, docType, sha256)
+}
+
+//==============================================================================================================================
+//	 validate_required_documents - Checks the required_documents of a configuration name gated functions and valid
+//								   document types, and the signed_documents valid types.
+//==============================================================================================================================
+func validate_required_documents(required map[string][]string, signed []string) error {
+
+	for _, docType := range signed {
+		if !DOCUMENT_TYPE.MatchString(docType) {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, invalid signed document type "+docType)
+		}
+	}
+
+	for function, types := range required {
+
+		if !DOCUMENT_GATED_FUNCTIONS[function] {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, required_documents cannot gate "+function)
+		}
+
+		for _, docType := range types {
+			if !DOCUMENT_TYPE.MatchString(docType) {
+				return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, invalid document type "+docType)
+			}
+		}
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 attach_document - Anchors a document to the bond. The sha256 is the hex digest of the document's content and the uri
+//					   where it can be fetched, a URL or the CID of the document on IPFS.
+//=================================================================================================================================
+func (t *SimpleChaincode) attach_document(stub shim.ChaincodeStubInterface, realEstateID string, docType string, sha256 string, uri string) ([]byte, error) {
+
+	sha256 = strings.ToLower(sha256)
+
+	err := validate_document(docType, sha256, uri).to_error("ATTACH_DOCUMENT", "document")
+
+	if err != nil {
+		return nil, err
+	}
+
+	content_id, path, ipfs := ipfs_uri(uri)
+
+	if ipfs {
+
+		c, _ := parse_ipfs_cid(content_id) // checked by validate_document
+
+		if c.Codec == CODEC_RAW && c.HashCode == MULTIHASH_SHA2_256 && path == "" && hex.EncodeToString(c.Digest) != sha256 {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, "ATTACH_DOCUMENT: The sha256 does not match the digest of the raw block "+content_id)
+		}
+
+		uri = IPFS_SCHEME + content_id + path
+
+	} else {
+		content_id = ""
+	}
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("ATTACH_DOCUMENT", err)
+	}
+
+	key := document_key(b.RealEstateID, docType, sha256)
+
+	existing, err := stub.GetState(key)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT: Error reading document: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "ATTACH_DOCUMENT: Error reading document "+key, err)
+	}
+
+	if existing != nil {
+		return nil, coded_error(CODE_INVALID_STATE, "ATTACH_DOCUMENT: The "+docType+" "+sha256+" is already attached to "+b.RealEstateID)
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("ATTACH_DOCUMENT", err)
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT: Error reading caller identity: %s", err)
+		return nil, wrap_error(CODE_NOT_AUTHORIZED, "ATTACH_DOCUMENT: Error reading caller identity", err)
+	}
+
+	msp, err := caller_msp(stub)
+
+	if err != nil {
+		return nil, prefix_error("ATTACH_DOCUMENT", err)
+	}
+
+	d := Document{
+		RealEstateID: b.RealEstateID,
+		DocType:      docType,
+		SHA256:       sha256,
+		URI:          uri,
+		CID:          content_id,
+		AttachedAt:   now.Format(TIME_LAYOUT),
+		AttachedBy:   actor,
+		AttachedMSP:  msp,
+		TxID:         stub.GetTxID(),
+	}
+
+	bytes, err := json.Marshal(d)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT: Error converting document: %s", err)
+		return nil, wrap_error(CODE_ERROR, "ATTACH_DOCUMENT: Error converting document", err)
+	}
+
+	err = stub.PutState(key, bytes)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT: Error storing document: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "ATTACH_DOCUMENT: Error storing document "+key, err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 get_stored_documents - Returns the documents of the bond, of the type given unless it is empty, in type and hash
+//							order.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_stored_documents(stub shim.ChaincodeStubInterface, realEstateID string, docType string) ([]Document, error) {
+
+	start := index_key(DOCUMENT_PREFIX, realEstateID) + INDEX_SEPARATOR
+
+	if docType != "" {
+		start += docType + INDEX_SEPARATOR
+	}
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_DOCUMENTS: Error querying documents: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Error querying documents", err)
+	}
+
+	defer iter.Close()
+
+	documents := []Document{}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_STORED_DOCUMENTS: Error reading documents: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "Error reading documents", err)
+		}
+
+		var d Document
+
+		err = json.Unmarshal(kv.Value, &d)
+
+		if err != nil {
+			return nil, errors.New("Corrupt document record " + string(kv.Value))
+		}
+
+		documents = append(documents, d)
+	}
+
+	return documents, nil
+}
+
+//=================================================================================================================================
+//	 get_documents - Returns the documents of the bond.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_documents(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("GET_DOCUMENTS", err)
+	}
+
+	documents, err := t.get_stored_documents(stub, b.RealEstateID, "")
+
+	if err != nil {
+		return nil, prefix_error("GET_DOCUMENTS", err)
+	}
+
+	bytes, err := json.Marshal(documents)
+
+	if err != nil {
+		log_errorf(stub, "GET_DOCUMENTS: Error converting documents: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_DOCUMENTS: Error converting documents", err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 check_required_documents - Returns an error naming the document types the bond lacks when the configuration
+//								requires them for the function. A document of a type in signed_documents is only
+//								counted once it carries a verified signature.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_required_documents(stub shim.ChaincodeStubInterface, function string, args []string) error {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	types := c.RequiredDocuments[function]
+
+	if len(types) == 0 || len(args) == 0 {
+		return nil
+	}
+
+	signed := make(map[string]bool)
+
+	for _, docType := range c.SignedDocuments {
+		signed[docType] = true
+	}
+
+	var missing []string
+
+	for _, docType := range types {
+
+		documents, err := t.get_stored_documents(stub, args[0], docType)
+
+		if err != nil {
+			return err
+		}
+
+		held := false
+
+		for _, d := range documents {
+			if !signed[docType] || len(d.Signatures) > 0 {
+				held = true
+			}
+		}
+
+		if !held && signed[docType] && len(documents) > 0 {
+			missing = append(missing, docType+" (unsigned)")
+		} else if !held {
+			missing = append(missing, docType)
+		}
+	}
+
+	if len(missing) > 0 {
+		return coded_error(CODE_INVALID_STATE, "Bond "+args[0]+" lacks the documents "+function+" requires: "+strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Document_Verification - The response of verify_document. Registered counts the documents of the type the bond holds,
+//							 telling a copy of another document from a bond holding none.
+//==============================================================================================================================
+type Document_Verification struct {
+	RealEstateID string    `json:"real_estate_id"`
+	DocType      string    `json:"doc_type"`
+	SHA256       string    `json:"sha256"`
+	Matches      bool      `json:"matches"`
+	Document     *Document `json:"document,omitempty"`
+	Registered   int       `json:"registered"`
+}
+
+//=================================================================================================================================
+//	 verify_document - Returns whether the sha256 of a presented copy is that of a document of the type attached to the
+//					   bond.
+//=================================================================================================================================
+func (t *SimpleChaincode) verify_document(stub shim.ChaincodeStubInterface, realEstateID string, docType string, sha256 string) ([]byte, error) {
+
+	sha256 = strings.ToLower(sha256)
+
+	if !SHA256_HEX.MatchString(sha256) {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "VERIFY_DOCUMENT: Invalid sha256 "+sha256+", expecting 64 hex digits")
+	}
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("VERIFY_DOCUMENT", err)
+	}
+
+	documents, err := t.get_stored_documents(stub, b.RealEstateID, docType)
+
+	if err != nil {
+		return nil, prefix_error("VERIFY_DOCUMENT", err)
+	}
+
+	v := Document_Verification{RealEstateID: b.RealEstateID, DocType: docType, SHA256: sha256, Registered: len(documents)}
+
+	for i := range documents {
+		if documents[i].SHA256 == sha256 {
+			v.Matches, v.Document = true, &documents[i]
+		}
+	}
+
+	bytes, err := json.Marshal(v)
+
+	if err != nil {
+		log_errorf(stub, "VERIFY_DOCUMENT: Error converting result: %s", err)
+		return nil, wrap_error(CODE_ERROR, "VERIFY_DOCUMENT: Error converting result", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Duplicate Detection - A parcel registered twice under slightly different realEstateIDs (e.g. 1232.021 and 1232.21)
+//						   or at the same spot is almost always a data entry mistake, so creation is refused and the
+//						   conflicting bonds listed unless the regulator explicitly forces it.
+//==============================================================================================================================
+
+const PARCEL_INDEX = "parcel"
+
+// Bonds closer than this many metres to each other are reported as probable duplicates, unless configured otherwise
+const DUPLICATE_DISTANCE = 2.0
+
+//==============================================================================================================================
+//	 Duplicate_Conflict - An existing bond that a new registration probably duplicates and why.
+//==============================================================================================================================
+type Duplicate_Conflict struct {
+	RealEstateID string `json:"real_estate_id"`
+	Reason       string `json:"reason"` // same_parcel or same_location
+}
+
+//==============================================================================================================================
+//	 normalize_parcel - Returns the canonical blueprint.parcel form of a realEstateID, with surrounding space and leading
+//						zeros removed from each number. IDs not in that form are only trimmed.
+//==============================================================================================================================
+func normalize_parcel(realEstateID string) string {
+
+	id := strings.TrimSpace(realEstateID)
+	parts := strings.Split(id, ".")
+
+	if len(parts) != 2 {
+		return id
+	}
+
+	for i, part := range parts {
+
+		part = strings.TrimSpace(part)
+
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return id
+		}
+
+		if part = strings.TrimLeft(part, "0"); part == "" {
+			part = "0"
+		}
+
+		parts[i] = part
+	}
+
+	return parts[0] + "." + parts[1]
+}
+
+//==============================================================================================================================
+//	 find_duplicates - Returns the existing bonds with the same normalised parcel number or within the configured
+//					   duplicate distance of the new bond.
+//==============================================================================================================================
+func (t *SimpleChaincode) find_duplicates(stub shim.ChaincodeStubInterface, b Bond) ([]Duplicate_Conflict, error) {
+
+	conflicts := []Duplicate_Conflict{}
+	seen := make(map[string]bool)
+
+	ids, err := t.scan_index(stub, PARCEL_INDEX, normalize_parcel(b.RealEstateID)+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if id != b.RealEstateID && !seen[id] {
+			seen[id] = true
+			conflicts = append(conflicts, Duplicate_Conflict{RealEstateID: id, Reason: "same_parcel"})
+		}
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dLat := c.DuplicateDistance / EARTH_RADIUS * 180 / math.Pi
+	dLong := dLat / math.Max(math.Cos(b.Coordinates.Lat*math.Pi/180), 0.01)
+
+	nearby, err := t.find_bonds_in_bbox(stub,
+		math.Max(b.Coordinates.Lat-dLat, -90), math.Max(b.Coordinates.Long-dLong, -180),
+		math.Min(b.Coordinates.Lat+dLat, 90), math.Min(b.Coordinates.Long+dLong, 180))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nearby {
+		if n.RealEstateID != b.RealEstateID && !seen[n.RealEstateID] &&
+			haversine(b.Coordinates.Lat, b.Coordinates.Long, n.Coordinates.Lat, n.Coordinates.Long) <= c.DuplicateDistance {
+			seen[n.RealEstateID] = true
+			conflicts = append(conflicts, Duplicate_Conflict{RealEstateID: n.RealEstateID, Reason: "same_location"})
+		}
+	}
+
+	return conflicts, nil
+}
+
+//==============================================================================================================================
+//	 check_duplicates - Returns an error listing the conflicting bonds as JSON when the new bond is a probable duplicate.
+//						Forcing skips the check but is only allowed for the regulator.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_duplicates(stub shim.ChaincodeStubInterface, b Bond, force bool) error {
+
+	if force {
+		return t.check_admin(stub)
+	}
+
+	conflicts, err := t.find_duplicates(stub, b)
+
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	listing, err := json.Marshal(conflicts)
+
+	if err != nil {
+		return wrap_error(CODE_ERROR, "Error converting duplicate conflicts", err)
+	}
+
+	return coded_error(CODE_BOND_EXISTS, "Probable duplicate registration: "+string(listing))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 User eCerts - The ecert of each user, stored under ecert~<name>. add_ecert registers one user and add_ecerts the
+//				   users of an organisation in one transaction, all of them or, when one fails, none. A name already
+//				   registered is refused unless the mode is "overwrite", so a typo cannot silently replace the ecert
+//				   of another user. Both are admin only.
+//==============================================================================================================================
+
+const ECERT_OVERWRITE = "overwrite"
+
+// Most users registered in one call of add_ecerts
+const MAX_BULK_ECERTS = 100
+
+//==============================================================================================================================
+//	 User_and_eCert - A user and their ecert, a record of the JSON array add_ecerts takes.
+//==============================================================================================================================
+type User_and_eCert struct {
+	Identity string `json:"identity"`
+	ECert    string `json:"ecert"`
+}
+
+//==============================================================================================================================
+//	 Ecert_Response - The response of add_ecert and add_ecerts.
+//==============================================================================================================================
+type Ecert_Response struct {
+	Registered  int      `json:"registered"`
+	Overwritten []string `json:"overwritten"` // names whose previous ecert was replaced
+}
+
+//==============================================================================================================================
+//	 get_ecert - Returns the ecert stored for the user, empty when there is none.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_ecert(stub shim.ChaincodeStubInterface, name string) ([]byte, error) {
+
+	ecert, err := stub.GetState(ecert_key(name))
+
+	if err != nil {
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Couldn't retrieve ecert for user "+name, err)
+	}
+
+	return ecert, nil
+}
+
+//==============================================================================================================================
+//	 add_ecert - Registers the ecert of the user, replacing a registered one only when mode is ECERT_OVERWRITE.
+//==============================================================================================================================
+func (t *SimpleChaincode) add_ecert(stub shim.ChaincodeStubInterface, name string, ecert string, mode string) ([]byte, error) {
+	return t.register_ecerts(stub, "ADD_ECERT", []User_and_eCert{{Identity: name, ECert: ecert}}, mode)
+}
+
+//==============================================================================================================================
+//	 add_ecerts - Registers the users of the JSON array of User_and_eCert records, replacing registered ones only when
+//				  mode is ECERT_OVERWRITE.
+//==============================================================================================================================
+func (t *SimpleChaincode) add_ecerts(stub shim.ChaincodeStubInterface, records string, mode string) ([]byte, error) {
+
+	var users []User_and_eCert
+
+	err := json.Unmarshal([]byte(records), &users)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ADD_ECERTS: Invalid JSON array of users: "+err.Error())
+	}
+
+	if len(users) == 0 || len(users) > MAX_BULK_ECERTS {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ADD_ECERTS: Expecting 1 to "+strconv.Itoa(MAX_BULK_ECERTS)+" users, got "+strconv.Itoa(len(users)))
+	}
+
+	return t.register_ecerts(stub, "ADD_ECERTS", users, mode)
+}
+
+//==============================================================================================================================
+//	 register_ecerts - Checks every user before writing any, so a failure leaves the registered ecerts as they were.
+//==============================================================================================================================
+func (t *SimpleChaincode) register_ecerts(stub shim.ChaincodeStubInterface, caller string, users []User_and_eCert, mode string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error(caller, err)
+	}
+
+	if mode != "" && mode != ECERT_OVERWRITE {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, caller+": Unknown mode "+mode+", expecting "+ECERT_OVERWRITE+" or nothing")
+	}
+
+	response := Ecert_Response{Overwritten: []string{}}
+	seen := make(map[string]bool)
+
+	for i, u := range users {
+
+		user := caller + ": User " + strconv.Itoa(i) + " (" + u.Identity + ")"
+
+		if strings.TrimSpace(u.Identity) == "" || strings.Contains(u.Identity, INDEX_SEPARATOR) {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, user+": Expecting a non-empty name without "+INDEX_SEPARATOR)
+		}
+
+		if strings.TrimSpace(u.ECert) == "" {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, user+": Expecting a non-empty ecert")
+		}
+
+		if seen[u.Identity] {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, user+": Name repeated in the batch")
+		}
+
+		seen[u.Identity] = true
+
+		existing, err := t.get_ecert(stub, u.Identity)
+
+		if err != nil {
+			return nil, prefix_error(user, err)
+		}
+
+		if existing != nil {
+
+			if mode != ECERT_OVERWRITE {
+				return nil, coded_error(CODE_INVALID_STATE, user+": Already registered, pass "+ECERT_OVERWRITE+" to replace the ecert")
+			}
+
+			response.Overwritten = append(response.Overwritten, u.Identity)
+		}
+	}
+
+	for _, u := range users {
+
+		err = stub.PutState(ecert_key(u.Identity), []byte(u.ECert))
+
+		if err != nil {
+			log_errorf(stub, "%s: Error storing ecert for user %s: %s", caller, u.Identity, err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, caller+": Error storing ecert for user "+u.Identity+" "+ecert_key(u.Identity), err)
+		}
+
+		response.Registered++
+	}
+
+	log_infof(stub, "%s: Registered %d users, overwrote %d", caller, response.Registered, len(response.Overwritten))
+
+	bytes, err := json.Marshal(response)
+
+	if err != nil {
+		log_errorf(stub, "%s: Error converting response: %s", caller, err)
+		return nil, wrap_error(CODE_ERROR, caller+": Error converting response", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/statebased"
+)
+
+//==============================================================================================================================
+//	 State Based Endorsement - Every bond key carries its own endorsement policy requiring a peer of the regulator's
+//							   organisation and a peer of the owner's organisation. A change to the bond, a transfer
+//							   included, must then be endorsed by both, whatever the chaincode level policy allows.
+//==============================================================================================================================
+
+// MSP ID of the regulator's organisation unless configured otherwise
+const REGULATOR_MSP = "RegulatorMSP"
+
+//==============================================================================================================================
+//	 caller_msp - Returns the MSP ID of the organisation of the client that submitted the transaction.
+//==============================================================================================================================
+func caller_msp(stub shim.ChaincodeStubInterface) (string, error) {
+
+	msp, err := cid.GetMSPID(stub)
+
+	if err != nil {
+		log_errorf(stub, "CALLER_MSP: Error reading caller MSP ID: %s", err)
+		return "", wrap_error(CODE_NOT_AUTHORIZED, "Error reading caller MSP ID", err)
+	}
+
+	return msp, nil
+}
+
+//==============================================================================================================================
+//	 set_bond_endorsement - Sets the endorsement policy of the bond key to the regulator's organisation and the owner's
+//							organisation. Bonds registered before owners had an organisation need the regulator only.
+//==============================================================================================================================
+func (t *SimpleChaincode) set_bond_endorsement(stub shim.ChaincodeStubInterface, b Bond) error {
+
+	ep, err := statebased.NewStateEP(nil)
+
+	if err != nil {
+		log_errorf(stub, "SET_BOND_ENDORSEMENT: Error creating endorsement policy: %s", err)
+		return wrap_error(CODE_ERROR, "Error creating endorsement policy", err)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	orgs := []string{c.RegulatorMSP}
+
+	if b.OwnerMSP != "" && b.OwnerMSP != c.RegulatorMSP {
+		orgs = append(orgs, b.OwnerMSP)
+	}
+
+	err = ep.AddOrgs(statebased.RoleTypePeer, orgs...)
+
+	if err != nil {
+		log_errorf(stub, "SET_BOND_ENDORSEMENT: Error adding organisations to endorsement policy: %s", err)
+		return wrap_error(CODE_ERROR, "Error adding organisations to endorsement policy", err)
+	}
+
+	policy, err := ep.Policy()
+
+	if err != nil {
+		log_errorf(stub, "SET_BOND_ENDORSEMENT: Error building endorsement policy: %s", err)
+		return wrap_error(CODE_ERROR, "Error building endorsement policy", err)
+	}
+
+	err = stub.SetStateValidationParameter(bond_key(b.RealEstateID), policy)
+
+	if err != nil {
+		log_errorf(stub, "SET_BOND_ENDORSEMENT: Error setting endorsement policy: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error setting endorsement policy", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+)
+
+//==============================================================================================================================
+//	 Error Codes - Errors clients are expected to act on carry one of the codes below, returned in the code field of the
+//				   response envelope. Any other error has the code ERROR. Prefixing the function name onto an error
+//				   with prefix_error keeps its code, errors.New does not.
+//
+//		BOND_NOT_FOUND	 - no bond with the realEstateID given.
+//		BOND_EXISTS		 - the bond, or probably the same parcel under another number, is already registered.
+//		NOT_AUTHORIZED	 - the caller's role does not allow the function.
+//		INVALID_STATE	 - the function does not apply to the bond or registry as it stands, e.g. changing an
+//						   exported bond or calling a function whose feature is disabled.
+//		VERSION_CONFLICT - the bond has been changed since the version the caller expected.
+//		INVALID_ARGUMENT - an argument is missing or malformed.
+//		UNKNOWN_FUNCTION - no function of that name.
+//		LEDGER_ERROR	 - the peer failed to read or write the ledger or the transaction, retrying may succeed.
+//		CORRUPT_RECORD	 - a record is stored but cannot be read back, e.g. a bond that no longer converts. Unlike
+//						   BOND_NOT_FOUND the key is taken, and retrying will not help until the record is repaired.
+//
+//				   Errors from the shim and the libraries are wrapped with wrap_error, so the message names the
+//				   operation and the key, followed by the cause, e.g.
+//
+//		Error retrieving bond 1232.1: GET_STATE failed: transaction ID: ...
+//==============================================================================================================================
+
+const CODE_BOND_NOT_FOUND = "BOND_NOT_FOUND"
+const CODE_BOND_EXISTS = "BOND_EXISTS"
+const CODE_NOT_AUTHORIZED = "NOT_AUTHORIZED"
+const CODE_INVALID_STATE = "INVALID_STATE"
+const CODE_VERSION_CONFLICT = "VERSION_CONFLICT"
+const CODE_INVALID_ARGUMENT = "INVALID_ARGUMENT"
+const CODE_UNKNOWN_FUNCTION = "UNKNOWN_FUNCTION"
+const CODE_LEDGER_ERROR = "LEDGER_ERROR"
+const CODE_CORRUPT_RECORD = "CORRUPT_RECORD"
+
+//==============================================================================================================================
+//	 Chaincode_Error - An error carrying its code and, when it wraps another error, the cause.
+//==============================================================================================================================
+type Chaincode_Error struct {
+	Code    string
+	Message string
+	Cause   error
+}
+
+func (e Chaincode_Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the cause, for errors.Is and errors.As on Go 1.13 and later
+func (e Chaincode_Error) Unwrap() error {
+	return e.Cause
+}
+
+//==============================================================================================================================
+//	 coded_error - Returns an error with the code and message given.
+//==============================================================================================================================
+func coded_error(code string, message string) error {
+	return Chaincode_Error{Code: code, Message: message}
+}
+
+//==============================================================================================================================
+//	 wrap_error - Returns an error with the message, followed by that of the cause, and the code given unless the cause
+//				  has its own.
+//==============================================================================================================================
+func wrap_error(code string, message string, err error) error {
+
+	if e, ok := err.(Chaincode_Error); ok {
+		code = e.Code
+	}
+
+	return Chaincode_Error{Code: code, Message: message + ": " + err.Error(), Cause: err}
+}
+
+//==============================================================================================================================
+//	 bond_not_found - Returns the BOND_NOT_FOUND error of a realEstateID no bond is stored for.
+//==============================================================================================================================
+func bond_not_found(realEstateID string) error {
+	return coded_error(CODE_BOND_NOT_FOUND, "No bond with realEstateID = "+realEstateID)
+}
+
+//==============================================================================================================================
+//	 corrupt_record - Returns the CORRUPT_RECORD error of the record stored under key, which did not convert. The code is
+//					  kept whatever the cause, as a record failing validation on the way in is an argument error.
+//==============================================================================================================================
+func corrupt_record(record string, key string, err error) error {
+	return Chaincode_Error{Code: CODE_CORRUPT_RECORD, Message: "Corrupt " + record + " record " + key + ": " + err.Error(), Cause: err}
+}
+
+//==============================================================================================================================
+//	 prefix_error - Returns the error with the prefix, usually the name of the function, in front of its message and
+//					the same code.
+//==============================================================================================================================
+func prefix_error(prefix string, err error) error {
+
+	if e, ok := err.(Chaincode_Error); ok {
+		return Chaincode_Error{Code: e.Code, Message: prefix + ": " + e.Message, Cause: e.Cause}
+	}
+
+	return errors.New(prefix + ": " + err.Error())
+}
+
+//==============================================================================================================================
+//	 error_code - Returns the code of the error, ERROR when it has none.
+//==============================================================================================================================
+func error_code(err error) string {
+
+	if e, ok := err.(Chaincode_Error); ok {
+		return e.Code
+	}
+
+	return CODE_ERROR
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Events - Chaincode events let client applications and off-chain listeners follow the registry without polling.
+//			  Fabric keeps a single event per transaction so each function emits at most one, after its changes are
+//			  written. Every event is wrapped in the same Event_Envelope.
+//
+//			  The routing of an envelope lets notification services pick the SMS or email to send without querying
+//			  the ledger. Owners are named by owner_routing_hash, the sha256 of "owner:" and the national ID, which a
+//			  service computes for each of its subscribers to match them.
+//==============================================================================================================================
+
+const BOND_CREATED_EVENT = "BondCreated"
+const BOND_TRANSFERRED_EVENT = "BondTransferred"
+const BONDS_CREATED_EVENT = "BondsCreated" // of create_bonds_bulk, the payload is the list of bonds and bond_id is empty
+
+// Severities of events, from the least urgent
+const SEVERITY_INFO = "info"     // the registry grew
+const SEVERITY_NOTICE = "notice" // a bond changed
+const SEVERITY_ALERT = "alert"   // a bond changed owner, which the owners should hear of at once
+
+var EVENT_SEVERITY = map[string]string{
+	BOND_CREATED_EVENT:     SEVERITY_NOTICE,
+	BOND_TRANSFERRED_EVENT: SEVERITY_ALERT,
+	BONDS_CREATED_EVENT:    SEVERITY_INFO,
+
+	BOND_WRAPPED_EVENT:             SEVERITY_NOTICE,
+	BOND_UNWRAPPED_EVENT:           SEVERITY_NOTICE,
+	IDENTITY_CHECK_REQUESTED_EVENT: SEVERITY_NOTICE,
+}
+
+// Version of the event envelope and payloads. Raised on changes that would break consumers, adding fields does not.
+const EVENT_SCHEMA_VERSION = 1
+
+//==============================================================================================================================
+//	 Event_Envelope - The JSON body of every chaincode event. Actor is the client identity that submitted the transaction
+//					  and Payload depends on the event type.
+//==============================================================================================================================
+type Event_Envelope struct {
+	EventType     string        `json:"event_type"`
+	SchemaVersion int           `json:"schema_version"`
+	BondID        string        `json:"bond_id"`
+	Actor         string        `json:"actor"`
+	TxID          string        `json:"txid"`
+	Payload       interface{}   `json:"payload"`
+	Routing       Event_Routing `json:"routing"`
+	Tenant        string        `json:"tenant,omitempty"` // see tenants.go
+}
+
+//==============================================================================================================================
+//	 Event_Routing - Who and where the event concerns, each list sorted and without repeats.
+//==============================================================================================================================
+type Event_Routing struct {
+	OwnerHashes []string `json:"owner_hashes"`
+	Districts   []string `json:"districts"`
+	Severity    string   `json:"severity"`
+}
+
+//==============================================================================================================================
+//	 owner_routing_hash - Returns the hash naming the owner in the routing of events.
+//==============================================================================================================================
+func owner_routing_hash(nationalID string) string {
+
+	sum := sha256.Sum256([]byte("owner:" + nationalID))
+
+	return hex.EncodeToString(sum[:])
+}
+
+//==============================================================================================================================
+//	 event_routing - Returns the routing of an event concerning the owners and districts given, ignoring empty ones.
+//==============================================================================================================================
+func event_routing(owners []string, districts []string) Event_Routing {
+
+	r := Event_Routing{OwnerHashes: []string{}, Districts: []string{}}
+
+	seen := make(map[string]bool)
+
+	for _, owner := range owners {
+		if owner != "" && !seen["owner:"+owner] {
+			seen["owner:"+owner] = true
+			r.OwnerHashes = append(r.OwnerHashes, owner_routing_hash(owner))
+		}
+	}
+
+	for _, district := range districts {
+		if district != "" && !seen["district:"+district] {
+			seen["district:"+district] = true
+			r.Districts = append(r.Districts, district)
+		}
+	}
+
+	sort.Strings(r.OwnerHashes)
+	sort.Strings(r.Districts)
+
+	return r
+}
+
+//==============================================================================================================================
+//	 emit_event - Sets the event of the current transaction, wrapping the payload in the event envelope with the routing
+//				  given and the severity of the event.
+//==============================================================================================================================
+func (t *SimpleChaincode) emit_event(stub shim.ChaincodeStubInterface, name string, bondID string, payload interface{}, routing Event_Routing) error {
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "EMIT_EVENT: Error reading caller identity: %s", err)
+		return wrap_error(CODE_NOT_AUTHORIZED, "Error reading caller identity", err)
+	}
+
+	routing.Severity = EVENT_SEVERITY[name]
+
+	envelope := Event_Envelope{
+		EventType:     name,
+		SchemaVersion: EVENT_SCHEMA_VERSION,
+		BondID:        bondID,
+		Actor:         actor,
+		TxID:          stub.GetTxID(),
+		Payload:       payload,
+		Routing:       routing,
+		Tenant:        stub_tenant(stub),
+	}
+
+	bytes, err := json.Marshal(envelope)
+
+	if err != nil {
+		log_errorf(stub, "EMIT_EVENT: Error converting %s payload: %s", name, err)
+		return wrap_error(CODE_ERROR, "Error converting "+name+" event payload", err)
+	}
+
+	err = stub.SetEvent(name, bytes)
+
+	if err != nil {
+		log_errorf(stub, "EMIT_EVENT: Error setting %s event: %s", name, err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error setting "+name+" event", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Export Functions - Paged access to complete bond records so off-chain systems can reconcile their copies against
+//						the ledger.
+//==============================================================================================================================
+
+// Largest page export_bonds will return unless configured otherwise
+const MAX_PAGE_SIZE = 1000
+
+//==============================================================================================================================
+//	 Exported_Bond - A bond together with the keys of the index entries that point to it and its hash, the leaf used
+//					 for it by get_registry_checksum.
+//==============================================================================================================================
+type Exported_Bond struct {
+	Bond    Bond     `json:"bond"`
+	Indexes []string `json:"indexes"`
+	Hash    string   `json:"hash"`
+}
+
+//==============================================================================================================================
+//	 Export_Page - The response of export_bonds. Bookmark is empty once the last page has been returned.
+//==============================================================================================================================
+type Export_Page struct {
+	Bonds    []Exported_Bond `json:"bonds"`
+	Bookmark string          `json:"bookmark"`
+	Skipped  []string        `json:"skipped,omitempty"` // bonds of the page left out, see get_skipped_bonds
+}
+
+//==============================================================================================================================
+//	 bond_index_keys - Returns the keys of every index entry the bond should have.
+//==============================================================================================================================
+func bond_index_keys(b Bond) []string {
+
+	keys := []string{
+		index_key(OWNER_INDEX, b.OwnerNationalID, b.RealEstateID),
+		index_key(STATUS_INDEX, b.Status.String(), b.RealEstateID),
+		index_key(PARCEL_INDEX, normalize_parcel(b.RealEstateID), b.RealEstateID),
+	}
+
+	if attributes, ok := blueprint_attributes(b.RealEstateID); ok {
+		keys = append(keys, index_key(BLUEPRINT_INDEX, attributes...))
+	}
+
+	if b.Geohash != "" {
+		keys = append(keys, index_key(GEOHASH_INDEX, b.Geohash, b.RealEstateID))
+	}
+
+	for _, cell := range boundary_cells(b.Boundary) {
+		keys = append(keys, index_key(BOUNDARY_INDEX, cell, b.RealEstateID))
+	}
+
+	if b.CityCode != "" {
+		keys = append(keys, index_key(ADDRESS_INDEX, address_attributes(b)...))
+	}
+
+	if b.UpdatedAt != "" {
+		keys = append(keys, index_key(MODIFIED_INDEX, b.UpdatedAt, b.RealEstateID))
+	}
+
+	if b.Provenance != nil {
+		keys = append(keys, index_key(MIGRATION_INDEX, b.Provenance.MigrationBatch, b.RealEstateID), index_key(LEGACY_DEED_INDEX, b.Provenance.LegacyDeedNumber, b.RealEstateID))
+	}
+
+	return keys
+}
+
+//==============================================================================================================================
+//	 bond_page - Returns the realEstateIDs of up to pageSize bonds after the bookmark of the query, and the bookmark of
+//				 the next page, empty after the last. Used by the paged exports. The page is read by range from the
+//				 bookmark's key and the scan stops one bond past the page, so each page costs the same however far
+//				 into the ledger it is. Bonds still listed only in bondIDs are merged in, see get_legacy_bond_ids.
+//==============================================================================================================================
+func (t *SimpleChaincode) bond_page(stub shim.ChaincodeStubInterface, query string, pageSize int, bookmark string) ([]string, string, error) {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pageSize < 1 || pageSize > c.MaxPageSize {
+		return nil, "", coded_error(CODE_INVALID_ARGUMENT, "Page size must be between 1 and "+strconv.Itoa(c.MaxPageSize))
+	}
+
+	after, err := decode_bookmark(query, bookmark)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := index_key(BOND_PREFIX, "")
+	from := start
+
+	if after != "" {
+		from = bond_key(after) + "\x00"
+	}
+
+	iter, err := stub.GetStateByRange(from, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "BOND_PAGE: Error querying bond records: %s", err)
+		return nil, "", wrap_error(CODE_LEDGER_ERROR, "Error querying bond records", err)
+	}
+
+	defer iter.Close()
+
+	found := make(map[string]bool)
+	var ids []string
+
+	for len(ids) <= pageSize && iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "BOND_PAGE: Error reading bond records: %s", err)
+			return nil, "", wrap_error(CODE_LEDGER_ERROR, "Error reading bond records", err)
+		}
+
+		found[kv.Key[len(start):]] = true
+		ids = append(ids, kv.Key[len(start):])
+	}
+
+	legacy, err := t.get_legacy_bond_ids(stub)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, id := range legacy {
+		if id > after && !found[id] {
+			found[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	if len(ids) <= pageSize {
+		return ids, "", nil
+	}
+
+	return ids[:pageSize], encode_bookmark(query, ids[pageSize-1]), nil
+}
+
+//=================================================================================================================================
+//	 export_bonds - Returns up to pageSize bonds ordered by realEstateID, starting after the bookmark returned with the
+//					previous page. An empty bookmark starts from the first bond.
+//=================================================================================================================================
+func (t *SimpleChaincode) export_bonds(stub shim.ChaincodeStubInterface, pageSize int, bookmark string) ([]byte, error) {
+
+	ids, next, err := t.bond_page(stub, "export_bonds", pageSize, bookmark)
+
+	if err != nil {
+		return nil, prefix_error("EXPORT_BONDS", err)
+	}
+
+	bonds, skipped, err := t.retrieve_bonds_skipping(stub, "EXPORT_BONDS", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := Export_Page{Bonds: []Exported_Bond{}, Bookmark: next, Skipped: skipped}
+
+	for _, b := range bonds {
+
+		hash, err := bond_hash(b)
+
+		if err != nil {
+			return nil, prefix_error("EXPORT_BONDS", err)
+		}
+
+		page.Bonds = append(page.Bonds, Exported_Bond{Bond: b, Indexes: bond_index_keys(b), Hash: hex.EncodeToString(hash)})
+	}
+
+	bytes, err := json.Marshal(page)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "EXPORT_BONDS: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Feature Flags - Behaviours that are rolled out gradually are switched on per deployment in the features map of the
+//					 configuration, e.g. set_config '{"features":{"cross_channel":true}}'. Every flag is off unless set.
+//
+//		cross_channel	  - enables export_bond, import_bond, release_bond_reference and reclaim_bond.
+//		strict_acl		  - restricts the functions changing parcel data, as opposed to ownership, to the admin role.
+//		strict_versioning - requires the expected_version of the bond with every change, see check_version.
+//		identity_oracle	  - requires a civil registry check of the recipient of a transfer, see oracle.go.
+//		token_wrapping	  - enables wrap_bond and unwrap_bond, see tokens.go.
+//==============================================================================================================================
+
+const FEATURE_CROSS_CHANNEL = "cross_channel"
+const FEATURE_STRICT_ACL = "strict_acl"
+const FEATURE_STRICT_VERSIONING = "strict_versioning"
+const FEATURE_IDENTITY_ORACLE = "identity_oracle"
+const FEATURE_TOKEN_WRAPPING = "token_wrapping"
+
+var KNOWN_FEATURES = []string{FEATURE_CROSS_CHANNEL, FEATURE_STRICT_ACL, FEATURE_STRICT_VERSIONING, FEATURE_IDENTITY_ORACLE, FEATURE_TOKEN_WRAPPING}
+
+// Functions that are only available while their feature is enabled
+var FEATURE_FUNCTIONS = map[string]string{
+	"export_bond":            FEATURE_CROSS_CHANNEL,
+	"import_bond":            FEATURE_CROSS_CHANNEL,
+	"release_bond_reference": FEATURE_CROSS_CHANNEL,
+	"reclaim_bond":           FEATURE_CROSS_CHANNEL,
+	"wrap_bond":              FEATURE_TOKEN_WRAPPING,
+	"unwrap_bond":            FEATURE_TOKEN_WRAPPING,
+}
+
+// Functions restricted to the admin role by strict_acl
+var STRICT_ACL_FUNCTIONS = map[string]bool{
+	"create_bond":              true,
+	"create_bonds_bulk":        true,
+	"change_realestate_status": true,
+	"change_coordinates":       true,
+	"change_boundary":          true,
+	"change_district":          true,
+	"change_address":           true,
+}
+
+//==============================================================================================================================
+//	 validate_features - Checks every flag set is a known feature so a misspelt flag is not silently ignored.
+//==============================================================================================================================
+func validate_features(features map[string]bool) error {
+
+	known := make(map[string]bool)
+
+	for _, f := range KNOWN_FEATURES {
+		known[f] = true
+	}
+
+	var unknown []string
+
+	for f := range features {
+		if !known[f] {
+			unknown = append(unknown, f)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, unknown feature "+unknown[0])
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 check_features - Returns an error when the function is disabled by the feature flags of the configuration, or
+//					  restricted by them to a role the caller does not have.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_features(stub shim.ChaincodeStubInterface, function string) error {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	feature, gated := FEATURE_FUNCTIONS[function]
+
+	if gated && !c.Features[feature] {
+		return coded_error(CODE_INVALID_STATE, "Function "+function+" is disabled, it requires the "+feature+" feature")
+	}
+
+	if c.Features[FEATURE_STRICT_ACL] && STRICT_ACL_FUNCTIONS[function] {
+		return t.check_admin(stub)
+	}
+
+	return nil
+}
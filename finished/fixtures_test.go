@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strconv"
+)
+
+//==============================================================================================================================
+//	 Fixtures - Builders for the records tests start from. Each builder returns a valid record that the test changes
+//				only where it matters, e.g. bond_fixture(1).owned_by(owner_fixture(2)).in("OLAYA", "King Fahd Road").
+//				Fixtures numbered differently never collide: their IDs differ and their parcels are about a kilometre
+//				apart, well clear of the duplicate and overlap checks.
+//==============================================================================================================================
+
+// City and district registered by seed_reference_data
+const TEST_CITY = "RUH"
+const TEST_DISTRICT = "OLAYA"
+
+// Commercial registration number of a company registered in Riyadh, which would pass for a citizen's national ID
+const COMPANY_CR = "1010123456"
+
+//==============================================================================================================================
+//	 Owner_Fixture - An owner as the chaincode sees one, a national ID and the MSP of the organisation they are with.
+//==============================================================================================================================
+type Owner_Fixture struct {
+	NationalID string
+	MSP        string
+}
+
+//==============================================================================================================================
+//	 owner_fixture - Returns the nth test owner.
+//==============================================================================================================================
+func owner_fixture(n int) Owner_Fixture {
+	return Owner_Fixture{NationalID: strconv.Itoa(1000000000 + n), MSP: "Org1MSP"}
+}
+
+//==============================================================================================================================
+//	 Bond_Fixture - The arguments of a create_bond call.
+//==============================================================================================================================
+type Bond_Fixture struct {
+	ID           string
+	RealEstateID string
+	Owner        Owner_Fixture
+	Status       string
+	Area         string
+	Long         string
+	Lat          string
+	Boundary     string
+	District     string
+	Street       string
+}
+
+//==============================================================================================================================
+//	 bond_fixture - Returns the nth test bond, a 500 m2 flat in Riyadh held by the first test owner.
+//==============================================================================================================================
+func bond_fixture(n int) Bond_Fixture {
+	return Bond_Fixture{
+		ID:           "bond" + strconv.Itoa(n),
+		RealEstateID: "1232." + strconv.Itoa(n),
+		Owner:        owner_fixture(1),
+		Status:       "flat",
+		Area:         "500",
+		Long:         strconv.FormatFloat(46.60+float64(n)/100, 'f', 4, 64),
+		Lat:          "24.70",
+	}
+}
+
+//==============================================================================================================================
+//	 owned_by - Returns the bond held by another owner.
+//==============================================================================================================================
+func (f Bond_Fixture) owned_by(o Owner_Fixture) Bond_Fixture {
+
+	f.Owner = o
+
+	return f
+}
+
+//==============================================================================================================================
+//	 in - Returns the bond registered to a district and street.
+//==============================================================================================================================
+func (f Bond_Fixture) in(district string, street string) Bond_Fixture {
+
+	f.District = district
+	f.Street = street
+
+	return f
+}
+
+//==============================================================================================================================
+//	 at - Returns the bond at other coordinates.
+//==============================================================================================================================
+func (f Bond_Fixture) at(long string, lat string) Bond_Fixture {
+
+	f.Long = long
+	f.Lat = lat
+
+	return f
+}
+
+//==============================================================================================================================
+//	 bounded - Returns the bond with a square boundary of side degrees around its coordinates and no declared area, so
+//			   the area is taken from the boundary.
+//==============================================================================================================================
+func (f Bond_Fixture) bounded(side float64) Bond_Fixture {
+
+	long, _ := strconv.ParseFloat(f.Long, 64)
+	lat, _ := strconv.ParseFloat(f.Lat, 64)
+
+	corner := func(dl float64, dt float64) string {
+		return "[" + strconv.FormatFloat(long+dl*side/2, 'f', -1, 64) + "," + strconv.FormatFloat(lat+dt*side/2, 'f', -1, 64) + "]"
+	}
+
+	f.Boundary = `{"type":"Polygon","coordinates":[[` + corner(-1, -1) + "," + corner(1, -1) + "," + corner(1, 1) + "," + corner(-1, 1) + "," + corner(-1, -1) + `]]}`
+	f.Area = ""
+
+	return f
+}
+
+//==============================================================================================================================
+//	 args - Returns the create_bond arguments for the bond.
+//==============================================================================================================================
+func (f Bond_Fixture) args() []string {
+	return []string{f.ID, f.RealEstateID, f.Owner.NationalID, f.Status, f.Area, f.Long, f.Lat, f.Boundary, f.District, "", f.Street}
+}
+
+//==============================================================================================================================
+//	 create - Registers the bonds, failing the test if any is rejected.
+//==============================================================================================================================
+func (h *harness) create(bonds ...Bond_Fixture) *harness {
+
+	h.t.Helper()
+
+	for _, f := range bonds {
+		h.must("create_bond", f.args()...)
+	}
+
+	return h
+}
+
+//==============================================================================================================================
+//	 seed_reference_data - Registers TEST_CITY and its TEST_DISTRICT.
+//==============================================================================================================================
+func (h *harness) seed_reference_data() *harness {
+
+	h.t.Helper()
+
+	h.must("add_city", TEST_CITY, "Riyadh")
+	h.must("add_district", TEST_CITY, TEST_DISTRICT, "Olaya")
+
+	return h
+}
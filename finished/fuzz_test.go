@@ -0,0 +1,93 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+//==============================================================================================================================
+//	 Fuzz Targets - Malformed arguments must be rejected with an error, never panic the chaincode. A panic is turned
+//					into an internal error by recover_panics, so the targets fail on that error as well as on a panic
+//					escaping. Run one with e.g.
+//
+//		go test -run XXX -fuzz FuzzInvoke -fuzztime 1m
+//
+//				   Without -fuzz, go test runs the seeds below and anything saved under testdata/fuzz.
+//==============================================================================================================================
+
+// Separates the arguments in the input of FuzzInvoke
+const FUZZ_SEPARATOR = "|"
+
+//==============================================================================================================================
+//	 fuzz_harness - Returns a seeded harness shared by the inputs of a fuzz target, reporting to the test of the input.
+//==============================================================================================================================
+func fuzz_harness(h **harness, t *testing.T) *harness {
+
+	if *h == nil {
+		*h = seeded_harness(t)
+	}
+
+	(*h).t = t
+
+	return *h
+}
+
+//==============================================================================================================================
+//	 check_no_panic - Fails the test when the response is the internal error of a panic.
+//==============================================================================================================================
+func check_no_panic(t *testing.T, h *harness, function string, args []string) {
+
+	r := h.call(function, args...)
+
+	if _, message := unwrap_response(r); strings.Contains(message, "INVOKE: Internal error") {
+		t.Fatalf("%s %q panicked", function, args)
+	}
+}
+
+func FuzzInvoke(f *testing.F) {
+
+	for _, name := range function_names() {
+		f.Add(name, "")
+		f.Add(name, "1232.1")
+		f.Add(name, "1232.1|1|2|3|4|5")
+	}
+
+	f.Add("get_bonds_in_bbox", "NaN|Inf|-Inf|1e309")
+	f.Add("get_bonds_in_bbox", "-1e9|-1e9|1e9|1e9") // once hung covering the box
+	f.Add("change_boundary", `1232.1|{"type":"Polygon","coordinates":[[[]]]}`)
+	f.Add("create_bonds_bulk", `[{"real_estate_id":"1232.7"},null,{}]`)
+	f.Add("migrate_legacy_bond", "B|LD")
+	f.Add("export_bonds", "-1|")
+	f.Add("get_audit_records", "99999999999999999999|%%%")
+
+	var shared *harness
+
+	f.Fuzz(func(t *testing.T, function string, args string) {
+
+		h := fuzz_harness(&shared, t)
+
+		check_no_panic(t, h, function, strings.Split(args, FUZZ_SEPARATOR))
+	})
+}
+
+func FuzzBondRecord(f *testing.F) {
+
+	f.Add(`{"real_estate_id":"1232.7","owner_national_id":"1000000001","status":"built","area":"500","longitude":46.67,"latitude":24.71}`)
+	f.Add(`{"area":500}`)
+	f.Add(`{"boundary":{"type":"Polygon"}}`)
+	f.Add(`{} {}`)
+	f.Add(`[`)
+
+	var shared *harness
+
+	f.Fuzz(func(t *testing.T, record string) {
+
+		decode_bond_record(record)
+		unmarshal_bond([]byte(record))
+
+		check_no_panic(t, fuzz_harness(&shared, t), "create_bond", []string{record})
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Geofence - The geofence of the configuration lists the regions bonds may lie in, each a GeoJSON polygon such as the
+//				outline of the country, e.g.
+//
+//		{"geofence":[{"name":"Saudi Arabia","boundary":{"type":"Polygon","coordinates":[[[34.5,16.3],...]]}}]}
+//
+//				create_bond and change_coordinates reject a position outside every region, which catches latitude and
+//				longitude passed the wrong way round and mistyped digits that still make a valid position. Without
+//				regions, the default, any position is accepted.
+//==============================================================================================================================
+
+// Most positions of the outline and holes of all the regions together, keeping the configuration a reasonable size
+const MAX_GEOFENCE_POSITIONS = 10000
+
+//==============================================================================================================================
+//	 Geofence_Region - A region of the geofence.
+//==============================================================================================================================
+type Geofence_Region struct {
+	Name     string  `json:"name"`
+	Boundary Polygon `json:"boundary"`
+}
+
+//==============================================================================================================================
+//	 validate_geofence - Checks every region of the geofence is named and has a valid boundary.
+//==============================================================================================================================
+func validate_geofence(regions []Geofence_Region) error {
+
+	positions := 0
+
+	for i, r := range regions {
+
+		if strings.TrimSpace(r.Name) == "" {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, geofence region "+strconv.Itoa(i)+" has no name")
+		}
+
+		err := validate_polygon(r.Boundary)
+
+		if err != nil {
+			return prefix_error("Invalid configuration, geofence region "+r.Name, err)
+		}
+
+		for _, ring := range r.Boundary.Coordinates {
+			positions += len(ring)
+		}
+	}
+
+	if positions > MAX_GEOFENCE_POSITIONS {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid configuration, the geofence has more than "+strconv.Itoa(MAX_GEOFENCE_POSITIONS)+" positions")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 contains - Reports whether the position lies inside the outline of the polygon and outside its holes.
+//==============================================================================================================================
+func (p Polygon) contains(pos [2]float64) bool {
+
+	if !point_strictly_inside(pos, p.outline()) {
+		return false
+	}
+
+	for _, hole := range p.Coordinates[1:] {
+		if point_strictly_inside(pos, hole[:len(hole)-1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//==============================================================================================================================
+//	 in_geofence - Reports whether the coordinates lie in a region of the geofence, always when it has none.
+//==============================================================================================================================
+func in_geofence(regions []Geofence_Region, c Coordinates) bool {
+
+	if len(regions) == 0 {
+		return true
+	}
+
+	for _, r := range regions {
+		if r.Boundary.contains([2]float64{c.Long, c.Lat}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 geofence_problem - Describes coordinates outside the geofence, pointing out when they would be inside swapped.
+//==============================================================================================================================
+func geofence_problem(regions []Geofence_Region, c Coordinates) string {
+
+	var names []string
+
+	for _, r := range regions {
+		names = append(names, r.Name)
+	}
+
+	problem := "Position long " + strconv.FormatFloat(c.Long, 'f', -1, 64) + ", lat " + strconv.FormatFloat(c.Lat, 'f', -1, 64) + " is outside the geofence (" + strings.Join(names, ", ") + ")"
+
+	if in_geofence(regions, Coordinates{Long: c.Lat, Lat: c.Long}) {
+		problem += ", the longitude and latitude look swapped"
+	}
+
+	return problem
+}
+
+//==============================================================================================================================
+//	 check_geofence - Returns an INVALID_ARGUMENT error when the coordinates lie outside the geofence of the configuration.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_geofence(stub shim.ChaincodeStubInterface, c Coordinates) error {
+
+	config, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	if !in_geofence(config.Geofence, c) {
+		return coded_error(CODE_INVALID_ARGUMENT, geofence_problem(config.Geofence, c))
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"math"
+)
+
+//==============================================================================================================================
+//	 Geohash - Encodes a latitude/longitude pair into a base32 string where every extra character narrows the cell
+//			   the point falls in. Points that share a prefix are close to each other which lets us store a spatial
+//			   index as ordinary ledger keys and answer area searches with range queries.
+//==============================================================================================================================
+
+const GEOHASH_ALPHABET = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Precision used for the entries written to the geohash index, 9 characters is a cell of roughly 5m x 5m
+const GEOHASH_INDEX_PRECISION = 9
+
+//==============================================================================================================================
+//	 geohash_encode - Returns the geohash of the given point using precision characters.
+//==============================================================================================================================
+func geohash_encode(lat float64, long float64, precision int) string {
+
+	minLat, maxLat := -90.0, 90.0
+	minLong, maxLong := -180.0, 180.0
+
+	var hash bytes.Buffer
+
+	bit, ch := 0, 0
+	even := true // bits alternate between longitude (even) and latitude (odd)
+
+	for hash.Len() < precision {
+
+		if even {
+			mid := (minLong + maxLong) / 2
+			if long >= mid {
+				ch = ch<<1 | 1
+				minLong = mid
+			} else {
+				ch = ch << 1
+				maxLong = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+
+		even = !even
+
+		if bit++; bit == 5 {
+			hash.WriteByte(GEOHASH_ALPHABET[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+//==============================================================================================================================
+//	 geohash_cell_size - Returns the height (latitude) and width (longitude) in degrees of a cell at the given precision.
+//==============================================================================================================================
+func geohash_cell_size(precision int) (float64, float64) {
+
+	bits := precision * 5
+	longBits := (bits + 1) / 2
+	latBits := bits / 2
+
+	return 180.0 / float64(uint64(1)<<uint(latBits)), 360.0 / float64(uint64(1)<<uint(longBits))
+}
+
+//==============================================================================================================================
+//	 geohash_cover - Returns the geohash cells that together cover the bounding box. The precision is chosen as the finest
+//					 one that needs no more than maxCells cells so the number of range queries stays bounded. A box
+//					 reaching past the poles or the antimeridian is cut to the globe.
+//==============================================================================================================================
+func geohash_cover(minLat float64, minLong float64, maxLat float64, maxLong float64, maxCells int) ([]string, error) {
+
+	if minLat > maxLat || minLong > maxLong {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GEOHASH_COVER: Bounding box minimum must not exceed maximum")
+	}
+
+	minLat, maxLat = math.Max(minLat, -90), math.Min(maxLat, 90)
+	minLong, maxLong = math.Max(minLong, -180), math.Min(maxLong, 180)
+
+	if minLat > maxLat || minLong > maxLong {
+		return nil, nil // the box is off the globe
+	}
+
+	precision := 1
+
+	for p := GEOHASH_INDEX_PRECISION; p >= 1; p-- {
+		rows, cols := geohash_cell_span(minLat, minLong, maxLat, maxLong, p)
+		if rows*cols <= maxCells {
+			precision = p
+			break
+		}
+	}
+
+	height, width := geohash_cell_size(precision)
+	rows, cols := geohash_cell_span(minLat, minLong, maxLat, maxLong, precision)
+
+	firstRow := geohash_cell_index(minLat+90, 180, height)
+	firstCol := geohash_cell_index(minLong+180, 360, width)
+
+	seen := make(map[string]bool)
+	var cells []string
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+
+			lat := -90 + (float64(firstRow+r)+0.5)*height
+			long := -180 + (float64(firstCol+c)+0.5)*width
+
+			cell := geohash_encode(lat, long, precision)
+
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+
+	return cells, nil
+}
+
+//==============================================================================================================================
+//	 geohash_cell_span - Returns how many cell rows and columns at precision the bounding box touches.
+//==============================================================================================================================
+func geohash_cell_span(minLat float64, minLong float64, maxLat float64, maxLong float64, precision int) (int, int) {
+
+	height, width := geohash_cell_size(precision)
+
+	rows := geohash_cell_index(maxLat+90, 180, height) - geohash_cell_index(minLat+90, 180, height) + 1
+	cols := geohash_cell_index(maxLong+180, 360, width) - geohash_cell_index(minLong+180, 360, width) + 1
+
+	return rows, cols
+}
+
+//==============================================================================================================================
+//	 geohash_cell_index - Returns the row or column of the cell containing offset degrees from the grid origin.
+//==============================================================================================================================
+func geohash_cell_index(offset float64, span float64, size float64) int {
+
+	i := int(offset / size)
+
+	if last := int(span/size) - 1; i > last { // points on the upper edge belong to the last cell
+		i = last
+	}
+
+	return i
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 GeoJSON Export - The parcels of a district as a GeoJSON (RFC 7946) FeatureCollection that municipal GIS tools
+//					  import as a layer. A parcel's geometry is its boundary, or the point of its coordinates when it
+//					  has none. Owners are left out of the properties as GIS layers are shared well beyond the
+//					  registry.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Feature_Collection - A GeoJSON FeatureCollection.
+//==============================================================================================================================
+type Feature_Collection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+//==============================================================================================================================
+//	 Feature - A GeoJSON Feature of a parcel, with the realEstateID as its id.
+//==============================================================================================================================
+type Feature struct {
+	Type       string             `json:"type"`
+	ID         string             `json:"id"`
+	Geometry   interface{}        `json:"geometry"` // *Polygon or Point
+	Properties Feature_Properties `json:"properties"`
+}
+
+//==============================================================================================================================
+//	 Point - A GeoJSON point, [long, lat].
+//==============================================================================================================================
+type Point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type Feature_Properties struct {
+	RealEstateID string  `json:"real_estate_id"`
+	BondID       string  `json:"bond_id"`
+	Status       string  `json:"status"`
+	Area         float64 `json:"area_sqm"`
+	CityCode     string  `json:"city_code"`
+	DistrictCode string  `json:"district_code"`
+	Street       string  `json:"street,omitempty"`
+	UpdatedAt    string  `json:"updated_at"`
+	Version      int     `json:"version"`
+}
+
+//==============================================================================================================================
+//	 bond_feature - Returns the feature of the bond.
+//==============================================================================================================================
+func bond_feature(b Bond) Feature {
+
+	f := Feature{
+		Type: "Feature",
+		ID:   b.RealEstateID,
+		Properties: Feature_Properties{
+			RealEstateID: b.RealEstateID,
+			BondID:       b.ID,
+			Status:       b.Status.String(),
+			Area:         b.Area.Value,
+			CityCode:     b.CityCode,
+			DistrictCode: b.DistrictCode,
+			Street:       b.Street,
+			UpdatedAt:    b.UpdatedAt,
+			Version:      b.Version,
+		},
+	}
+
+	if b.Boundary != nil {
+		f.Geometry = b.Boundary
+	} else {
+		f.Geometry = Point{Type: "Point", Coordinates: [2]float64{b.Coordinates.Long, b.Coordinates.Lat}}
+	}
+
+	return f
+}
+
+//=================================================================================================================================
+//	 get_district_geojson - Returns the FeatureCollection of the parcels registered to the district, found through the
+//							address index.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_district_geojson(stub shim.ChaincodeStubInterface, districtCode string) ([]byte, error) {
+
+	d, err := t.lookup_district(stub, districtCode)
+
+	if err != nil {
+		return nil, prefix_error("GET_DISTRICT_GEOJSON", err)
+	}
+
+	ids, err := t.scan_index(stub, ADDRESS_INDEX, d.CityCode+INDEX_SEPARATOR+d.Code+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, prefix_error("GET_DISTRICT_GEOJSON", err)
+	}
+
+	bonds, err := t.retrieve_listed_bonds(stub, "GET_DISTRICT_GEOJSON", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	collection := Feature_Collection{Type: "FeatureCollection", Features: []Feature{}}
+
+	for _, b := range bonds {
+		collection.Features = append(collection.Features, bond_feature(b))
+	}
+
+	bytes, err := json.Marshal(collection)
+
+	if err != nil {
+		log_errorf(stub, "GET_DISTRICT_GEOJSON: Error converting features: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_DISTRICT_GEOJSON: Error converting features", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//==============================================================================================================================
+//	 Golden Files - TestGolden runs every query against the same ledger, built by golden_harness at pinned times, and
+//					compares each response envelope with its file in testdata/golden. A change to what a query returns
+//					shows up as a failing test and, once intended, a diff to the file clients can review. Rewrite the
+//					files with
+//
+//		go test -run TestGolden -update
+//==============================================================================================================================
+
+var update_golden = flag.Bool("update", false, "rewrite the golden files of TestGolden")
+
+// Time the golden ledger starts from
+var GOLDEN_START = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+//==============================================================================================================================
+//	 golden_harness - Returns the harness of the golden ledger: the seeded bonds, a sale, a document, a price index, a
+//					  migrated bond and a verification key, all registered at pinned times.
+//==============================================================================================================================
+func golden_harness(t *testing.T) *harness {
+
+	h := new_harness(t).pin(GOLDEN_START)
+
+	h.seed_reference_data()
+	h.create(bond_fixture(1).in(TEST_DISTRICT, "King Fahd Road"), bond_fixture(2).owned_by(owner_fixture(2)).bounded(0.001))
+
+	h.as("market", "MarketDataMSP", PRICE_ORACLE).must("post_price_index", TEST_DISTRICT, "1000", "2024-01-01")
+	h.as("regulator", REGULATOR_MSP, AUTHORITY)
+
+	h.with_transient(map[string]string{SALE_PRICE_SALT: "pepper"}).must("tranfer_bond", "1232.1", owner_fixture(2).NationalID, "750000", owner_fixture(2).MSP)
+	h.with_transient(nil)
+
+	h.must("attach_document", "1232.2", "deed", strings.Repeat("ab", 32), "https://docs.example/deeds/1232.2.pdf")
+	h.must("migrate_legacy_bond", append([]string{"B-2024-01", "LD-77"}, bond_fixture(3).args()...)...)
+
+	enable(FEATURE_IDENTITY_ORACLE)(h)
+	set_verification_key(h)
+	h.with_transient(nil)
+
+	return h
+}
+
+//==============================================================================================================================
+//	 golden_query - A query of TestGolden, its response compared with testdata/golden/<name>.json.
+//==============================================================================================================================
+type golden_query struct {
+	name     string
+	function string
+	args     []string
+}
+
+func TestGolden(t *testing.T) {
+
+	h := golden_harness(t)
+
+	var check Identity_Check
+
+	decode(t, h.must("request_identity_check", "1232.2", owner_fixture(3).NationalID), &check)
+
+	var certificate Signed_Certificate
+
+	decode(t, h.must("get_ownership_certificate", "1232.2"), &certificate)
+
+	var verification Issued_Verification
+
+	decode(t, h.must("get_verification_payload", "1232.2"), &verification)
+
+	queries := []golden_query{
+		{"get_bond_details", "get_bond_details", []string{"1232.1"}},
+		{"get_bond_details_expand", "get_bond_details", []string{"1232.1", "expand"}},
+		{"check_unique_real_estate_id", "check_unique_real_estate_id", []string{"1232.9"}},
+		{"bond_exists", "bond_exists", []string{"1232.2"}},
+		{"get_bonds", "get_bonds", nil},
+		{"get_bonds_in_bbox", "get_bonds_in_bbox", []string{"24", "46", "25", "47"}},
+		{"get_nearby_bonds", "get_nearby_bonds", []string{"24.70", "46.61", "2000"}},
+		{"get_transfer_stats", "get_transfer_stats", []string{"district", "2024-01-01", "2024-12-31"}},
+		{"get_registry_stats", "get_registry_stats", nil},
+		{"export_bonds", "export_bonds", []string{"2", ""}},
+		{"export_ladm", "export_ladm", []string{"2", ""}},
+		{"get_registry_checksum", "get_registry_checksum", nil},
+		{"get_owner_summary", "get_owner_summary", []string{owner_fixture(2).NationalID}},
+		{"get_bonds_by_owner", "get_bonds_by_owner", []string{owner_fixture(2).NationalID}},
+		{"get_bonds_modified_since", "get_bonds_modified_since", []string{"2024-01-01T00:00:00Z"}},
+		{"get_cities", "get_cities", nil},
+		{"get_districts", "get_districts", []string{TEST_CITY}},
+		{"get_district", "get_district", []string{TEST_DISTRICT}},
+		{"search_by_address", "search_by_address", []string{TEST_CITY, TEST_DISTRICT, "King Fahd Road"}},
+		{"get_district_geojson", "get_district_geojson", []string{TEST_DISTRICT}},
+		{"get_bond_reference", "get_bond_reference", []string{"1232.1"}},
+		{"get_config", "get_config", nil},
+		{"get_config_changes", "get_config_changes", nil},
+		{"get_audit_log", "get_audit_log", []string{"1232.1"}},
+		{"get_audit_records", "get_audit_records", []string{"3", ""}},
+		{"get_documents", "get_documents", []string{"1232.2"}},
+		{"verify_document", "verify_document", []string{"1232.2", "deed", strings.Repeat("ab", 32)}},
+		{"get_price_index", "get_price_index", []string{TEST_DISTRICT}},
+		{"get_price_flags", "get_price_flags", []string{"2024-01-01", "2024-12-31"}},
+		{"get_tenant", "get_tenant", nil},
+		{"get_migration_batch", "get_migration_batch", []string{"B-2024-01"}},
+		{"get_bond_by_legacy_deed", "get_bond_by_legacy_deed", []string{"LD-77"}},
+		{"get_identity_check", "get_identity_check", []string{check.ID}},
+		{"get_document_cas", "get_document_cas", nil},
+		{"get_verification_payload", "get_verification_payload", []string{"1232.2"}},
+		{"verify_verification_payload", "verify_verification_payload", []string{verification.Payload}},
+		{"get_ownership_certificate", "get_ownership_certificate", []string{"1232.2"}},
+		{"verify_ownership_certificate", "verify_ownership_certificate", []string{certificate.Certificate, certificate.Signature}},
+		{"get_ecert", "get_ecert", []string{"nobody"}},
+		{"health", "health", nil},
+		{"list_functions", "list_functions", nil},
+		{"get_bond_details_not_found", "get_bond_details", []string{"1232.9"}},
+		{"check_unique_real_estate_id_taken", "check_unique_real_estate_id", []string{"1232.1"}},
+		{"get_bonds_by_blueprint", "get_bonds_by_blueprint", []string{"1232"}},
+		{"get_skipped_bonds", "get_skipped_bonds", nil},
+	}
+
+	covered := map[string]bool{}
+
+	for _, q := range queries {
+
+		covered[q.function] = true
+
+		r := h.call(q.function, q.args...)
+
+		var got bytes.Buffer
+
+		if err := json.Indent(&got, r.Payload, "", "  "); err != nil {
+			t.Fatalf("%s: response is not JSON: %s", q.name, r.Payload)
+		}
+
+		got.WriteString("\n")
+
+		file := filepath.Join("testdata", "golden", q.name+".json")
+
+		if *update_golden {
+			if err := ioutil.WriteFile(file, got.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			t.Fatalf("%s: %s, run go test -run TestGolden -update to create it", q.name, err)
+		}
+
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: response differs from %s\ngot:\n%s", q.name, file, got.Bytes())
+		}
+	}
+
+	for name, entry := range FUNCTIONS {
+		if !entry.Writes && !covered[name] {
+			t.Errorf("query %s has no golden file", name)
+		}
+	}
+}
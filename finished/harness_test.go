@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//==============================================================================================================================
+//	 Test Harness - Runs the chaincode in process on a shim.MockStub. Every harness is a fresh ledger on TEST_CHANNEL,
+//					instantiated with the default configuration and invoked by a regulator of RegulatorMSP until as
+//					switches the identity.
+//==============================================================================================================================
+
+const TEST_CHANNEL = "registry"
+
+// Object identifier of the certificate extension the Fabric CA stores attributes in, read by the cid library
+var ATTRIBUTES_OID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+//==============================================================================================================================
+//	 harness - A chaincode under test and the events of its last transaction.
+//==============================================================================================================================
+type harness struct {
+	t      testing.TB
+	stub   *shim.MockStub
+	tx     int
+	events []*pb.ChaincodeEvent
+	clock  *time.Time // see pin
+}
+
+//==============================================================================================================================
+//	 new_harness - Instantiates the chaincode, passing the init arguments given if any.
+//==============================================================================================================================
+func new_harness(t testing.TB, args ...string) *harness {
+
+	h := &harness{t: t, stub: shim.NewMockStub("registry", new(SimpleChaincode))}
+
+	h.stub.ChannelID = TEST_CHANNEL
+
+	h.as("regulator", REGULATOR_MSP, AUTHORITY)
+
+	r := h.stub.MockInit(h.next_tx(), test_args("init", args))
+
+	if _, message := unwrap_response(r); r.Status != shim.OK {
+		t.Fatalf("init failed: %s", message)
+	}
+
+	return h
+}
+
+//==============================================================================================================================
+//	 as - Invokes the following transactions as the user of the organisation with the role attribute given. An empty
+//		  role gives a certificate without attributes.
+//==============================================================================================================================
+func (h *harness) as(name string, mspid string, role string) *harness {
+
+	h.stub.Creator = test_identity(h.t, name, mspid, role)
+
+	return h
+}
+
+//==============================================================================================================================
+//	 with_transient - Passes the fields as transient data to the following transactions, nil clears them.
+//==============================================================================================================================
+func (h *harness) with_transient(fields map[string]string) *harness {
+
+	h.stub.TransientMap = nil
+
+	if fields != nil {
+		h.stub.TransientMap = make(map[string][]byte)
+		for k, v := range fields {
+			h.stub.TransientMap[k] = []byte(v)
+		}
+	}
+
+	return h
+}
+
+//==============================================================================================================================
+//	 pin - Times the following transactions from start, a second apart, rather than by the clock, so what they store
+//		   and return is the same on every run.
+//==============================================================================================================================
+func (h *harness) pin(start time.Time) *harness {
+
+	h.clock = &start
+
+	return h
+}
+
+//==============================================================================================================================
+//	 pinned_stub - The stub of a pinned transaction. MockInvoke stamps transactions with the time of the clock, so
+//				   pinned ones are passed their arguments by the stub instead.
+//==============================================================================================================================
+type pinned_stub struct {
+	*shim.MockStub
+	args [][]byte
+}
+
+func (s pinned_stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s pinned_stub) GetStringArgs() []string {
+
+	out := make([]string, len(s.args))
+
+	for i, a := range s.args {
+		out[i] = string(a)
+	}
+
+	return out
+}
+
+func (s pinned_stub) GetFunctionAndParameters() (string, []string) {
+
+	args := s.GetStringArgs()
+
+	if len(args) == 0 {
+		return "", []string{}
+	}
+
+	return args[0], args[1:]
+}
+
+//==============================================================================================================================
+//	 invoke - Invokes the chaincode in the transaction, at the time of the pinned clock if any.
+//==============================================================================================================================
+func (h *harness) invoke(tx string, args [][]byte) pb.Response {
+
+	if h.clock == nil {
+		return h.stub.MockInvoke(tx, args)
+	}
+
+	*h.clock = h.clock.Add(time.Second)
+
+	h.stub.MockTransactionStart(tx)
+	h.stub.TxTimestamp = &timestamp.Timestamp{Seconds: h.clock.Unix()}
+
+	r := new(SimpleChaincode).Invoke(pinned_stub{MockStub: h.stub, args: args})
+
+	h.stub.MockTransactionEnd(tx)
+
+	return r
+}
+
+//==============================================================================================================================
+//	 call - Invokes the function in a new transaction and returns the peer response.
+//==============================================================================================================================
+func (h *harness) call(function string, args ...string) pb.Response {
+
+	r := h.invoke(h.next_tx(), test_args(function, args))
+
+	h.events = nil
+
+	for {
+		select {
+		case e := <-h.stub.ChaincodeEventsChannel:
+			h.events = append(h.events, e)
+		default:
+			return r
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 must - Invokes the function and fails the test unless it succeeds. Returns the data of the response.
+//==============================================================================================================================
+func (h *harness) must(function string, args ...string) []byte {
+
+	h.t.Helper()
+
+	r := h.call(function, args...)
+	data, message := unwrap_response(r)
+
+	if r.Status != shim.OK {
+		h.t.Fatalf("%s %v failed: %s", function, args, message)
+	}
+
+	return data
+}
+
+//==============================================================================================================================
+//	 fails - Invokes the function and fails the test unless it is rejected with a message containing want.
+//==============================================================================================================================
+func (h *harness) fails(want string, function string, args ...string) {
+
+	h.t.Helper()
+
+	r := h.call(function, args...)
+	_, message := unwrap_response(r)
+
+	if r.Status == shim.OK {
+		h.t.Fatalf("%s %v succeeded, expecting %q", function, args, want)
+	}
+
+	if !strings.Contains(message, want) {
+		h.t.Fatalf("%s %v failed with %q, expecting %q", function, args, message, want)
+	}
+}
+
+//==============================================================================================================================
+//	 bond - Returns the bond as get_bond_details reports it.
+//==============================================================================================================================
+func (h *harness) bond(realEstateID string) Bond {
+
+	h.t.Helper()
+
+	var b Bond
+
+	decode(h.t, h.must("get_bond_details", realEstateID), &b)
+
+	return b
+}
+
+//==============================================================================================================================
+//	 next_tx - Returns the ID of the next transaction.
+//==============================================================================================================================
+func (h *harness) next_tx() string {
+
+	h.tx++
+
+	return "tx" + strconv.Itoa(h.tx)
+}
+
+//==============================================================================================================================
+//	 decode - Unmarshals a JSON payload, failing the test if it is not valid.
+//==============================================================================================================================
+func decode(t testing.TB, payload []byte, v interface{}) {
+
+	t.Helper()
+
+	err := json.Unmarshal(payload, v)
+
+	if err != nil {
+		t.Fatalf("invalid payload %s: %s", payload, err)
+	}
+}
+
+//==============================================================================================================================
+//	 test_args - Builds the arguments of a transaction.
+//==============================================================================================================================
+func test_args(function string, args []string) [][]byte {
+
+	out := [][]byte{[]byte(function)}
+
+	for _, a := range args {
+		out = append(out, []byte(a))
+	}
+
+	return out
+}
+
+//==============================================================================================================================
+//	 test_identity - Returns a serialized identity of the MSP whose self signed certificate carries the role attribute
+//					 the way the Fabric CA enrols users.
+//==============================================================================================================================
+func test_identity(t testing.TB, name string, mspid string, role string) []byte {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{mspid}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	if role != "" {
+		attrs, _ := json.Marshal(map[string]map[string]string{"attrs": {"role": role}})
+		template.ExtraExtensions = []pkix.Extension{{Id: ATTRIBUTES_OID, Value: attrs}}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspid,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return creator
+}
+
+//==============================================================================================================================
+//	 fake_token - An ERC-721 style token chaincode answering mint, owner_of, transfer and burn, for wrap_bond.
+//==============================================================================================================================
+type fake_token struct {
+}
+
+func (f *fake_token) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (f *fake_token) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+
+	function, args := stub.GetFunctionAndParameters()
+
+	holder, _ := stub.GetState(args[0])
+
+	switch {
+	case function == "mint" && holder == nil:
+		stub.PutState(args[0], []byte(args[1]))
+	case function == "owner_of" && holder != nil:
+		return shim.Success(holder)
+	case function == "transfer" && holder != nil:
+		stub.PutState(args[0], []byte(args[1]))
+	case function == "burn" && holder != nil:
+		stub.DelState(args[0])
+	default:
+		return shim.Error("Cannot " + function + " token " + args[0])
+	}
+
+	return shim.Success(nil)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Health Check - health tells operators which build and data model a peer runs, so a peer left behind by an upgrade
+//					shows up by comparing the answers of each. The version is set when the chaincode is packaged, e.g.
+//					go build -ldflags "-X main.CHAINCODE_VERSION=2.3.0", and is dev otherwise.
+//==============================================================================================================================
+
+var CHAINCODE_VERSION = "dev"
+
+//==============================================================================================================================
+//	 Health - The response of health. ConfigHash is the sha256 of the configuration in effect, as get_config returns
+//			  it.
+//==============================================================================================================================
+type Health struct {
+	Status             string `json:"status"`
+	ChaincodeVersion   string `json:"chaincode_version"`
+	SchemaVersion      int    `json:"schema_version"`
+	EventSchemaVersion int    `json:"event_schema_version"`
+	ConfigHash         string `json:"config_hash"`
+	Channel            string `json:"channel"`
+	Tenant             string `json:"tenant,omitempty"`
+	Bonds              int    `json:"bonds"`
+	Transfers          int    `json:"transfers"`
+	Migrating          bool   `json:"migrating"` // a migrate run has not completed
+	Timestamp          string `json:"timestamp"`
+}
+
+//=================================================================================================================================
+//	 health - Returns the Health of the chaincode on this peer.
+//=================================================================================================================================
+func (t *SimpleChaincode) health(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("HEALTH", err)
+	}
+
+	config, err := json.Marshal(c)
+
+	if err != nil {
+		log_errorf(stub, "HEALTH: Error converting configuration: %s", err)
+		return nil, wrap_error(CODE_ERROR, "HEALTH: Error converting configuration", err)
+	}
+
+	sum := sha256.Sum256(config)
+
+	ids, err := t.get_sorted_bond_ids(stub)
+
+	if err != nil {
+		return nil, prefix_error("HEALTH", err)
+	}
+
+	transfers, err := t.get_transfers(stub, "", "9999")
+
+	if err != nil {
+		return nil, prefix_error("HEALTH", err)
+	}
+
+	progress, err := stub.GetState(MIGRATION_KEY)
+
+	if err != nil {
+		log_errorf(stub, "HEALTH: Error reading migration progress: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "HEALTH: Error reading migration progress "+MIGRATION_KEY, err)
+	}
+
+	var p Migration_Progress
+
+	if progress != nil {
+		err = json.Unmarshal(progress, &p)
+		if err != nil {
+			return nil, errors.New("HEALTH: Corrupt migration progress " + string(progress))
+		}
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("HEALTH", err)
+	}
+
+	bytes, err := json.Marshal(Health{
+		Status:             "ok",
+		ChaincodeVersion:   CHAINCODE_VERSION,
+		SchemaVersion:      SCHEMA_VERSION,
+		EventSchemaVersion: EVENT_SCHEMA_VERSION,
+		ConfigHash:         hex.EncodeToString(sum[:]),
+		Channel:            stub.GetChannelID(),
+		Tenant:             stub_tenant(stub),
+		Bonds:              len(ids),
+		Transfers:          len(transfers),
+		Migrating:          progress != nil && !p.Done,
+		Timestamp:          now.Format(TIME_LAYOUT),
+	})
+
+	if err != nil {
+		log_errorf(stub, "HEALTH: Error converting health: %s", err)
+		return nil, wrap_error(CODE_ERROR, "HEALTH: Error converting health", err)
+	}
+
+	return bytes, nil
+}
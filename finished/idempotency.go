@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Idempotent Operations - A client retrying a transaction it has no answer for submits a new transaction, which the
+//							 peers cannot tell from a second request. Passing the same idempotency_key in the transient
+//							 map of every attempt makes the functions below run once: the first attempt stores its
+//							 result under operation~key and later attempts return that result without changing
+//							 anything. Attempts committed in the same block read the key before either wrote it, so
+//							 all but the first fail validation with an MVCC conflict. Resubmitting the same transaction
+//							 needs no key, the peers already reject a repeated transaction ID.
+//==============================================================================================================================
+
+const OPERATION_PREFIX = "operation"
+
+const TRANSIENT_IDEMPOTENCY_KEY = "idempotency_key"
+
+const MAX_IDEMPOTENCY_KEY_LENGTH = 128
+
+// Functions whose attempts are de-duplicated by idempotency key
+var IDEMPOTENT_FUNCTIONS = map[string]bool{
+	"create_bond":       true,
+	"tranfer_bond":      true,
+	"create_bonds_bulk": true,
+}
+
+//==============================================================================================================================
+//	 Operation_Record - The result of the first attempt of an operation.
+//==============================================================================================================================
+type Operation_Record struct {
+	Function string `json:"function"`
+	TxID     string `json:"txid"`
+	Result   []byte `json:"result"`
+}
+
+//==============================================================================================================================
+//	 invoke_once - Invokes the function unless an earlier attempt with the same idempotency key did, in which case the
+//				   result of that attempt is returned. Functions that are not idempotent and calls without a key are
+//				   invoked as they are.
+//==============================================================================================================================
+func (t *SimpleChaincode) invoke_once(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+
+	if !IDEMPOTENT_FUNCTIONS[function] {
+		return t.invoke(stub, function, args)
+	}
+
+	key, found, err := transient_field(stub, TRANSIENT_IDEMPOTENCY_KEY)
+
+	if err != nil {
+		return nil, prefix_error("INVOKE_ONCE", err)
+	}
+
+	if !found {
+		return t.invoke(stub, function, args)
+	}
+
+	if len(key) > MAX_IDEMPOTENCY_KEY_LENGTH {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "INVOKE_ONCE: Idempotency key longer than "+strconv.Itoa(MAX_IDEMPOTENCY_KEY_LENGTH)+" characters")
+	}
+
+	bytes, err := stub.GetState(index_key(OPERATION_PREFIX, key))
+
+	if err != nil {
+		log_errorf(stub, "INVOKE_ONCE: Error retrieving operation %s: %s", key, err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "INVOKE_ONCE: Error retrieving operation "+key, err)
+	}
+
+	if bytes != nil {
+
+		var op Operation_Record
+
+		err = json.Unmarshal(bytes, &op)
+
+		if err != nil {
+			return nil, errors.New("INVOKE_ONCE: Corrupt operation record " + string(bytes))
+		}
+
+		if op.Function != function {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, "INVOKE_ONCE: Idempotency key "+key+" was used for "+op.Function)
+		}
+
+		log_infof(stub, "INVOKE_ONCE: Operation %s already done by %s", key, op.TxID)
+
+		return op.Result, nil
+	}
+
+	result, err := t.invoke(stub, function, args)
+
+	if err != nil {
+		return nil, err // nothing is stored so a corrected attempt can reuse the key
+	}
+
+	bytes, err = json.Marshal(Operation_Record{Function: function, TxID: stub.GetTxID(), Result: result})
+
+	if err != nil {
+		log_errorf(stub, "INVOKE_ONCE: Error converting operation record: %s", err)
+		return nil, wrap_error(CODE_ERROR, "INVOKE_ONCE: Error converting operation record", err)
+	}
+
+	err = stub.PutState(index_key(OPERATION_PREFIX, key), bytes)
+
+	if err != nil {
+		log_errorf(stub, "INVOKE_ONCE: Error storing operation record: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "INVOKE_ONCE: Error storing operation record "+index_key(OPERATION_PREFIX, key), err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 Bond IDs - Callers may leave the bond ID to create_bond, which derives it from the ID of the transaction and the
+//				blueprint number of the parcel, e.g. B1232-3f9a0c17e2b4. Every endorsing peer derives the same ID and a
+//				transaction ID is never reused, so generated IDs cannot collide the way IDs chosen by clients can.
+//==============================================================================================================================
+
+// Number of hex digits of the hash kept in a generated ID
+const BOND_ID_HASH_LENGTH = 12
+
+//==============================================================================================================================
+//	 blueprint_number - Returns the blueprint part of a blueprint.parcel realEstateID, or the whole ID for IDs not in
+//						that form.
+//==============================================================================================================================
+func blueprint_number(realEstateID string) string {
+	return strings.SplitN(normalize_parcel(realEstateID), ".", 2)[0]
+}
+
+//==============================================================================================================================
+//	 generate_bond_id - Returns the bond ID for the parcel registered by the transaction.
+//==============================================================================================================================
+func generate_bond_id(txID string, realEstateID string) string {
+
+	blueprint := blueprint_number(realEstateID)
+
+	sum := sha256.Sum256([]byte(txID + INDEX_SEPARATOR + blueprint))
+
+	return "B" + blueprint + "-" + hex.EncodeToString(sum[:])[:BOND_ID_HASH_LENGTH]
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Indexes - Secondary indexes are stored as empty ledger entries whose key is made of the index name, the indexed
+//			   attributes and finally the realEstateID of the bond e.g. geohash~sv8d7cxyz~1232.21. Finding the bonds
+//			   for an attribute is then a range query over the key prefix.
+//==============================================================================================================================
+
+const INDEX_SEPARATOR = "~"
+
+const STATUS_INDEX = "status"
+
+// Value stored against index keys, the key itself carries all the information
+var INDEX_VALUE = []byte{0x00}
+
+//==============================================================================================================================
+//	 index_key - Builds the ledger key for an index entry from the index name and its attributes.
+//==============================================================================================================================
+func index_key(index string, attributes ...string) string {
+	return index + INDEX_SEPARATOR + strings.Join(attributes, INDEX_SEPARATOR)
+}
+
+//==============================================================================================================================
+//	 put_index - Writes the index entry for the attributes passed. The last attribute is the realEstateID.
+//==============================================================================================================================
+func (t *SimpleChaincode) put_index(stub shim.ChaincodeStubInterface, index string, attributes ...string) error {
+
+	err := stub.PutState(index_key(index, attributes...), INDEX_VALUE)
+
+	if err != nil {
+		log_errorf(stub, "PUT_INDEX: Error storing %s index entry: %s", index, err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing "+index+" index entry", err)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 del_index - Removes the index entry for the attributes passed.
+//==============================================================================================================================
+func (t *SimpleChaincode) del_index(stub shim.ChaincodeStubInterface, index string, attributes ...string) error {
+
+	err := stub.DelState(index_key(index, attributes...))
+
+	if err != nil {
+		log_errorf(stub, "DEL_INDEX: Error removing %s index entry: %s", index, err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error removing "+index+" index entry", err)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 move_index - Moves the bond's entry in a single valued index such as owner or status from the previous value to
+//				  the new one. An empty previous value only adds the new entry, as when a bond is created.
+//==============================================================================================================================
+func (t *SimpleChaincode) move_index(stub shim.ChaincodeStubInterface, index string, realEstateID string, previous string, value string) error {
+
+	if previous == value {
+		return nil
+	}
+
+	if previous != "" {
+		err := t.del_index(stub, index, previous, realEstateID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.put_index(stub, index, value, realEstateID)
+}
+
+//==============================================================================================================================
+//	 scan_index - Returns the realEstateIDs of every entry in the index whose key starts with index~prefix. The prefix
+//				  does not have to end on an attribute boundary which lets geohash cells match all the finer cells
+//				  inside them.
+//==============================================================================================================================
+func (t *SimpleChaincode) scan_index(stub shim.ChaincodeStubInterface, index string, prefix string) ([]string, error) {
+
+	start := index + INDEX_SEPARATOR + prefix
+
+	return t.scan_index_range(stub, start, start+"\xff")
+}
+
+//==============================================================================================================================
+//	 scan_index_range - Returns the realEstateIDs of every index entry with a key from start up to end, in key order.
+//==============================================================================================================================
+func (t *SimpleChaincode) scan_index_range(stub shim.ChaincodeStubInterface, start string, end string) ([]string, error) {
+
+	iter, err := stub.GetStateByRange(start, end)
+
+	if err != nil {
+		log_errorf(stub, "SCAN_INDEX_RANGE: Error querying index: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Error querying index", err)
+	}
+
+	defer iter.Close()
+
+	var ids []string
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "SCAN_INDEX_RANGE: Error reading index: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "Error reading index", err)
+		}
+
+		ids = append(ids, kv.Key[strings.LastIndex(kv.Key, INDEX_SEPARATOR)+1:])
+	}
+
+	return ids, nil
+}
+
+//==============================================================================================================================
+//	 clear_index - Deletes every entry of the index and returns how many there were.
+//==============================================================================================================================
+func (t *SimpleChaincode) clear_index(stub shim.ChaincodeStubInterface, index string) (int, error) {
+
+	start := index + INDEX_SEPARATOR
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "CLEAR_INDEX: Error querying %s index: %s", index, err)
+		return 0, wrap_error(CODE_LEDGER_ERROR, "Error querying "+index+" index", err)
+	}
+
+	var keys []string
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			iter.Close()
+			log_errorf(stub, "CLEAR_INDEX: Error reading %s index: %s", index, err)
+			return 0, wrap_error(CODE_LEDGER_ERROR, "Error reading "+index+" index", err)
+		}
+
+		keys = append(keys, kv.Key)
+	}
+
+	iter.Close()
+
+	for _, key := range keys {
+
+		err = stub.DelState(key)
+
+		if err != nil {
+			log_errorf(stub, "CLEAR_INDEX: Error removing %s index entry: %s", index, err)
+			return 0, wrap_error(CODE_LEDGER_ERROR, "Error removing "+index+" index entry", err)
+		}
+	}
+
+	return len(keys), nil
+}
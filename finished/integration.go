@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Chaincode Integration - Calls to other chaincodes go through a Chaincode_Caller so the functions that depend on them
+//							 can be given a fake caller in tests. Chaincode_Target is the caller used on the network.
+//							 A chaincode on another channel can only be read, Fabric does not commit its writes.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Chaincode_Caller - Calls a function of another chaincode and returns its payload, taken out of the response
+//						envelope when the chaincode answers in one.
+//==============================================================================================================================
+type Chaincode_Caller interface {
+	Call(stub shim.ChaincodeStubInterface, function string, args ...string) ([]byte, error)
+}
+
+//==============================================================================================================================
+//	 Chaincode_Target - A chaincode called through InvokeChaincode. Responses with a server error status are retried
+//						up to Attempts times in total, client errors are returned at once.
+//==============================================================================================================================
+type Chaincode_Target struct {
+	Name     string
+	Channel  string // empty for the channel of this chaincode
+	Attempts int
+}
+
+//==============================================================================================================================
+//	 Call - Invokes the function of the target chaincode with the arguments passed.
+//==============================================================================================================================
+func (c Chaincode_Target) Call(stub shim.ChaincodeStubInterface, function string, args ...string) ([]byte, error) {
+
+	if c.Name == "" {
+		return nil, errors.New("CALL: No chaincode name configured")
+	}
+
+	invokeArgs := [][]byte{[]byte(function)}
+
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+
+	attempts := c.Attempts
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var message string
+
+	for i := 0; i < attempts; i++ {
+
+		response := stub.InvokeChaincode(c.Name, invokeArgs, c.Channel)
+
+		data, text := unwrap_response(response)
+
+		if response.Status < shim.ERRORTHRESHOLD {
+			return data, nil
+		}
+
+		message = "status " + strconv.Itoa(int(response.Status)) + ": " + text
+
+		if response.Status < shim.ERROR {
+			break
+		}
+
+		log_warningf(stub, "CALL: Attempt %d of %s %s failed with %s", i+1, c.Name, function, message)
+	}
+
+	return nil, errors.New("Calling " + function + " on chaincode " + c.Name + " failed with " + message)
+}
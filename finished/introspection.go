@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Introspection - list_functions describes the functions of the registry, their arguments and the role a caller
+//					 needs, so clients can discover the API of the deployed chaincode rather than of its documentation.
+//					 The function is registered in init as its handler reads the registry.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Arg_Description - An argument in the response of list_functions. Type is string, integer, number or JSON.
+//==============================================================================================================================
+type Arg_Description struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+}
+
+//==============================================================================================================================
+//	 Function_Description - A function in the response of list_functions. Role is empty for functions any caller may
+//							call, otherwise admin, oracle or price_oracle, the roles named in the configuration.
+//==============================================================================================================================
+type Function_Description struct {
+	Name     string            `json:"name"`
+	Args     []Arg_Description `json:"args"`
+	Variadic bool              `json:"variadic"`
+	Role     string            `json:"role"`
+	Writes   bool              `json:"writes"`
+}
+
+func init() {
+	FUNCTIONS["list_functions"] = Function_Entry{
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.list_functions(stub)
+		},
+	}
+}
+
+//==============================================================================================================================
+//	 describe_function - Returns the Function_Description of the function of the registry.
+//==============================================================================================================================
+func describe_function(name string, entry Function_Entry) Function_Description {
+
+	d := Function_Description{Name: name, Args: []Arg_Description{}, Variadic: entry.Args.Variadic, Role: entry.Role, Writes: entry.Writes}
+
+	for _, a := range entry.Args.Args {
+
+		kind := a.Type
+
+		if kind == ARG_STRING {
+			kind = "string"
+		}
+
+		d.Args = append(d.Args, Arg_Description{Name: a.Name, Type: kind, Optional: a.Optional})
+	}
+
+	return d
+}
+
+//=================================================================================================================================
+//	 list_functions - Returns the Function_Description of every function of the registry, in alphabetical order.
+//=================================================================================================================================
+func (t *SimpleChaincode) list_functions(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	functions := []Function_Description{}
+
+	for _, name := range function_names() {
+		functions = append(functions, describe_function(name, FUNCTIONS[name]))
+	}
+
+	bytes, err := json.Marshal(functions)
+
+	if err != nil {
+		log_errorf(stub, "LIST_FUNCTIONS: Error converting functions: %s", err)
+		return nil, wrap_error(CODE_ERROR, "LIST_FUNCTIONS: Error converting functions", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 IPFS - Documents stored on IPFS are attached by their CID, as ipfs://<cid> or the bare CID, keeping large files
+//			off-chain but content addressed. Both CID versions are accepted:
+//
+//		CIDv0	base58btc of a sha2-256 multihash, starting Qm
+//		CIDv1	multibase prefix, b for base32 or z for base58btc, of <version><codec><multihash>
+//
+//			IPFS hashes the chunked DAG of a file rather than its bytes, so the sha256 of a document only equals the
+//			digest of its CID for a single raw block; attach_document checks that case and otherwise records both.
+//==============================================================================================================================
+
+const IPFS_SCHEME = "ipfs://"
+
+// Multicodec codes of interest
+const CODEC_RAW = 0x55
+const CODEC_DAG_PB = 0x70
+const MULTIHASH_SHA2_256 = 0x12
+
+const BASE58_ALPHABET = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var BASE32_LOWER = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+//==============================================================================================================================
+//	 IPFS_CID - A parsed CID.
+//==============================================================================================================================
+type IPFS_CID struct {
+	Version  int
+	Codec    uint64
+	HashCode uint64
+	Digest   []byte
+}
+
+//==============================================================================================================================
+//	 ipfs_uri - Returns the CID of an ipfs:// URI or bare CID, with the path of the URI if any, and whether the URI is
+//				one. A URI with another scheme is not an IPFS URI.
+//==============================================================================================================================
+func ipfs_uri(uri string) (string, string, bool) {
+
+	if strings.HasPrefix(uri, IPFS_SCHEME) {
+
+		rest := strings.TrimPrefix(uri, IPFS_SCHEME)
+
+		if i := strings.Index(rest, "/"); i >= 0 {
+			return rest[:i], rest[i:], true
+		}
+
+		return rest, "", true
+	}
+
+	if !strings.Contains(uri, ":") && !strings.Contains(uri, "/") {
+		return uri, "", true
+	}
+
+	return "", "", false
+}
+
+//==============================================================================================================================
+//	 parse_ipfs_cid - Decodes and checks a CID.
+//==============================================================================================================================
+func parse_ipfs_cid(s string) (IPFS_CID, error) {
+
+	var c IPFS_CID
+
+	if len(s) == 46 && strings.HasPrefix(s, "Qm") {
+
+		bytes, err := base58_decode(s)
+
+		if err != nil {
+			return c, err
+		}
+
+		c.Version, c.Codec = 0, CODEC_DAG_PB
+
+		return c, c.read_multihash(bytes)
+	}
+
+	if len(s) < 2 {
+		return c, errors.New("CID too short")
+	}
+
+	var bytes []byte
+	var err error
+
+	switch s[0] {
+	case 'b':
+		bytes, err = BASE32_LOWER.DecodeString(s[1:])
+	case 'z':
+		bytes, err = base58_decode(s[1:])
+	default:
+		return c, errors.New("Unsupported multibase " + s[:1] + ", expecting b (base32) or z (base58btc)")
+	}
+
+	if err != nil {
+		return c, errors.New("Invalid multibase encoding: " + err.Error())
+	}
+
+	version, n := binary.Uvarint(bytes)
+
+	if n <= 0 || version != 1 {
+		return c, errors.New("Unsupported CID version")
+	}
+
+	codec, m := binary.Uvarint(bytes[n:])
+
+	if m <= 0 {
+		return c, errors.New("Invalid CID codec")
+	}
+
+	c.Version, c.Codec = 1, codec
+
+	return c, c.read_multihash(bytes[n+m:])
+}
+
+//==============================================================================================================================
+//	 read_multihash - Reads the <hash code><length><digest> multihash, which must be the whole of the bytes.
+//==============================================================================================================================
+func (c *IPFS_CID) read_multihash(bytes []byte) error {
+
+	code, n := binary.Uvarint(bytes)
+
+	if n <= 0 {
+		return errors.New("Invalid multihash code")
+	}
+
+	length, m := binary.Uvarint(bytes[n:])
+
+	if m <= 0 || length == 0 || uint64(len(bytes)-n-m) != length {
+		return errors.New("Invalid multihash length")
+	}
+
+	if code == MULTIHASH_SHA2_256 && length != 32 {
+		return errors.New("Invalid sha2-256 multihash length")
+	}
+
+	c.HashCode, c.Digest = code, bytes[n+m:]
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 base58_decode - Decodes base58btc, keeping leading zero bytes written as 1s.
+//==============================================================================================================================
+func base58_decode(s string) ([]byte, error) {
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+
+	for _, r := range s {
+
+		i := strings.IndexRune(BASE58_ALPHABET, r)
+
+		if i < 0 {
+			return nil, errors.New("Invalid base58 character " + string(r))
+		}
+
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	zeros := 0
+
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
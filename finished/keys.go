@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Ledger Keys - Records keyed by a value the caller chooses live under a prefix per record type, e.g. bond~1232.21
+//				   and ecert~bob, the same way index entries do. A realEstateID can then never be read back as an
+//				   ecert, nor overwrite one of the fixed keys such as config, bondIDs or migration_progress, which
+//				   contain no separator and so never start with a prefix. Listing the bonds is a range query over
+//				   bond~, so creating one only writes keys of its own and creations in the same block do not conflict.
+//==============================================================================================================================
+
+const BOND_PREFIX = "bond"
+
+const ECERT_PREFIX = "ecert"
+
+// Key of the list of realEstateIDs written before bonds were listed by range, see get_legacy_bond_ids
+const BOND_LIST_KEY = "bondIDs"
+
+//==============================================================================================================================
+//	 bond_key - Returns the ledger key of the bond record.
+//==============================================================================================================================
+func bond_key(realEstateID string) string {
+	return index_key(BOND_PREFIX, realEstateID)
+}
+
+//==============================================================================================================================
+//	 ecert_key - Returns the ledger key of the user's ecert.
+//==============================================================================================================================
+func ecert_key(name string) string {
+	return index_key(ECERT_PREFIX, name)
+}
+
+//==============================================================================================================================
+//	 get_stored_bond - Reads the bond record as stored and reports whether there is one. Bonds written before schema
+//					   version 3 are under their bare realEstateID until they are next saved, see put_bond. A bare
+//					   key only counts if it holds the bond with that realEstateID.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_stored_bond(stub shim.ChaincodeStubInterface, realEstateID string) (Bond, bool, error) {
+
+	var b Bond
+
+	bytes, err := stub.GetState(bond_key(realEstateID))
+	legacy := false
+
+	if err == nil && bytes == nil {
+		bytes, err = stub.GetState(realEstateID)
+		legacy = true
+	}
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_BOND: Error retrieving bond %s: %s", realEstateID, err)
+		return b, false, wrap_error(CODE_LEDGER_ERROR, "Error retrieving bond "+realEstateID, err)
+	}
+
+	if len(bytes) == 0 {
+		return b, false, nil
+	}
+
+	b, err = unmarshal_bond(bytes)
+
+	if legacy && (bytes[0] != '{' || err != nil || b.RealEstateID != realEstateID) {
+		return Bond{}, false, nil // not a bond, e.g. an ecert stored before ecert~
+	}
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_BOND: Corrupt bond record %s: %s", realEstateID, err)
+		return b, false, corrupt_record("bond", bond_key(realEstateID), err)
+	}
+
+	return b, true, nil
+}
+
+//==============================================================================================================================
+//	 get_legacy_bond_ids - Returns the realEstateIDs in the bondIDs record, the list bonds were kept in before they
+//						   were listed by range over bond~. Some may still be under their bare key. migrate removes the
+//						   record once every bond has been moved.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_legacy_bond_ids(stub shim.ChaincodeStubInterface) ([]string, error) {
+
+	bytes, err := stub.GetState(BOND_LIST_KEY)
+
+	if err != nil {
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Unable to get bondIDs "+BOND_LIST_KEY, err)
+	}
+
+	if bytes == nil {
+		return nil, nil
+	}
+
+	var bondIDs Bond_Holder
+
+	err = json.Unmarshal(bytes, &bondIDs)
+
+	if err != nil {
+		return nil, corrupt_record("Bond_Holder", BOND_LIST_KEY, err)
+	}
+
+	return bondIDs.BondIDs, nil
+}
+
+//==============================================================================================================================
+//	 get_sorted_bond_ids - Returns the realEstateIDs of every bond in ascending order.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_sorted_bond_ids(stub shim.ChaincodeStubInterface) ([]string, error) {
+
+	found := make(map[string]bool)
+
+	ids, err := t.scan_index(stub, BOND_PREFIX, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		found[id] = true
+	}
+
+	legacy, err := t.get_legacy_bond_ids(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range legacy {
+		if !found[id] {
+			found[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+//==============================================================================================================================
+//	 scan_bonds - Calls read with every bond, upgraded as retrieve_bond does. The bonds under bond~ are decoded as the
+//				  range query returns them, in realEstateID order, followed by any left under their bare key. Records
+//				  that cannot be read and listed bonds that are missing are passed to skip instead, see repair.go.
+//==============================================================================================================================
+func (t *SimpleChaincode) scan_bonds(stub shim.ChaincodeStubInterface, read func(Bond) error, skip func(Skipped_Bond) error) error {
+
+	start := index_key(BOND_PREFIX, "")
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "SCAN_BONDS: Error querying bond records: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error querying bond records", err)
+	}
+
+	defer iter.Close()
+
+	found := make(map[string]bool)
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "SCAN_BONDS: Error reading bond records: %s", err)
+			return wrap_error(CODE_LEDGER_ERROR, "Error reading bond records", err)
+		}
+
+		b, err := unmarshal_bond(kv.Value)
+
+		if err != nil {
+
+			id := kv.Key[len(start):]
+			found[id] = true
+
+			err = skip(Skipped_Bond{RealEstateID: id, Code: CODE_CORRUPT_RECORD, Message: corrupt_record("bond", kv.Key, err).Error()})
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		found[b.RealEstateID] = true
+
+		err = read(upgrade_bond(b))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	legacy, err := t.get_legacy_bond_ids(stub)
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range legacy {
+
+		if found[id] {
+			continue
+		}
+
+		b, err := t.retrieve_bond(stub, id)
+
+		if err != nil && skippable(err) {
+			err = skip(Skipped_Bond{RealEstateID: id, Code: error_code(err), Message: err.Error()})
+		} else if err == nil {
+			err = read(b)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 LADM Export - The registry mapped onto the classes of the Land Administration Domain Model (ISO 19152), which
+//				   land registries of other jurisdictions exchange their records in. Each bond becomes:
+//
+//		LA_BAUnit		 - the basic property unit, named by the realEstateID.
+//		LA_SpatialUnit	 - the parcel with its official area, address and boundary, or point when it has none.
+//		LA_Party		 - the owner, a natural person identified by the national ID or a non-natural person by the
+//						   commercial registration number, once per page.
+//		LA_Right		 - the owner's full ownership of the unit.
+//		LA_Restriction	 - the locks of export_bond and wrap_bond, with the extension types crossChannelLock and
+//						   tokenLock. Mortgages and other encumbrances are not recorded on this ledger.
+//		LA_AdministrativeSource - the documents anchored to the bond, and the legacy deed of a migrated bond.
+//
+//	 Identifiers are prefixed by class so they stay unique when merged with another registry's export. Attribute
+//	 names follow the standard rather than the snake case of the chaincode's own records.
+//==============================================================================================================================
+
+const LADM_MODEL = "ISO 19152:2012"
+
+//==============================================================================================================================
+//	 LADM_Party - An LA_Party.
+//==============================================================================================================================
+type LADM_Party struct {
+	PID    string `json:"pID"`
+	Type   string `json:"type"`   // naturalPerson, or nonNaturalPerson for a company
+	ExtPID string `json:"extPID"` // national ID or CR number
+}
+
+//==============================================================================================================================
+//	 LADM_BAUnit - An LA_BAUnit.
+//==============================================================================================================================
+type LADM_BAUnit struct {
+	UID                  string   `json:"uID"`
+	Name                 string   `json:"name"`
+	Type                 string   `json:"type"` // basicPropertyUnit
+	SpatialUnits         []string `json:"suID"`
+	BeginLifespanVersion string   `json:"beginLifespanVersion"`
+	Version              int      `json:"version"`
+}
+
+//==============================================================================================================================
+//	 LADM_Area - An LA_AreaValue.
+//==============================================================================================================================
+type LADM_Area struct {
+	AreaSize float64 `json:"areaSize"` // square metres
+	Type     string  `json:"type"`     // officialArea
+}
+
+//==============================================================================================================================
+//	 LADM_Address - An ExtAddress.
+//==============================================================================================================================
+type LADM_Address struct {
+	AddressAreaName string `json:"addressAreaName,omitempty"` // district
+	PostName        string `json:"postName,omitempty"`        // city
+	StreetName      string `json:"streetName,omitempty"`
+}
+
+//==============================================================================================================================
+//	 LADM_Spatial_Unit - An LA_SpatialUnit.
+//==============================================================================================================================
+type LADM_Spatial_Unit struct {
+	SUID           string        `json:"suID"`
+	Area           []LADM_Area   `json:"area"`
+	ExtAddress     *LADM_Address `json:"extAddress,omitempty"`
+	Geometry       interface{}   `json:"geometry"` // GeoJSON *Polygon or Point
+	ReferencePoint Point         `json:"referencePoint"`
+	DimensionType  string        `json:"dimension"` // 2D
+	LandUse        string        `json:"landUse"`   // status of the bond
+}
+
+//==============================================================================================================================
+//	 LADM_RRR - An LA_Right or LA_Restriction of a party, when any, on a unit.
+//==============================================================================================================================
+type LADM_RRR struct {
+	RID                  string   `json:"rID"`
+	Type                 string   `json:"type"`
+	Share                string   `json:"share,omitempty"`
+	PID                  string   `json:"pID,omitempty"`
+	UID                  string   `json:"uID"`
+	Sources              []string `json:"sID,omitempty"`
+	Description          string   `json:"description,omitempty"`
+	BeginLifespanVersion string   `json:"beginLifespanVersion"`
+}
+
+//==============================================================================================================================
+//	 LADM_Source - An LA_AdministrativeSource.
+//==============================================================================================================================
+type LADM_Source struct {
+	SID            string `json:"sID"`
+	Type           string `json:"type"`
+	AcceptanceDate string `json:"acceptance,omitempty"`
+	ExtArchiveID   string `json:"extArchiveID"` // URI of a document, number of a legacy deed
+	SHA256         string `json:"sha256,omitempty"`
+}
+
+//==============================================================================================================================
+//	 LADM_Page - The response of export_ladm. Bookmark is empty once the last page has been returned.
+//==============================================================================================================================
+type LADM_Page struct {
+	Model        string              `json:"model"`
+	Parties      []LADM_Party        `json:"LA_Party"`
+	BAUnits      []LADM_BAUnit       `json:"LA_BAUnit"`
+	SpatialUnits []LADM_Spatial_Unit `json:"LA_SpatialUnit"`
+	Rights       []LADM_RRR          `json:"LA_Right"`
+	Restrictions []LADM_RRR          `json:"LA_Restriction"`
+	Sources      []LADM_Source       `json:"LA_AdministrativeSource"`
+	Bookmark     string              `json:"bookmark"`
+	Skipped      []string            `json:"skipped,omitempty"` // bonds of the page left out, see get_skipped_bonds
+}
+
+//==============================================================================================================================
+//	 ladm_spatial_unit - Returns the spatial unit of the bond.
+//==============================================================================================================================
+func ladm_spatial_unit(b Bond) LADM_Spatial_Unit {
+
+	su := LADM_Spatial_Unit{
+		SUID:           "su:" + b.RealEstateID,
+		Area:           []LADM_Area{{AreaSize: b.Area.Value, Type: "officialArea"}},
+		Geometry:       bond_feature(b).Geometry,
+		ReferencePoint: Point{Type: "Point", Coordinates: [2]float64{b.Coordinates.Long, b.Coordinates.Lat}},
+		DimensionType:  "2D",
+		LandUse:        b.Status.String(),
+	}
+
+	if b.DistrictCode != "" || b.CityCode != "" || b.Street != "" {
+		su.ExtAddress = &LADM_Address{AddressAreaName: b.DistrictCode, PostName: b.CityCode, StreetName: b.Street}
+	}
+
+	return su
+}
+
+//==============================================================================================================================
+//	 add_ladm_bond - Adds the classes of the bond and its documents to the page.
+//==============================================================================================================================
+func add_ladm_bond(page *LADM_Page, parties map[string]bool, b Bond, documents []Document) {
+
+	unit := "baunit:" + b.RealEstateID
+	party, kind := "party:"+b.OwnerNationalID, "naturalPerson"
+
+	if b.owner_id_type() == OWNER_CR {
+		party, kind = "party:cr:"+b.OwnerNationalID, "nonNaturalPerson" // a CR number may repeat a national ID
+	}
+
+	if !parties[party] {
+		parties[party] = true
+		page.Parties = append(page.Parties, LADM_Party{PID: party, Type: kind, ExtPID: b.OwnerNationalID})
+	}
+
+	page.BAUnits = append(page.BAUnits, LADM_BAUnit{
+		UID:                  unit,
+		Name:                 b.RealEstateID,
+		Type:                 "basicPropertyUnit",
+		SpatialUnits:         []string{"su:" + b.RealEstateID},
+		BeginLifespanVersion: b.UpdatedAt,
+		Version:              b.Version,
+	})
+
+	page.SpatialUnits = append(page.SpatialUnits, ladm_spatial_unit(b))
+
+	var sources []string
+
+	for _, d := range documents {
+		s := LADM_Source{SID: "source:" + d.RealEstateID + ":" + d.SHA256, Type: d.DocType, AcceptanceDate: d.AttachedAt, ExtArchiveID: d.URI, SHA256: d.SHA256}
+		sources = append(sources, s.SID)
+		page.Sources = append(page.Sources, s)
+	}
+
+	if b.Provenance != nil {
+		s := LADM_Source{SID: "source:legacy:" + b.Provenance.LegacyDeedNumber, Type: "legacyDeed", AcceptanceDate: b.Provenance.MigratedAt, ExtArchiveID: b.Provenance.LegacyDeedNumber}
+		sources = append(sources, s.SID)
+		page.Sources = append(page.Sources, s)
+	}
+
+	page.Rights = append(page.Rights, LADM_RRR{
+		RID:                  "right:" + b.RealEstateID,
+		Type:                 "ownership",
+		Share:                "1/1",
+		PID:                  party,
+		UID:                  unit,
+		Sources:              sources,
+		BeginLifespanVersion: b.UpdatedAt,
+	})
+
+	if b.ExportedTo != "" {
+		page.Restrictions = append(page.Restrictions, LADM_RRR{RID: "restriction:channel:" + b.RealEstateID, Type: "crossChannelLock", UID: unit, Description: "Held on channel " + b.ExportedTo, BeginLifespanVersion: b.UpdatedAt})
+	}
+
+	if b.TokenID != "" {
+		page.Restrictions = append(page.Restrictions, LADM_RRR{RID: "restriction:token:" + b.RealEstateID, Type: "tokenLock", UID: unit, Description: "Wrapped as token " + b.TokenID, BeginLifespanVersion: b.UpdatedAt})
+	}
+}
+
+//=================================================================================================================================
+//	 export_ladm - Returns the LADM classes of up to pageSize bonds ordered by realEstateID, starting after the bookmark
+//				   returned with the previous page. An empty bookmark starts from the first bond.
+//=================================================================================================================================
+func (t *SimpleChaincode) export_ladm(stub shim.ChaincodeStubInterface, pageSize int, bookmark string) ([]byte, error) {
+
+	ids, next, err := t.bond_page(stub, "export_ladm", pageSize, bookmark)
+
+	if err != nil {
+		return nil, prefix_error("EXPORT_LADM", err)
+	}
+
+	page := LADM_Page{
+		Model:        LADM_MODEL,
+		Parties:      []LADM_Party{},
+		BAUnits:      []LADM_BAUnit{},
+		SpatialUnits: []LADM_Spatial_Unit{},
+		Rights:       []LADM_RRR{},
+		Restrictions: []LADM_RRR{},
+		Sources:      []LADM_Source{},
+		Bookmark:     next,
+	}
+
+	bonds, skipped, err := t.retrieve_bonds_skipping(stub, "EXPORT_LADM", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page.Skipped = skipped
+	parties := make(map[string]bool)
+
+	for _, b := range bonds {
+
+		documents, err := t.get_stored_documents(stub, b.RealEstateID, "")
+
+		if err != nil {
+			return nil, prefix_error("EXPORT_LADM", err)
+		}
+
+		add_ladm_bond(&page, parties, b, documents)
+	}
+
+	bytes, err := json.Marshal(page)
+
+	if err != nil {
+		log_errorf(stub, "EXPORT_LADM: Error converting records: %s", err)
+		return nil, wrap_error(CODE_ERROR, "EXPORT_LADM: Error converting records", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Legacy Migration - Deeds of the paper and legacy registries are brought onto the ledger by migrate_legacy_bond,
+//						in batches named by the migration team. A migrated bond records its provenance: the legacy
+//						deed number and batch it came from, when and by whom. The old records were surveyed to other
+//						standards, so migrated bonds keep their declared area and are not checked for duplicates or
+//						overlapping boundaries; those conflicts are for the registry to resolve after migration, and
+//						get_migration_batch and get_bond_by_legacy_deed trace every bond back to its source.
+//==============================================================================================================================
+
+const MIGRATION_INDEX = "migration_batch" // migration_batch~batch~realEstateID
+const LEGACY_DEED_INDEX = "legacy_deed"   // legacy_deed~deedNumber~realEstateID
+
+// Origin of a migrated bond
+const PROVENANCE_MIGRATED = "migrated"
+
+var LEGACY_REFERENCE = regexp.MustCompile(`^[A-Za-z0-9._/-]{1,64}$`)
+
+//==============================================================================================================================
+//	 Provenance - Where a bond that was not created on the ledger came from.
+//==============================================================================================================================
+type Provenance struct {
+	Origin           string `json:"origin"`
+	LegacyDeedNumber string `json:"legacy_deed_number"`
+	MigrationBatch   string `json:"migration_batch"`
+	MigratedAt       string `json:"migrated_at"`
+	MigratedBy       string `json:"migrated_by"`
+}
+
+//=================================================================================================================================
+//	 migrate_legacy_bond - Creates the bond of the create_bond arguments as migrated from the legacy deed in the batch.
+//						   Returns the bond ID. Admin only.
+//=================================================================================================================================
+func (t *SimpleChaincode) migrate_legacy_bond(stub shim.ChaincodeStubInterface, batchID string, deedNumber string, args []string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !LEGACY_REFERENCE.MatchString(batchID) {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "MIGRATE_LEGACY_BOND: Invalid batch ID "+batchID)
+	}
+
+	if !LEGACY_REFERENCE.MatchString(deedNumber) {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "MIGRATE_LEGACY_BOND: Invalid legacy deed number "+deedNumber)
+	}
+
+	migrated, err := t.scan_index(stub, LEGACY_DEED_INDEX, deedNumber+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, prefix_error("MIGRATE_LEGACY_BOND", err)
+	}
+
+	if len(migrated) > 0 {
+		return nil, coded_error(CODE_BOND_EXISTS, "MIGRATE_LEGACY_BOND: Legacy deed "+deedNumber+" was migrated as "+migrated[0])
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("MIGRATE_LEGACY_BOND", err)
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "MIGRATE_LEGACY_BOND: Error reading caller identity: %s", err)
+		return nil, wrap_error(CODE_NOT_AUTHORIZED, "MIGRATE_LEGACY_BOND: Error reading caller identity", err)
+	}
+
+	p := Provenance{
+		Origin:           PROVENANCE_MIGRATED,
+		LegacyDeedNumber: deedNumber,
+		MigrationBatch:   batchID,
+		MigratedAt:       now.Format(TIME_LAYOUT),
+		MigratedBy:       actor,
+	}
+
+	id, err := t.new_bond(stub, args, &p)
+
+	if err != nil {
+		return nil, prefix_error("MIGRATE_LEGACY_BOND", err)
+	}
+
+	err = t.put_index(stub, MIGRATION_INDEX, batchID, args[1])
+
+	if err != nil {
+		log_errorf(stub, "MIGRATE_LEGACY_BOND: Error updating migration index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "MIGRATE_LEGACY_BOND: Error updating migration index", err)
+	}
+
+	err = t.put_index(stub, LEGACY_DEED_INDEX, deedNumber, args[1])
+
+	if err != nil {
+		log_errorf(stub, "MIGRATE_LEGACY_BOND: Error updating legacy deed index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "MIGRATE_LEGACY_BOND: Error updating legacy deed index", err)
+	}
+
+	return id, nil
+}
+
+//=================================================================================================================================
+//	 get_migration_batch - Returns the bonds migrated in the batch.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_migration_batch(stub shim.ChaincodeStubInterface, batchID string) ([]byte, error) {
+
+	ids, err := t.scan_index(stub, MIGRATION_INDEX, batchID+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, prefix_error("GET_MIGRATION_BATCH", err)
+	}
+
+	bonds, err := t.retrieve_listed_bonds(stub, "GET_MIGRATION_BATCH", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		log_errorf(stub, "GET_MIGRATION_BATCH: Error converting bonds: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_MIGRATION_BATCH: Error converting bonds", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 get_bond_by_legacy_deed - Returns the bond migrated from the legacy deed.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bond_by_legacy_deed(stub shim.ChaincodeStubInterface, deedNumber string) ([]byte, error) {
+
+	ids, err := t.scan_index(stub, LEGACY_DEED_INDEX, deedNumber+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, prefix_error("GET_BOND_BY_LEGACY_DEED", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, coded_error(CODE_BOND_NOT_FOUND, "GET_BOND_BY_LEGACY_DEED: No bond was migrated from legacy deed "+deedNumber)
+	}
+
+	b, err := t.retrieve_bond(stub, ids[0])
+
+	if err != nil {
+		return nil, prefix_error("GET_BOND_BY_LEGACY_DEED", err)
+	}
+
+	bytes, err := json.Marshal(b)
+
+	if err != nil {
+		log_errorf(stub, "GET_BOND_BY_LEGACY_DEED: Error converting bond: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_BOND_BY_LEGACY_DEED: Error converting bond", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Logging - Entries go to the shim logger of the chaincode, which the peer collects with the chaincode container output, each tagged
+//			   with the transaction so the entries of one invocation can be found among those of the others, e.g.
+//
+//		[tx=9f2c... fn=create_bond msp=Org1MSP] CREATE_BOND: Error updating owner index: ...
+//
+//			   The level is the log_level parameter of the configuration, so set_config '{"log_level":"DEBUG"}'
+//			   changes it on every peer without restarting the chaincode.
+//==============================================================================================================================
+
+const DEFAULT_LOG_LEVEL = "INFO"
+
+//==============================================================================================================================
+//	 set_log_level - Sets the level of the logger, DEBUG, INFO, NOTICE, WARNING, ERROR or CRITICAL.
+//==============================================================================================================================
+func set_log_level(level string) error {
+
+	l, err := shim.LogLevel(level)
+
+	if err != nil {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid log level "+level)
+	}
+
+	logger.SetLevel(l)
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 log_context - Returns the tag of the entries logged by the transaction. The MSP is read directly rather than with
+//				   caller_msp as that logs its own errors.
+//==============================================================================================================================
+func log_context(stub shim.ChaincodeStubInterface) string {
+
+	function, _ := stub.GetFunctionAndParameters()
+
+	msp, err := cid.GetMSPID(stub)
+
+	if err != nil {
+		msp = "unknown"
+	}
+
+	return "[tx=" + stub.GetTxID() + " fn=" + function + " msp=" + msp + "] "
+}
+
+//==============================================================================================================================
+//	 log_debugf, log_infof, log_warningf, log_errorf - Log the message at their level, tagged with the transaction.
+//==============================================================================================================================
+func log_debugf(stub shim.ChaincodeStubInterface, format string, args ...interface{}) {
+	if logger.IsEnabledFor(shim.LogDebug) {
+		logger.Debug(log_context(stub) + strings.TrimSpace(fmt.Sprintf(format, args...)))
+	}
+}
+
+func log_infof(stub shim.ChaincodeStubInterface, format string, args ...interface{}) {
+	if logger.IsEnabledFor(shim.LogInfo) {
+		logger.Info(log_context(stub) + strings.TrimSpace(fmt.Sprintf(format, args...)))
+	}
+}
+
+func log_warningf(stub shim.ChaincodeStubInterface, format string, args ...interface{}) {
+	if logger.IsEnabledFor(shim.LogWarning) {
+		logger.Warning(log_context(stub) + strings.TrimSpace(fmt.Sprintf(format, args...)))
+	}
+}
+
+func log_errorf(stub shim.ChaincodeStubInterface, format string, args ...interface{}) {
+	if logger.IsEnabledFor(shim.LogError) {
+		logger.Error(log_context(stub) + strings.TrimSpace(fmt.Sprintf(format, args...)))
+	}
+}
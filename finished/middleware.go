@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"runtime/debug"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Middleware - Invoke passes every call through a chain of middleware before its handler, so the concerns shared by
+//				  all functions are handled once rather than in each function. The chain runs in the order of
+//				  MIDDLEWARE, each stage calling the next or returning early with an error:
+//
+//		recover_panics  turns a panic into an error so one bad call cannot take the chaincode down
+//		log_calls       logs the call and its failure
+//		measure_calls   logs how long the call took
+//		audit_writes    logs who attempted a function changing the ledger and whether it succeeded
+//		check_input     rejects payloads that are too large and arguments not matching the Arg_Spec
+//		check_access    rejects callers lacking the role of the function or its feature flags
+//		check_documents rejects calls on bonds lacking the documents the configuration requires
+//
+//				  Changes to bonds are also recorded on the ledger by record_audit, see audit.go.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Middleware - Returns the handler calling next for the function, adding a concern around it.
+//==============================================================================================================================
+type Middleware func(function string, entry Function_Entry, next Handler) Handler
+
+//==============================================================================================================================
+//	 MIDDLEWARE - The chain, outermost first.
+//==============================================================================================================================
+var MIDDLEWARE = []Middleware{
+	recover_panics,
+	log_calls,
+	measure_calls,
+	audit_writes,
+	check_input,
+	check_access,
+	check_documents,
+}
+
+//==============================================================================================================================
+//	 pipeline - Returns the handler of the function wrapped in the chain.
+//==============================================================================================================================
+func pipeline(function string, entry Function_Entry, handler Handler) Handler {
+
+	for i := len(MIDDLEWARE) - 1; i >= 0; i-- {
+		handler = MIDDLEWARE[i](function, entry, handler)
+	}
+
+	return handler
+}
+
+//==============================================================================================================================
+//	 recover_panics - Returns the error of a call that panicked, logging the stack.
+//==============================================================================================================================
+func recover_panics(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) (bytes []byte, err error) {
+
+		defer func() {
+			if r := recover(); r != nil {
+				log_errorf(stub, "INVOKE: Panic in %s: %v\n%s", function, r, debug.Stack())
+				bytes, err = nil, errors.New("INVOKE: Internal error in "+function)
+			}
+		}()
+
+		return next(t, stub, args)
+	}
+}
+
+//==============================================================================================================================
+//	 log_calls - Logs the call and, when it fails, the error and its code.
+//==============================================================================================================================
+func log_calls(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		log_debugf(stub, "INVOKE: Called with %d arguments", len(args))
+
+		bytes, err := next(t, stub, args)
+
+		if err != nil {
+			log_warningf(stub, "INVOKE: Failed with %s: %s", error_code(err), err)
+		}
+
+		return bytes, err
+	}
+}
+
+//==============================================================================================================================
+//	 measure_calls - Logs the time the call took. The time is that of the peer, not of the transaction, as it is only
+//					 logged and never stored.
+//==============================================================================================================================
+func measure_calls(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		start := time.Now()
+
+		bytes, err := next(t, stub, args)
+
+		log_debugf(stub, "INVOKE: Took %s", time.Since(start))
+
+		return bytes, err
+	}
+}
+
+//==============================================================================================================================
+//	 audit_writes - Logs the caller and outcome of a function changing the ledger, rejected attempts included.
+//==============================================================================================================================
+func audit_writes(function string, entry Function_Entry, next Handler) Handler {
+
+	if !entry.Writes {
+		return next
+	}
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		bytes, err := next(t, stub, args)
+
+		actor, e := cid.GetID(stub)
+
+		if e != nil {
+			actor = "unknown"
+		}
+
+		outcome := "succeeded"
+
+		if err != nil {
+			outcome = "failed with " + error_code(err)
+		}
+
+		log_infof(stub, "AUDIT: %s by %s %s", function, actor, outcome)
+
+		return bytes, err
+	}
+}
+
+//==============================================================================================================================
+//	 check_input - Rejects the call when the payload is too large or the arguments do not match the Arg_Spec.
+//==============================================================================================================================
+func check_input(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		err := t.check_payload_size(stub)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = check_args(function, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return next(t, stub, args)
+	}
+}
+
+//==============================================================================================================================
+//	 check_access - Rejects the call when the caller lacks the role of the function, or the feature flags disable or
+//					restrict it.
+//==============================================================================================================================
+func check_access(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		err := t.check_role(stub, function, entry.Role)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.check_features(stub, function)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return next(t, stub, args)
+	}
+}
+
+//==============================================================================================================================
+//	 check_documents - Rejects the call when the bond lacks the documents the configuration requires for the function.
+//==============================================================================================================================
+func check_documents(function string, entry Function_Entry, next Handler) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		err := t.check_required_documents(stub, function, args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return next(t, stub, args)
+	}
+}
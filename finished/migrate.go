@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Data Migration - Bonds are stamped with the version of the schema they were written in. retrieve_bond upgrades
+//					  older bonds to the current version as they are read, and they are stored upgraded the next time
+//					  they are saved. After an upgrade changes the schema the regulator can also run migrate to rewrite
+//					  every bond at once. Bonds are migrated a batch at a time, in realEstateID order, so large
+//					  registries fit in several transactions; the progress is kept on the ledger and each call resumes
+//					  from it.
+//==============================================================================================================================
+
+const MIGRATION_KEY = "migration_progress"
+
+// Bonds rewritten per migrate call unless a batch size is passed
+const MIGRATION_BATCH_SIZE = 100
+
+// Version of the bond schema written by this chaincode
+const SCHEMA_VERSION = 3
+
+//==============================================================================================================================
+//	 MIGRATIONS - The step that brings a bond from the previous schema version up to the version it is keyed by. Steps
+//				  only change the bond, the index entries of upgraded bonds are written when they are saved.
+//
+//		1 - bonds stored before versioning, which have no schema_version.
+//		2 - typed area and coordinates instead of free text, and a geohash kept in the geohash index.
+//		3 - stored under bond~realEstateID instead of the bare realEstateID. No step, put_bond moves the record and
+//			a completed migration removes the bondIDs list.
+//==============================================================================================================================
+var MIGRATIONS = map[int]func(Bond) Bond{
+	2: migrate_typed_fields,
+}
+
+//==============================================================================================================================
+//	 Migration_Progress - The state of a migration, returned by every migrate call. After is the last bond migrated.
+//==============================================================================================================================
+type Migration_Progress struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	After    string `json:"after"`
+	Migrated int    `json:"migrated"`
+	Done     bool   `json:"done"`
+}
+
+//=================================================================================================================================
+//	 migrate - Admin function migrating the next batch of bonds from one schema version to the current one. Call it
+//			   again until the progress returned is done. Starting a migration between other versions discards the
+//			   progress of the previous one.
+//=================================================================================================================================
+func (t *SimpleChaincode) migrate(stub shim.ChaincodeStubInterface, from int, to int, batchSize int) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("MIGRATE", err)
+	}
+
+	if from < 1 || from >= to || to != SCHEMA_VERSION {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "MIGRATE: Cannot migrate from version "+strconv.Itoa(from)+" to "+strconv.Itoa(to))
+	}
+
+	if batchSize < 1 {
+		batchSize = MIGRATION_BATCH_SIZE
+	}
+
+	progress := Migration_Progress{From: from, To: to}
+
+	bytes, err := stub.GetState(MIGRATION_KEY)
+
+	if err != nil {
+		return nil, wrap_error(CODE_LEDGER_ERROR, "MIGRATE: Error retrieving migration progress "+MIGRATION_KEY, err)
+	}
+
+	if bytes != nil {
+
+		var previous Migration_Progress
+
+		err = json.Unmarshal(bytes, &previous)
+
+		if err != nil {
+			return nil, errors.New("MIGRATE: Corrupt migration progress " + string(bytes))
+		}
+
+		if previous.From == from && previous.To == to {
+			progress = previous
+		}
+	}
+
+	if !progress.Done {
+
+		ids, err := t.get_sorted_bond_ids(stub)
+
+		if err != nil {
+			return nil, err
+		}
+
+		count := 0
+
+		for _, id := range ids {
+
+			if id <= progress.After {
+				continue
+			}
+
+			if count == batchSize {
+				break
+			}
+
+			b, err := t.retrieve_bond(stub, id) // upgraded as it is read
+
+			if err != nil {
+				return nil, wrap_error(CODE_ERROR, "MIGRATE: Failed to retrieve bond "+id, err)
+			}
+
+			err = t.put_bond(stub, b)
+
+			if err != nil {
+				return nil, prefix_error("MIGRATE", err)
+			}
+
+			progress.After = id
+			progress.Migrated++
+			count++
+		}
+
+		progress.Done = count < batchSize || progress.After == ids[len(ids)-1]
+
+		if progress.Done {
+
+			err = stub.DelState(BOND_LIST_KEY) // every bond listed is now under bond~
+
+			if err != nil {
+				return nil, wrap_error(CODE_LEDGER_ERROR, "MIGRATE: Error removing bondIDs "+BOND_LIST_KEY, err)
+			}
+		}
+	}
+
+	bytes, err = json.Marshal(progress)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "MIGRATE: Error converting migration progress", err)
+	}
+
+	err = stub.PutState(MIGRATION_KEY, bytes)
+
+	if err != nil {
+		return nil, wrap_error(CODE_LEDGER_ERROR, "MIGRATE: Error storing migration progress "+MIGRATION_KEY, err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 upgrade_bond - Applies the migration steps from the bond's schema version up to the current one. Upgraded bonds are
+//					marked so put_bond writes their index entries.
+//==============================================================================================================================
+func upgrade_bond(b Bond) Bond {
+
+	version := b.SchemaVersion
+
+	if version == 0 {
+		version = 1
+	}
+
+	if version >= SCHEMA_VERSION {
+		return b
+	}
+
+	for v := version + 1; v <= SCHEMA_VERSION; v++ {
+
+		step, ok := MIGRATIONS[v]
+
+		if ok {
+			b = step(b)
+		}
+	}
+
+	b.SchemaVersion = SCHEMA_VERSION
+	b.upgraded = true
+
+	return b
+}
+
+//==============================================================================================================================
+//	 migrate_typed_fields - Version 2. Legacy text areas and coordinates are already parsed when the bond is read so
+//							writing it back stores them typed, only the geohash has to be computed. Bonds whose coordinates
+//							did not parse are left without one.
+//==============================================================================================================================
+func migrate_typed_fields(b Bond) Bond {
+
+	b.Geohash = b.Coordinates.geohash()
+
+	return b
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Modified Index - Bonds are indexed by the time they were last changed so downstream systems can pull only what
+//					  changed since their last sync.
+//==============================================================================================================================
+
+const MODIFIED_INDEX = "modified"
+
+//==============================================================================================================================
+//	 touch_bond - Sets UpdatedAt and LastModifiedTx to the time and ID of the current transaction and moves the bond's
+//				  modified index entry to match. Returns the bond ready to be saved.
+//==============================================================================================================================
+func (t *SimpleChaincode) touch_bond(stub shim.ChaincodeStubInterface, b Bond) (Bond, error) {
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return b, err
+	}
+
+	if b.UpdatedAt != "" {
+		err = t.del_index(stub, MODIFIED_INDEX, b.UpdatedAt, b.RealEstateID)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	b.UpdatedAt = now.Format(TIME_LAYOUT)
+	b.LastModifiedTx = stub.GetTxID()
+
+	return b, t.put_index(stub, MODIFIED_INDEX, b.UpdatedAt, b.RealEstateID)
+}
+
+//=================================================================================================================================
+//	 get_bonds_modified_since - Returns the bonds changed at or after the RFC 3339 timestamp, oldest change first.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bonds_modified_since(stub shim.ChaincodeStubInterface, timestamp string) ([]byte, error) {
+
+	since, err := time.Parse(time.RFC3339, timestamp)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_BONDS_MODIFIED_SINCE: Invalid timestamp "+timestamp+", expecting RFC 3339")
+	}
+
+	ids, err := t.scan_index_range(stub, index_key(MODIFIED_INDEX, since.UTC().Format(TIME_LAYOUT)), index_key(MODIFIED_INDEX, "\xff"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds, err := t.retrieve_listed_bonds(stub, "GET_BONDS_MODIFIED_SINCE", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_BONDS_MODIFIED_SINCE: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
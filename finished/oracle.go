@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Identity Oracle - The civil registry confirms off-chain that the recipient of a transfer is who they claim to be and
+//					   may hold property. request_identity_check records a pending check of the recipient for the bond
+//					   and emits IdentityCheckRequested; the civil registry's oracle, a client holding the oracle_role
+//					   of the configuration, answers it with post_identity_check.
+//
+//					   With the identity_oracle feature on, tranfer_bond needs a check of the recipient for the bond
+//					   confirmed within IDENTITY_CHECK_VALIDITY, and uses it up. Checks are stored under
+//					   identity_check~<txid of the request> and indexed by bond and recipient.
+//==============================================================================================================================
+
+const IDENTITY_CHECK_PREFIX = "identity_check"
+const IDENTITY_CHECK_INDEX = "identity_check_index"
+
+const IDENTITY_CHECK_REQUESTED_EVENT = "IdentityCheckRequested"
+
+// Default role of the civil registry's oracle
+const CIVIL_REGISTRY_ORACLE = "civil_registry"
+
+// How long a confirmation can be used for a transfer
+const IDENTITY_CHECK_VALIDITY = 30 * 24 * time.Hour
+
+// States of a check
+const CHECK_PENDING = "pending"
+const CHECK_CONFIRMED = "confirmed" // the recipient is who they claim to be and may hold the bond
+const CHECK_REJECTED = "rejected"
+
+//==============================================================================================================================
+//	 Identity_Check - A check of the recipient of a transfer. UsedTx is the transfer that used up a confirmed check.
+//==============================================================================================================================
+type Identity_Check struct {
+	ID           string        `json:"id"`
+	RealEstateID string        `json:"real_estate_id"`
+	NationalID   string        `json:"national_id"` // or CR number of a company
+	IDType       Owner_ID_Type `json:"id_type"`
+	Foreign      bool          `json:"foreign,omitempty"` // a resident, subject to the foreign ownership rules
+	Status       string        `json:"status"`
+	RequestedAt  string        `json:"requested_at"`
+	RequestedBy  string        `json:"requested_by"`
+	RespondedAt  string        `json:"responded_at,omitempty"`
+	RespondedBy  string        `json:"responded_by,omitempty"`
+	Reference    string        `json:"reference,omitempty"` // of the civil registry's decision
+	UsedTx       string        `json:"used_tx,omitempty"`
+}
+
+//==============================================================================================================================
+//	 get_stored_identity_check - Returns the check of the ID, or a not found error.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_stored_identity_check(stub shim.ChaincodeStubInterface, id string) (Identity_Check, error) {
+
+	var c Identity_Check
+
+	bytes, err := stub.GetState(index_key(IDENTITY_CHECK_PREFIX, id))
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_IDENTITY_CHECK: Error reading check %s: %s", id, err)
+		return c, wrap_error(CODE_LEDGER_ERROR, "Error reading identity check "+index_key(IDENTITY_CHECK_PREFIX, id), err)
+	}
+
+	if bytes == nil {
+		return c, coded_error(CODE_INVALID_ARGUMENT, "No identity check "+id)
+	}
+
+	err = json.Unmarshal(bytes, &c)
+
+	if err != nil {
+		return c, errors.New("Corrupt identity check " + string(bytes))
+	}
+
+	return c, nil
+}
+
+//==============================================================================================================================
+//	 put_identity_check - Writes the check.
+//==============================================================================================================================
+func (t *SimpleChaincode) put_identity_check(stub shim.ChaincodeStubInterface, c Identity_Check) ([]byte, error) {
+
+	bytes, err := json.Marshal(c)
+
+	if err != nil {
+		log_errorf(stub, "PUT_IDENTITY_CHECK: Error converting check: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error converting identity check", err)
+	}
+
+	err = stub.PutState(index_key(IDENTITY_CHECK_PREFIX, c.ID), bytes)
+
+	if err != nil {
+		log_errorf(stub, "PUT_IDENTITY_CHECK: Error storing check: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Error storing identity check "+index_key(IDENTITY_CHECK_PREFIX, c.ID), err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 request_identity_check - Asks the civil registry to check the recipient of a transfer of the bond, a person unless
+//							  idType is cr. Returns the check, whose ID the oracle answers.
+//=================================================================================================================================
+func (t *SimpleChaincode) request_identity_check(stub shim.ChaincodeStubInterface, realEstateID string, nationalID string, idType string) ([]byte, error) {
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("REQUEST_IDENTITY_CHECK", err)
+	}
+
+	var problems Field_Errors
+
+	problems.check_owner_id("national_id", nationalID, "id_type", idType)
+
+	err = problems.to_error("REQUEST_IDENTITY_CHECK", "identity check")
+
+	if err != nil {
+		return nil, err
+	}
+
+	kind, _ := parse_owner_id_type(idType)
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("REQUEST_IDENTITY_CHECK", err)
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "REQUEST_IDENTITY_CHECK: Error reading caller identity: %s", err)
+		return nil, wrap_error(CODE_NOT_AUTHORIZED, "REQUEST_IDENTITY_CHECK: Error reading caller identity", err)
+	}
+
+	c := Identity_Check{
+		ID:           stub.GetTxID(),
+		RealEstateID: b.RealEstateID,
+		NationalID:   nationalID,
+		IDType:       kind,
+		Foreign:      is_foreign_owner(kind, nationalID),
+		Status:       CHECK_PENDING,
+		RequestedAt:  now.Format(TIME_LAYOUT),
+		RequestedBy:  actor,
+	}
+
+	bytes, err := t.put_identity_check(stub, c)
+
+	if err != nil {
+		return nil, prefix_error("REQUEST_IDENTITY_CHECK", err)
+	}
+
+	err = t.put_index(stub, IDENTITY_CHECK_INDEX, c.RealEstateID, c.NationalID, c.ID)
+
+	if err != nil {
+		log_errorf(stub, "REQUEST_IDENTITY_CHECK: Error updating check index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "REQUEST_IDENTITY_CHECK: Error updating check index", err)
+	}
+
+	err = t.emit_event(stub, IDENTITY_CHECK_REQUESTED_EVENT, c.RealEstateID, c, event_routing([]string{c.NationalID}, []string{b.DistrictCode}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 post_identity_check - Records the civil registry's answer to a pending check, confirmed or rejected. Oracle only.
+//=================================================================================================================================
+func (t *SimpleChaincode) post_identity_check(stub shim.ChaincodeStubInterface, id string, result string, reference string) ([]byte, error) {
+
+	role, err := t.check_affiliation(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_IDENTITY_CHECK", err)
+	}
+
+	config, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_IDENTITY_CHECK", err)
+	}
+
+	if role != config.OracleRole {
+		return nil, coded_error(CODE_NOT_AUTHORIZED, "POST_IDENTITY_CHECK: Permission denied, checks are answered by "+config.OracleRole)
+	}
+
+	if result != CHECK_CONFIRMED && result != CHECK_REJECTED {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "POST_IDENTITY_CHECK: Invalid result "+result+", expecting "+CHECK_CONFIRMED+" or "+CHECK_REJECTED)
+	}
+
+	c, err := t.get_stored_identity_check(stub, id)
+
+	if err != nil {
+		return nil, prefix_error("POST_IDENTITY_CHECK", err)
+	}
+
+	if c.Status != CHECK_PENDING {
+		return nil, coded_error(CODE_INVALID_STATE, "POST_IDENTITY_CHECK: Check "+id+" is already "+c.Status)
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_IDENTITY_CHECK", err)
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "POST_IDENTITY_CHECK: Error reading caller identity: %s", err)
+		return nil, wrap_error(CODE_NOT_AUTHORIZED, "POST_IDENTITY_CHECK: Error reading caller identity", err)
+	}
+
+	c.Status = result
+	c.RespondedAt = now.Format(TIME_LAYOUT)
+	c.RespondedBy = actor
+	c.Reference = reference
+
+	bytes, err := t.put_identity_check(stub, c)
+
+	if err != nil {
+		return nil, prefix_error("POST_IDENTITY_CHECK", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 get_identity_check - Returns the check of the ID.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_identity_check(stub shim.ChaincodeStubInterface, id string) ([]byte, error) {
+
+	c, err := t.get_stored_identity_check(stub, id)
+
+	if err != nil {
+		return nil, prefix_error("GET_IDENTITY_CHECK", err)
+	}
+
+	bytes, err := json.Marshal(c)
+
+	if err != nil {
+		log_errorf(stub, "GET_IDENTITY_CHECK: Error converting check: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_IDENTITY_CHECK: Error converting check", err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 use_identity_check - Uses up a confirmed check of the recipient for the bond, when the identity_oracle feature
+//						  requires one. Returns an error when there is none.
+//==============================================================================================================================
+func (t *SimpleChaincode) use_identity_check(stub shim.ChaincodeStubInterface, realEstateID string, nationalID string) error {
+
+	config, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	if !config.Features[FEATURE_IDENTITY_ORACLE] {
+		return nil
+	}
+
+	ids, err := t.scan_index(stub, IDENTITY_CHECK_INDEX, index_key(realEstateID, nationalID)+INDEX_SEPARATOR)
+
+	if err != nil {
+		return err
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+
+		c, err := t.get_stored_identity_check(stub, id)
+
+		if err != nil {
+			return err
+		}
+
+		if c.Status != CHECK_CONFIRMED || c.UsedTx != "" {
+			continue
+		}
+
+		responded, err := time.Parse(TIME_LAYOUT, c.RespondedAt)
+
+		if err != nil || now.Sub(responded) > IDENTITY_CHECK_VALIDITY {
+			continue
+		}
+
+		c.UsedTx = stub.GetTxID()
+
+		_, err = t.put_identity_check(stub, c)
+
+		return err
+	}
+
+	return coded_error(CODE_INVALID_STATE, "No confirmed civil registry check of "+nationalID+" for "+realEstateID)
+}
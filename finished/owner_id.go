@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+//==============================================================================================================================
+//	 Owner Identifiers - An owner is a person, identified by their national ID, or a company, identified by its commercial
+//						 registration (CR) number. Both are ten digits and a CR number can look like a citizen's ID,
+//						 so the type is passed alongside the number rather than guessed from it:
+//
+//		national_id - ten digits starting with 1 for citizens and 2 for residents. The default.
+//		cr			- ten digits, the first four the registering office, e.g. 1010 for Riyadh.
+//
+//						 The number stays in owner_national_id, so indexes, owner queries and events are unchanged;
+//						 owner_id_type is only stored for companies. Residents are foreign owners, which the civil
+//						 registry is told of with each identity check so it applies the foreign ownership rules. The
+//						 nationality of a company is not in its number and is left to the registry.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Owner_ID_Type - The type of an owner identifier.
+//==============================================================================================================================
+type Owner_ID_Type string
+
+const OWNER_NATIONAL_ID Owner_ID_Type = "national_id"
+const OWNER_CR Owner_ID_Type = "cr"
+
+// Ten digits, the first four the office of the Ministry of Commerce that registered the company
+var COMMERCIAL_REGISTRATION = regexp.MustCompile(`^[1-7][0-9]{9}$`)
+
+//==============================================================================================================================
+//	 parse_owner_id_type - Returns the type named, OWNER_NATIONAL_ID when the name is empty.
+//==============================================================================================================================
+func parse_owner_id_type(name string) (Owner_ID_Type, error) {
+
+	switch Owner_ID_Type(name) {
+	case "", OWNER_NATIONAL_ID:
+		return OWNER_NATIONAL_ID, nil
+	case OWNER_CR:
+		return OWNER_CR, nil
+	}
+
+	return OWNER_NATIONAL_ID, coded_error(CODE_INVALID_ARGUMENT, "Unknown owner ID type "+name+", expecting "+string(OWNER_NATIONAL_ID)+" or "+string(OWNER_CR))
+}
+
+//==============================================================================================================================
+//	 stored - Returns the type as stored on a bond, empty for a national ID as on bonds registered before companies.
+//==============================================================================================================================
+func (k Owner_ID_Type) stored() Owner_ID_Type {
+
+	if k == OWNER_NATIONAL_ID {
+		return ""
+	}
+
+	return k
+}
+
+//==============================================================================================================================
+//	 owner_id_type - Returns the type of the owner identifier of the bond.
+//==============================================================================================================================
+func (b Bond) owner_id_type() Owner_ID_Type {
+
+	if b.OwnerIDType == "" {
+		return OWNER_NATIONAL_ID
+	}
+
+	return b.OwnerIDType
+}
+
+//==============================================================================================================================
+//	 is_foreign_owner - Reports whether the owner is known from the identifier to be foreign, a resident.
+//==============================================================================================================================
+func is_foreign_owner(kind Owner_ID_Type, id string) bool {
+	return kind == OWNER_NATIONAL_ID && strings.HasPrefix(id, "2")
+}
+
+//==============================================================================================================================
+//	 check_owner_id - Checks the identifier of an owner against the rules of its type, named by typeField. The type
+//					  may be empty for a national ID.
+//==============================================================================================================================
+func (e *Field_Errors) check_owner_id(field string, id string, typeField string, kind string) {
+
+	parsed, err := parse_owner_id_type(kind)
+
+	if err != nil {
+		e.add(typeField, FIELD_INVALID_FORMAT, err.Error())
+		e.required(field, id)
+		return
+	}
+
+	if parsed == OWNER_NATIONAL_ID {
+		e.check_national_id(field, id)
+		return
+	}
+
+	if e.required(field, id) && !COMMERCIAL_REGISTRATION.MatchString(id) {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid commercial registration number "+id+", expecting 10 digits starting with 1 to 7")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Owner Functions - Bonds are indexed by the national ID of their owner so an owner's holdings can be found without
+//					   reading every bond.
+//==============================================================================================================================
+
+const OWNER_INDEX = "owner"
+
+//==============================================================================================================================
+//	 Owner_Summary - The response of get_owner_summary.
+//==============================================================================================================================
+type Owner_Summary struct {
+	OwnerNationalID string         `json:"owner_national_id"`
+	TotalBonds      int            `json:"total_bonds"`
+	TotalArea       Land_Area      `json:"total_area"`
+	BondsByStatus   map[string]int `json:"bonds_by_status"`
+	RealEstateIDs   []string       `json:"real_estate_ids"`
+}
+
+//==============================================================================================================================
+//	 get_owner_bonds - Returns every bond held by the owner.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_owner_bonds(stub shim.ChaincodeStubInterface, nationalID string) ([]Bond, error) {
+
+	ids, err := t.scan_index(stub, OWNER_INDEX, nationalID+INDEX_SEPARATOR)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t.retrieve_listed_bonds(stub, "GET_OWNER_BONDS", ids)
+}
+
+//=================================================================================================================================
+//	 get_owner_summary - Aggregates the number, total area and statuses of the bonds held by the owner.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_owner_summary(stub shim.ChaincodeStubInterface, nationalID string) ([]byte, error) {
+
+	bonds, err := t.get_owner_bonds(stub, nationalID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	summary := Owner_Summary{
+		OwnerNationalID: nationalID,
+		TotalArea:       Land_Area{Unit: AREA_UNIT},
+		BondsByStatus:   make(map[string]int),
+		RealEstateIDs:   []string{},
+	}
+
+	for _, b := range bonds {
+		summary.TotalBonds++
+		summary.TotalArea.Value += b.Area.Value
+		summary.BondsByStatus[b.Status.String()]++
+		summary.RealEstateIDs = append(summary.RealEstateIDs, b.RealEstateID)
+	}
+
+	bytes, err := json.Marshal(summary)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_OWNER_SUMMARY: Error converting owner summary", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 get_bonds_by_owner - Returns every bond held by the owner as a JSON array.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bonds_by_owner(stub shim.ChaincodeStubInterface, nationalID string) ([]byte, error) {
+
+	bonds, err := t.get_owner_bonds(stub, nationalID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_BONDS_BY_OWNER: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
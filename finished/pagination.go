@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+//==============================================================================================================================
+//	 Pagination - Listing queries return a bookmark that carries everything needed to continue, the query it belongs to
+//				  and the last key returned. Nothing is kept between calls so every peer returns the same next page for
+//				  the same bookmark, and the encoding is fixed so the same page always produces the same bookmark.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Bookmark - The decoded contents of a pagination bookmark.
+//==============================================================================================================================
+type Bookmark struct {
+	Query string `json:"q"`
+	After string `json:"after"`
+}
+
+//==============================================================================================================================
+//	 encode_bookmark - Returns the bookmark continuing query after the given key.
+//==============================================================================================================================
+func encode_bookmark(query string, after string) string {
+
+	bytes, _ := json.Marshal(Bookmark{Query: query, After: after}) // marshalling two strings cannot fail
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+//==============================================================================================================================
+//	 decode_bookmark - Returns the key a bookmark continues after. An empty bookmark starts from the beginning, bookmarks
+//					   issued by another query are rejected.
+//==============================================================================================================================
+func decode_bookmark(query string, bookmark string) (string, error) {
+
+	if bookmark == "" {
+		return "", nil
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(bookmark)
+
+	if err != nil {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "Invalid bookmark")
+	}
+
+	var b Bookmark
+
+	err = json.Unmarshal(bytes, &b)
+
+	if err != nil || b.After == "" {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "Invalid bookmark")
+	}
+
+	if b.Query != query {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "Bookmark was issued by "+b.Query+" not "+query)
+	}
+
+	return b.After, nil
+}
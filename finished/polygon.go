@@ -0,0 +1,236 @@
+package main
+
+import "math"
+
+//==============================================================================================================================
+//	 Polygon Functions - Parcel boundaries and planar geometry on [long, lat] rings. Parcels are small enough that
+//						 treating degrees as flat coordinates is accurate for deciding whether two boundaries overlap.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Polygon - A GeoJSON (RFC 7946) polygon. The first ring is the parcel outline and any further rings are holes cut out
+//			   of it. Every ring is closed, its last position repeating the first, and positions are [long, lat].
+//==============================================================================================================================
+type Polygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+//==============================================================================================================================
+//	 validate_polygon - Checks the polygon is a well formed GeoJSON polygon: closed rings of at least four positions within
+//						range, the outline wound counterclockwise and holes wound clockwise as RFC 7946 requires.
+//==============================================================================================================================
+func validate_polygon(p Polygon) error {
+
+	if p.Type != "Polygon" {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, expecting a GeoJSON Polygon")
+	}
+
+	if len(p.Coordinates) == 0 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, polygon has no rings")
+	}
+
+	for i, ring := range p.Coordinates {
+
+		if len(ring) < 4 {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, rings need at least 4 positions")
+		}
+
+		if ring[0] != ring[len(ring)-1] {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, rings must be closed")
+		}
+
+		for _, pos := range ring {
+			if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+				return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, position out of range")
+			}
+		}
+
+		area := signed_area(ring[:len(ring)-1])
+
+		if area == 0 {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, ring has no area")
+		}
+
+		if i == 0 && area < 0 {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, outline must be wound counterclockwise")
+		}
+
+		if i > 0 && area > 0 {
+			return coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, holes must be wound clockwise")
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 outline - Returns the outline of the polygon without its closing position, the form the ring functions work on.
+//==============================================================================================================================
+func (p Polygon) outline() [][2]float64 {
+	return p.Coordinates[0][:len(p.Coordinates[0])-1]
+}
+
+//==============================================================================================================================
+//	 signed_area - Shoelace area of the ring in square degrees, positive when wound counterclockwise.
+//==============================================================================================================================
+func signed_area(ring [][2]float64) float64 {
+
+	area := 0.0
+
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+
+	return area / 2
+}
+
+//==============================================================================================================================
+//	 polygon_area - Returns the area of the polygon in square metres, the outline less its holes. Positions are
+//					projected onto a plane tangent at the outline's first position which is accurate at parcel scale.
+//==============================================================================================================================
+func polygon_area(p Polygon) float64 {
+
+	rad := math.Pi / 180
+	lat0 := p.Coordinates[0][0][1] * rad
+
+	area := 0.0
+
+	for _, ring := range p.Coordinates {
+
+		projected := make([][2]float64, len(ring)-1)
+
+		for i, pos := range ring[:len(ring)-1] {
+			projected[i] = [2]float64{pos[0] * rad * EARTH_RADIUS * math.Cos(lat0), pos[1] * rad * EARTH_RADIUS}
+		}
+
+		area += signed_area(projected) // holes are wound clockwise so their area is negative
+	}
+
+	return math.Abs(area)
+}
+
+//==============================================================================================================================
+//	 ring_bbox - Returns the minLat, minLong, maxLat, maxLong of the ring.
+//==============================================================================================================================
+func ring_bbox(ring [][2]float64) (float64, float64, float64, float64) {
+
+	minLat, minLong := ring[0][1], ring[0][0]
+	maxLat, maxLong := minLat, minLong
+
+	for _, p := range ring[1:] {
+		if p[1] < minLat {
+			minLat = p[1]
+		}
+		if p[1] > maxLat {
+			maxLat = p[1]
+		}
+		if p[0] < minLong {
+			minLong = p[0]
+		}
+		if p[0] > maxLong {
+			maxLong = p[0]
+		}
+	}
+
+	return minLat, minLong, maxLat, maxLong
+}
+
+//==============================================================================================================================
+//	 rings_overlap - Reports whether the interiors of two rings intersect. Rings that only share edges or corners, as
+//					 neighbouring parcels do, are not overlapping.
+//==============================================================================================================================
+func rings_overlap(a [][2]float64, b [][2]float64) bool {
+
+	for i := range a {
+		for j := range b {
+			if segments_cross(a[i], a[(i+1)%len(a)], b[j], b[(j+1)%len(b)]) {
+				return true
+			}
+		}
+	}
+
+	return ring_has_point_inside(a, b) || ring_has_point_inside(b, a)
+}
+
+//==============================================================================================================================
+//	 ring_has_point_inside - Reports whether a vertex, an edge midpoint or the centroid of a lies strictly inside b.
+//							 Together with the edge crossing test this also catches identical and nested rings.
+//==============================================================================================================================
+func ring_has_point_inside(a [][2]float64, b [][2]float64) bool {
+
+	var centroid [2]float64
+
+	for i, p := range a {
+
+		q := a[(i+1)%len(a)]
+
+		if point_strictly_inside(p, b) || point_strictly_inside([2]float64{(p[0] + q[0]) / 2, (p[1] + q[1]) / 2}, b) {
+			return true
+		}
+
+		centroid[0] += p[0] / float64(len(a))
+		centroid[1] += p[1] / float64(len(a))
+	}
+
+	return point_strictly_inside(centroid, a) && point_strictly_inside(centroid, b)
+}
+
+//==============================================================================================================================
+//	 point_strictly_inside - Ray casting point in polygon test. Points on the boundary are not inside.
+//==============================================================================================================================
+func point_strictly_inside(p [2]float64, ring [][2]float64) bool {
+
+	inside := false
+
+	for i := range ring {
+
+		a, b := ring[i], ring[(i+1)%len(ring)]
+
+		if cross(a, b, p) == 0 && between(a, b, p) {
+			return false
+		}
+
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+//==============================================================================================================================
+//	 segments_cross - Reports whether segments ab and cd properly cross, touching or collinear segments do not count.
+//==============================================================================================================================
+func segments_cross(a [2]float64, b [2]float64, c [2]float64, d [2]float64) bool {
+
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// cross - z component of (b - a) x (p - a), positive when p is to the left of ab
+func cross(a [2]float64, b [2]float64, p [2]float64) float64 {
+	return (b[0]-a[0])*(p[1]-a[1]) - (b[1]-a[1])*(p[0]-a[0])
+}
+
+// between - whether p, known to be collinear with ab, lies within the segment
+func between(a [2]float64, b [2]float64, p [2]float64) bool {
+	return p[0] >= min_float(a[0], b[0]) && p[0] <= max_float(a[0], b[0]) && p[1] >= min_float(a[1], b[1]) && p[1] <= max_float(a[1], b[1])
+}
+
+func min_float(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max_float(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
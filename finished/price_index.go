@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Price Indices - A market data provider, a client holding the price_oracle_role of the configuration, posts the price
+//					 per square metre of each district with the day it takes effect, stored under
+//					 price_index~district~day. The index in effect on the day of a transfer sanity checks its declared
+//					 sale price: a price more than price_alert_threshold below the index times the area of the bond is
+//					 flagged for anti-fraud monitoring.
+//
+//					 Flags reveal roughly what was paid, so they are kept in the salePrices collection beside the
+//					 price under price_flag~timestamp~realEstateID~txid, and listed by get_price_flags.
+//==============================================================================================================================
+
+const PRICE_INDEX_PREFIX = "price_index"
+const PRICE_FLAG_PREFIX = "price_flag"
+
+// Default role of the market data provider
+const PRICE_ORACLE = "price_oracle"
+
+// Default fraction below the index at which a declared price is flagged
+const PRICE_ALERT_THRESHOLD = 0.3
+
+//==============================================================================================================================
+//	 Price_Index - The price per square metre of a district from a day on.
+//==============================================================================================================================
+type Price_Index struct {
+	DistrictCode  string  `json:"district_code"`
+	PricePerSqm   float64 `json:"price_per_sqm"`
+	EffectiveDate string  `json:"effective_date"` // YYYY-MM-DD
+	PostedAt      string  `json:"posted_at"`
+	PostedBy      string  `json:"posted_by"`
+	TxID          string  `json:"txid"`
+}
+
+//==============================================================================================================================
+//	 Price_Flag - A transfer declared well below the price index, stored in the salePrices collection.
+//==============================================================================================================================
+type Price_Flag struct {
+	RealEstateID  string  `json:"real_estate_id"`
+	DistrictCode  string  `json:"district_code"`
+	Timestamp     string  `json:"timestamp"`
+	TxID          string  `json:"txid"`
+	DeclaredValue float64 `json:"declared_value"`
+	ExpectedValue float64 `json:"expected_value"` // index price times the area of the bond
+	IndexDate     string  `json:"index_date"`
+	Shortfall     float64 `json:"shortfall"` // fraction of the expected value missing from the declared
+}
+
+//=================================================================================================================================
+//	 post_price_index - Records the price per square metre of the district from the day given. Posting again for the
+//						same day corrects the index. Price oracle only.
+//=================================================================================================================================
+func (t *SimpleChaincode) post_price_index(stub shim.ChaincodeStubInterface, districtCode string, price string, effectiveDate string) ([]byte, error) {
+
+	role, err := t.check_affiliation(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_PRICE_INDEX", err)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_PRICE_INDEX", err)
+	}
+
+	if role != c.PriceOracleRole {
+		return nil, coded_error(CODE_NOT_AUTHORIZED, "POST_PRICE_INDEX: Permission denied, price indices are posted by "+c.PriceOracleRole)
+	}
+
+	d, err := t.lookup_district(stub, districtCode)
+
+	if err != nil {
+		return nil, prefix_error("POST_PRICE_INDEX", err)
+	}
+
+	perSqm, err := strconv.ParseFloat(price, 64)
+
+	if err != nil || perSqm <= 0 {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "POST_PRICE_INDEX: Invalid price per square metre "+price)
+	}
+
+	if _, err := time.Parse(DAY_LAYOUT, effectiveDate); err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "POST_PRICE_INDEX: Invalid effective date "+effectiveDate+", expecting YYYY-MM-DD")
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("POST_PRICE_INDEX", err)
+	}
+
+	actor, err := cid.GetID(stub)
+
+	if err != nil {
+		log_errorf(stub, "POST_PRICE_INDEX: Error reading caller identity: %s", err)
+		return nil, wrap_error(CODE_NOT_AUTHORIZED, "POST_PRICE_INDEX: Error reading caller identity", err)
+	}
+
+	bytes, err := json.Marshal(Price_Index{
+		DistrictCode:  d.Code,
+		PricePerSqm:   perSqm,
+		EffectiveDate: effectiveDate,
+		PostedAt:      now.Format(TIME_LAYOUT),
+		PostedBy:      actor,
+		TxID:          stub.GetTxID(),
+	})
+
+	if err != nil {
+		log_errorf(stub, "POST_PRICE_INDEX: Error converting index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "POST_PRICE_INDEX: Error converting index", err)
+	}
+
+	err = stub.PutState(index_key(PRICE_INDEX_PREFIX, d.Code, effectiveDate), bytes)
+
+	if err != nil {
+		log_errorf(stub, "POST_PRICE_INDEX: Error storing index: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "POST_PRICE_INDEX: Error storing index "+index_key(PRICE_INDEX_PREFIX, d.Code, effectiveDate), err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 price_index_on - Returns the index of the district in effect on the day, and false when none had been posted.
+//==============================================================================================================================
+func (t *SimpleChaincode) price_index_on(stub shim.ChaincodeStubInterface, districtCode string, day string) (Price_Index, bool, error) {
+
+	var index Price_Index
+
+	iter, err := stub.GetStateByRange(index_key(PRICE_INDEX_PREFIX, districtCode)+INDEX_SEPARATOR, index_key(PRICE_INDEX_PREFIX, districtCode, day)+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "PRICE_INDEX_ON: Error querying indices: %s", err)
+		return index, false, wrap_error(CODE_LEDGER_ERROR, "Error querying price indices", err)
+	}
+
+	defer iter.Close()
+
+	var last []byte
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "PRICE_INDEX_ON: Error reading indices: %s", err)
+			return index, false, wrap_error(CODE_LEDGER_ERROR, "Error reading price indices", err)
+		}
+
+		last = kv.Value
+	}
+
+	if last == nil {
+		return index, false, nil
+	}
+
+	err = json.Unmarshal(last, &index)
+
+	if err != nil {
+		return index, false, errors.New("Corrupt price index " + string(last))
+	}
+
+	return index, true, nil
+}
+
+//=================================================================================================================================
+//	 get_price_index - Returns the index of the district in effect on the day, today when it is empty.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_price_index(stub shim.ChaincodeStubInterface, districtCode string, day string) ([]byte, error) {
+
+	if day == "" {
+
+		now, err := tx_time(stub)
+
+		if err != nil {
+			return nil, prefix_error("GET_PRICE_INDEX", err)
+		}
+
+		day = now.Format(DAY_LAYOUT)
+	}
+
+	if _, err := time.Parse(DAY_LAYOUT, day); err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_PRICE_INDEX: Invalid date "+day+", expecting YYYY-MM-DD")
+	}
+
+	index, found, err := t.price_index_on(stub, districtCode, day)
+
+	if err != nil {
+		return nil, prefix_error("GET_PRICE_INDEX", err)
+	}
+
+	if !found {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_PRICE_INDEX: No price index of "+districtCode+" on "+day)
+	}
+
+	bytes, err := json.Marshal(index)
+
+	if err != nil {
+		log_errorf(stub, "GET_PRICE_INDEX: Error converting index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_PRICE_INDEX: Error converting index", err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 flag_sale_price - Flags the transfer when the declared value is further below the price index of the bond's district
+//					   than the threshold of the configuration. Transfers without a declared value, district, area or
+//					   index are not checked.
+//==============================================================================================================================
+func (t *SimpleChaincode) flag_sale_price(stub shim.ChaincodeStubInterface, b Bond, tr Transfer_Record, value float64) error {
+
+	if value <= 0 || tr.DistrictCode == "" || b.Area.Value <= 0 {
+		return nil
+	}
+
+	index, found, err := t.price_index_on(stub, tr.DistrictCode, tr.Timestamp[:len(DAY_LAYOUT)])
+
+	if err != nil || !found {
+		return err
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	expected := index.PricePerSqm * b.Area.Value
+
+	if value >= expected*(1-c.PriceAlert) {
+		return nil
+	}
+
+	bytes, err := json.Marshal(Price_Flag{
+		RealEstateID:  tr.RealEstateID,
+		DistrictCode:  tr.DistrictCode,
+		Timestamp:     tr.Timestamp,
+		TxID:          tr.TxID,
+		DeclaredValue: value,
+		ExpectedValue: expected,
+		IndexDate:     index.EffectiveDate,
+		Shortfall:     1 - value/expected,
+	})
+
+	if err != nil {
+		log_errorf(stub, "FLAG_SALE_PRICE: Error converting flag: %s", err)
+		return wrap_error(CODE_ERROR, "Error converting price flag", err)
+	}
+
+	err = stub.PutPrivateData(SALE_PRICE_COLLECTION, index_key(PRICE_FLAG_PREFIX, tr.Timestamp, tr.RealEstateID, tr.TxID), bytes)
+
+	if err != nil {
+		log_errorf(stub, "FLAG_SALE_PRICE: Error storing flag: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing price flag "+index_key(PRICE_FLAG_PREFIX, tr.Timestamp, tr.RealEstateID, tr.TxID), err)
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 get_price_flags - Returns the transfers flagged between the from and to days inclusive that this peer holds. Admin
+//					   only.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_price_flags(stub shim.ChaincodeStubInterface, from string, to string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, day := range []string{from, to} {
+		if _, err := time.Parse(DAY_LAYOUT, day); err != nil {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_PRICE_FLAGS: Invalid date "+day+", expecting YYYY-MM-DD")
+		}
+	}
+
+	iter, err := stub.GetPrivateDataByRange(SALE_PRICE_COLLECTION, index_key(PRICE_FLAG_PREFIX, from), index_key(PRICE_FLAG_PREFIX, to)+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_PRICE_FLAGS: Error querying flags: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "GET_PRICE_FLAGS: Error querying flags", err)
+	}
+
+	defer iter.Close()
+
+	flags := []Price_Flag{}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_PRICE_FLAGS: Error reading flags: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "GET_PRICE_FLAGS: Error reading flags", err)
+		}
+
+		var f Price_Flag
+
+		err = json.Unmarshal(kv.Value, &f)
+
+		if err != nil {
+			return nil, errors.New("GET_PRICE_FLAGS: Corrupt price flag " + string(kv.Value))
+		}
+
+		flags = append(flags, f)
+	}
+
+	bytes, err := json.Marshal(flags)
+
+	if err != nil {
+		log_errorf(stub, "GET_PRICE_FLAGS: Error converting flags: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_PRICE_FLAGS: Error converting flags", err)
+	}
+
+	return bytes, nil
+}
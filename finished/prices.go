@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Sale Prices - Declared sale prices are kept in the salePrices private data collection, shared between the buyer and
+//				   seller organisations and the regulator (see collections_config.json). The public transfer record only
+//				   carries a salted hash of the price so the parties can prove it without revealing it.
+//==============================================================================================================================
+
+const SALE_PRICE_COLLECTION = "salePrices"
+
+// Transient map field carrying the salt of the price hash. It is chosen by the client so it is the same on every endorser
+// and never written to the public ledger.
+const SALE_PRICE_SALT = "salt"
+
+//==============================================================================================================================
+//	 Sale_Price - The private record of a declared sale price, stored under the key of its transfer record.
+//==============================================================================================================================
+type Sale_Price struct {
+	RealEstateID  string  `json:"real_estate_id"`
+	TxID          string  `json:"txid"`
+	DeclaredValue float64 `json:"declared_value"`
+	Salt          string  `json:"salt"`
+}
+
+//==============================================================================================================================
+//	 price_hash - Returns the hex encoded sha256 of the salt followed by the price in its shortest decimal form.
+//==============================================================================================================================
+func price_hash(salt string, value float64) string {
+
+	sum := sha256.Sum256([]byte(salt + strconv.FormatFloat(value, 'f', -1, 64)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+//==============================================================================================================================
+//	 put_sale_price - Stores the declared price of the transfer in the private collection and returns its salted hash.
+//					  The salt is read from the transient map and is required.
+//==============================================================================================================================
+func (t *SimpleChaincode) put_sale_price(stub shim.ChaincodeStubInterface, key string, tr Transfer_Record, value float64) (string, error) {
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		log_errorf(stub, "PUT_SALE_PRICE: Error reading transient map: %s", err)
+		return "", wrap_error(CODE_LEDGER_ERROR, "Error reading transient map", err)
+	}
+
+	salt := string(transient[SALE_PRICE_SALT])
+
+	if salt == "" {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "A declared value requires a "+SALE_PRICE_SALT+" in the transient map")
+	}
+
+	bytes, err := json.Marshal(Sale_Price{RealEstateID: tr.RealEstateID, TxID: tr.TxID, DeclaredValue: value, Salt: salt})
+
+	if err != nil {
+		log_errorf(stub, "PUT_SALE_PRICE: Error converting sale price: %s", err)
+		return "", wrap_error(CODE_ERROR, "Error converting sale price", err)
+	}
+
+	err = stub.PutPrivateData(SALE_PRICE_COLLECTION, key, bytes)
+
+	if err != nil {
+		log_errorf(stub, "PUT_SALE_PRICE: Error storing sale price: %s", err)
+		return "", wrap_error(CODE_LEDGER_ERROR, "Error storing sale price "+key, err)
+	}
+
+	return price_hash(salt, value), nil
+}
+
+//==============================================================================================================================
+//	 get_sale_price - Reads the declared price of the transfer stored under key. Returns false when the price is not
+//					  available on this peer, as on peers of organisations outside the collection.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_sale_price(stub shim.ChaincodeStubInterface, key string) (Sale_Price, bool, error) {
+
+	var sp Sale_Price
+
+	bytes, err := stub.GetPrivateData(SALE_PRICE_COLLECTION, key)
+
+	if err != nil {
+		log_errorf(stub, "GET_SALE_PRICE: Error reading sale price: %s", err)
+		return sp, false, wrap_error(CODE_LEDGER_ERROR, "Error reading sale price "+key, err)
+	}
+
+	if bytes == nil {
+		return sp, false, nil
+	}
+
+	err = json.Unmarshal(bytes, &sp)
+
+	if err != nil {
+		return sp, false, errors.New("Corrupt sale price record " + string(bytes))
+	}
+
+	return sp, true, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/alfaifiisa/learn-chaincode/finished/bondpb"
+	"google.golang.org/protobuf/proto"
+)
+
+//==============================================================================================================================
+//	 State Encoding - Bonds are stored as JSON unless the state_encoding parameter of the configuration is protobuf, in
+//					  which case they are written as the Bond message of bond.proto, typically under half the size and
+//					  cheaper to marshal. Both forms are always readable, a JSON record starts with { and a Bond message
+//					  never does, so the encoding can be switched at any time and records convert as they are saved.
+//					  The message types are generated into bondpb, see bond.proto.
+//==============================================================================================================================
+
+const ENCODING_JSON = "json"
+const ENCODING_PROTOBUF = "protobuf"
+
+// Endorsing peers must write the same bytes for the same bond
+var BOND_MARSHAL_OPTIONS = proto.MarshalOptions{Deterministic: true}
+
+//==============================================================================================================================
+//	 marshal_bond - Converts the bond into its stored form in the encoding given.
+//==============================================================================================================================
+func marshal_bond(b Bond, encoding string) ([]byte, error) {
+
+	if encoding == ENCODING_PROTOBUF {
+		return BOND_MARSHAL_OPTIONS.Marshal(bond_message(b))
+	}
+
+	return json.Marshal(b)
+}
+
+//==============================================================================================================================
+//	 unmarshal_bond - Reads a stored bond in either encoding.
+//==============================================================================================================================
+func unmarshal_bond(data []byte) (Bond, error) {
+
+	var b Bond
+
+	if len(data) > 0 && data[0] != '{' {
+
+		var m bondpb.Bond
+
+		err := proto.Unmarshal(data, &m)
+
+		if err != nil {
+			return b, err
+		}
+
+		return bond_from_message(&m), nil
+	}
+
+	err := json.Unmarshal(data, &b)
+
+	return b, err
+}
+
+//==============================================================================================================================
+//	 bond_message - Returns the Bond message of the bond. Empty area and coordinates are left out as before.
+//==============================================================================================================================
+func bond_message(b Bond) *bondpb.Bond {
+
+	m := &bondpb.Bond{
+		Id:              b.ID,
+		RealEstateId:    b.RealEstateID,
+		OwnerNationalId: b.OwnerNationalID,
+		OwnerMsp:        b.OwnerMSP,
+		Status:          b.Status.String(),
+		Geohash:         b.Geohash,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
+		CreatedTx:       b.CreatedTx,
+		LastModifiedTx:  b.LastModifiedTx,
+		DistrictCode:    b.DistrictCode,
+		CityCode:        b.CityCode,
+		Street:          b.Street,
+		ExportedTo:      b.ExportedTo,
+		SchemaVersion:   int64(b.SchemaVersion),
+		Version:         int64(b.Version),
+		TokenId:         b.TokenID,
+		OwnerIdType:     string(b.OwnerIDType),
+	}
+
+	if b.Area != (Land_Area{}) {
+		m.Area = &bondpb.LandArea{Value: b.Area.Value, Unit: b.Area.Unit}
+	}
+
+	if b.Coordinates != (Coordinates{}) {
+		m.Coordinates = &bondpb.Coordinates{Long: b.Coordinates.Long, Lat: b.Coordinates.Lat, Unknown: b.Coordinates.Unknown}
+	}
+
+	if b.Boundary != nil {
+
+		m.Boundary = &bondpb.Polygon{Type: b.Boundary.Type}
+
+		for _, ring := range b.Boundary.Coordinates {
+
+			r := &bondpb.Ring{}
+
+			for _, position := range ring {
+				r.Positions = append(r.Positions, position[0], position[1])
+			}
+
+			m.Boundary.Rings = append(m.Boundary.Rings, r)
+		}
+	}
+
+	if b.Provenance != nil {
+		m.Provenance = &bondpb.Provenance{
+			Origin:           b.Provenance.Origin,
+			LegacyDeedNumber: b.Provenance.LegacyDeedNumber,
+			MigrationBatch:   b.Provenance.MigrationBatch,
+			MigratedAt:       b.Provenance.MigratedAt,
+			MigratedBy:       b.Provenance.MigratedBy,
+		}
+	}
+
+	return m
+}
+
+//==============================================================================================================================
+//	 bond_from_message - Returns the bond of a Bond message.
+//==============================================================================================================================
+func bond_from_message(m *bondpb.Bond) Bond {
+
+	b := Bond{
+		ID:              m.Id,
+		RealEstateID:    m.RealEstateId,
+		OwnerNationalID: m.OwnerNationalId,
+		OwnerMSP:        m.OwnerMsp,
+		Status:          Bond_Status(m.Status),
+		Geohash:         m.Geohash,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+		CreatedTx:       m.CreatedTx,
+		LastModifiedTx:  m.LastModifiedTx,
+		DistrictCode:    m.DistrictCode,
+		CityCode:        m.CityCode,
+		Street:          m.Street,
+		ExportedTo:      m.ExportedTo,
+		SchemaVersion:   int(m.SchemaVersion),
+		Version:         int(m.Version),
+		TokenID:         m.TokenId,
+		OwnerIDType:     Owner_ID_Type(m.OwnerIdType),
+	}
+
+	if m.Area != nil {
+		b.Area = Land_Area{Value: m.Area.Value, Unit: m.Area.Unit}
+	}
+
+	if m.Coordinates != nil {
+		b.Coordinates = Coordinates{Long: m.Coordinates.Long, Lat: m.Coordinates.Lat, Unknown: m.Coordinates.Unknown}
+	}
+
+	if m.Boundary != nil {
+
+		b.Boundary = &Polygon{Type: m.Boundary.Type}
+
+		for _, r := range m.Boundary.Rings {
+
+			var ring [][2]float64
+
+			for i := 0; i+1 < len(r.Positions); i += 2 {
+				ring = append(ring, [2]float64{r.Positions[i], r.Positions[i+1]})
+			}
+
+			b.Boundary.Coordinates = append(b.Boundary.Coordinates, ring)
+		}
+	}
+
+	if m.Provenance != nil {
+		b.Provenance = &Provenance{
+			Origin:           m.Provenance.Origin,
+			LegacyDeedNumber: m.Provenance.LegacyDeedNumber,
+			MigrationBatch:   m.Provenance.MigrationBatch,
+			MigratedAt:       m.Provenance.MigratedAt,
+			MigratedBy:       m.Provenance.MigratedBy,
+		}
+	}
+
+	return b
+}
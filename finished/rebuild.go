@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Rebuild_Result - The response of rebuild_indexes.
+//==============================================================================================================================
+type Rebuild_Result struct {
+	Bonds          int      `json:"bonds"`
+	EntriesRemoved int      `json:"entries_removed"`
+	EntriesWritten int      `json:"entries_written"`
+	Skipped        []string `json:"skipped,omitempty"` // realEstateIDs of bonds that could not be read, left unindexed
+}
+
+// Indexes rebuilt from the bond records by rebuild_indexes
+var REBUILT_INDEXES = []string{OWNER_INDEX, STATUS_INDEX, PARCEL_INDEX, BLUEPRINT_INDEX, ADDRESS_INDEX, GEOHASH_INDEX, BOUNDARY_INDEX, MODIFIED_INDEX, MIGRATION_INDEX, LEGACY_DEED_INDEX}
+
+//=================================================================================================================================
+//	 rebuild_indexes - Admin function that drops every entry of the REBUILT_INDEXES and writes them again from the bond
+//					   records, recovering indexes left stale by bugs or migrations. Bonds whose geohash is missing or out
+//					   of date are corrected on the way, bonds that cannot be read are skipped and reported.
+//=================================================================================================================================
+func (t *SimpleChaincode) rebuild_indexes(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("REBUILD_INDEXES", err)
+	}
+
+	var result Rebuild_Result
+
+	for _, index := range REBUILT_INDEXES {
+
+		removed, err := t.clear_index(stub, index)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.EntriesRemoved += removed
+	}
+
+	ids, err := t.get_sorted_bond_ids(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+
+		b, err := t.retrieve_bond(stub, id)
+
+		if err != nil && skippable(err) {
+			log_warningf(stub, "REBUILD_INDEXES: Skipping %s, run repair_bond_listing: %s", id, err)
+			result.Skipped = append(result.Skipped, id)
+			continue
+		}
+
+		if err != nil {
+			return nil, wrap_error(CODE_ERROR, "REBUILD_INDEXES: Failed to retrieve bond "+id, err)
+		}
+
+		hash := b.Coordinates.geohash()
+
+		if hash != b.Geohash {
+
+			b.Geohash = hash
+
+			err = t.put_bond(stub, b)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, key := range bond_index_keys(b) {
+
+			err = stub.PutState(key, INDEX_VALUE)
+
+			if err != nil {
+				return nil, wrap_error(CODE_LEDGER_ERROR, "REBUILD_INDEXES: Error storing index entry "+key, err)
+			}
+
+			result.EntriesWritten++
+		}
+
+		result.Bonds++
+	}
+
+	bytes, err := json.Marshal(result)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "REBUILD_INDEXES: Error converting result", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Reference Data - Cities and the districts within them are registered by the regulator. Bonds refer to their district
+//					  by code, which is checked against this registry, and queries can expand the codes into names.
+//==============================================================================================================================
+
+const CITY_PREFIX = "ref_city"
+const DISTRICT_PREFIX = "ref_district"
+
+//==============================================================================================================================
+//	 City - A city registered by the regulator.
+//==============================================================================================================================
+type City struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+//==============================================================================================================================
+//	 District - A district within a city. District codes are unique across all cities.
+//==============================================================================================================================
+type District struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	CityCode string `json:"city_code"`
+	CityName string `json:"city_name,omitempty"` // only filled in when returned by a query
+}
+
+//==============================================================================================================================
+//	 put_reference - Stores a reference data record under prefix~code.
+//==============================================================================================================================
+func (t *SimpleChaincode) put_reference(stub shim.ChaincodeStubInterface, prefix string, code string, record interface{}) error {
+
+	bytes, err := json.Marshal(record)
+
+	if err != nil {
+		log_errorf(stub, "PUT_REFERENCE: Error converting %s record: %s", prefix, err)
+		return wrap_error(CODE_ERROR, "Error converting "+prefix+" record", err)
+	}
+
+	err = stub.PutState(index_key(prefix, code), bytes)
+
+	if err != nil {
+		log_errorf(stub, "PUT_REFERENCE: Error storing %s record: %s", prefix, err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error storing "+prefix+" record", err)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 get_reference - Reads the reference data record stored under prefix~code into record. Returns false when there is
+//					 no such record.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_reference(stub shim.ChaincodeStubInterface, prefix string, code string, record interface{}) (bool, error) {
+
+	bytes, err := stub.GetState(index_key(prefix, code))
+
+	if err != nil {
+		return false, wrap_error(CODE_LEDGER_ERROR, "Error retrieving "+prefix+" "+code, err)
+	}
+
+	if bytes == nil {
+		return false, nil
+	}
+
+	err = json.Unmarshal(bytes, record)
+
+	if err != nil {
+		return false, errors.New("Corrupt " + prefix + " record " + string(bytes))
+	}
+
+	return true, nil
+}
+
+//=================================================================================================================================
+//	 add_city - Admin function that registers a city or renames an existing one.
+//=================================================================================================================================
+func (t *SimpleChaincode) add_city(stub shim.ChaincodeStubInterface, code string, name string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("ADD_CITY", err)
+	}
+
+	err = validate_reference(code, name).to_error("ADD_CITY", "city")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, t.put_reference(stub, CITY_PREFIX, code, City{Code: code, Name: name})
+}
+
+//=================================================================================================================================
+//	 add_district - Admin function that registers a district within a registered city or renames an existing one.
+//=================================================================================================================================
+func (t *SimpleChaincode) add_district(stub shim.ChaincodeStubInterface, cityCode string, code string, name string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("ADD_DISTRICT", err)
+	}
+
+	err = validate_reference(code, name).to_error("ADD_DISTRICT", "district")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var c City
+
+	found, err := t.get_reference(stub, CITY_PREFIX, cityCode, &c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ADD_DISTRICT: Unknown city "+cityCode)
+	}
+
+	var existing District
+
+	found, err = t.get_reference(stub, DISTRICT_PREFIX, code, &existing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found && existing.CityCode != cityCode {
+		return nil, coded_error(CODE_INVALID_STATE, "ADD_DISTRICT: District "+code+" already belongs to city "+existing.CityCode)
+	}
+
+	return nil, t.put_reference(stub, DISTRICT_PREFIX, code, District{Code: code, Name: name, CityCode: cityCode})
+}
+
+//==============================================================================================================================
+//	 lookup_district - Returns the district with its city name filled in, or an error if the code is not registered.
+//==============================================================================================================================
+func (t *SimpleChaincode) lookup_district(stub shim.ChaincodeStubInterface, code string) (District, error) {
+
+	var d District
+
+	found, err := t.get_reference(stub, DISTRICT_PREFIX, code, &d)
+
+	if err != nil {
+		return d, err
+	}
+
+	if !found {
+		return d, coded_error(CODE_INVALID_ARGUMENT, "Unknown district "+code)
+	}
+
+	var c City
+
+	_, err = t.get_reference(stub, CITY_PREFIX, d.CityCode, &c)
+
+	if err != nil {
+		return d, err
+	}
+
+	d.CityName = c.Name
+
+	return d, nil
+}
+
+//=================================================================================================================================
+//	 get_cities - Returns every registered city.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_cities(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	cities := []City{}
+
+	err := t.list_references(stub, CITY_PREFIX, func(bytes []byte) error {
+		var c City
+		err := json.Unmarshal(bytes, &c)
+		cities = append(cities, c)
+		return err
+	})
+
+	if err != nil {
+		return nil, prefix_error("GET_CITIES", err)
+	}
+
+	return json.Marshal(cities)
+}
+
+//=================================================================================================================================
+//	 get_districts - Returns the registered districts of a city, or of every city when cityCode is empty.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_districts(stub shim.ChaincodeStubInterface, cityCode string) ([]byte, error) {
+
+	districts := []District{}
+
+	err := t.list_references(stub, DISTRICT_PREFIX, func(bytes []byte) error {
+		var d District
+		err := json.Unmarshal(bytes, &d)
+		if cityCode == "" || d.CityCode == cityCode {
+			districts = append(districts, d)
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, prefix_error("GET_DISTRICTS", err)
+	}
+
+	return json.Marshal(districts)
+}
+
+//=================================================================================================================================
+//	 get_district - Returns a district with the name of its city.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_district(stub shim.ChaincodeStubInterface, code string) ([]byte, error) {
+
+	d, err := t.lookup_district(stub, code)
+
+	if err != nil {
+		return nil, prefix_error("GET_DISTRICT", err)
+	}
+
+	return json.Marshal(d)
+}
+
+//==============================================================================================================================
+//	 list_references - Calls read with every record stored under the prefix, in code order.
+//==============================================================================================================================
+func (t *SimpleChaincode) list_references(stub shim.ChaincodeStubInterface, prefix string, read func([]byte) error) error {
+
+	start := prefix + INDEX_SEPARATOR
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		return wrap_error(CODE_LEDGER_ERROR, "Error querying "+prefix+" records", err)
+	}
+
+	defer iter.Close()
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			return wrap_error(CODE_LEDGER_ERROR, "Error reading "+prefix+" records", err)
+		}
+
+		err = read(kv.Value)
+
+		if err != nil {
+			return errors.New("Corrupt " + prefix + " record " + string(kv.Value))
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Bond_Details - A bond with its district and city codes expanded into names, returned by get_bond_details when
+//					asked to expand.
+//==============================================================================================================================
+type Bond_Details struct {
+	Bond     Bond      `json:"bond"`
+	District *District `json:"district,omitempty"`
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Function Registry - Every function a client can call, by name: the handler routing its arguments, the arguments it
+//						 takes, the role a caller needs and whether it changes the ledger. Invoke checks the arguments
+//						 and the role against the entry before calling the handler, so handlers only convert the
+//						 arguments they are given. Functions also check the role themselves, as they are called
+//						 directly by other functions and the tests.
+//==============================================================================================================================
+
+// Roles a function can require, named in the configuration as admin_role, oracle_role and price_oracle_role
+const ROLE_ANY = ""
+const ROLE_ADMIN = "admin"
+const ROLE_ORACLE = "oracle"
+const ROLE_PRICE_ORACLE = "price_oracle"
+
+//==============================================================================================================================
+//	 Handler - Calls a function with the arguments of the transaction.
+//==============================================================================================================================
+type Handler func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error)
+
+//==============================================================================================================================
+//	 Function_Entry - A function of the registry.
+//==============================================================================================================================
+type Function_Entry struct {
+	Handler Handler
+	Args    Arg_Spec
+	Role    string // ROLE_ANY unless the function is restricted
+	Writes  bool   // changes the ledger, as opposed to a query
+}
+
+//==============================================================================================================================
+//	 on_bond - Returns the handler of a function changing the bond named by the first argument.
+//==============================================================================================================================
+func on_bond(f func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error)) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		b, err := t.retrieve_bond_for_update(stub, args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return f(t, stub, b, args)
+	}
+}
+
+//==============================================================================================================================
+//	 on_locked_bond - Returns the handler of a function unlocking the bond named by the first argument, which
+//					  retrieve_bond_for_update refuses.
+//==============================================================================================================================
+func on_locked_bond(f func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error)) Handler {
+
+	return func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+
+		b, err := t.retrieve_bond(stub, args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return f(t, stub, b, args)
+	}
+}
+
+//==============================================================================================================================
+//	 page_size - Returns the page size of the first argument, an integer by the Arg_Spec of the function.
+//==============================================================================================================================
+func page_size(args []string) int {
+
+	n, _ := strconv.Atoi(args[0])
+
+	return n
+}
+
+//==============================================================================================================================
+//	 optional_value - Returns the ith argument, empty when it was not passed.
+//==============================================================================================================================
+func optional_value(args []string, i int) string {
+
+	if i < len(args) {
+		return args[i]
+	}
+
+	return ""
+}
+
+//==============================================================================================================================
+//	 FUNCTIONS - The registry.
+//==============================================================================================================================
+var FUNCTIONS = map[string]Function_Entry{
+
+	"create_bond": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.create_bond(stub, args)
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("bond JSON object or id", ARG_STRING)}, Variadic: true},
+		Writes: true,
+	},
+	"register_tenant": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.register_tenant(stub, args[0], args[1])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("tenantID", ARG_STRING), required_arg("MSP ID", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"migrate_legacy_bond": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			if len(args) < 9 {
+				return nil, coded_error(CODE_INVALID_ARGUMENT, "Incorrect number of arguments. Expecting batchID, legacy deed number and the arguments of create_bond")
+			}
+			return t.migrate_legacy_bond(stub, args[0], args[1], args[2:])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("batchID", ARG_STRING), required_arg("legacy deed number", ARG_STRING)}, Variadic: true},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"tranfer_bond": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.tranfer_bond(stub, b, args)
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, optional_arg("recipient national ID", ARG_STRING), optional_arg("declared value", ARG_NUMBER), optional_arg("recipient MSP", ARG_STRING), optional_arg("recipient ID type", ARG_STRING)}},
+		Writes: true,
+	},
+	"change_realestate_status": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.change_bond_status(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("status", ARG_STRING)}},
+		Writes: true,
+	},
+	"change_coordinates": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.change_coordinates(stub, b, args[1], args[2])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("long", ARG_NUMBER), required_arg("lat", ARG_NUMBER)}},
+		Writes: true,
+	},
+	"add_city": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.add_city(stub, args[0], args[1])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("code", ARG_STRING), required_arg("name", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"add_district": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.add_district(stub, args[0], args[1], args[2])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("cityCode", ARG_STRING), required_arg("code", ARG_STRING), required_arg("name", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"change_district": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.change_district(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("districtCode", ARG_STRING)}},
+		Writes: true,
+	},
+	"change_address": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.change_address(stub, b, args[1], args[2])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("districtCode", ARG_STRING), required_arg("street", ARG_STRING)}},
+		Writes: true,
+	},
+	"export_bond": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.export_bond(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("targetChannel", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"import_bond": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.import_bond(stub, args[0], args[1], args[2])
+		},
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("sourceChannel", ARG_STRING), required_arg("sourceChaincode", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"release_bond_reference": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.release_bond_reference(stub, args[0])
+		},
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"reclaim_bond": {
+		Handler: on_locked_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.reclaim_bond(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("chaincode", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"wrap_bond": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.wrap_bond(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("holder", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"unwrap_bond": {
+		Handler: on_locked_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.unwrap_bond(stub, b)
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"migrate": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			numbers := make([]int, 3)
+			for i, arg := range args {
+				numbers[i], _ = strconv.Atoi(arg)
+			}
+			return t.migrate(stub, numbers[0], numbers[1], numbers[2])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("fromVersion", ARG_INT), required_arg("toVersion", ARG_INT), optional_arg("batchSize", ARG_INT)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"set_config": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.set_config(stub, args[0])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("configuration", ARG_JSON)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"rebuild_indexes": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.rebuild_indexes(stub)
+		},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"repair_bond_listing": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.repair_bond_listing(stub)
+		},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"change_boundary": {
+		Handler: on_bond(func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, b Bond, args []string) ([]byte, error) {
+			return t.change_boundary(stub, b, args[1])
+		}),
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("boundary", ARG_JSON)}},
+		Writes: true,
+	},
+	"create_bonds_bulk": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.create_bonds_bulk(stub, args[0], optional_value(args, 1))
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("bonds", ARG_JSON), optional_arg("mode", ARG_STRING)}},
+		Writes: true,
+	},
+	"attach_document": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.attach_document(stub, args[0], args[1], args[2], args[3])
+		},
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("docType", ARG_STRING), required_arg("sha256", ARG_STRING), required_arg("uri", ARG_STRING)}},
+		Writes: true,
+	},
+	"register_document_ca": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.register_document_ca(stub, args[0], args[1])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("name", ARG_STRING), required_arg("PEM certificate", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"attach_document_signature": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.attach_document_signature(stub, args[0], args[1], args[2], args[3], args[4])
+		},
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("docType", ARG_STRING), required_arg("sha256", ARG_STRING), required_arg("base64 signature", ARG_STRING), required_arg("PEM signer certificate", ARG_STRING)}},
+		Writes: true,
+	},
+	"request_identity_check": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.request_identity_check(stub, args[0], args[1], optional_value(args, 2))
+		},
+		Args:   Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("national ID", ARG_STRING), optional_arg("ID type", ARG_STRING)}},
+		Writes: true,
+	},
+	"post_identity_check": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.post_identity_check(stub, args[0], args[1], args[2])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("check ID", ARG_STRING), required_arg("result", ARG_STRING), required_arg("reference", ARG_STRING)}},
+		Role:   ROLE_ORACLE,
+		Writes: true,
+	},
+	"post_price_index": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.post_price_index(stub, args[0], args[1], args[2])
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("districtCode", ARG_STRING), required_arg("price per square metre", ARG_NUMBER), required_arg("effective date", ARG_STRING)}},
+		Role:   ROLE_PRICE_ORACLE,
+		Writes: true,
+	},
+	"set_verification_key": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.set_verification_key(stub)
+		},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+
+	"get_bond_details": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			b, err := t.retrieve_bond(stub, args[0])
+			if err != nil {
+				log_errorf(stub, "QUERY: Error retrieving bond: %s", err)
+				return nil, prefix_error("QUERY", err)
+			}
+			if optional_value(args, 1) == "expand" {
+				return t.get_expanded_bond_details(stub, b)
+			}
+			return t.get_bond_details(stub, b)
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, optional_arg("expand", ARG_STRING)}},
+	},
+	"check_unique_real_estate_id": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.check_unique_real_estate_id(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"bond_exists": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.bond_exists(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"get_bonds": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bonds(stub)
+		},
+	},
+	"get_skipped_bonds": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_skipped_bonds(stub)
+		},
+	},
+	"get_bonds_in_bbox": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			bbox, err := parse_floats(args, "minLat, minLong, maxLat, maxLong", 4)
+			if err != nil {
+				return nil, prefix_error("QUERY", err)
+			}
+			return t.get_bonds_in_bbox(stub, bbox[0], bbox[1], bbox[2], bbox[3])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("minLat", ARG_NUMBER), required_arg("minLong", ARG_NUMBER), required_arg("maxLat", ARG_NUMBER), required_arg("maxLong", ARG_NUMBER)}},
+	},
+	"get_nearby_bonds": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			point, err := parse_floats(args, "lat, long, radius", 3)
+			if err != nil {
+				return nil, prefix_error("QUERY", err)
+			}
+			return t.get_nearby_bonds(stub, point[0], point[1], point[2])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("lat", ARG_NUMBER), required_arg("long", ARG_NUMBER), required_arg("radius", ARG_NUMBER)}},
+	},
+	"get_transfer_stats": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_transfer_stats(stub, args[0], args[1], args[2])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("groupBy", ARG_STRING), required_arg("from", ARG_STRING), required_arg("to", ARG_STRING)}},
+	},
+	"get_registry_stats": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_registry_stats(stub)
+		},
+	},
+	"export_bonds": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.export_bonds(stub, page_size(args), args[1])
+		},
+		Args: Arg_Spec{Args: PAGE_ARGS},
+	},
+	"export_ladm": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.export_ladm(stub, page_size(args), args[1])
+		},
+		Args: Arg_Spec{Args: PAGE_ARGS},
+	},
+	"get_registry_checksum": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_registry_checksum(stub)
+		},
+	},
+	"get_owner_summary": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_owner_summary(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("nationalID", ARG_STRING)}},
+	},
+	"get_bonds_by_owner": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bonds_by_owner(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("nationalID", ARG_STRING)}},
+	},
+	"get_bonds_by_blueprint": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bonds_by_blueprint(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("blueprint", ARG_STRING)}},
+	},
+	"get_bonds_modified_since": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bonds_modified_since(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("timestamp", ARG_STRING)}},
+	},
+	"get_cities": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_cities(stub)
+		},
+	},
+	"get_districts": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_districts(stub, optional_value(args, 0))
+		},
+		Args: Arg_Spec{Args: []Arg{optional_arg("cityCode", ARG_STRING)}},
+	},
+	"get_district": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_district(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("districtCode", ARG_STRING)}},
+	},
+	"search_by_address": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.search_by_address(stub, args[0], args[1], args[2])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("cityCode", ARG_STRING), required_arg("districtCode", ARG_STRING), required_arg("street", ARG_STRING)}},
+	},
+	"get_district_geojson": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_district_geojson(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("districtCode", ARG_STRING)}},
+	},
+	"get_bond_reference": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bond_reference(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"get_config": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_config(stub)
+		},
+		Role: ROLE_ADMIN,
+	},
+	"get_config_changes": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_config_changes(stub)
+		},
+		Role: ROLE_ADMIN,
+	},
+	"get_audit_log": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_audit_log(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+		Role: ROLE_ADMIN,
+	},
+	"get_audit_records": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_audit_records(stub, page_size(args), args[1])
+		},
+		Args: Arg_Spec{Args: PAGE_ARGS},
+		Role: ROLE_ADMIN,
+	},
+	"get_documents": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_documents(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"verify_document": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.verify_document(stub, args[0], args[1], args[2])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG, required_arg("docType", ARG_STRING), required_arg("sha256", ARG_STRING)}},
+	},
+	"get_price_index": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_price_index(stub, args[0], optional_value(args, 1))
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("districtCode", ARG_STRING), optional_arg("date", ARG_STRING)}},
+	},
+	"get_price_flags": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_price_flags(stub, args[0], args[1])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("from", ARG_STRING), required_arg("to", ARG_STRING)}},
+		Role: ROLE_ADMIN,
+	},
+	"get_tenant": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_tenant(stub)
+		},
+	},
+	"get_migration_batch": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_migration_batch(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("batchID", ARG_STRING)}},
+	},
+	"get_bond_by_legacy_deed": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_bond_by_legacy_deed(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("legacy deed number", ARG_STRING)}},
+	},
+	"get_identity_check": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_identity_check(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("check ID", ARG_STRING)}},
+	},
+	"get_document_cas": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_document_cas(stub)
+		},
+	},
+	"get_verification_payload": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_verification_payload(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"verify_verification_payload": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.verify_verification_payload(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("payload", ARG_STRING)}},
+	},
+	"get_ownership_certificate": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_ownership_certificate(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{REAL_ESTATE_ID_ARG}},
+	},
+	"verify_ownership_certificate": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.verify_ownership_certificate(stub, args[0], args[1])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("certificate JSON", ARG_JSON), required_arg("signature", ARG_STRING)}},
+	},
+	"get_ecert": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.get_ecert(stub, args[0])
+		},
+		Args: Arg_Spec{Args: []Arg{required_arg("name", ARG_STRING)}},
+	},
+	"add_ecert": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.add_ecert(stub, args[0], args[1], optional_value(args, 2))
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("name", ARG_STRING), required_arg("ecert", ARG_STRING), optional_arg("mode", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"add_ecerts": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.add_ecerts(stub, args[0], optional_value(args, 1))
+		},
+		Args:   Arg_Spec{Args: []Arg{required_arg("users", ARG_JSON), optional_arg("mode", ARG_STRING)}},
+		Role:   ROLE_ADMIN,
+		Writes: true,
+	},
+	"health": {
+		Handler: func(t *SimpleChaincode, stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+			return t.health(stub)
+		},
+	},
+}
+
+//==============================================================================================================================
+//	 function_names - Returns the names of the functions of the registry in alphabetical order.
+//==============================================================================================================================
+func function_names() []string {
+
+	names := make([]string, 0, len(FUNCTIONS))
+
+	for name := range FUNCTIONS {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+//==============================================================================================================================
+//	 unknown_function - Returns the UNKNOWN_FUNCTION error of a name not in the registry, listing the valid names as a
+//						JSON array.
+//==============================================================================================================================
+func unknown_function(function string) error {
+
+	names, _ := json.Marshal(function_names()) // a list of strings always converts
+
+	return coded_error(CODE_UNKNOWN_FUNCTION, "Received unknown function invocation "+function+", expecting one of "+string(names))
+}
+
+//==============================================================================================================================
+//	 check_role - Returns an error unless the caller has the role the function requires.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_role(stub shim.ChaincodeStubInterface, function string, role string) error {
+
+	if role == ROLE_ANY {
+		return nil
+	}
+
+	if role == ROLE_ADMIN {
+		return t.check_admin(stub)
+	}
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	wanted := c.OracleRole
+
+	if role == ROLE_PRICE_ORACLE {
+		wanted = c.PriceOracleRole
+	}
+
+	caller, err := t.check_affiliation(stub)
+
+	if err != nil {
+		log_errorf(stub, "CHECK_ROLE: Error retrieving caller role: %s", err)
+		return coded_error(CODE_NOT_AUTHORIZED, "Error retrieving caller role")
+	}
+
+	if caller != wanted {
+		return coded_error(CODE_NOT_AUTHORIZED, strings.ToUpper(function)+": Permission denied, restricted to "+wanted)
+	}
+
+	return nil
+}
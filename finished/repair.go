@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Dangling Entries - A bond record that no longer converts, or a realEstateID listed in bondIDs or an index with no
+//						bond behind it, would otherwise fail every listing that reaches it. Listings skip such
+//						entries instead and log them. get_skipped_bonds lists every such entry, and the summaries
+//						and exports name the ones they left out in their skipped field. The regulator then repairs the registry with repair_bond_listing, which moves unreadable records
+//						aside under corrupt_bond~<realEstateID> for recovery and drops missing bonds from bondIDs,
+//						followed by rebuild_indexes, which drops index entries whose bond is gone.
+//==============================================================================================================================
+
+const CORRUPT_BOND_PREFIX = "corrupt_bond"
+
+//==============================================================================================================================
+//	 Skipped_Bond - An entry a listing skipped. Code is BOND_NOT_FOUND or CORRUPT_RECORD.
+//==============================================================================================================================
+type Skipped_Bond struct {
+	RealEstateID string `json:"real_estate_id"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+}
+
+//==============================================================================================================================
+//	 Repair_Result - The response of repair_bond_listing, the realEstateIDs of the records moved aside and of those
+//					 dropped from bondIDs.
+//==============================================================================================================================
+type Repair_Result struct {
+	Quarantined []string `json:"quarantined"`
+	Unlisted    []string `json:"unlisted"`
+}
+
+//==============================================================================================================================
+//	 skippable - Reports whether a listing may skip the bond it failed to retrieve with the error and carry on. Other
+//				 errors, such as those of the ledger, still fail the listing.
+//==============================================================================================================================
+func skippable(err error) bool {
+
+	code := error_code(err)
+
+	return code == CODE_BOND_NOT_FOUND || code == CODE_CORRUPT_RECORD
+}
+
+//==============================================================================================================================
+//	 retrieve_listed_bonds - Returns the bonds of the realEstateIDs found in an index, leaving out and logging those
+//							 that are missing or cannot be read.
+//==============================================================================================================================
+func (t *SimpleChaincode) retrieve_listed_bonds(stub shim.ChaincodeStubInterface, function string, ids []string) ([]Bond, error) {
+
+	bonds, _, err := t.retrieve_bonds_skipping(stub, function, ids)
+
+	return bonds, err
+}
+
+//==============================================================================================================================
+//	 retrieve_bonds_skipping - Returns the bonds of the realEstateIDs as retrieve_listed_bonds does, along with the
+//							   realEstateIDs it left out, for responses that report them.
+//==============================================================================================================================
+func (t *SimpleChaincode) retrieve_bonds_skipping(stub shim.ChaincodeStubInterface, function string, ids []string) ([]Bond, []string, error) {
+
+	bonds := []Bond{}
+	var skipped []string
+
+	for _, id := range ids {
+
+		b, err := t.retrieve_bond(stub, id)
+
+		if err != nil && skippable(err) {
+			log_warningf(stub, "%s: Skipping %s: %s", function, id, err)
+			skipped = append(skipped, id)
+			continue
+		}
+
+		if err != nil {
+			return nil, nil, wrap_error(CODE_ERROR, function+": Failed to retrieve bond "+id, err)
+		}
+
+		bonds = append(bonds, b)
+	}
+
+	return bonds, skipped, nil
+}
+
+//=================================================================================================================================
+//	 get_skipped_bonds - Returns every entry the bond listings skip, corrupt records and realEstateIDs in bondIDs with
+//						 no bond, as a JSON array. Empty once repair_bond_listing has run.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_skipped_bonds(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	skipped := []Skipped_Bond{}
+
+	err := t.scan_bonds(stub, func(b Bond) error {
+		return nil
+	}, func(s Skipped_Bond) error {
+		skipped = append(skipped, s)
+		return nil
+	})
+
+	if err != nil {
+		return nil, prefix_error("GET_SKIPPED_BONDS", err)
+	}
+
+	bytes, err := json.Marshal(skipped)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_SKIPPED_BONDS: Error converting skipped entries", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 repair_bond_listing - Admin function that moves every bond record that cannot be read to corrupt_bond~ and drops
+//						   the realEstateIDs with no bond from bondIDs, so listings no longer skip them. Run
+//						   rebuild_indexes afterwards to drop the index entries of the bonds moved aside.
+//=================================================================================================================================
+func (t *SimpleChaincode) repair_bond_listing(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("REPAIR_BOND_LISTING", err)
+	}
+
+	result := Repair_Result{Quarantined: []string{}, Unlisted: []string{}}
+
+	start := index_key(BOND_PREFIX, "")
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "REPAIR_BOND_LISTING: Error querying bond records: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "REPAIR_BOND_LISTING: Error querying bond records", err)
+	}
+
+	corrupt := make(map[string][]byte)
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			iter.Close()
+			log_errorf(stub, "REPAIR_BOND_LISTING: Error reading bond records: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "REPAIR_BOND_LISTING: Error reading bond records", err)
+		}
+
+		if _, err := unmarshal_bond(kv.Value); err != nil {
+			id := kv.Key[len(start):]
+			corrupt[id] = kv.Value
+			result.Quarantined = append(result.Quarantined, id)
+		}
+	}
+
+	iter.Close()
+
+	for _, id := range result.Quarantined {
+
+		err = stub.PutState(index_key(CORRUPT_BOND_PREFIX, id), corrupt[id])
+
+		if err != nil {
+			log_errorf(stub, "REPAIR_BOND_LISTING: Error storing corrupt record %s: %s", id, err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "REPAIR_BOND_LISTING: Error storing corrupt record "+index_key(CORRUPT_BOND_PREFIX, id), err)
+		}
+
+		err = stub.DelState(bond_key(id))
+
+		if err != nil {
+			log_errorf(stub, "REPAIR_BOND_LISTING: Error removing bond record %s: %s", id, err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "REPAIR_BOND_LISTING: Error removing bond record "+bond_key(id), err)
+		}
+
+		log_warningf(stub, "REPAIR_BOND_LISTING: Moved corrupt bond record %s to %s", id, index_key(CORRUPT_BOND_PREFIX, id))
+	}
+
+	legacy, err := t.get_legacy_bond_ids(stub)
+
+	if err != nil {
+		return nil, prefix_error("REPAIR_BOND_LISTING", err)
+	}
+
+	listed := Bond_Holder{BondIDs: []string{}}
+
+	for _, id := range legacy {
+
+		_, found, err := t.get_stored_bond(stub, id)
+
+		if err != nil && !skippable(err) {
+			return nil, prefix_error("REPAIR_BOND_LISTING", err)
+		}
+
+		if found {
+			listed.BondIDs = append(listed.BondIDs, id)
+		} else {
+			result.Unlisted = append(result.Unlisted, id)
+		}
+	}
+
+	if len(result.Unlisted) > 0 {
+
+		bytes, err := json.Marshal(listed)
+
+		if err != nil {
+			return nil, wrap_error(CODE_ERROR, "REPAIR_BOND_LISTING: Error converting bondIDs", err)
+		}
+
+		err = stub.PutState(BOND_LIST_KEY, bytes)
+
+		if err != nil {
+			log_errorf(stub, "REPAIR_BOND_LISTING: Error storing bondIDs: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "REPAIR_BOND_LISTING: Error storing bondIDs "+BOND_LIST_KEY, err)
+		}
+
+		log_warningf(stub, "REPAIR_BOND_LISTING: Dropped %v from bondIDs", result.Unlisted)
+	}
+
+	bytes, err := json.Marshal(result)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "REPAIR_BOND_LISTING: Error converting result", err)
+	}
+
+	return bytes, nil
+}
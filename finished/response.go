@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//==============================================================================================================================
+//	 Response Envelope - Every response of Init and Invoke carries the same JSON object as its payload, e.g.
+//
+//		{"status":200,"code":"OK","message":"","data":{"id":"bond1",...}}
+//		{"status":500,"code":"BOND_EXISTS","message":"Bond already exists"}
+//
+//	 so clients read one shape whatever the function and whether or not it succeeded. A failing response also carries
+//	 the envelope as its message since most SDKs only pass the message of a failed endorsement on. Payloads that are
+//	 not JSON, such as the bond ID returned by create_bond, are given as a JSON string.
+//==============================================================================================================================
+
+const CODE_OK = "OK"
+const CODE_ERROR = "ERROR"
+
+//==============================================================================================================================
+//	 Response_Envelope - The payload of every response.
+//==============================================================================================================================
+type Response_Envelope struct {
+	Status  int32           `json:"status"`
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+//==============================================================================================================================
+//	 success - Returns a successful response with the payload as its data.
+//==============================================================================================================================
+func success(payload []byte) pb.Response {
+
+	envelope := Response_Envelope{Status: shim.OK, Code: CODE_OK}
+
+	if len(payload) > 0 && json.Valid(payload) {
+		envelope.Data = payload
+	} else if len(payload) > 0 {
+		envelope.Data, _ = json.Marshal(string(payload))
+	}
+
+	bytes, _ := json.Marshal(envelope) // an envelope of valid JSON always converts
+
+	return shim.Success(bytes)
+}
+
+//==============================================================================================================================
+//	 failure - Returns an error response with the code and message given, see error_code.
+//==============================================================================================================================
+func failure(code string, message string) pb.Response {
+
+	bytes, _ := json.Marshal(Response_Envelope{Status: shim.ERROR, Code: code, Message: message})
+
+	return pb.Response{Status: shim.ERROR, Message: string(bytes), Payload: bytes}
+}
+
+//==============================================================================================================================
+//	 unwrap_response - Returns the data and message of a response from a chaincode answering in envelopes. Responses
+//					   of other chaincodes are returned as they are.
+//==============================================================================================================================
+func unwrap_response(response pb.Response) ([]byte, string) {
+
+	body := response.Payload
+
+	if len(body) == 0 {
+		body = []byte(response.Message)
+	}
+
+	var envelope Response_Envelope
+
+	if json.Unmarshal(body, &envelope) != nil || envelope.Code == "" {
+		return response.Payload, response.Message
+	}
+
+	var s string
+
+	if json.Unmarshal(envelope.Data, &s) == nil {
+		return []byte(s), envelope.Message // a payload that was not JSON
+	}
+
+	return envelope.Data, envelope.Message
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Document Signatures - Notaries and courts sign documents with certificates of their own PKI. The admin registers
+//						   the CA certificates trusted for this with register_document_ca; a detached signature is
+//						   then attached to a document with the signer's certificate, and kept only when the
+//						   certificate chains to a registered CA at the time of the transaction and the signature is
+//						   over the sha256 of the document.
+//
+//						   Signatures are raw PKCS#1 v1.5 RSA or ASN.1 ECDSA signatures of the SHA-256 digest, as
+//						   made by openssl dgst -sha256 -sign; CMS/PKCS#7 envelopes are not read. The document types
+//						   listed in signed_documents of the configuration only count toward required_documents
+//						   once they carry a verified signature.
+//==============================================================================================================================
+
+const DOCUMENT_CA_PREFIX = "document_ca"
+
+//==============================================================================================================================
+//	 Document_CA - A CA trusted to certify document signers.
+//==============================================================================================================================
+type Document_CA struct {
+	Name         string `json:"name"`
+	Subject      string `json:"subject"`
+	Certificate  string `json:"certificate"` // PEM
+	RegisteredAt string `json:"registered_at"`
+}
+
+//==============================================================================================================================
+//	 Document_Signature - A verified signature of a document.
+//==============================================================================================================================
+type Document_Signature struct {
+	Signer      string `json:"signer"` // subject of the signer's certificate
+	CA          string `json:"ca"`     // name of the registered CA it chains to
+	Fingerprint string `json:"fingerprint"`
+	Signature   string `json:"signature"` // base64
+	VerifiedAt  string `json:"verified_at"`
+	TxID        string `json:"txid"`
+}
+
+//==============================================================================================================================
+//	 parse_certificate - Parses a PEM certificate.
+//==============================================================================================================================
+func parse_certificate(certPEM string) (*x509.Certificate, error) {
+
+	block, _ := pem.Decode([]byte(certPEM))
+
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("Expecting a PEM certificate")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+//=================================================================================================================================
+//	 register_document_ca - Trusts the CA certificate under the name given, replacing any CA of the name. Admin only.
+//=================================================================================================================================
+func (t *SimpleChaincode) register_document_ca(stub shim.ChaincodeStubInterface, name string, certPEM string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !DOCUMENT_TYPE.MatchString(name) {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "REGISTER_DOCUMENT_CA: Invalid name "+name+", expecting lower case letters, digits and _")
+	}
+
+	cert, err := parse_certificate(certPEM)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "REGISTER_DOCUMENT_CA: "+err.Error())
+	}
+
+	if !cert.IsCA {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "REGISTER_DOCUMENT_CA: "+cert.Subject.String()+" is not a CA certificate")
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("REGISTER_DOCUMENT_CA", err)
+	}
+
+	bytes, err := json.Marshal(Document_CA{Name: name, Subject: cert.Subject.String(), Certificate: certPEM, RegisteredAt: now.Format(TIME_LAYOUT)})
+
+	if err != nil {
+		log_errorf(stub, "REGISTER_DOCUMENT_CA: Error converting CA: %s", err)
+		return nil, wrap_error(CODE_ERROR, "REGISTER_DOCUMENT_CA: Error converting CA", err)
+	}
+
+	err = stub.PutState(index_key(DOCUMENT_CA_PREFIX, name), bytes)
+
+	if err != nil {
+		log_errorf(stub, "REGISTER_DOCUMENT_CA: Error storing CA: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "REGISTER_DOCUMENT_CA: Error storing CA "+index_key(DOCUMENT_CA_PREFIX, name), err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 get_stored_document_cas - Returns the registered CAs in name order.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_stored_document_cas(stub shim.ChaincodeStubInterface) ([]Document_CA, error) {
+
+	start := DOCUMENT_CA_PREFIX + INDEX_SEPARATOR
+
+	iter, err := stub.GetStateByRange(start, start+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_DOCUMENT_CAS: Error querying CAs: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Error querying document CAs", err)
+	}
+
+	defer iter.Close()
+
+	cas := []Document_CA{}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_STORED_DOCUMENT_CAS: Error reading CAs: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "Error reading document CAs", err)
+		}
+
+		var ca Document_CA
+
+		err = json.Unmarshal(kv.Value, &ca)
+
+		if err != nil {
+			return nil, errors.New("Corrupt document CA " + string(kv.Value))
+		}
+
+		cas = append(cas, ca)
+	}
+
+	return cas, nil
+}
+
+//=================================================================================================================================
+//	 get_document_cas - Returns the registered CAs.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_document_cas(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	cas, err := t.get_stored_document_cas(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_DOCUMENT_CAS", err)
+	}
+
+	bytes, err := json.Marshal(cas)
+
+	if err != nil {
+		log_errorf(stub, "GET_DOCUMENT_CAS: Error converting CAs: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_DOCUMENT_CAS: Error converting CAs", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 attach_document_signature - Verifies the detached signature of an attached document and records it on the document.
+//=================================================================================================================================
+func (t *SimpleChaincode) attach_document_signature(stub shim.ChaincodeStubInterface, realEstateID string, docType string, digest string, signature string, certPEM string) ([]byte, error) {
+
+	digest = strings.ToLower(digest)
+
+	bytes, err := stub.GetState(document_key(realEstateID, docType, digest))
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT_SIGNATURE: Error reading document: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "ATTACH_DOCUMENT_SIGNATURE: Error reading document "+document_key(realEstateID, docType, digest), err)
+	}
+
+	if bytes == nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ATTACH_DOCUMENT_SIGNATURE: No "+docType+" "+digest+" is attached to "+realEstateID)
+	}
+
+	var d Document
+
+	err = json.Unmarshal(bytes, &d)
+
+	if err != nil {
+		return nil, errors.New("ATTACH_DOCUMENT_SIGNATURE: Corrupt document record " + string(bytes))
+	}
+
+	signer, err := parse_certificate(certPEM)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ATTACH_DOCUMENT_SIGNATURE: Invalid signer certificate: "+err.Error())
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "ATTACH_DOCUMENT_SIGNATURE: Invalid base64 signature")
+	}
+
+	ca, err := t.verify_signer(stub, signer)
+
+	if err != nil {
+		return nil, prefix_error("ATTACH_DOCUMENT_SIGNATURE", err)
+	}
+
+	sum, _ := hex.DecodeString(d.SHA256)
+
+	if !verify_digest_signature(signer.PublicKey, sum, sig) {
+		return nil, coded_error(CODE_NOT_AUTHORIZED, "ATTACH_DOCUMENT_SIGNATURE: The signature of "+signer.Subject.String()+" is not of the document")
+	}
+
+	fingerprint := sha256.Sum256(signer.Raw)
+
+	for _, s := range d.Signatures {
+		if s.Fingerprint == hex.EncodeToString(fingerprint[:]) {
+			return nil, coded_error(CODE_INVALID_STATE, "ATTACH_DOCUMENT_SIGNATURE: The document is already signed by "+s.Signer)
+		}
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("ATTACH_DOCUMENT_SIGNATURE", err)
+	}
+
+	d.Signatures = append(d.Signatures, Document_Signature{
+		Signer:      signer.Subject.String(),
+		CA:          ca,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		Signature:   signature,
+		VerifiedAt:  now.Format(TIME_LAYOUT),
+		TxID:        stub.GetTxID(),
+	})
+
+	bytes, err = json.Marshal(d)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT_SIGNATURE: Error converting document: %s", err)
+		return nil, wrap_error(CODE_ERROR, "ATTACH_DOCUMENT_SIGNATURE: Error converting document", err)
+	}
+
+	err = stub.PutState(document_key(d.RealEstateID, d.DocType, d.SHA256), bytes)
+
+	if err != nil {
+		log_errorf(stub, "ATTACH_DOCUMENT_SIGNATURE: Error storing document: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "ATTACH_DOCUMENT_SIGNATURE: Error storing document "+document_key(d.RealEstateID, d.DocType, d.SHA256), err)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 verify_signer - Returns the name of the registered CA the certificate chains to at the time of the transaction.
+//==============================================================================================================================
+func (t *SimpleChaincode) verify_signer(stub shim.ChaincodeStubInterface, signer *x509.Certificate) (string, error) {
+
+	cas, err := t.get_stored_document_cas(stub)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(cas) == 0 {
+		return "", coded_error(CODE_INVALID_STATE, "No document CAs are registered")
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return "", err
+	}
+
+	roots := x509.NewCertPool()
+	names := make(map[string]string)
+
+	for _, ca := range cas {
+
+		cert, err := parse_certificate(ca.Certificate)
+
+		if err != nil {
+			return "", errors.New("Corrupt document CA " + ca.Name)
+		}
+
+		roots.AddCert(cert)
+		names[string(cert.Raw)] = ca.Name
+	}
+
+	chains, err := signer.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: now, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+
+	if err != nil {
+		return "", coded_error(CODE_NOT_AUTHORIZED, "The certificate of "+signer.Subject.String()+" does not chain to a registered CA: "+err.Error())
+	}
+
+	var matched []string
+
+	for _, chain := range chains {
+		if name, ok := names[string(chain[len(chain)-1].Raw)]; ok {
+			matched = append(matched, name)
+		}
+	}
+
+	sort.Strings(matched) // the same CA on every endorser when several match
+
+	return matched[0], nil
+}
+
+//==============================================================================================================================
+//	 verify_digest_signature - Returns whether the signature is of the SHA-256 digest by the key.
+//==============================================================================================================================
+func verify_digest_signature(key interface{}, digest []byte, signature []byte) bool {
+
+	switch public := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+			return false
+		}
+		return ecdsa.Verify(public, digest, sig.R, sig.S)
+	}
+
+	return false
+}
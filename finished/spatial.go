@@ -0,0 +1,598 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Spatial Functions - Bonds are indexed by the geohash of their coordinates so area searches only read the bonds in
+//						 the cells around the requested area instead of every bond on the ledger. Boundaries are indexed
+//						 separately under the cells covering their bounding box, boundary~<cell>~<realEstateID>, so a
+//						 large parcel is found by overlap checks anywhere along it and not only near its coordinates.
+//==============================================================================================================================
+
+const GEOHASH_INDEX = "geohash"
+const BOUNDARY_INDEX = "boundary"
+
+// Upper bound on the number of geohash cells a boundary is indexed under
+const MAX_BOUNDARY_CELLS = 8
+
+// Upper bound on the number of geohash cells (and so range queries) a single area search may use
+const MAX_SEARCH_CELLS = 32
+
+// Fraction by which a declared area may differ from the area of the bond's boundary
+const AREA_TOLERANCE = 0.05
+
+//==============================================================================================================================
+//	 Coordinates - The reference point of a parcel in decimal degrees. Unknown marks a bond stored before coordinates
+//				   were typed whose text did not parse, it has no geohash until the coordinates are changed.
+//==============================================================================================================================
+type Coordinates struct {
+	Long    float64 `json:"long"`
+	Lat     float64 `json:"lat"`
+	Unknown bool    `json:"unknown,omitempty"`
+}
+
+//==============================================================================================================================
+//	 parse_coordinates - Converts longitude and latitude arguments into validated coordinates.
+//==============================================================================================================================
+func parse_coordinates(long string, lat string) (Coordinates, error) {
+
+	var c Coordinates
+
+	values, err := parse_floats([]string{long, lat}, "long, lat", 2)
+
+	if err != nil {
+		return c, err
+	}
+
+	c.Long, c.Lat = values[0], values[1]
+
+	return c, validate_coordinates(c)
+}
+
+//==============================================================================================================================
+//	 validate_coordinates - Checks the latitude is within -90..90 and the longitude within -180..180.
+//==============================================================================================================================
+func validate_coordinates(c Coordinates) error {
+
+	if c.Lat < -90 || c.Lat > 90 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Latitude out of range "+strconv.FormatFloat(c.Lat, 'f', -1, 64))
+	}
+
+	if c.Long < -180 || c.Long > 180 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Longitude out of range "+strconv.FormatFloat(c.Long, 'f', -1, 64))
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 UnmarshalJSON - Reads both numeric coordinates and the strings older bonds were stored with. Legacy text that is
+//					 not a valid position, often free text or empty, is read as unknown coordinates rather than failing
+//					 the whole record.
+//==============================================================================================================================
+func (c *Coordinates) UnmarshalJSON(data []byte) error {
+
+	var legacy struct {
+		Long string `json:"long"`
+		Lat  string `json:"lat"`
+	}
+
+	if json.Unmarshal(data, &legacy) == nil {
+		parsed, err := parse_coordinates(legacy.Long, legacy.Lat)
+		if err != nil {
+			parsed = Coordinates{Unknown: true}
+		}
+		*c = parsed
+		return nil
+	}
+
+	type typed Coordinates // avoids recursing into this method
+
+	return json.Unmarshal(data, (*typed)(c))
+}
+
+//==============================================================================================================================
+//	 geohash - Returns the geohash the coordinates are indexed under, empty when they are unknown.
+//==============================================================================================================================
+func (c Coordinates) geohash() string {
+
+	if c.Unknown {
+		return ""
+	}
+
+	return geohash_encode(c.Lat, c.Long, GEOHASH_INDEX_PRECISION)
+}
+
+//==============================================================================================================================
+//	 update_geohash - Recomputes the geohash of the bond from its coordinates and moves its entry in the geohash index
+//					  when it changed. Returns the bond with its Geohash field updated, ready to be saved.
+//==============================================================================================================================
+func (t *SimpleChaincode) update_geohash(stub shim.ChaincodeStubInterface, b Bond) (Bond, error) {
+
+	hash := b.Coordinates.geohash()
+
+	if hash == b.Geohash {
+		return b, nil
+	}
+
+	if b.Geohash != "" {
+		err := t.del_index(stub, GEOHASH_INDEX, b.Geohash, b.RealEstateID)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	if hash != "" {
+		err := t.put_index(stub, GEOHASH_INDEX, hash, b.RealEstateID)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	b.Geohash = hash
+
+	return b, nil
+}
+
+//=================================================================================================================================
+//	 change_coordinates - Moves the bond to new coordinates and updates its geohash index entry.
+//=================================================================================================================================
+func (t *SimpleChaincode) change_coordinates(stub shim.ChaincodeStubInterface, b Bond, long string, lat string) ([]byte, error) {
+
+	coordinates, err := parse_coordinates(long, lat)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_COORDINATES", err)
+	}
+
+	err = t.check_geofence(stub, coordinates)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_COORDINATES", err)
+	}
+
+	b.Coordinates = coordinates
+
+	b, err = t.update_geohash(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_COORDINATES: Error updating geohash index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating geohash index", err)
+	}
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_COORDINATES: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 parse_boundary - Converts a GeoJSON polygon argument into a validated boundary.
+//=================================================================================================================================
+func parse_boundary(arg string) (*Polygon, error) {
+
+	var p Polygon
+
+	err := json.Unmarshal([]byte(arg), &p)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "Invalid boundary, expecting a GeoJSON Polygon")
+	}
+
+	err = validate_polygon(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+//=================================================================================================================================
+//	 check_area - Fills in the area of a bond with a boundary from the polygon when none was declared, otherwise checks
+//				  the declared area is within AREA_TOLERANCE of the polygon's area.
+//=================================================================================================================================
+func check_area(b Bond) (Bond, error) {
+
+	if b.Boundary == nil {
+		return b, nil
+	}
+
+	computed := Land_Area{Value: polygon_area(*b.Boundary), Unit: AREA_UNIT}
+
+	if b.Area.Value == 0 {
+		b.Area = computed
+		return b, nil
+	}
+
+	if math.Abs(b.Area.Value-computed.Value) > computed.Value*AREA_TOLERANCE {
+		return b, coded_error(CODE_INVALID_ARGUMENT, "Declared area "+b.Area.String()+" does not match boundary area "+computed.String())
+	}
+
+	return b, nil
+}
+
+//==============================================================================================================================
+//	 boundary_cells - Returns the geohash cells the boundary is indexed under, those covering its bounding box at the
+//					  finest precision needing no more than MAX_BOUNDARY_CELLS. None for a bond without a boundary.
+//==============================================================================================================================
+func boundary_cells(p *Polygon) []string {
+
+	if p == nil {
+		return nil
+	}
+
+	minLat, minLong, maxLat, maxLong := ring_bbox(p.outline())
+
+	cells, _ := geohash_cover(minLat, minLong, maxLat, maxLong, MAX_BOUNDARY_CELLS) // a validated boundary is a valid box
+
+	return cells
+}
+
+//==============================================================================================================================
+//	 move_boundary_index - Replaces the boundary index entries of the bond as it was with those of the bond as it is.
+//==============================================================================================================================
+func (t *SimpleChaincode) move_boundary_index(stub shim.ChaincodeStubInterface, previous Bond, b Bond) error {
+
+	before, after := boundary_cells(previous.Boundary), boundary_cells(b.Boundary)
+
+	if strings.Join(before, INDEX_SEPARATOR) == strings.Join(after, INDEX_SEPARATOR) {
+		return nil
+	}
+
+	for _, cell := range before {
+		err := t.del_index(stub, BOUNDARY_INDEX, cell, b.RealEstateID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cell := range after {
+		err := t.put_index(stub, BOUNDARY_INDEX, cell, b.RealEstateID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 find_boundaries_in_bbox - Returns the bonds whose boundary's bounding box intersects the bounding box. A boundary
+//							   indexed under a cell at least as fine as a cell covering the box is found by scanning
+//							   that cell's prefix, one indexed under a coarser cell by looking up each shorter prefix.
+//==============================================================================================================================
+func (t *SimpleChaincode) find_boundaries_in_bbox(stub shim.ChaincodeStubInterface, minLat float64, minLong float64, maxLat float64, maxLong float64) ([]Bond, error) {
+
+	cells, err := geohash_cover(minLat, minLong, maxLat, maxLong, MAX_SEARCH_CELLS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scanned := make(map[string]bool)
+	found := make(map[string]bool)
+	var ids []string
+
+	for _, cell := range cells {
+
+		prefixes := []string{cell}
+
+		for k := 1; k < len(cell); k++ {
+			prefixes = append(prefixes, cell[:k]+INDEX_SEPARATOR)
+		}
+
+		for _, prefix := range prefixes {
+
+			if scanned[prefix] {
+				continue
+			}
+
+			scanned[prefix] = true
+
+			matches, err := t.scan_index(stub, BOUNDARY_INDEX, prefix)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, id := range matches {
+				if !found[id] {
+					found[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	candidates, err := t.retrieve_listed_bonds(stub, "FIND_BOUNDARIES_IN_BBOX", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds := []Bond{}
+
+	for _, b := range candidates {
+
+		if b.Boundary == nil {
+			continue
+		}
+
+		bMinLat, bMinLong, bMaxLat, bMaxLong := ring_bbox(b.Boundary.outline())
+
+		if bMinLat <= maxLat && bMaxLat >= minLat && bMinLong <= maxLong && bMaxLong >= minLong {
+			bonds = append(bonds, b)
+		}
+	}
+
+	return bonds, nil
+}
+
+//=================================================================================================================================
+//	 check_overlaps - Rejects the boundary of the bond when it overlaps the boundary of another bond. The candidates are
+//					  the bonds whose boundaries' bounding boxes intersect that of the new boundary, found through the
+//					  boundary index wherever their coordinates are.
+//=================================================================================================================================
+func (t *SimpleChaincode) check_overlaps(stub shim.ChaincodeStubInterface, b Bond) error {
+
+	if b.Boundary == nil {
+		return nil
+	}
+
+	minLat, minLong, maxLat, maxLong := ring_bbox(b.Boundary.outline())
+
+	neighbours, err := t.find_boundaries_in_bbox(stub, minLat, minLong, maxLat, maxLong)
+
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+
+	for _, n := range neighbours {
+		if n.RealEstateID != b.RealEstateID && n.Boundary != nil && rings_overlap(b.Boundary.outline(), n.Boundary.outline()) {
+			conflicts = append(conflicts, n.RealEstateID)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Boundary overlaps bonds "+strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 change_boundary - Amends the boundary of the bond after checking it against its neighbours.
+//=================================================================================================================================
+func (t *SimpleChaincode) change_boundary(stub shim.ChaincodeStubInterface, b Bond, boundary string) ([]byte, error) {
+
+	var problems Field_Errors
+
+	problems.check_length("boundary", boundary, MAX_BOUNDARY_LENGTH)
+
+	err := problems.to_error("CHANGE_BOUNDARY", "boundary")
+
+	if err != nil {
+		return nil, err
+	}
+
+	polygon, err := parse_boundary(boundary)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_BOUNDARY", err)
+	}
+
+	previous := b
+
+	b.Boundary = polygon
+	b.Area = Land_Area{} // the area follows the amended boundary
+
+	b, err = check_area(b)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_BOUNDARY", err)
+	}
+
+	err = t.check_overlaps(stub, b)
+
+	if err != nil {
+		return nil, prefix_error("CHANGE_BOUNDARY", err)
+	}
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_BOUNDARY: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	err = t.move_boundary_index(stub, previous, b)
+
+	if err != nil {
+		log_errorf(stub, "CHANGE_BOUNDARY: Error updating boundary index: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error updating boundary index", err)
+	}
+
+	return nil, nil
+}
+
+//=================================================================================================================================
+//	 find_bonds_in_bbox - Returns every bond whose coordinates lie inside the bounding box. The geohash cells covering
+//						  the box are scanned and the bonds found are then checked against the exact box edges.
+//=================================================================================================================================
+func (t *SimpleChaincode) find_bonds_in_bbox(stub shim.ChaincodeStubInterface, minLat float64, minLong float64, maxLat float64, maxLong float64) ([]Bond, error) {
+
+	cells, err := geohash_cover(minLat, minLong, maxLat, maxLong, MAX_SEARCH_CELLS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds := []Bond{}
+
+	for _, cell := range cells {
+
+		ids, err := t.scan_index(stub, GEOHASH_INDEX, cell)
+
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := t.retrieve_listed_bonds(stub, "FIND_BONDS_IN_BBOX", ids)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range found {
+
+			lat, long := b.Coordinates.Lat, b.Coordinates.Long
+
+			if lat >= minLat && lat <= maxLat && long >= minLong && long <= maxLong {
+				bonds = append(bonds, b)
+			}
+		}
+	}
+
+	return bonds, nil
+}
+
+//=================================================================================================================================
+//	 get_bonds_in_bbox - Returns the JSON array of bonds inside the bounding box.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_bonds_in_bbox(stub shim.ChaincodeStubInterface, minLat float64, minLong float64, maxLat float64, maxLong float64) ([]byte, error) {
+
+	bonds, err := t.find_bonds_in_bbox(stub, minLat, minLong, maxLat, maxLong)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(bonds)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_BONDS_IN_BBOX: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 get_nearby_bonds - Returns the bonds within radius metres of the point, nearest first. The bounding box around the
+//						circle is searched through the geohash index and the results are then filtered on their
+//						great-circle distance.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_nearby_bonds(stub shim.ChaincodeStubInterface, lat float64, long float64, radius float64) ([]byte, error) {
+
+	if lat < -90 || lat > 90 || long < -180 || long > 180 {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_NEARBY_BONDS: Coordinates out of range")
+	}
+
+	if radius <= 0 {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_NEARBY_BONDS: Radius must be positive")
+	}
+
+	dLat := radius / EARTH_RADIUS * 180 / math.Pi
+	dLong := 180.0
+
+	if c := math.Cos(lat * math.Pi / 180); c > dLat/180 {
+		dLong = math.Min(dLat/c, 180)
+	}
+
+	bonds, err := t.find_bonds_in_bbox(stub, math.Max(lat-dLat, -90), math.Max(long-dLong, -180), math.Min(lat+dLat, 90), math.Min(long+dLong, 180))
+
+	if err != nil {
+		return nil, err
+	}
+
+	nearby := Nearby_Bonds{}
+
+	for _, b := range bonds {
+
+		if d := haversine(lat, long, b.Coordinates.Lat, b.Coordinates.Long); d <= radius {
+			nearby = append(nearby, Nearby_Bond{Bond: b, Distance: d})
+		}
+	}
+
+	sort.Sort(nearby)
+
+	bytes, err := json.Marshal(nearby)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_NEARBY_BONDS: Error converting bond records", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 Nearby_Bond - A bond returned by get_nearby_bonds along with its distance in metres from the searched point.
+//=================================================================================================================================
+type Nearby_Bond struct {
+	Bond     Bond    `json:"bond"`
+	Distance float64 `json:"distance"`
+}
+
+type Nearby_Bonds []Nearby_Bond
+
+func (n Nearby_Bonds) Len() int           { return len(n) }
+func (n Nearby_Bonds) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n Nearby_Bonds) Less(i, j int) bool { return n[i].Distance < n[j].Distance }
+
+// Mean earth radius in metres used for distance calculations
+const EARTH_RADIUS = 6371000.0
+
+//=================================================================================================================================
+//	 haversine - Returns the great-circle distance in metres between two points.
+//=================================================================================================================================
+func haversine(lat1 float64, long1 float64, lat2 float64, long2 float64) float64 {
+
+	rad := math.Pi / 180
+
+	dLat := (lat2 - lat1) * rad
+	dLong := (long2 - long1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLong/2)*math.Sin(dLong/2)
+
+	return 2 * EARTH_RADIUS * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+//=================================================================================================================================
+//	 parse_floats - Converts query arguments into numbers, naming the expected arguments in the error when the count is
+//					wrong.
+//=================================================================================================================================
+func parse_floats(args []string, names string, count int) ([]float64, error) {
+
+	if len(args) != count {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "Incorrect number of arguments. Expecting "+names)
+	}
+
+	values := make([]float64, count)
+
+	for i, arg := range args {
+
+		v, err := strconv.ParseFloat(arg, 64)
+
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, coded_error(CODE_INVALID_ARGUMENT, "Invalid number "+arg)
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+}
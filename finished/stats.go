@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Registry_Stats - The response of get_registry_stats, a summary of the whole registry for monitoring dashboards.
+//==============================================================================================================================
+type Registry_Stats struct {
+	TotalBonds     int            `json:"total_bonds"`
+	UniqueOwners   int            `json:"unique_owners"`
+	BondsByStatus  map[string]int `json:"bonds_by_status"`
+	TotalTransfers int            `json:"total_transfers"`
+	LastTransferAt string         `json:"last_transfer_at"`
+	Skipped        []string       `json:"skipped,omitempty"` // bonds left out, see get_skipped_bonds
+}
+
+//=================================================================================================================================
+//	 get_registry_stats - Counts the bonds, their owners and statuses along with the recorded transfers and the time of
+//						  the most recent one.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_registry_stats(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	ids, err := t.get_sorted_bond_ids(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bonds, skipped, err := t.retrieve_bonds_skipping(stub, "GET_REGISTRY_STATS", ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats := Registry_Stats{BondsByStatus: make(map[string]int), Skipped: skipped}
+	owners := make(map[string]bool)
+
+	for _, b := range bonds {
+
+		stats.TotalBonds++
+		stats.BondsByStatus[b.Status.String()]++
+		owners[b.OwnerNationalID] = true
+	}
+
+	stats.UniqueOwners = len(owners)
+
+	transfers, err := t.get_transfers(stub, "", "9999")
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TotalTransfers = len(transfers)
+
+	if len(transfers) > 0 {
+		stats.LastTransferAt = transfers[len(transfers)-1].Timestamp
+	}
+
+	bytes, err := json.Marshal(stats)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_REGISTRY_STATS: Error converting registry statistics", err)
+	}
+
+	return bytes, nil
+}
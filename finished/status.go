@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+)
+
+//==============================================================================================================================
+//	 Bond Statuses - What stands on the parcel. create_bond, create_bonds_bulk and change_realestate_status only accept
+//					 the statuses below, spelt exactly so, so the status index and the statistics by status never
+//					 split one status over several spellings. Bonds stored before the statuses were enforced keep
+//					 whatever status they had until it is next changed.
+//==============================================================================================================================
+
+//==============================================================================================================================
+//	 Bond_Status - The status of a bond, one of BOND_STATUSES for any bond created or changed since.
+//==============================================================================================================================
+type Bond_Status string
+
+const STATUS_FLAT Bond_Status = "flat"   // an apartment
+const STATUS_BUILT Bond_Status = "built" // a parcel with a building
+
+// Every status, in the order they are listed in messages
+var BOND_STATUSES = []Bond_Status{STATUS_FLAT, STATUS_BUILT}
+
+func (s Bond_Status) String() string {
+	return string(s)
+}
+
+//==============================================================================================================================
+//	 valid - Returns whether the status is one of BOND_STATUSES.
+//==============================================================================================================================
+func (s Bond_Status) valid() bool {
+
+	for _, status := range BOND_STATUSES {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 status_names - Returns the statuses for messages, e.g. flat, built.
+//==============================================================================================================================
+func status_names() string {
+
+	var names []string
+
+	for _, status := range BOND_STATUSES {
+		names = append(names, status.String())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+//==============================================================================================================================
+//	 parse_status - Returns the status named, or an error naming the statuses accepted.
+//==============================================================================================================================
+func parse_status(name string) (Bond_Status, error) {
+
+	s := Bond_Status(name)
+
+	if !s.valid() {
+		return s, coded_error(CODE_INVALID_ARGUMENT, "Unknown status "+name+", expecting one of "+status_names())
+	}
+
+	return s, nil
+}
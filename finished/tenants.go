@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+//==============================================================================================================================
+//	 Tenants - Several municipalities can share one deployment of the chaincode. The regulator of the deployment, an
+//			   admin of its regulator_msp, registers each municipality's organisation with register_tenant. Every
+//			   transaction submitted by a client of a registered organisation then runs on a tenant_stub, which
+//			   keeps every key it reads and writes, private data and key level endorsement included, under
+//			   ns~<tenant>~. A tenant has its own bonds, indexes and configuration and cannot read another's;
+//			   organisations that are not registered share the deployment's own keys as before.
+//
+//			   A tenant starts with the default configuration with its first organisation as regulator_msp, and
+//			   its admins manage it with set_config like any deployment. Tenants are kept under tenant~<MSP ID>,
+//			   outside every namespace.
+//==============================================================================================================================
+
+const TENANT_PREFIX = "tenant"
+const NAMESPACE_PREFIX = "ns"
+
+var TENANT_ID = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+//==============================================================================================================================
+//	 Tenant - An organisation registered to a municipality's namespace.
+//==============================================================================================================================
+type Tenant struct {
+	ID           string `json:"id"`
+	MSP          string `json:"msp"`
+	RegisteredAt string `json:"registered_at"`
+}
+
+//==============================================================================================================================
+//	 tenant_stub - A stub keeping the keys of a tenant under its namespace. Keys returned by range scans are given back
+//				   without the namespace, so the code running on it cannot tell it apart from a stub of its own.
+//==============================================================================================================================
+type tenant_stub struct {
+	shim.ChaincodeStubInterface
+	tenant    string
+	namespace string
+}
+
+func new_tenant_stub(stub shim.ChaincodeStubInterface, tenant string) *tenant_stub {
+	return &tenant_stub{ChaincodeStubInterface: stub, tenant: tenant, namespace: index_key(NAMESPACE_PREFIX, tenant) + INDEX_SEPARATOR}
+}
+
+func (s *tenant_stub) GetState(key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetState(s.namespace + key)
+}
+
+func (s *tenant_stub) PutState(key string, value []byte) error {
+	return s.ChaincodeStubInterface.PutState(s.namespace+key, value)
+}
+
+func (s *tenant_stub) DelState(key string) error {
+	return s.ChaincodeStubInterface.DelState(s.namespace + key)
+}
+
+func (s *tenant_stub) GetStateByRange(start string, end string) (shim.StateQueryIteratorInterface, error) {
+
+	iter, err := s.ChaincodeStubInterface.GetStateByRange(s.namespace+start, s.namespace+end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tenant_iterator{StateQueryIteratorInterface: iter, namespace: s.namespace}, nil
+}
+
+func (s *tenant_stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return s.ChaincodeStubInterface.GetHistoryForKey(s.namespace + key)
+}
+
+func (s *tenant_stub) SetStateValidationParameter(key string, ep []byte) error {
+	return s.ChaincodeStubInterface.SetStateValidationParameter(s.namespace+key, ep)
+}
+
+func (s *tenant_stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetStateValidationParameter(s.namespace + key)
+}
+
+func (s *tenant_stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetPrivateData(collection, s.namespace+key)
+}
+
+func (s *tenant_stub) PutPrivateData(collection string, key string, value []byte) error {
+	return s.ChaincodeStubInterface.PutPrivateData(collection, s.namespace+key, value)
+}
+
+func (s *tenant_stub) DelPrivateData(collection string, key string) error {
+	return s.ChaincodeStubInterface.DelPrivateData(collection, s.namespace+key)
+}
+
+func (s *tenant_stub) GetPrivateDataByRange(collection string, start string, end string) (shim.StateQueryIteratorInterface, error) {
+
+	iter, err := s.ChaincodeStubInterface.GetPrivateDataByRange(collection, s.namespace+start, s.namespace+end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tenant_iterator{StateQueryIteratorInterface: iter, namespace: s.namespace}, nil
+}
+
+//==============================================================================================================================
+//	 tenant_iterator - A range scan of a namespace, returning the keys without it.
+//==============================================================================================================================
+type tenant_iterator struct {
+	shim.StateQueryIteratorInterface
+	namespace string
+}
+
+func (i *tenant_iterator) Next() (*queryresult.KV, error) {
+
+	kv, err := i.StateQueryIteratorInterface.Next()
+
+	if err != nil || kv == nil {
+		return kv, err
+	}
+
+	return &queryresult.KV{Namespace: kv.Namespace, Key: kv.Key[len(i.namespace):], Value: kv.Value}, nil
+}
+
+//==============================================================================================================================
+//	 stub_tenant - Returns the tenant the stub runs for, empty for the deployment's own keys.
+//==============================================================================================================================
+func stub_tenant(stub shim.ChaincodeStubInterface) string {
+
+	switch s := stub.(type) {
+	case *tenant_stub:
+		return s.tenant
+	case *staged_stub:
+		return stub_tenant(s.ChaincodeStubInterface)
+	}
+
+	return ""
+}
+
+//==============================================================================================================================
+//	 root_stub - Returns the stub of the deployment's own keys under a tenant_stub.
+//==============================================================================================================================
+func root_stub(stub shim.ChaincodeStubInterface) shim.ChaincodeStubInterface {
+
+	if s, ok := stub.(*tenant_stub); ok {
+		return s.ChaincodeStubInterface
+	}
+
+	return stub
+}
+
+//==============================================================================================================================
+//	 get_stored_tenant - Returns the tenant the organisation is registered to, and false when it is not registered.
+//==============================================================================================================================
+func get_stored_tenant(stub shim.ChaincodeStubInterface, msp string) (Tenant, bool, error) {
+
+	var tenant Tenant
+
+	bytes, err := root_stub(stub).GetState(index_key(TENANT_PREFIX, msp))
+
+	if err != nil {
+		log_errorf(stub, "GET_STORED_TENANT: Error reading tenant of %s: %s", msp, err)
+		return tenant, false, wrap_error(CODE_LEDGER_ERROR, "Error reading tenant "+index_key(TENANT_PREFIX, msp), err)
+	}
+
+	if bytes == nil {
+		return tenant, false, nil
+	}
+
+	err = json.Unmarshal(bytes, &tenant)
+
+	if err != nil {
+		return tenant, false, errors.New("Corrupt tenant " + string(bytes))
+	}
+
+	return tenant, true, nil
+}
+
+//==============================================================================================================================
+//	 tenant_stub_for - Returns the stub the transaction runs on: a tenant_stub when the caller's organisation is
+//					   registered to a tenant, the stub itself otherwise.
+//==============================================================================================================================
+func (t *SimpleChaincode) tenant_stub_for(stub shim.ChaincodeStubInterface) (shim.ChaincodeStubInterface, error) {
+
+	msp, err := caller_msp(stub)
+
+	if err != nil {
+		return stub, err
+	}
+
+	tenant, found, err := get_stored_tenant(stub, msp)
+
+	if err != nil || !found {
+		return stub, err
+	}
+
+	return new_tenant_stub(stub, tenant.ID), nil
+}
+
+//=================================================================================================================================
+//	 register_tenant - Registers the organisation to the tenant, creating the tenant with the organisation as its
+//					   regulator when it is new. Regulator of the deployment only.
+//=================================================================================================================================
+func (t *SimpleChaincode) register_tenant(stub shim.ChaincodeStubInterface, tenantID string, msp string) ([]byte, error) {
+
+	root := root_stub(stub)
+
+	err := t.check_admin(root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := t.load_config(root)
+
+	if err != nil {
+		return nil, prefix_error("REGISTER_TENANT", err)
+	}
+
+	caller, err := caller_msp(root)
+
+	if err != nil {
+		return nil, prefix_error("REGISTER_TENANT", err)
+	}
+
+	if caller != c.RegulatorMSP {
+		return nil, coded_error(CODE_NOT_AUTHORIZED, "REGISTER_TENANT: Permission denied, tenants are registered by "+c.RegulatorMSP)
+	}
+
+	if !TENANT_ID.MatchString(tenantID) {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "REGISTER_TENANT: Invalid tenant ID "+tenantID+", expecting lower case letters, digits and _")
+	}
+
+	if msp == "" || msp == c.RegulatorMSP {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "REGISTER_TENANT: Invalid organisation "+msp)
+	}
+
+	existing, found, err := get_stored_tenant(root, msp)
+
+	if err != nil {
+		return nil, prefix_error("REGISTER_TENANT", err)
+	}
+
+	if found {
+		return nil, coded_error(CODE_INVALID_STATE, "REGISTER_TENANT: "+msp+" is already registered to "+existing.ID)
+	}
+
+	now, err := tx_time(root)
+
+	if err != nil {
+		return nil, prefix_error("REGISTER_TENANT", err)
+	}
+
+	tenant := new_tenant_stub(root, tenantID)
+
+	configured, err := tenant.GetState(CONFIG_KEY)
+
+	if err != nil {
+		log_errorf(stub, "REGISTER_TENANT: Error reading tenant configuration: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "REGISTER_TENANT: Error reading tenant configuration "+CONFIG_KEY, err)
+	}
+
+	if configured == nil {
+
+		tc := DEFAULT_CONFIG
+		tc.RegulatorMSP = msp
+
+		err = t.put_config(tenant, tc)
+
+		if err != nil {
+			return nil, prefix_error("REGISTER_TENANT", err)
+		}
+	}
+
+	bytes, err := json.Marshal(Tenant{ID: tenantID, MSP: msp, RegisteredAt: now.Format(TIME_LAYOUT)})
+
+	if err != nil {
+		log_errorf(stub, "REGISTER_TENANT: Error converting tenant: %s", err)
+		return nil, wrap_error(CODE_ERROR, "REGISTER_TENANT: Error converting tenant", err)
+	}
+
+	err = root.PutState(index_key(TENANT_PREFIX, msp), bytes)
+
+	if err != nil {
+		log_errorf(stub, "REGISTER_TENANT: Error storing tenant: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "REGISTER_TENANT: Error storing tenant "+index_key(TENANT_PREFIX, msp), err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 get_tenant - Returns the tenant of the caller's organisation.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_tenant(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	msp, err := caller_msp(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_TENANT", err)
+	}
+
+	tenant, found, err := get_stored_tenant(stub, msp)
+
+	if err != nil {
+		return nil, prefix_error("GET_TENANT", err)
+	}
+
+	if !found {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_TENANT: "+msp+" is not registered to a tenant")
+	}
+
+	bytes, err := json.Marshal(tenant)
+
+	if err != nil {
+		log_errorf(stub, "GET_TENANT: Error converting tenant: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_TENANT: Error converting tenant", err)
+	}
+
+	return bytes, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Bond Tokens - A bond can be wrapped as a non-fungible token of an ERC-721 style token chaincode on this channel,
+//				   named by token_chaincode in the configuration, so it can be held and traded by the token's
+//				   tools. wrap_bond locks the bond like export_bond does and mints the token to the holder given;
+//				   unwrap_bond burns the token and unlocks the bond. Both run in one transaction with the call to
+//				   the token chaincode, so the lock and the token never disagree.
+//
+//				   The token chaincode is expected to answer mint(tokenID, holder, metadata), owner_of(tokenID)
+//				   and burn(tokenID), and to let the registry burn tokens it minted. Trading the token does not
+//				   change the registered owner: unwrap_bond returns the holder at the time of burning for the
+//				   registry to transfer the bond to if they differ.
+//==============================================================================================================================
+
+const TOKEN_ID_PREFIX = "bond:"
+
+const BOND_WRAPPED_EVENT = "BondWrapped"
+const BOND_UNWRAPPED_EVENT = "BondUnwrapped"
+
+//==============================================================================================================================
+//	 Token_Metadata - What the token says of the bond it stands for.
+//==============================================================================================================================
+type Token_Metadata struct {
+	RealEstateID string `json:"real_estate_id"`
+	BondID       string `json:"bond_id"`
+	Channel      string `json:"channel"`
+}
+
+//==============================================================================================================================
+//	 Token_Wrap - The response and event payload of wrap_bond and unwrap_bond.
+//==============================================================================================================================
+type Token_Wrap struct {
+	RealEstateID string `json:"real_estate_id"`
+	TokenID      string `json:"token_id"`
+	Chaincode    string `json:"chaincode"`
+	Holder       string `json:"holder"`
+}
+
+//==============================================================================================================================
+//	 token_caller - Returns the caller of the configured token chaincode.
+//==============================================================================================================================
+func (t *SimpleChaincode) token_caller(stub shim.ChaincodeStubInterface) (Chaincode_Target, error) {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return Chaincode_Target{}, err
+	}
+
+	return Chaincode_Target{Name: c.TokenChaincode, Attempts: 1}, nil
+}
+
+//=================================================================================================================================
+//	 wrap_bond - Locks the bond and mints its token to the holder. Returns the Token_Wrap. Admin only.
+//=================================================================================================================================
+func (t *SimpleChaincode) wrap_bond(stub shim.ChaincodeStubInterface, b Bond, holder string) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("WRAP_BOND", err)
+	}
+
+	if b.TokenID != "" {
+		return nil, coded_error(CODE_INVALID_STATE, "WRAP_BOND: Bond "+b.RealEstateID+" is already wrapped as "+b.TokenID)
+	}
+
+	if holder == "" {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "WRAP_BOND: The holder of the token is required")
+	}
+
+	caller, err := t.token_caller(stub)
+
+	if err != nil {
+		return nil, prefix_error("WRAP_BOND", err)
+	}
+
+	metadata, err := json.Marshal(Token_Metadata{RealEstateID: b.RealEstateID, BondID: b.ID, Channel: stub.GetChannelID()})
+
+	if err != nil {
+		log_errorf(stub, "WRAP_BOND: Error converting metadata: %s", err)
+		return nil, wrap_error(CODE_ERROR, "WRAP_BOND: Error converting metadata", err)
+	}
+
+	w := Token_Wrap{RealEstateID: b.RealEstateID, TokenID: TOKEN_ID_PREFIX + b.RealEstateID, Chaincode: caller.Name, Holder: holder}
+
+	_, err = caller.Call(stub, "mint", w.TokenID, holder, string(metadata))
+
+	if err != nil {
+		return nil, prefix_error("WRAP_BOND", err)
+	}
+
+	b.TokenID = w.TokenID
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "WRAP_BOND: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	err = t.emit_event(stub, BOND_WRAPPED_EVENT, b.RealEstateID, w, event_routing([]string{b.OwnerNationalID}, []string{b.DistrictCode}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(w)
+}
+
+//=================================================================================================================================
+//	 unwrap_bond - Burns the token of the bond and unlocks it. Returns the Token_Wrap with the holder of the token when it
+//				   was burnt. Admin only.
+//=================================================================================================================================
+func (t *SimpleChaincode) unwrap_bond(stub shim.ChaincodeStubInterface, b Bond) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, prefix_error("UNWRAP_BOND", err)
+	}
+
+	if b.TokenID == "" {
+		return nil, coded_error(CODE_INVALID_STATE, "UNWRAP_BOND: Bond "+b.RealEstateID+" is not wrapped")
+	}
+
+	caller, err := t.token_caller(stub)
+
+	if err != nil {
+		return nil, prefix_error("UNWRAP_BOND", err)
+	}
+
+	holder, err := caller.Call(stub, "owner_of", b.TokenID)
+
+	if err != nil {
+		return nil, prefix_error("UNWRAP_BOND", err)
+	}
+
+	_, err = caller.Call(stub, "burn", b.TokenID)
+
+	if err != nil {
+		return nil, prefix_error("UNWRAP_BOND", err)
+	}
+
+	w := Token_Wrap{RealEstateID: b.RealEstateID, TokenID: b.TokenID, Chaincode: caller.Name, Holder: string(holder)}
+
+	b.TokenID = ""
+
+	_, err = t.save_changes(stub, b)
+
+	if err != nil {
+		log_errorf(stub, "UNWRAP_BOND: Error saving changes: %s", err)
+		return nil, wrap_error(CODE_ERROR, "Error saving changes", err)
+	}
+
+	err = t.emit_event(stub, BOND_UNWRAPPED_EVENT, b.RealEstateID, w, event_routing([]string{b.OwnerNationalID}, []string{b.DistrictCode}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(w)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Transfer Records - Every change of ownership is recorded under a key starting with the time of the transaction so
+//						the transfers in a period can be read with a single range query.
+//==============================================================================================================================
+
+const TRANSFER_INDEX = "transfer"
+
+// Layout of the timestamps used in transfer keys, fixed width so keys sort in time order
+const TIME_LAYOUT = "2006-01-02T15:04:05Z"
+
+// Layout of the day used when grouping and when passing date ranges
+const DAY_LAYOUT = "2006-01-02"
+
+//==============================================================================================================================
+//	 Transfer_Record - Defines the structure of a recorded transfer. The sale price stated by the parties is stored in the
+//					   salePrices collection and PriceHash is its salted hash, see put_sale_price.
+//==============================================================================================================================
+type Transfer_Record struct {
+	RealEstateID  string  `json:"real_estate_id"`
+	DistrictCode  string  `json:"district_code"` // district of the bond at the time of the transfer
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	DeclaredValue float64 `json:"declared_value,omitempty"` // only public on transfers recorded before salePrices
+	PriceHash     string  `json:"price_hash,omitempty"`
+	Timestamp     string  `json:"timestamp"`
+	TxID          string  `json:"txid"`
+}
+
+//==============================================================================================================================
+//	 Transfer_Group - The transfers aggregated under one key of a get_transfer_stats grouping.
+//==============================================================================================================================
+type Transfer_Group struct {
+	Key        string  `json:"key"`
+	Count      int     `json:"count"`
+	TotalValue float64 `json:"total_value"`
+}
+
+//==============================================================================================================================
+//	 Transfer_Stats - The response of get_transfer_stats.
+//==============================================================================================================================
+type Transfer_Stats struct {
+	GroupBy    string           `json:"group_by"`
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Count      int              `json:"count"`
+	TotalValue float64          `json:"total_value"`
+	Groups     []Transfer_Group `json:"groups"`
+}
+
+//==============================================================================================================================
+//	 tx_time - Returns the timestamp of the current transaction. The proposal timestamp is the same on every peer,
+//			   unlike the wall clock, so it is safe to write to the ledger.
+//==============================================================================================================================
+func tx_time(stub shim.ChaincodeStubInterface) (time.Time, error) {
+
+	ts, err := stub.GetTxTimestamp()
+
+	if err != nil {
+		log_errorf(stub, "TX_TIME: Error reading transaction timestamp: %s", err)
+		return time.Time{}, wrap_error(CODE_LEDGER_ERROR, "Error reading transaction timestamp", err)
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+//==============================================================================================================================
+//	 transfer_key - Returns the ledger key of the transfer record, also the key of its sale price.
+//==============================================================================================================================
+func transfer_key(tr Transfer_Record) string {
+	return index_key(TRANSFER_INDEX, tr.Timestamp, tr.RealEstateID, tr.TxID)
+}
+
+//==============================================================================================================================
+//	 record_transfer - Stores the transfer record of the current transaction and returns it as stored. A declared value
+//					   is moved to the salePrices collection, leaving only its hash on the record.
+//==============================================================================================================================
+func (t *SimpleChaincode) record_transfer(stub shim.ChaincodeStubInterface, tr Transfer_Record) (Transfer_Record, error) {
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return tr, err
+	}
+
+	tr.Timestamp = now.Format(TIME_LAYOUT)
+	tr.TxID = stub.GetTxID()
+
+	if tr.DeclaredValue > 0 {
+
+		tr.PriceHash, err = t.put_sale_price(stub, transfer_key(tr), tr, tr.DeclaredValue)
+
+		if err != nil {
+			return tr, err
+		}
+
+		tr.DeclaredValue = 0
+	}
+
+	bytes, err := json.Marshal(tr)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_TRANSFER: Error converting transfer record: %s", err)
+		return tr, wrap_error(CODE_ERROR, "Error converting transfer record", err)
+	}
+
+	err = stub.PutState(transfer_key(tr), bytes)
+
+	if err != nil {
+		log_errorf(stub, "RECORD_TRANSFER: Error storing transfer record: %s", err)
+		return tr, wrap_error(CODE_LEDGER_ERROR, "Error storing transfer record "+transfer_key(tr), err)
+	}
+
+	return tr, nil
+}
+
+//==============================================================================================================================
+//	 get_transfers - Returns the transfer records between the from and to days inclusive.
+//==============================================================================================================================
+func (t *SimpleChaincode) get_transfers(stub shim.ChaincodeStubInterface, from string, to string) ([]Transfer_Record, error) {
+
+	iter, err := stub.GetStateByRange(index_key(TRANSFER_INDEX, from), index_key(TRANSFER_INDEX, to)+"\xff")
+
+	if err != nil {
+		log_errorf(stub, "GET_TRANSFERS: Error querying transfer records: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "Error querying transfer records", err)
+	}
+
+	defer iter.Close()
+
+	var transfers []Transfer_Record
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			log_errorf(stub, "GET_TRANSFERS: Error reading transfer records: %s", err)
+			return nil, wrap_error(CODE_LEDGER_ERROR, "Error reading transfer records", err)
+		}
+
+		var tr Transfer_Record
+
+		err = json.Unmarshal(kv.Value, &tr)
+
+		if err != nil {
+			return nil, errors.New("Corrupt transfer record " + string(kv.Value))
+		}
+
+		transfers = append(transfers, tr)
+	}
+
+	return transfers, nil
+}
+
+//=================================================================================================================================
+//	 get_transfer_stats - Counts the transfers and sums their declared values between the from and to days (YYYY-MM-DD,
+//						  inclusive), grouped by the day of the transfer or the district of the bond. Only the sale
+//						  prices this peer holds in the salePrices collection are summed.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_transfer_stats(stub shim.ChaincodeStubInterface, groupBy string, from string, to string) ([]byte, error) {
+
+	if groupBy != "day" && groupBy != "district" {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_TRANSFER_STATS: Unsupported grouping "+groupBy+", expecting day or district")
+	}
+
+	_, err := time.Parse(DAY_LAYOUT, from)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_TRANSFER_STATS: Invalid from date "+from)
+	}
+
+	_, err = time.Parse(DAY_LAYOUT, to)
+
+	if err != nil {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "GET_TRANSFER_STATS: Invalid to date "+to)
+	}
+
+	transfers, err := t.get_transfers(stub, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats := Transfer_Stats{GroupBy: groupBy, From: from, To: to, Groups: []Transfer_Group{}}
+	groups := make(map[string]*Transfer_Group)
+	var keys []string
+
+	for _, tr := range transfers {
+
+		key := tr.DistrictCode // transfers of bonds without a district are grouped under ""
+
+		if groupBy == "day" {
+			key = tr.Timestamp[:len(DAY_LAYOUT)]
+		}
+
+		g, ok := groups[key]
+
+		if !ok {
+			g = &Transfer_Group{Key: key}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+
+		value := tr.DeclaredValue
+
+		if tr.PriceHash != "" {
+
+			sp, found, err := t.get_sale_price(stub, transfer_key(tr))
+
+			if err != nil {
+				return nil, prefix_error("GET_TRANSFER_STATS", err)
+			}
+
+			if found {
+				value = sp.DeclaredValue
+			}
+		}
+
+		g.Count++
+		g.TotalValue += value
+
+		stats.Count++
+		stats.TotalValue += value
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		stats.Groups = append(stats.Groups, *groups[key])
+	}
+
+	bytes, err := json.Marshal(stats)
+
+	if err != nil {
+		return nil, wrap_error(CODE_ERROR, "GET_TRANSFER_STATS: Error converting transfer statistics", err)
+	}
+
+	return bytes, nil
+}
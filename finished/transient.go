@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Transient Input - Sensitive arguments can be passed in the transient map of the proposal instead of the public
+//					   arguments so they are not stored in the block as arguments of the transaction. The positional
+//					   argument is then left empty and the transient field with the name below becomes required.
+//					   Only the declared value is kept out of the block altogether, in the private collection of
+//					   put_sale_price. The national IDs of owners and recipients are still written to public state,
+//					   in the bond, the owner index and the transfer records, and so are part of the write set.
+//==============================================================================================================================
+
+const TRANSIENT_OWNER = "owner_national_id"
+const TRANSIENT_RECIPIENT = "recipient_national_id"
+const TRANSIENT_DECLARED_VALUE = "declared_value"
+
+//==============================================================================================================================
+//	 transient_field - Returns the named field of the transient map and whether it was present and not empty.
+//==============================================================================================================================
+func transient_field(stub shim.ChaincodeStubInterface, name string) (string, bool, error) {
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		log_errorf(stub, "TRANSIENT_FIELD: Error reading transient map: %s", err)
+		return "", false, wrap_error(CODE_LEDGER_ERROR, "Error reading transient map", err)
+	}
+
+	value := string(transient[name])
+
+	return value, value != "", nil
+}
+
+//==============================================================================================================================
+//	 arg_or_transient - Returns args[i] or, when it is missing or empty, the named transient field. One of the two is
+//						required.
+//==============================================================================================================================
+func arg_or_transient(stub shim.ChaincodeStubInterface, args []string, i int, name string) (string, error) {
+
+	if i < len(args) && args[i] != "" {
+		return args[i], nil
+	}
+
+	value, found, err := transient_field(stub, name)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", coded_error(CODE_INVALID_ARGUMENT, "Missing "+name+", expecting it as argument "+strconv.Itoa(i)+" or in the transient map")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Validation - Every record a client sends, a bond, an owner taking a bond over or a document, is checked field by
+//				  field before anything is written. All the problems found are returned together as a JSON array of
+//				  Field_Error in an INVALID_ARGUMENT error, e.g.
+//
+//		CREATE_BOND: Invalid bond: [{"field":"status","code":"REQUIRED","message":"required"}]
+//
+//				  so a client can mark every bad field of a form at once rather than fixing them one call at a time.
+//				  Fields are named as in the JSON of the record. Checks needing the ledger, such as whether a bond
+//				  already exists, come after and fail on their own. Leases and liens are not recorded on this ledger.
+//
+//				  Text is bounded in length and identifiers and codes, which end up in state and index keys, are
+//				  limited to letters, digits and . _ - so no key separator or control character gets into a key.
+//				  Transactions whose arguments and transient data exceed max_payload_size of the configuration are
+//				  rejected before they reach any function, see check_payload_size.
+//==============================================================================================================================
+
+const FIELD_REQUIRED = "REQUIRED"
+const FIELD_INVALID_FORMAT = "INVALID_FORMAT"
+const FIELD_OUT_OF_RANGE = "OUT_OF_RANGE"
+const FIELD_UNKNOWN_REFERENCE = "UNKNOWN_REFERENCE" // e.g. a district that is not registered
+const FIELD_INVALID_TYPE = "INVALID_TYPE"
+const FIELD_UNKNOWN = "UNKNOWN_FIELD"
+
+// Ten digits, starting with 1 for citizens and 2 for residents
+var NATIONAL_ID = regexp.MustCompile(`^[12][0-9]{9}$`)
+
+// Bond IDs, realEstateIDs are checked against REAL_ESTATE_ID
+var IDENTIFIER = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z._-]*$`)
+
+// The codes of cities and districts
+var CODE = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+const MAX_ID_LENGTH = 64
+const MAX_CODE_LENGTH = 32
+const MAX_NAME_LENGTH = 128 // names of cities and districts, streets
+const MAX_AREA_LENGTH = 32
+const MAX_BOUNDARY_LENGTH = 65536
+
+// Default of max_payload_size, in bytes
+const MAX_PAYLOAD_SIZE = 1048576
+
+//==============================================================================================================================
+//	 Field_Error - A problem with a field of a record. Field is empty for a record that is not a JSON object.
+//==============================================================================================================================
+type Field_Error struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//==============================================================================================================================
+//	 Field_Errors - The problems found with a record, none when it is valid.
+//==============================================================================================================================
+type Field_Errors []Field_Error
+
+func (e *Field_Errors) add(field string, code string, message string) {
+	*e = append(*e, Field_Error{Field: field, Code: code, Message: message})
+}
+
+//==============================================================================================================================
+//	 required - Adds a REQUIRED problem when the value is empty. Returns whether it is set, so further checks can be
+//				skipped for a missing field.
+//==============================================================================================================================
+func (e *Field_Errors) required(field string, value string) bool {
+
+	if strings.TrimSpace(value) == "" {
+		e.add(field, FIELD_REQUIRED, "required")
+		return false
+	}
+
+	return true
+}
+
+//==============================================================================================================================
+//	 to_error - Returns the INVALID_ARGUMENT error listing the problems, nil when there are none.
+//==============================================================================================================================
+func (e Field_Errors) to_error(function string, record string) error {
+
+	if len(e) == 0 {
+		return nil
+	}
+
+	listing, err := json.Marshal(e)
+
+	if err != nil {
+		return wrap_error(CODE_ERROR, function+": Error converting field errors", err)
+	}
+
+	return coded_error(CODE_INVALID_ARGUMENT, function+": Invalid "+record+": "+string(listing))
+}
+
+//==============================================================================================================================
+//	 check_number - Checks the value is a number within min..max.
+//==============================================================================================================================
+func (e *Field_Errors) check_number(field string, value string, min float64, max float64) {
+
+	v, err := strconv.ParseFloat(value, 64)
+
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid number "+value)
+		return
+	}
+
+	if v < min || v > max {
+		e.add(field, FIELD_OUT_OF_RANGE, "Out of range "+value+", expecting "+strconv.FormatFloat(min, 'f', -1, 64)+" to "+strconv.FormatFloat(max, 'f', -1, 64))
+	}
+}
+
+//==============================================================================================================================
+//	 check_length - Adds an OUT_OF_RANGE problem when the value is longer than max bytes. Returns whether it is not.
+//==============================================================================================================================
+func (e *Field_Errors) check_length(field string, value string, max int) bool {
+
+	if len(value) > max {
+		e.add(field, FIELD_OUT_OF_RANGE, "Longer than "+strconv.Itoa(max)+" characters")
+		return false
+	}
+
+	return true
+}
+
+//==============================================================================================================================
+//	 check_identifier - Checks a required identifier or code is within max characters of the pattern.
+//==============================================================================================================================
+func (e *Field_Errors) check_identifier(field string, value string, max int, pattern *regexp.Regexp) {
+
+	if e.required(field, value) && e.check_length(field, value, max) && !pattern.MatchString(value) {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid "+value+", expecting letters, digits and "+pattern_punctuation(pattern))
+	}
+}
+
+//==============================================================================================================================
+//	 check_text - Checks free text is within max characters and holds no control characters. Any script is allowed.
+//==============================================================================================================================
+func (e *Field_Errors) check_text(field string, value string, max int) {
+
+	if !e.check_length(field, value, max) {
+		return
+	}
+
+	if !utf8.ValidString(value) {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid UTF-8")
+		return
+	}
+
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			e.add(field, FIELD_INVALID_FORMAT, "Invalid character "+strconv.QuoteRune(r))
+			return
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 check_street - Checks a street name is text that can be used in the address index.
+//==============================================================================================================================
+func (e *Field_Errors) check_street(field string, street string) {
+
+	if _, err := parse_street(street); err != nil {
+		e.add(field, FIELD_INVALID_FORMAT, err.Error())
+		return
+	}
+
+	e.check_text(field, street, MAX_NAME_LENGTH)
+}
+
+//==============================================================================================================================
+//	 pattern_punctuation - Returns the punctuation the pattern allows, for messages.
+//==============================================================================================================================
+func pattern_punctuation(pattern *regexp.Regexp) string {
+
+	if pattern == IDENTIFIER {
+		return ". _ -"
+	}
+
+	return "_ -"
+}
+
+//==============================================================================================================================
+//	 check_status - Checks the status is one of BOND_STATUSES.
+//==============================================================================================================================
+func (e *Field_Errors) check_status(field string, status string) {
+
+	if !e.required(field, status) {
+		return
+	}
+
+	if _, err := parse_status(status); err != nil {
+		e.add(field, FIELD_INVALID_FORMAT, err.Error())
+	}
+}
+
+//==============================================================================================================================
+//	 check_national_id - Checks the national ID of an owner.
+//==============================================================================================================================
+func (e *Field_Errors) check_national_id(field string, nationalID string) {
+
+	if e.required(field, nationalID) && !NATIONAL_ID.MatchString(nationalID) {
+		e.add(field, FIELD_INVALID_FORMAT, "Invalid national ID "+nationalID+", expecting 10 digits starting with 1 or 2")
+	}
+}
+
+//==============================================================================================================================
+//	 validate_bond - Checks the create_bond arguments. The owner may be given in the transient map instead.
+//==============================================================================================================================
+func (t *SimpleChaincode) validate_bond(stub shim.ChaincodeStubInterface, args []string) Field_Errors {
+
+	var problems Field_Errors
+
+	if args[0] != "" {
+		problems.check_identifier("id", args[0], MAX_ID_LENGTH, IDENTIFIER)
+	}
+
+	problems.check_real_estate_id("real_estate_id", args[1])
+
+	if owner, err := arg_or_transient(stub, args, 2, TRANSIENT_OWNER); err != nil {
+		problems.add("owner_national_id", FIELD_REQUIRED, "required, unless passed as the transient "+TRANSIENT_OWNER)
+	} else {
+		problems.check_owner_id("owner_national_id", owner, "owner_id_type", optional_value(args, 11))
+	}
+
+	problems.check_status("status", args[3])
+
+	if args[4] != "" && problems.check_length("area", args[4], MAX_AREA_LENGTH) {
+		if _, err := parse_area(args[4]); err != nil {
+			problems.add("area", FIELD_INVALID_FORMAT, err.Error())
+		}
+	}
+
+	before := len(problems)
+
+	problems.check_number("longitude", args[5], -180, 180)
+	problems.check_number("latitude", args[6], -90, 90)
+
+	if len(problems) == before {
+		if c, err := parse_coordinates(args[5], args[6]); err == nil {
+			if err = t.check_geofence(stub, c); err != nil {
+				problems.add("longitude", FIELD_OUT_OF_RANGE, err.Error())
+				problems.add("latitude", FIELD_OUT_OF_RANGE, err.Error())
+			}
+		}
+	}
+
+	if len(args) > 7 && args[7] != "" && problems.check_length("boundary", args[7], MAX_BOUNDARY_LENGTH) {
+		if _, err := parse_boundary(args[7]); err != nil {
+			problems.add("boundary", FIELD_INVALID_FORMAT, err.Error())
+		}
+	}
+
+	if len(args) > 8 && args[8] != "" && problems.check_length("district_code", args[8], MAX_CODE_LENGTH) {
+		if _, err := t.lookup_district(stub, args[8]); err != nil {
+			problems.add("district_code", FIELD_UNKNOWN_REFERENCE, err.Error())
+		}
+	}
+
+	if len(args) > 10 {
+		problems.check_street("street", args[10])
+	}
+
+	return problems
+}
+
+//==============================================================================================================================
+//	 validate_reference - Checks the code and name of a city or district.
+//==============================================================================================================================
+func validate_reference(code string, name string) Field_Errors {
+
+	var problems Field_Errors
+
+	problems.check_identifier("code", code, MAX_CODE_LENGTH, CODE)
+
+	if problems.required("name", name) {
+		problems.check_text("name", name, MAX_NAME_LENGTH)
+	}
+
+	return problems
+}
+
+//==============================================================================================================================
+//	 check_payload_size - Rejects a transaction whose function name, arguments and transient data together exceed
+//						  max_payload_size bytes.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_payload_size(stub shim.ChaincodeStubInterface) error {
+
+	c, err := t.load_config(stub)
+
+	if err != nil {
+		return err
+	}
+
+	size := 0
+
+	for _, arg := range stub.GetArgs() {
+		size += len(arg)
+	}
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		log_errorf(stub, "CHECK_PAYLOAD_SIZE: Error reading transient data: %s", err)
+		return wrap_error(CODE_LEDGER_ERROR, "Error reading transient data", err)
+	}
+
+	for name, value := range transient {
+		size += len(name) + len(value)
+	}
+
+	if size > c.MaxPayloadSize {
+		return coded_error(CODE_INVALID_ARGUMENT, "Payload of "+strconv.Itoa(size)+" bytes exceeds max_payload_size of "+strconv.Itoa(c.MaxPayloadSize))
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 validate_transfer - Checks the new owner of a transfer, against the rules of their ID type, and the value they
+//						 declared, which may be left empty.
+//==============================================================================================================================
+func validate_transfer(recipient string, recipientType string, declaredValue string) Field_Errors {
+
+	var problems Field_Errors
+
+	problems.check_owner_id("recipient_national_id", recipient, "recipient_id_type", recipientType)
+
+	if declaredValue != "" {
+		problems.check_number("declared_value", declaredValue, 0, math.MaxFloat64)
+	}
+
+	return problems
+}
+
+//==============================================================================================================================
+//	 validate_document - Checks a document to be attached to a bond. The sha256 is expected in lower case.
+//==============================================================================================================================
+func validate_document(docType string, sha256 string, uri string) Field_Errors {
+
+	var problems Field_Errors
+
+	if problems.required("doc_type", docType) && !DOCUMENT_TYPE.MatchString(docType) {
+		problems.add("doc_type", FIELD_INVALID_FORMAT, "Invalid document type "+docType+", expecting lower case letters, digits and _")
+	}
+
+	if problems.required("sha256", sha256) && !SHA256_HEX.MatchString(sha256) {
+		problems.add("sha256", FIELD_INVALID_FORMAT, "Invalid sha256 "+sha256+", expecting 64 hex digits")
+	}
+
+	if !problems.required("uri", uri) {
+		return problems
+	}
+
+	if len(uri) > MAX_DOCUMENT_URI_LENGTH {
+		problems.add("uri", FIELD_OUT_OF_RANGE, "The uri is longer than "+strconv.Itoa(MAX_DOCUMENT_URI_LENGTH)+" characters")
+	} else if content_id, _, ipfs := ipfs_uri(uri); ipfs {
+		if _, err := parse_ipfs_cid(content_id); err != nil {
+			problems.add("uri", FIELD_INVALID_FORMAT, "Invalid IPFS CID "+content_id+": "+err.Error())
+		}
+	} else if u, err := url.Parse(uri); err != nil || u.Scheme == "" {
+		problems.add("uri", FIELD_INVALID_FORMAT, "Invalid uri "+uri)
+	}
+
+	return problems
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Deed Verification - A printed deed carries a QR code of a verification payload: the realEstateID, the hash of the
+//						 bond and of its owner when it was issued, and the time, signed with HMAC-SHA256. Scanning it
+//						 and passing it to verify_verification_payload tells whether the deed is genuine and whether the
+//						 bond or its owner has changed since.
+//
+//		<base64url of the payload JSON>.<base64url of its HMAC>
+//
+//						 The signing keys are kept in the registrySecrets private data collection, so only peers of
+//						 the regulator can issue or verify payloads and every one of them signs alike. Keys are
+//						 stored by ID and never removed; set_verification_key makes a new key current and payloads
+//						 issued with earlier keys still verify. The owner is hashed with the key, as a national ID has
+//						 too few digits for a plain hash to hide it.
+//==============================================================================================================================
+
+const SECRETS_COLLECTION = "registrySecrets"
+
+const VERIFICATION_KEY_PREFIX = "verification_key"
+
+// Public key holding the ID of the current verification key
+const VERIFICATION_KEY_CURRENT = "verification_key_current"
+
+// Transient map field carrying a new verification key
+const TRANSIENT_VERIFICATION_KEY = "verification_key"
+
+const MIN_VERIFICATION_KEY_LENGTH = 32
+
+const VERIFICATION_PAYLOAD_VERSION = 1
+
+// Results of verify_verification_payload
+const VERIFICATION_VALID = "valid"           // signed by the registry and the bond is unchanged
+const VERIFICATION_SUPERSEDED = "superseded" // signed by the registry but the bond has changed since
+const VERIFICATION_INVALID = "invalid"       // not issued by the registry, or altered
+
+//==============================================================================================================================
+//	 Verification_Payload - The signed content of a QR code, with short field names to keep the code small.
+//==============================================================================================================================
+type Verification_Payload struct {
+	Version      int    `json:"v"`
+	RealEstateID string `json:"id"`
+	BondHash     string `json:"bh"` // see bond_hash
+	OwnerHash    string `json:"oh"` // see owner_hash
+	IssuedAt     string `json:"ts"`
+	KeyID        string `json:"k"`
+}
+
+//==============================================================================================================================
+//	 Issued_Verification - The response of get_verification_payload.
+//==============================================================================================================================
+type Issued_Verification struct {
+	Payload      string `json:"payload"`
+	RealEstateID string `json:"real_estate_id"`
+	IssuedAt     string `json:"issued_at"`
+	KeyID        string `json:"key_id"`
+}
+
+//==============================================================================================================================
+//	 Verification_Result - The response of verify_verification_payload. The bond and owner are only compared for a
+//						   payload with a valid signature.
+//==============================================================================================================================
+type Verification_Result struct {
+	Result       string `json:"result"`
+	Reason       string `json:"reason,omitempty"`
+	RealEstateID string `json:"real_estate_id,omitempty"`
+	IssuedAt     string `json:"issued_at,omitempty"`
+	BondCurrent  bool   `json:"bond_current"`
+	OwnerCurrent bool   `json:"owner_current"`
+}
+
+//==============================================================================================================================
+//	 set_verification_key - Stores the key passed in the transient map and makes it current. Returns its ID, the first
+//							16 hex digits of its sha256. Admin only.
+//==============================================================================================================================
+func (t *SimpleChaincode) set_verification_key(stub shim.ChaincodeStubInterface) ([]byte, error) {
+
+	err := t.check_admin(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, found, err := transient_field(stub, TRANSIENT_VERIFICATION_KEY)
+
+	if err != nil {
+		return nil, prefix_error("SET_VERIFICATION_KEY", err)
+	}
+
+	if !found || len(key) < MIN_VERIFICATION_KEY_LENGTH {
+		return nil, coded_error(CODE_INVALID_ARGUMENT, "SET_VERIFICATION_KEY: Expecting a key of at least 32 bytes as "+TRANSIENT_VERIFICATION_KEY+" in the transient map")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:8])
+
+	err = stub.PutPrivateData(SECRETS_COLLECTION, index_key(VERIFICATION_KEY_PREFIX, id), []byte(key))
+
+	if err != nil {
+		log_errorf(stub, "SET_VERIFICATION_KEY: Error storing key: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "SET_VERIFICATION_KEY: Error storing key "+id, err)
+	}
+
+	err = stub.PutState(VERIFICATION_KEY_CURRENT, []byte(id))
+
+	if err != nil {
+		log_errorf(stub, "SET_VERIFICATION_KEY: Error storing current key ID: %s", err)
+		return nil, wrap_error(CODE_LEDGER_ERROR, "SET_VERIFICATION_KEY: Error storing current key ID "+VERIFICATION_KEY_CURRENT, err)
+	}
+
+	return []byte(id), nil
+}
+
+//==============================================================================================================================
+//	 verification_key - Returns the verification key of the ID given, or of the current key when the ID is empty.
+//==============================================================================================================================
+func (t *SimpleChaincode) verification_key(stub shim.ChaincodeStubInterface, id string) (string, []byte, error) {
+
+	if id == "" {
+
+		current, err := stub.GetState(VERIFICATION_KEY_CURRENT)
+
+		if err != nil {
+			log_errorf(stub, "VERIFICATION_KEY: Error reading current key ID: %s", err)
+			return "", nil, wrap_error(CODE_LEDGER_ERROR, "Error reading current verification key ID "+VERIFICATION_KEY_CURRENT, err)
+		}
+
+		if current == nil {
+			return "", nil, coded_error(CODE_INVALID_STATE, "No verification key has been set")
+		}
+
+		id = string(current)
+	}
+
+	key, err := stub.GetPrivateData(SECRETS_COLLECTION, index_key(VERIFICATION_KEY_PREFIX, id))
+
+	if err != nil {
+		log_errorf(stub, "VERIFICATION_KEY: Error reading key %s: %s", id, err)
+		return "", nil, wrap_error(CODE_LEDGER_ERROR, "Error reading verification key "+index_key(VERIFICATION_KEY_PREFIX, id), err)
+	}
+
+	if key == nil {
+		return "", nil, coded_error(CODE_INVALID_STATE, "Verification key "+id+" is not available on this peer")
+	}
+
+	return id, key, nil
+}
+
+//==============================================================================================================================
+//	 owner_hash - Returns the keyed hash of the owner's national ID.
+//==============================================================================================================================
+func owner_hash(key []byte, nationalID string) string {
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("owner:" + nationalID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//==============================================================================================================================
+//	 payload_mac - Returns the HMAC of the encoded payload.
+//==============================================================================================================================
+func payload_mac(key []byte, encoded string) []byte {
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+
+	return mac.Sum(nil)
+}
+
+//=================================================================================================================================
+//	 get_verification_payload - Returns the signed verification payload of the bond as it is now.
+//=================================================================================================================================
+func (t *SimpleChaincode) get_verification_payload(stub shim.ChaincodeStubInterface, realEstateID string) ([]byte, error) {
+
+	b, err := t.retrieve_bond(stub, realEstateID)
+
+	if err != nil {
+		return nil, prefix_error("GET_VERIFICATION_PAYLOAD", err)
+	}
+
+	id, key, err := t.verification_key(stub, "")
+
+	if err != nil {
+		return nil, prefix_error("GET_VERIFICATION_PAYLOAD", err)
+	}
+
+	hash, err := bond_hash(b)
+
+	if err != nil {
+		return nil, prefix_error("GET_VERIFICATION_PAYLOAD", err)
+	}
+
+	now, err := tx_time(stub)
+
+	if err != nil {
+		return nil, prefix_error("GET_VERIFICATION_PAYLOAD", err)
+	}
+
+	p := Verification_Payload{
+		Version:      VERIFICATION_PAYLOAD_VERSION,
+		RealEstateID: b.RealEstateID,
+		BondHash:     hex.EncodeToString(hash),
+		OwnerHash:    owner_hash(key, b.OwnerNationalID),
+		IssuedAt:     now.Format(TIME_LAYOUT),
+		KeyID:        id,
+	}
+
+	bytes, err := json.Marshal(p)
+
+	if err != nil {
+		log_errorf(stub, "GET_VERIFICATION_PAYLOAD: Error converting payload: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_VERIFICATION_PAYLOAD: Error converting payload", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(bytes)
+	signed := encoded + "." + base64.RawURLEncoding.EncodeToString(payload_mac(key, encoded))
+
+	bytes, err = json.Marshal(Issued_Verification{Payload: signed, RealEstateID: b.RealEstateID, IssuedAt: p.IssuedAt, KeyID: id})
+
+	if err != nil {
+		log_errorf(stub, "GET_VERIFICATION_PAYLOAD: Error converting response: %s", err)
+		return nil, wrap_error(CODE_ERROR, "GET_VERIFICATION_PAYLOAD: Error converting response", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 verify_verification_payload - Checks the payload was issued by the registry and compares it with the bond as it
+//								   is now. A payload that does not verify is reported as invalid, not as an error.
+//=================================================================================================================================
+func (t *SimpleChaincode) verify_verification_payload(stub shim.ChaincodeStubInterface, signed string) ([]byte, error) {
+
+	r, err := t.check_verification_payload(stub, signed)
+
+	if err != nil {
+		return nil, prefix_error("VERIFY_VERIFICATION_PAYLOAD", err)
+	}
+
+	bytes, err := json.Marshal(r)
+
+	if err != nil {
+		log_errorf(stub, "VERIFY_VERIFICATION_PAYLOAD: Error converting result: %s", err)
+		return nil, wrap_error(CODE_ERROR, "VERIFY_VERIFICATION_PAYLOAD: Error converting result", err)
+	}
+
+	return bytes, nil
+}
+
+//=================================================================================================================================
+//	 check_verification_payload - Returns the result of verifying the payload. An error is only returned when the peer
+//								  cannot tell, such as a peer without the keys.
+//=================================================================================================================================
+func (t *SimpleChaincode) check_verification_payload(stub shim.ChaincodeStubInterface, signed string) (Verification_Result, error) {
+
+	r := Verification_Result{Result: VERIFICATION_INVALID}
+
+	parts := strings.Split(strings.TrimSpace(signed), ".")
+
+	if len(parts) != 2 {
+		r.Reason = "malformed payload"
+		return r, nil
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	mac, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+
+	var p Verification_Payload
+
+	if err != nil || err2 != nil || json.Unmarshal(bytes, &p) != nil || p.Version != VERIFICATION_PAYLOAD_VERSION || p.KeyID == "" {
+		r.Reason = "malformed payload"
+		return r, nil
+	}
+
+	_, key, err := t.verification_key(stub, p.KeyID)
+
+	if error_code(err) == CODE_INVALID_STATE {
+		if _, _, current := t.verification_key(stub, ""); current == nil {
+			r.Reason = "unknown signing key" // this peer holds the keys, none has the ID
+			return r, nil
+		}
+	}
+
+	if err != nil {
+		return r, err
+	}
+
+	if !hmac.Equal(mac, payload_mac(key, parts[0])) {
+		r.Reason = "signature does not match"
+		return r, nil
+	}
+
+	r.RealEstateID, r.IssuedAt = p.RealEstateID, p.IssuedAt
+
+	b, found, err := t.get_stored_bond(stub, p.RealEstateID)
+
+	if err != nil {
+		return r, err
+	}
+
+	r.Result = VERIFICATION_SUPERSEDED
+
+	if !found {
+		r.Reason = "bond no longer exists"
+		return r, nil
+	}
+
+	hash, err := bond_hash(upgrade_bond(b))
+
+	if err != nil {
+		return r, err
+	}
+
+	r.BondCurrent = hex.EncodeToString(hash) == p.BondHash
+	r.OwnerCurrent = owner_hash(key, b.OwnerNationalID) == p.OwnerHash
+
+	switch {
+	case r.BondCurrent:
+		r.Result = VERIFICATION_VALID
+	case r.OwnerCurrent:
+		r.Reason = "bond changed since the payload was issued"
+	default:
+		r.Reason = "bond transferred since the payload was issued"
+	}
+
+	return r, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//==============================================================================================================================
+//	 Bond Versions - Every save counts up the version of the bond, so a client that read version 4 can pass
+//					 expected_version 4 in the transient map of its change and have it rejected with VERSION_CONFLICT
+//					 if someone else changed the bond in the meantime, instead of silently overwriting their change.
+//					 The check is optional unless the strict_versioning feature is enabled. Bonds saved before
+//					 versions were counted are at version 0.
+//==============================================================================================================================
+
+const TRANSIENT_EXPECTED_VERSION = "expected_version"
+
+//==============================================================================================================================
+//	 check_version - Returns an error if the caller expects another version of the bond than the one stored.
+//==============================================================================================================================
+func (t *SimpleChaincode) check_version(stub shim.ChaincodeStubInterface, b Bond) error {
+
+	value, found, err := transient_field(stub, TRANSIENT_EXPECTED_VERSION)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+
+		c, err := t.load_config(stub)
+
+		if err != nil {
+			return err
+		}
+
+		if c.Features[FEATURE_STRICT_VERSIONING] {
+			return coded_error(CODE_INVALID_ARGUMENT, "Missing "+TRANSIENT_EXPECTED_VERSION+" of bond "+b.RealEstateID)
+		}
+
+		return nil
+	}
+
+	expected, err := strconv.Atoi(value)
+
+	if err != nil || expected < 0 {
+		return coded_error(CODE_INVALID_ARGUMENT, "Invalid "+TRANSIENT_EXPECTED_VERSION+" "+value)
+	}
+
+	if expected != b.Version {
+		return coded_error(CODE_VERSION_CONFLICT, "Bond "+b.RealEstateID+" is at version "+strconv.Itoa(b.Version)+", not "+value)
+	}
+
+	return nil
+}